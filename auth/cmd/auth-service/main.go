@@ -1,17 +1,27 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"time"
+	_ "time/tzdata" // user timezones must load without zoneinfo in the container
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/apperr"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/avatars"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/clients"
 	"github.com/VerSysLabTin23/TodolistProject/auth/internal/events"
 	"github.com/VerSysLabTin23/TodolistProject/auth/internal/handlers"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/keys"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/lockout"
 	"github.com/VerSysLabTin23/TodolistProject/auth/internal/middleware"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/oidc"
 	"github.com/VerSysLabTin23/TodolistProject/auth/internal/repository"
 	"github.com/VerSysLabTin23/TodolistProject/auth/internal/service"
 )
@@ -33,44 +43,252 @@ func main() {
 	// Schema is managed by dbmate migrations; avoid automatic schema changes here.
 
 	userRepo := repository.NewUserRepository(db)
-	authService := service.NewAuthService(userRepo)
+	refreshRepo := repository.NewRefreshTokenRepository(db)
+	oneTimeRepo := repository.NewOneTimeTokenRepository(db)
+	hasher, err := service.NewPasswordHasherFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid password hashing configuration: %v", err)
+	}
+	// Retired signing keys stay published for as long as the longest-lived token they signed
+	_, refreshTTL := service.TokenTTLsFromEnv()
+	keyManager, err := keys.NewManagerFromEnv(refreshTTL)
+	if err != nil {
+		log.Fatalf("Failed to load JWT signing keys: %v", err)
+	}
+	go keyManager.Run(nil)
+	mfaCfg, err := service.MFAConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid 2FA configuration: %v", err)
+	}
+	repos := service.Repositories{
+		Users:          userRepo,
+		RefreshTokens:  refreshRepo,
+		OneTimeTokens:  oneTimeRepo,
+		RecoveryCodes:  repository.NewMFARecoveryCodeRepository(db),
+		Settings:       repository.NewSettingsRepository(db),
+		Roles:          repository.NewRoleRepository(db),
+		Identities:     repository.NewIdentityRepository(db),
+		OIDCStates:     repository.NewOIDCStateRepository(db),
+		OAuthClients:   repository.NewOAuthClientRepository(db),
+		OAuthConsents:  repository.NewOAuthConsentRepository(db),
+		OAuthCodes:     repository.NewOAuthCodeRepository(db),
+		PersonalTokens: repository.NewPersonalAccessTokenRepository(db),
+		Sessions:       repository.NewSessionRepository(db),
+		Erasures:       repository.NewAccountErasureRepository(db),
+		Exports:        repository.NewDataExportRepository(db),
+		Impersonations: repository.NewImpersonationRepository(db),
+		Imports:        repository.NewUserImportRepository(db),
+		SCIMGroups:     repository.NewSCIMGroupRepository(db),
+	}
+	lockoutCfg, err := lockout.ConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid login lockout configuration: %v", err)
+	}
+	// Failed-login counters are in memory by default; share them between replicas via the database
+	var counters lockout.Store
+	var loginFailures repository.LoginFailureRepository
+	switch store := getEnv("LOGIN_COUNTER_STORE", "memory"); store {
+	case "memory":
+		counters = lockout.NewMemoryStore()
+	case "database":
+		loginFailures = repository.NewLoginFailureRepository(db)
+		counters = loginFailures
+	default:
+		log.Fatalf("Invalid LOGIN_COUNTER_STORE %q (use memory or database)", store)
+	}
+	providers, err := oidc.ProvidersFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid OIDC provider configuration: %v", err)
+	}
+	authService := service.NewAuthService(repos, hasher, keyManager, mfaCfg, lockout.NewGuard(lockoutCfg, counters), providers)
+	serviceClient := clients.NewServiceClient()
+	authService.SetExportSources(serviceClient)
+	authService.SetTeamDirectory(serviceClient)
+	avatarCfg, err := avatars.ConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid avatar configuration: %v", err)
+	}
+	avatarStore, err := avatars.NewLocalStore(avatarCfg.Dir)
+	if err != nil {
+		log.Fatalf("Failed to open avatar directory: %v", err)
+	}
+	authService.SetAvatarStore(avatarStore, avatarCfg.MaxBytes)
+	go purgeExpiredTokens(refreshRepo, oneTimeRepo, repos.OIDCStates, repos.OAuthCodes, repos.Sessions, repos.Exports, loginFailures)
 	producer := events.NewKafkaProducer()
 	h := handlers.NewAuthHandlers(authService, userRepo, producer)
+	authService.OnAccountLocked(h.PublishUserLocked)
+	authService.OnTokensRevoked(h.PublishTokensRevoked)
+	authService.OnSessionRevoked(h.PublishSessionRevoked)
+	authService.OnErasureRequested(h.PublishErasureRequested)
+	authService.OnAccountErased(h.PublishAccountErased)
+	authService.OnImpersonation(h.PublishImpersonation)
+	// Deleted accounts are erased once their grace period is over; services report back
+	go processErasures(authService)
+	go events.ConsumeErasureReports(context.Background(), authService.CompleteErasureStep)
+	authService.OnDataExportReady(h.PublishExportReady)
+	go processDataExports(authService)
+	authService.OnUserImported(h.PublishUserImported)
+	go processUserImports(authService)
 	jwt := middleware.NewJWTMiddleware(authService)
 
-	r := gin.Default()
+	// Every request gets an ID that is logged and returned in error responses
+	r := gin.New()
+	r.Use(apperr.RequestID(), gin.LoggerWithFormatter(apperr.LogFormatter), gin.CustomRecovery(apperr.Recovered))
+	r.NoRoute(apperr.NoRoute)
 
 	r.GET("/healthz", h.HealthCheck)
 
-	// JWT validation for other services
+	// Public signing keys so other services can verify tokens without calling /validate
+	r.GET("/.well-known/jwks.json", h.JWKS)
+
+	// Avatar thumbnails; keys are unguessable and change with every upload
+	r.GET("/avatars/:key", h.GetAvatar)
+
+	// JWT validation for other services; only access tokens and personal access tokens
+	// are accepted. Tokens issued to OAuth clients report their clientId and scope, and
+	// personal access tokens (tokenType "personal") their scope, which the caller must enforce.
+	// Impersonation tokens are flagged with impersonated and the admin's impersonatorId.
 	r.POST("/validate", jwt.RequireAuth(), func(c *gin.Context) {
 		userID, _ := middleware.GetUserIDFromContext(c)
 		username, _ := middleware.GetUsernameFromContext(c)
 		role, _ := middleware.GetUserRoleFromContext(c)
-		c.JSON(200, gin.H{"valid": true, "user": gin.H{"id": userID, "username": username, "role": role}})
+		permissions := middleware.GetPermissionsFromContext(c)
+		impersonatorID := middleware.GetImpersonatorIDFromContext(c)
+		c.JSON(200, gin.H{"valid": true, "tokenType": c.GetString("tokenType"), "restricted": c.GetBool("restricted"), "clientId": c.GetString("clientID"), "scope": c.GetString("scope"),
+			"impersonated": impersonatorID != 0, "impersonatorId": impersonatorID, "user": gin.H{"id": userID, "username": username, "role": role, "permissions": permissions, "timezone": c.GetString("timezone")}})
 	})
 
-	// Internal service endpoint for getting user info (no auth required for simplicity in dev)
-	r.GET("/internal/users/:id", h.GetUser)
+	// OAuth 2.0 token endpoint: backend services exchange SERVICE_CLIENTS secrets for
+	// service tokens; registered clients use the authorization code, refresh token or
+	// client credentials grants. Introspection is for resource servers and clients.
+	r.POST("/oauth/token", h.Token)
+	r.POST("/oauth/introspect", h.Introspect)
+
+	// Consent and client registration for signed-in users. The frontend's consent page
+	// forwards the authorization request here and follows the returned redirect.
+	firstParty := jwt.RequireFirstParty()
+	// Impersonation tokens cannot change credentials, 2FA or OAuth grants of the user
+	realUser := jwt.RequireRealUser()
+	oauth := r.Group("/oauth", jwt.RequireAuth(), firstParty, realUser, jwt.RequireVerifiedEmail())
+	{
+		oauth.GET("/authorize", h.Authorize)
+		oauth.POST("/authorize", h.AuthorizeDecision)
+		oauth.GET("/clients", h.ListOAuthClients)
+		oauth.POST("/clients", h.CreateOAuthClient)
+		oauth.DELETE("/clients/:clientId", h.DeleteOAuthClient) // Owner or admin
+	}
+
+	// Internal service endpoints; only callers with a valid service token are accepted
+	internal := r.Group("/internal", jwt.RequireService())
+	{
+		internal.GET("/users/:id", h.GetInternalUser)
+	}
 
 	auth := r.Group("/auth")
 	{
 		auth.POST("/register", h.Register)
 		auth.POST("/login", h.Login)
+		// Second login step when 2FA is enabled or required; limited against code guessing
+		mfaLimit := middleware.NewRateLimiter(10, 5*time.Minute).Limit()
+		auth.POST("/login/mfa", mfaLimit, h.LoginMFA)
+		auth.POST("/login/mfa/enroll", mfaLimit, h.LoginMFAEnroll)
 		auth.POST("/refresh", h.Refresh)
 		auth.POST("/logout", h.Logout)
+		auth.POST("/verify-email", h.VerifyEmail)
+		// Endpoints that send email or redeem emailed tokens are rate limited per client IP
+		emailLimit := middleware.NewRateLimiter(5, 15*time.Minute).Limit()
+		auth.POST("/resend-verification", emailLimit, h.ResendVerification)
+		auth.POST("/forgot-password", emailLimit, h.ForgotPassword)
+		auth.POST("/reset-password", emailLimit, h.ResetPassword)
+		// Sign-in with external OpenID Connect providers (OIDC_PROVIDERS)
+		auth.GET("/oidc/providers", h.OIDCProviders)
+		auth.GET("/oidc/:provider/login", h.OIDCLogin)
+		auth.GET("/oidc/:provider/callback", h.OIDCCallback)
 	}
 
-	users := r.Group("/users", jwt.RequireAuth())
+	// Authorization is enforced per handler by the policy package, based on the
+	// permissions of the caller's role. Users with an
+	// unverified email (restricted tokens) can only reach their own profile. Tokens
+	// issued to OAuth clients and personal access tokens are not accepted here.
+	verified := jwt.RequireVerifiedEmail()
+	users := r.Group("/users", jwt.RequireAuth(), firstParty)
 	{
-		users.GET("", h.ListUsers)   // Admin should be enforced upstream if needed
-		users.POST("", h.CreateUser) // Admin only (can add jwt.RequireAdmin())
-		users.GET(":id", h.GetUser)  // Self or admin (enforce inside handler or via middleware)
-		users.PUT(":id", h.UpdateUser)
-		users.DELETE(":id", h.DeleteUser)
+		users.GET("", verified, h.ListUsers)                                   // users.read
+		users.GET("/search", verified, h.SearchUsers)                          // Any user; public fields only
+		users.POST("", verified, h.CreateUser)                                 // users.write; roles other than user need roles.manage
+		users.POST("/import", verified, h.ImportUsers)                         // users.write, as CreateUser for every role; ?dryRun=true only validates
+		users.GET("/import/:importId", verified, h.GetUserImport)              // users.write
+		users.GET(":id", verified, h.GetUser)                                  // Self or users.read
+		users.PUT(":id", verified, h.UpdateUser)                               // Self or users.write; role needs roles.manage, isActive users.write
+		users.DELETE(":id", verified, h.DeleteUser)                            // users.delete, not self
+		users.DELETE(":id/mfa", verified, h.ResetUserMFA)                      // users.write
+		users.POST(":id/unlock", verified, h.UnlockUser)                       // users.write
+		users.POST(":id/restore", verified, h.RestoreUser)                     // users.delete
+		users.GET(":id/erasure", verified, h.GetUserErasure)                   // users.read
+		users.GET(":id/sessions", verified, h.ListUserSessions)                // Self or users.write
+		users.DELETE(":id/sessions", verified, h.RevokeUserSessions)           // Self or users.write
+		users.DELETE(":id/sessions/:sessionId", verified, h.RevokeUserSession) // Self or users.write
+		users.POST(":id/impersonate", verified, realUser, h.Impersonate)       // users.impersonate
+		users.GET(":id/impersonations", verified, h.ListImpersonations)        // users.read
 		users.GET("/profile", h.GetProfile)
 		users.PUT("/profile", h.UpdateProfile)
-		users.POST("/change-password", h.ChangePassword)
+		users.DELETE("/profile", realUser, h.DeleteOwnAccount)
+		users.PUT("/profile/avatar", h.UploadAvatar)
+		users.DELETE("/profile/avatar", h.DeleteAvatar)
+		users.POST("/change-password", realUser, h.ChangePassword)
+		users.POST("/profile/export", h.RequestDataExport)
+		users.GET("/profile/export/:exportId", h.GetDataExport)
+		users.GET("/profile/export/:exportId/download", h.DownloadDataExport)
+		users.GET("/profile/identities", h.ListIdentities)
+		users.GET("/profile/sessions", h.ListSessions)
+		users.DELETE("/profile/sessions/:sessionId", h.RevokeSession)
+		users.GET("/profile/authorizations", h.ListAuthorizations)
+		users.DELETE("/profile/authorizations/:clientId", h.RevokeAuthorization)
+		users.GET("/profile/tokens", h.ListPersonalAccessTokens)
+		users.POST("/profile/tokens", realUser, h.CreatePersonalAccessToken)
+		users.DELETE("/profile/tokens/:id", h.DeletePersonalAccessToken)
+		users.POST("/profile/mfa/enroll", realUser, h.EnrollMFA)
+		users.POST("/profile/mfa/confirm", realUser, h.ConfirmMFA)
+		users.DELETE("/profile/mfa", realUser, h.DisableMFA)
+		users.POST("/profile/mfa/recovery-codes", realUser, h.RegenerateRecoveryCodes)
+	}
+
+	// SCIM 2.0 provisioning for external directories. Callers need users.write and either
+	// a first-party token or a personal access token with the scim scope. Groups are
+	// backed by teams in the team service, owned by the caller.
+	scimAPI := r.Group("/scim/v2", jwt.RequireAuth(), jwt.RequireScope(models.ScopeSCIM), verified, realUser, jwt.RequirePermission(models.PermUsersWrite))
+	{
+		scimAPI.GET("/ServiceProviderConfig", h.SCIMServiceProviderConfig)
+		scimAPI.GET("/ResourceTypes", h.SCIMResourceTypes)
+		scimAPI.GET("/Users", h.ListSCIMUsers)
+		scimAPI.POST("/Users", h.CreateSCIMUser)
+		scimAPI.GET("/Users/:id", h.GetSCIMUser)
+		scimAPI.PUT("/Users/:id", h.ReplaceSCIMUser)
+		scimAPI.PATCH("/Users/:id", h.PatchSCIMUser)
+		scimAPI.DELETE("/Users/:id", h.DeleteSCIMUser) // users.delete
+		scimAPI.GET("/Groups", h.ListSCIMGroups)
+		scimAPI.POST("/Groups", h.CreateSCIMGroup)
+		scimAPI.GET("/Groups/:id", h.GetSCIMGroup)
+		scimAPI.PUT("/Groups/:id", h.ReplaceSCIMGroup)
+		scimAPI.PATCH("/Groups/:id", h.PatchSCIMGroup)
+		scimAPI.DELETE("/Groups/:id", h.DeleteSCIMGroup)
+	}
+
+	settings := r.Group("/settings", jwt.RequireAuth(), firstParty, verified, jwt.RequirePermission(models.PermSettingsManage))
+	{
+		settings.GET("/mfa", h.GetMFAPolicy)
+		settings.PUT("/mfa", h.SetMFAPolicy)
+	}
+
+	// Roles and the permissions they grant; users are assigned a role with PUT /users/:id
+	roles := r.Group("/roles", jwt.RequireAuth(), firstParty, verified, jwt.RequirePermission(models.PermRolesManage))
+	{
+		roles.GET("", h.ListRoles)
+		roles.POST("", h.CreateRole)
+		roles.GET("/permissions", h.ListPermissions)
+		roles.GET("/:name", h.GetRole)
+		roles.PUT("/:name", h.UpdateRole)
+		roles.DELETE("/:name", h.DeleteRole)
 	}
 
 	log.Printf("Auth Service starting on port %s", port)
@@ -79,6 +297,75 @@ func main() {
 	}
 }
 
+// purgeExpiredTokens periodically deletes refresh and one-time tokens that can no longer
+// be used, abandoned OIDC logins, unredeemed authorization codes, expired sessions and
+// data exports, and expired login failure counters when they are kept in the database
+func purgeExpiredTokens(refresh repository.RefreshTokenRepository, oneTime repository.OneTimeTokenRepository, oidcStates repository.OIDCStateRepository, oauthCodes repository.OAuthCodeRepository, sessions repository.SessionRepository, exports repository.DataExportRepository, loginFailures repository.LoginFailureRepository) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		now := time.Now().UTC()
+		if n, err := refresh.DeleteExpired(now); err != nil {
+			log.Printf("Failed to purge expired refresh tokens: %v", err)
+		} else if n > 0 {
+			log.Printf("Purged %d expired refresh tokens", n)
+		}
+		if n, err := oneTime.DeleteExpired(now); err != nil {
+			log.Printf("Failed to purge expired one-time tokens: %v", err)
+		} else if n > 0 {
+			log.Printf("Purged %d expired one-time tokens", n)
+		}
+		if _, err := oidcStates.DeleteExpired(now); err != nil {
+			log.Printf("Failed to purge expired OIDC login states: %v", err)
+		}
+		if _, err := oauthCodes.DeleteExpired(now); err != nil {
+			log.Printf("Failed to purge expired authorization codes: %v", err)
+		}
+		if _, err := sessions.DeleteExpired(now); err != nil {
+			log.Printf("Failed to purge expired sessions: %v", err)
+		}
+		if _, err := exports.DeleteExpired(now); err != nil {
+			log.Printf("Failed to purge expired data exports: %v", err)
+		}
+		if loginFailures == nil {
+			continue
+		}
+		if _, err := loginFailures.DeleteExpired(now); err != nil {
+			log.Printf("Failed to purge expired login failure counters: %v", err)
+		}
+	}
+}
+
+// processErasures regularly starts the erasure of accounts whose grace period is over
+// and asks services that have not reported back again
+func processErasures(authService *service.AuthService) {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		authService.ProcessErasures(time.Now().UTC())
+	}
+}
+
+// processDataExports picks up data exports that were not started right away or whose
+// instance stopped while running them
+func processDataExports(authService *service.AuthService) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		authService.ProcessDataExports(time.Now().UTC())
+	}
+}
+
+// processUserImports picks up bulk user imports that were not started right away,
+// reports interrupted ones and drops old reports
+func processUserImports(authService *service.AuthService) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		authService.ProcessUserImports(time.Now().UTC())
+	}
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
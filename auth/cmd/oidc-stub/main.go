@@ -0,0 +1,207 @@
+// Command oidc-stub is a minimal OpenID Connect provider for local development and
+// tests/test_oidc_login.sh. It signs in whoever is named in the login_hint parameter
+// without asking for a password. Never expose it outside a development machine.
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type pendingCode struct {
+	clientID    string
+	redirectURI string
+	challenge   string
+	nonce       string
+	email       string
+	expiresAt   time.Time
+}
+
+type stub struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	key          *rsa.PrivateKey
+
+	mu     sync.Mutex
+	codes  map[string]pendingCode
+	tokens map[string]string // access token -> email
+}
+
+func main() {
+	port := getEnv("PORT", "9999")
+	s := &stub{
+		issuer:       getEnv("STUB_ISSUER", "http://localhost:"+port),
+		clientID:     getEnv("STUB_CLIENT_ID", "todo-app"),
+		clientSecret: getEnv("STUB_CLIENT_SECRET", "stub-secret"),
+		codes:        map[string]pendingCode{},
+		tokens:       map[string]string{},
+	}
+	var err error
+	if s.key, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		log.Fatalf("Failed to generate key: %v", err)
+	}
+
+	http.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	http.HandleFunc("/jwks", s.jwks)
+	http.HandleFunc("/authorize", s.authorize)
+	http.HandleFunc("/token", s.token)
+	http.HandleFunc("/userinfo", s.userinfo)
+
+	log.Printf("OIDC stub provider %s (client %s) listening on port %s", s.issuer, s.clientID, port)
+	log.Fatal(http.ListenAndServe(":"+port, nil))
+}
+
+func (s *stub) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.issuer,
+		"authorization_endpoint":                s.issuer + "/authorize",
+		"token_endpoint":                        s.issuer + "/token",
+		"userinfo_endpoint":                     s.issuer + "/userinfo",
+		"jwks_uri":                              s.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (s *stub) jwks(w http.ResponseWriter, r *http.Request) {
+	b64 := base64.RawURLEncoding
+	writeJSON(w, http.StatusOK, map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA", "kid": "stub", "use": "sig", "alg": "RS256",
+		"n": b64.EncodeToString(s.key.N.Bytes()),
+		"e": b64.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+	}}})
+}
+
+// authorize approves every request immediately and redirects back with a code
+func (s *stub) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != s.clientID || q.Get("response_type") != "code" || q.Get("redirect_uri") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+	email := q.Get("login_hint")
+	if email == "" {
+		email = "stub.user@example.com"
+	}
+	code := randomHex(16)
+	s.mu.Lock()
+	s.codes[code] = pendingCode{clientID: s.clientID, redirectURI: q.Get("redirect_uri"), challenge: q.Get("code_challenge"), nonce: q.Get("nonce"), email: email, expiresAt: time.Now().Add(time.Minute)}
+	s.mu.Unlock()
+
+	target, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	back := target.Query()
+	back.Set("code", code)
+	back.Set("state", q.Get("state"))
+	target.RawQuery = back.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+func (s *stub) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	id, secret, ok := r.BasicAuth()
+	if ok {
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	}
+	if id != s.clientID || subtle.ConstantTimeCompare([]byte(secret), []byte(s.clientSecret)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	code := r.PostForm.Get("code")
+	s.mu.Lock()
+	pending, found := s.codes[code]
+	delete(s.codes, code)
+	s.mu.Unlock()
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !found || time.Now().After(pending.expiresAt) || pending.redirectURI != r.PostForm.Get("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != pending.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	local := strings.SplitN(pending.email, "@", 2)[0]
+	claims := jwt.MapClaims{
+		"iss": s.issuer, "aud": s.clientID, "sub": subject(pending.email),
+		"iat": now.Unix(), "exp": now.Add(5 * time.Minute).Unix(), "nonce": pending.nonce,
+		"email": pending.email, "email_verified": true,
+		"preferred_username": local, "given_name": "Stub", "family_name": "User",
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "stub"
+	idToken, err := token.SignedString(s.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	accessToken := randomHex(16)
+	s.mu.Lock()
+	s.tokens[accessToken] = pending.email
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"access_token": accessToken, "token_type": "Bearer", "expires_in": 300, "id_token": idToken})
+}
+
+func (s *stub) userinfo(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	email, ok := s.tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+	s.mu.Unlock()
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"sub": subject(email), "email": email, "email_verified": true})
+}
+
+// subject derives a stable, opaque subject from the email like real providers do
+func subject(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(email)))
+	return hex.EncodeToString(sum[:10])
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,93 @@
+package apperr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Report fields by their JSON (or query) name rather than the Go field name
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			for _, tag := range []string{"json", "form"} {
+				name := strings.SplitN(f.Tag.Get(tag), ",", 2)[0]
+				if name == "-" {
+					return ""
+				}
+				if name != "" {
+					return name
+				}
+			}
+			return f.Name
+		})
+	}
+}
+
+// Validation turns an error from ShouldBindJSON or ShouldBindQuery into a 400 problem
+// that lists every offending field
+func Validation(err error) *Error {
+	e := &Error{Kind: Invalid, Code: "VALIDATION_FAILED", Message: "The request is invalid"}
+	var fieldErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &fieldErrs):
+		for _, fe := range fieldErrs {
+			e.Fields = append(e.Fields, FieldError{Field: fieldPath(fe), Message: fieldMessage(fe)})
+		}
+	case errors.As(err, &typeErr):
+		e.Fields = []FieldError{{Field: typeErr.Field, Message: "must be of type " + typeErr.Type.String()}}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		e.Message = "The request body is not valid JSON"
+	}
+	return e
+}
+
+// fieldPath drops the request struct's name from the namespace, e.g.
+// "CreateTaskRequest.labels[0]" becomes "labels[0]"
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.Index(ns, "."); i >= 0 {
+		return ns[i+1:]
+	}
+	return fe.Field()
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	unit := ""
+	switch fe.Kind() {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = " items"
+	}
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "url", "http_url":
+		return "must be a valid URL"
+	case "min", "gte":
+		return fmt.Sprintf("must be at least %s%s", fe.Param(), unit)
+	case "max", "lte":
+		return fmt.Sprintf("must be at most %s%s", fe.Param(), unit)
+	case "gt":
+		return fmt.Sprintf("must be greater than %s%s", fe.Param(), unit)
+	case "lt":
+		return fmt.Sprintf("must be less than %s%s", fe.Param(), unit)
+	case "len":
+		return fmt.Sprintf("must be exactly %s%s", fe.Param(), unit)
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(fe.Param()), ", ")
+	default:
+		return "failed the " + fe.Tag() + " check"
+	}
+}
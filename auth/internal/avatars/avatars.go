@@ -0,0 +1,154 @@
+// Package avatars turns uploaded profile pictures into fixed-size square thumbnails and
+// keeps them in a pluggable Store. The content type is sniffed from the data, not taken
+// from the upload, and only PNG, JPEG and GIF are accepted. Thumbnails are always
+// re-encoded as PNG, so nothing of the original file (e.g. EXIF metadata) is served.
+package avatars
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // register decoders for image.Decode
+	_ "image/jpeg"
+	"image/png"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/apperr"
+)
+
+// Sizes are the edge lengths in pixels of the thumbnails generated for every avatar
+var Sizes = []int{256, 128, 64}
+
+// DefaultSize is served when no size is requested
+const DefaultSize = 256
+
+// maxPixels bounds the decoded image size so small files cannot expand into huge bitmaps
+const maxPixels = 4096 * 4096
+
+// Config controls uploads
+type Config struct {
+	MaxBytes int64  // largest accepted upload (AVATAR_MAX_BYTES, default 5 MiB)
+	Dir      string // directory of the local store (AVATAR_DIR, default ./data/avatars)
+}
+
+// ConfigFromEnv returns the avatar settings with their defaults
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{MaxBytes: 5 << 20, Dir: "./data/avatars"}
+	if s := os.Getenv("AVATAR_MAX_BYTES"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n <= 0 {
+			return cfg, fmt.Errorf("invalid AVATAR_MAX_BYTES %q", s)
+		}
+		cfg.MaxBytes = n
+	}
+	if s := os.Getenv("AVATAR_DIR"); s != "" {
+		cfg.Dir = s
+	}
+	return cfg, nil
+}
+
+// Store keeps the thumbnails of an avatar under a key
+type Store interface {
+	Save(key string, thumbnails map[int][]byte) error
+	Open(key string, size int) ([]byte, error)
+	Delete(key string) error
+}
+
+// ValidSize reports whether thumbnails of size are generated
+func ValidSize(size int) bool {
+	for _, s := range Sizes {
+		if s == size {
+			return true
+		}
+	}
+	return false
+}
+
+// Errors returned by Thumbnails for uploads that cannot be used as an avatar
+var (
+	ErrUnsupportedType = apperr.New(apperr.UnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE", "Avatars must be PNG, JPEG or GIF images")
+	ErrInvalidImage    = apperr.New(apperr.Invalid, "INVALID_IMAGE", "The image could not be decoded")
+	ErrImageTooLarge   = apperr.New(apperr.TooLarge, "AVATAR_TOO_LARGE", "The image may have at most 4096x4096 pixels")
+)
+
+// Thumbnails decodes an uploaded image, crops it to a centered square and returns a
+// PNG for every entry of Sizes
+func Thumbnails(data []byte) (map[int][]byte, error) {
+	switch http.DetectContentType(data) {
+	case "image/png", "image/jpeg", "image/gif":
+	default:
+		return nil, ErrUnsupportedType
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrImageTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	square := cropSquare(img)
+	thumbnails := make(map[int][]byte, len(Sizes))
+	for _, size := range Sizes {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, resize(square, size)); err != nil {
+			return nil, err
+		}
+		thumbnails[size] = buf.Bytes()
+	}
+	return thumbnails, nil
+}
+
+// cropSquare copies the centered square of img into an RGBA image
+func cropSquare(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	edge := b.Dx()
+	if b.Dy() < edge {
+		edge = b.Dy()
+	}
+	origin := image.Pt(b.Min.X+(b.Dx()-edge)/2, b.Min.Y+(b.Dy()-edge)/2)
+	square := image.NewRGBA(image.Rect(0, 0, edge, edge))
+	draw.Draw(square, square.Bounds(), img, origin, draw.Src)
+	return square
+}
+
+// resize scales a square image to size x size. Every target pixel is the average of
+// the source pixels it covers (box filter); smaller images are scaled up by repeating
+// pixels. Averaging premultiplied RGBA keeps transparent edges clean.
+func resize(src *image.RGBA, size int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	edge := src.Bounds().Dx()
+	for y := 0; y < size; y++ {
+		y0, y1 := y*edge/size, (y+1)*edge/size
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < size; x++ {
+			x0, x1 := x*edge/size, (x+1)*edge/size
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					for c := 0; c < 4; c++ {
+						sum[c] += int(row[sx*4+c])
+					}
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			i := y*dst.Stride + x*4
+			for c := 0; c < 4; c++ {
+				dst.Pix[i+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,61 @@
+package avatars
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// keyPattern matches the random hex keys the auth service assigns; anything else could
+// escape the store's directory
+var keyPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// LocalStore keeps thumbnails on the local filesystem as <dir>/<key>/<size>.png. With
+// several replicas dir has to be a shared volume.
+type LocalStore struct {
+	dir string
+}
+
+// NewLocalStore creates dir if needed and returns a store writing into it
+func NewLocalStore(dir string) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalStore{dir: dir}, nil
+}
+
+// Save writes all thumbnails of an avatar; a partly written avatar is removed again
+func (s *LocalStore) Save(key string, thumbnails map[int][]byte) error {
+	if !keyPattern.MatchString(key) {
+		return errors.New("invalid avatar key")
+	}
+	dir := filepath.Join(s.dir, key)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for size, data := range thumbnails {
+		if err := os.WriteFile(filepath.Join(dir, strconv.Itoa(size)+".png"), data, 0o644); err != nil {
+			os.RemoveAll(dir)
+			return err
+		}
+	}
+	return nil
+}
+
+// Open returns one thumbnail; os.ErrNotExist is returned for unknown keys or sizes
+func (s *LocalStore) Open(key string, size int) ([]byte, error) {
+	if !keyPattern.MatchString(key) || !ValidSize(size) {
+		return nil, os.ErrNotExist
+	}
+	return os.ReadFile(filepath.Join(s.dir, key, strconv.Itoa(size)+".png"))
+}
+
+// Delete removes all thumbnails of an avatar
+func (s *LocalStore) Delete(key string) error {
+	if !keyPattern.MatchString(key) {
+		return errors.New("invalid avatar key")
+	}
+	return os.RemoveAll(filepath.Join(s.dir, key))
+}
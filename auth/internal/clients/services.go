@@ -0,0 +1,155 @@
+package clients
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ServiceClient reads a user's data from the team and task services' /internal routes
+// and manages the teams backing SCIM groups, authenticating with a service token issued
+// by this service
+type ServiceClient struct {
+	teamURL    string
+	taskURL    string
+	httpClient *http.Client
+}
+
+// NewServiceClient creates a client for TEAM_SERVICE_URL and TASK_SERVICE_URL
+func NewServiceClient() *ServiceClient {
+	teamURL := os.Getenv("TEAM_SERVICE_URL")
+	if teamURL == "" {
+		teamURL = "http://localhost:8083" // fallback for local development
+	}
+	taskURL := os.Getenv("TASK_SERVICE_URL")
+	if taskURL == "" {
+		taskURL = "http://localhost:8081"
+	}
+	return &ServiceClient{
+		teamURL: teamURL,
+		taskURL: taskURL,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// UserMemberships returns the teams a user belongs to, as JSON from the team service
+func (sc *ServiceClient) UserMemberships(userID int, serviceToken string) (json.RawMessage, error) {
+	return sc.get(fmt.Sprintf("%s/internal/users/%d/memberships", sc.teamURL, userID), serviceToken)
+}
+
+// UserTasks returns the tasks a user created or is assigned to, as JSON from the task service
+func (sc *ServiceClient) UserTasks(userID int, serviceToken string) (json.RawMessage, error) {
+	return sc.get(fmt.Sprintf("%s/internal/users/%d/tasks", sc.taskURL, userID), serviceToken)
+}
+
+// CreateTeam creates a team owned by ownerID and returns its ID
+func (sc *ServiceClient) CreateTeam(name string, ownerID, actorID int, serviceToken string) (int, error) {
+	body, err := sc.send("POST", sc.teamURL+"/internal/teams", serviceToken, actorID, map[string]interface{}{"name": name, "ownerId": ownerID}, http.StatusCreated)
+	if err != nil {
+		return 0, err
+	}
+	var team struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(body, &team); err != nil || team.ID == 0 {
+		return 0, fmt.Errorf("team service returned an invalid team")
+	}
+	return team.ID, nil
+}
+
+// RenameTeam changes the name of a team
+func (sc *ServiceClient) RenameTeam(teamID int, name string, actorID int, serviceToken string) error {
+	_, err := sc.send("PUT", fmt.Sprintf("%s/internal/teams/%d", sc.teamURL, teamID), serviceToken, actorID, map[string]interface{}{"name": name}, http.StatusOK)
+	return err
+}
+
+// DeleteTeam deletes a team with its memberships; deleting a missing team succeeds
+func (sc *ServiceClient) DeleteTeam(teamID, actorID int, serviceToken string) error {
+	_, err := sc.send("DELETE", fmt.Sprintf("%s/internal/teams/%d", sc.teamURL, teamID), serviceToken, actorID, nil, http.StatusNoContent)
+	return err
+}
+
+// AddTeamMember makes a user a member of a team unless they already belong to it
+func (sc *ServiceClient) AddTeamMember(teamID, userID, actorID int, serviceToken string) error {
+	_, err := sc.send("PUT", fmt.Sprintf("%s/internal/teams/%d/members/%d", sc.teamURL, teamID, userID), serviceToken, actorID, nil, http.StatusOK, http.StatusCreated)
+	return err
+}
+
+// RemoveTeamMember removes a user from a team. The team's owner is kept (409), which
+// is not an error.
+func (sc *ServiceClient) RemoveTeamMember(teamID, userID, actorID int, serviceToken string) error {
+	_, err := sc.send("DELETE", fmt.Sprintf("%s/internal/teams/%d/members/%d", sc.teamURL, teamID, userID), serviceToken, actorID, nil, http.StatusNoContent, http.StatusConflict)
+	return err
+}
+
+// send makes a request on behalf of actorID (X-Actor-Id) and returns the response body
+// if its status is one of ok
+func (sc *ServiceClient) send(method, url, serviceToken string, actorID int, payload interface{}, ok ...int) ([]byte, error) {
+	var reqBody io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, url, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+serviceToken)
+	req.Header.Set("X-Actor-Id", strconv.Itoa(actorID))
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := sc.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	for _, status := range ok {
+		if resp.StatusCode == status {
+			return body, nil
+		}
+	}
+	return nil, fmt.Errorf("%s %s returned status: %d", method, url, resp.StatusCode)
+}
+
+func (sc *ServiceClient) get(url, serviceToken string) (json.RawMessage, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+serviceToken)
+
+	resp, err := sc.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned status: %d", url, resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("%s returned invalid JSON", url)
+	}
+	return body, nil
+}
@@ -0,0 +1,69 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ErasureCompletedTopic carries the reports of services that erased a user's data
+const ErasureCompletedTopic = "user.erasure_completed"
+
+// erasureCompletedEvent is a service's report on user.erasure_completed
+type erasureCompletedEvent struct {
+	UserID  int    `json:"userId"`
+	Service string `json:"service"`
+}
+
+// ConsumeErasureReports calls handle for every user.erasure_completed event until ctx
+// is cancelled. All instances share one consumer group; an event is only committed
+// after handle succeeded.
+func ConsumeErasureReports(ctx context.Context, handle func(userID int, service string) error) {
+	brokers := os.Getenv("KAFKA_BROKERS")
+	if brokers == "" {
+		brokers = "dev_kafka:9092"
+	}
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: []string{brokers},
+		Topic:   ErasureCompletedTopic,
+		GroupID: "auth-service",
+		MaxWait: 1 * time.Second,
+	})
+	defer r.Close()
+
+	for {
+		m, err := r.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Kafka read error on topic %s: %v", ErasureCompletedTopic, err)
+			time.Sleep(time.Second)
+			continue
+		}
+		var evt erasureCompletedEvent
+		if err := json.Unmarshal(m.Value, &evt); err != nil || evt.UserID == 0 || evt.Service == "" {
+			log.Printf("Ignoring malformed %s event: %s", ErasureCompletedTopic, string(m.Value))
+		} else {
+			for backoff := time.Second; ; backoff = min(2*backoff, time.Minute) {
+				err := handle(evt.UserID, evt.Service)
+				if err == nil {
+					break
+				}
+				log.Printf("Failed to record erasure of user %d by %s, retrying in %s: %v", evt.UserID, evt.Service, backoff, err)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(backoff):
+				}
+			}
+		}
+		if err := r.CommitMessages(ctx, m); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to commit %s offset: %v", ErasureCompletedTopic, err)
+		}
+	}
+}
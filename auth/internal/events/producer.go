@@ -14,10 +14,15 @@ type KafkaProducer struct {
 }
 
 type UserEvent struct {
-	EventType string      `json:"eventType"`
-	UserID    int         `json:"userId"`
-	Timestamp time.Time   `json:"timestamp"`
-	Payload   interface{} `json:"payload,omitempty"`
+	EventType string `json:"eventType"`
+	UserID    int    `json:"userId"`
+	// ActorID is the user who made the change; lifecycle events only
+	ActorID int `json:"actorId,omitempty"`
+	// ImpersonatorID is the admin who acted through an impersonation token; the
+	// request's ActorID is then the impersonated user
+	ImpersonatorID int         `json:"impersonatorId,omitempty"`
+	Timestamp      time.Time   `json:"timestamp"`
+	Payload        interface{} `json:"payload,omitempty"`
 }
 
 func NewKafkaProducer() *KafkaProducer {
@@ -41,10 +46,24 @@ func (p *KafkaProducer) Close() error {
 	return p.writer.Close()
 }
 
+type impersonatorKey struct{}
+
+// WithImpersonator marks events published with ctx as caused by an impersonation
+// session of impersonatorID; 0 leaves ctx unchanged
+func WithImpersonator(ctx context.Context, impersonatorID int) context.Context {
+	if impersonatorID == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, impersonatorKey{}, impersonatorID)
+}
+
 func (p *KafkaProducer) publish(ctx context.Context, topic string, evt UserEvent) error {
 	if p == nil || p.writer == nil {
 		return nil
 	}
+	if id, ok := ctx.Value(impersonatorKey{}).(int); ok && evt.ImpersonatorID == 0 {
+		evt.ImpersonatorID = id
+	}
 	b, err := json.Marshal(evt)
 	if err != nil {
 		return err
@@ -69,6 +88,193 @@ func (p *KafkaProducer) UserCreated(ctx context.Context, userID int, email, user
 	})
 }
 
+// VerificationRequested asks the notification service to email a verification link.
+// Like the other emailed events it carries the user's timezone for formatting times.
+func (p *KafkaProducer) VerificationRequested(ctx context.Context, userID int, email, username, timezone, link string, expiresAt time.Time) error {
+	return p.publish(ctx, "user.verification_requested", UserEvent{
+		EventType: "user.verification_requested",
+		UserID:    userID,
+		Timestamp: time.Now(),
+		Payload: map[string]interface{}{
+			"email":            email,
+			"username":         username,
+			"timezone":         timezone,
+			"verificationLink": link,
+			"expiresAt":        expiresAt.UTC(),
+		},
+	})
+}
+
+// PasswordResetRequested asks the notification service to email a password reset link
+func (p *KafkaProducer) PasswordResetRequested(ctx context.Context, userID int, email, username, timezone, link string, expiresAt time.Time) error {
+	return p.publish(ctx, "user.password_reset_requested", UserEvent{
+		EventType: "user.password_reset_requested",
+		UserID:    userID,
+		Timestamp: time.Now(),
+		Payload: map[string]interface{}{
+			"email":     email,
+			"username":  username,
+			"timezone":  timezone,
+			"resetLink": link,
+			"expiresAt": expiresAt.UTC(),
+		},
+	})
+}
+
+// ExportReady tells the account owner that their data export can be downloaded
+func (p *KafkaProducer) ExportReady(ctx context.Context, userID int, email, username, timezone, exportID, link string, expiresAt time.Time) error {
+	return p.publish(ctx, "user.export_ready", UserEvent{
+		EventType: "user.export_ready",
+		UserID:    userID,
+		Timestamp: time.Now(),
+		Payload: map[string]interface{}{
+			"email":      email,
+			"username":   username,
+			"timezone":   timezone,
+			"exportId":   exportID,
+			"exportLink": link,
+			"expiresAt":  expiresAt.UTC(),
+		},
+	})
+}
+
+// UserLocked tells the account owner that repeated failed logins locked their account
+func (p *KafkaProducer) UserLocked(ctx context.Context, userID int, email, username, timezone string, lockedUntil time.Time, ipAddress string) error {
+	return p.publish(ctx, "user.locked", UserEvent{
+		EventType: "user.locked",
+		UserID:    userID,
+		Timestamp: time.Now(),
+		Payload: map[string]interface{}{
+			"email":       email,
+			"username":    username,
+			"timezone":    timezone,
+			"lockedUntil": lockedUntil.UTC(),
+			"ipAddress":   ipAddress,
+		},
+	})
+}
+
+// TokensRevoked tells the other services that every token of the user issued before
+// tokenVersion is revoked, so they drop cached validations and open connections
+func (p *KafkaProducer) TokensRevoked(ctx context.Context, userID, tokenVersion int, reason string) error {
+	return p.publish(ctx, "user.tokens_revoked", UserEvent{
+		EventType: "user.tokens_revoked",
+		UserID:    userID,
+		Timestamp: time.Now(),
+		Payload: map[string]interface{}{
+			"tokenVersion": tokenVersion,
+			"reason":       reason,
+		},
+	})
+}
+
+// SessionRevoked tells the other services that the access tokens of one of the user's
+// sessions (claim "sid") are revoked. None of them is valid after until.
+func (p *KafkaProducer) SessionRevoked(ctx context.Context, userID int, sessionID string, until time.Time) error {
+	return p.publish(ctx, "user.session_revoked", UserEvent{
+		EventType: "user.session_revoked",
+		UserID:    userID,
+		Timestamp: time.Now(),
+		Payload: map[string]interface{}{
+			"sessionId": sessionID,
+			"until":     until.UTC(),
+		},
+	})
+}
+
+// changeEvent publishes a lifecycle event whose payload holds the state before and after the change
+func (p *KafkaProducer) changeEvent(ctx context.Context, eventType string, userID, actorID int, before, after interface{}) error {
+	return p.publish(ctx, eventType, UserEvent{
+		EventType: eventType,
+		UserID:    userID,
+		ActorID:   actorID,
+		Timestamp: time.Now(),
+		Payload: map[string]interface{}{
+			"before": before,
+			"after":  after,
+		},
+	})
+}
+
+// UserUpdated announces a change of a user's profile (username, email or name)
+func (p *KafkaProducer) UserUpdated(ctx context.Context, userID, actorID int, before, after interface{}) error {
+	return p.changeEvent(ctx, "user.updated", userID, actorID, before, after)
+}
+
+// UserRoleChanged announces that an admin gave a user another role
+func (p *KafkaProducer) UserRoleChanged(ctx context.Context, userID, actorID int, before, after string) error {
+	return p.changeEvent(ctx, "user.role_changed", userID, actorID, map[string]interface{}{"role": before}, map[string]interface{}{"role": after})
+}
+
+// UserDeactivated announces that a user's account was deactivated
+func (p *KafkaProducer) UserDeactivated(ctx context.Context, userID, actorID int) error {
+	return p.changeEvent(ctx, "user.deactivated", userID, actorID, map[string]interface{}{"isActive": true}, map[string]interface{}{"isActive": false})
+}
+
+// UserReactivated announces that a deactivated account was activated again
+func (p *KafkaProducer) UserReactivated(ctx context.Context, userID, actorID int) error {
+	return p.changeEvent(ctx, "user.reactivated", userID, actorID, map[string]interface{}{"isActive": false}, map[string]interface{}{"isActive": true})
+}
+
+// UserDeleted announces that a user was deleted. Other services remove the user's
+// memberships and assignments. The account is being erased, so the event carries no
+// personal data.
+func (p *KafkaProducer) UserDeleted(ctx context.Context, userID, actorID int) error {
+	return p.publish(ctx, "user.deleted", UserEvent{
+		EventType: "user.deleted",
+		UserID:    userID,
+		ActorID:   actorID,
+		Timestamp: time.Now(),
+	})
+}
+
+// UserRestored announces that a deleted account was restored within its grace period;
+// after is the restored user. user.deleted has not been published for it yet.
+func (p *KafkaProducer) UserRestored(ctx context.Context, userID, actorID int, after interface{}) error {
+	return p.changeEvent(ctx, "user.restored", userID, actorID, nil, after)
+}
+
+// ErasureRequested asks the other services to erase a deleted user's data and to
+// report back on user.erasure_completed
+func (p *KafkaProducer) ErasureRequested(ctx context.Context, userID int) error {
+	return p.publish(ctx, "user.erasure_requested", UserEvent{
+		EventType: "user.erasure_requested",
+		UserID:    userID,
+		Timestamp: time.Now(),
+	})
+}
+
+// UserImpersonated announces that actorID obtained an impersonation token for userID
+func (p *KafkaProducer) UserImpersonated(ctx context.Context, userID, actorID int, reason string, expiresAt time.Time) error {
+	return p.publish(ctx, "user.impersonated", UserEvent{
+		EventType:      "user.impersonated",
+		UserID:         userID,
+		ActorID:        actorID,
+		ImpersonatorID: actorID,
+		Timestamp:      time.Now(),
+		Payload: map[string]interface{}{
+			"reason":    reason,
+			"expiresAt": expiresAt,
+		},
+	})
+}
+
+// PasswordChanged announces a new password, set by the user ("change") or with an
+// emailed link ("reset"), so the owner can be warned if it was not them
+func (p *KafkaProducer) PasswordChanged(ctx context.Context, userID, actorID int, email, username, method string) error {
+	return p.publish(ctx, "user.password_changed", UserEvent{
+		EventType: "user.password_changed",
+		UserID:    userID,
+		ActorID:   actorID,
+		Timestamp: time.Now(),
+		Payload: map[string]interface{}{
+			"email":    email,
+			"username": username,
+			"method":   method,
+		},
+	})
+}
+
 // small itoa to avoid fmt import
 func itoa(i int) string {
 	if i == 0 {
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/apperr"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/policy"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/service"
+)
+
+// DeleteOwnAccount deletes the current user's account. It can be restored by an admin
+// until the grace period ends; then the user's data is erased in every service.
+func (h *AuthHandlers) DeleteOwnAccount(c *gin.Context) {
+	var req models.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Write(c, apperr.Validation(err))
+		return
+	}
+	if denied(c, policy.CanDeleteOwnAccount(actor(c))) {
+		return
+	}
+	userID := c.GetInt("userID")
+	user, err := h.userRepo.GetByID(userID)
+	if err != nil {
+		apperr.Write(c, service.ErrUserNotFound)
+		return
+	}
+	erasure, err := h.authService.DeleteOwnAccount(userID, req)
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	h.publishDeletionScheduled(eventContext(c), user.ToUserResponse(), userID)
+	c.JSON(http.StatusAccepted, erasure.ToResponse())
+}
+
+// RestoreUser undoes the deletion of an account during its grace period (admin only)
+func (h *AuthHandlers) RestoreUser(c *gin.Context) {
+	targetID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "Invalid user ID")
+		return
+	}
+	if denied(c, policy.CanRestoreUser(actor(c))) {
+		return
+	}
+	deleted, err := h.userRepo.GetDeletedByID(targetID)
+	if err != nil {
+		apperr.Write(c, apperr.Wrap(err, "Failed to get user"))
+		return
+	}
+	if deleted == nil {
+		apperr.Write(c, service.ErrUserNotFound)
+		return
+	}
+	if h.deniedTarget(c, deleted) {
+		return
+	}
+	user, err := h.authService.RestoreAccount(targetID)
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	if h.producer != nil {
+		if err := h.producer.UserRestored(eventContext(c), user.ID, c.GetInt("userID"), user.ToUserResponse()); err != nil {
+			log.Printf("Failed to send user.restored event: %v", err)
+		}
+		// Undoes the user.deactivated published on deletion
+		if user.IsActive {
+			if err := h.producer.UserReactivated(eventContext(c), user.ID, c.GetInt("userID")); err != nil {
+				log.Printf("Failed to send user.reactivated event: %v", err)
+			}
+		}
+	}
+	c.JSON(http.StatusOK, user.ToUserResponse())
+}
+
+// GetUserErasure reports the grace period and per-service progress of a deleted
+// account's erasure (admin only)
+func (h *AuthHandlers) GetUserErasure(c *gin.Context) {
+	targetID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "Invalid user ID")
+		return
+	}
+	if denied(c, policy.CanViewErasure(actor(c))) {
+		return
+	}
+	erasure, err := h.authService.AccountErasure(targetID)
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, erasure)
+}
+
+// PublishAccountErased announces a deleted user whose grace period is over as
+// user.deleted; it is registered with AuthService.OnAccountErased
+func (h *AuthHandlers) PublishAccountErased(userID, actorID int) {
+	h.publishUserDeleted(context.Background(), userID, actorID)
+}
+
+// PublishErasureRequested asks the other services to erase a user's data; it is
+// registered with AuthService.OnErasureRequested
+func (h *AuthHandlers) PublishErasureRequested(userID int) {
+	if h.producer == nil {
+		return
+	}
+	if err := h.producer.ErasureRequested(context.Background(), userID); err != nil {
+		log.Printf("Failed to send user.erasure_requested event: %v", err)
+	}
+}
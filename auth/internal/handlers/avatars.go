@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/apperr"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/avatars"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/service"
+)
+
+// multipartOverhead is allowed on top of the image for boundaries and part headers
+const multipartOverhead = 64 << 10
+
+// UploadAvatar replaces the current user's avatar with the image in the multipart
+// field "avatar" (PNG, JPEG or GIF)
+func (h *AuthHandlers) UploadAvatar(c *gin.Context) {
+	maxBytes := h.authService.AvatarMaxBytes()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+multipartOverhead)
+	header, err := c.FormFile("avatar")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		apperr.Respond(c, http.StatusRequestEntityTooLarge, "AVATAR_TOO_LARGE", "The image may have at most "+strconv.FormatInt(maxBytes, 10)+" bytes")
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "Send the image as multipart field \"avatar\" of at most "+strconv.FormatInt(maxBytes, 10)+" bytes")
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "Failed to read the upload")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "Failed to read the upload")
+		return
+	}
+	if int64(len(data)) > maxBytes {
+		apperr.Respond(c, http.StatusRequestEntityTooLarge, "AVATAR_TOO_LARGE", "The image may have at most "+strconv.FormatInt(maxBytes, 10)+" bytes")
+		return
+	}
+	userID := c.GetInt("userID")
+	before, err := h.userRepo.GetByID(userID)
+	if err != nil {
+		apperr.Write(c, service.ErrUserNotFound)
+		return
+	}
+	user, err := h.authService.UploadAvatar(userID, data)
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	h.publishUserChanges(eventContext(c), before.ToUserResponse(), user.ToUserResponse(), userID)
+	c.JSON(http.StatusOK, user.ToUserResponse())
+}
+
+// DeleteAvatar removes the current user's avatar
+func (h *AuthHandlers) DeleteAvatar(c *gin.Context) {
+	userID := c.GetInt("userID")
+	before, err := h.userRepo.GetByID(userID)
+	if err != nil {
+		apperr.Write(c, service.ErrUserNotFound)
+		return
+	}
+	user, err := h.authService.RemoveAvatar(userID)
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	h.publishUserChanges(eventContext(c), before.ToUserResponse(), user.ToUserResponse(), userID)
+	c.JSON(http.StatusOK, user.ToUserResponse())
+}
+
+// GetAvatar serves an avatar thumbnail (?size=64, 128 or 256). Keys are random and
+// change with every upload, so the route is public and responses are cached for good.
+func (h *AuthHandlers) GetAvatar(c *gin.Context) {
+	size := avatars.DefaultSize
+	if s := c.Query("size"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || !avatars.ValidSize(n) {
+			apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "size must be 64, 128 or 256")
+			return
+		}
+		size = n
+	}
+	data, err := h.authService.AvatarThumbnail(c.Param("key"), size)
+	if err != nil {
+		apperr.Write(c, service.ErrAvatarNotFound)
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, "image/png", data)
+}
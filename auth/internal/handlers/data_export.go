@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/apperr"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/service"
+)
+
+// RequestDataExport starts an export of everything the system holds about the current
+// user; the job runs in the background and its status is polled with GetDataExport
+func (h *AuthHandlers) RequestDataExport(c *gin.Context) {
+	export, created, err := h.authService.RequestDataExport(c.GetInt("userID"))
+	if err != nil {
+		apperr.Write(c, apperr.Wrap(err, "Failed to start data export"))
+		return
+	}
+	c.Header("Location", "/users/profile/export/"+export.ID)
+	if !created {
+		// An export is already in progress; report that one instead of starting another
+		c.JSON(http.StatusOK, dataExportResponse(export))
+		return
+	}
+	c.JSON(http.StatusAccepted, dataExportResponse(export))
+}
+
+// GetDataExport reports the status of one of the current user's data exports
+func (h *AuthHandlers) GetDataExport(c *gin.Context) {
+	export, err := h.authService.DataExport(c.GetInt("userID"), c.Param("exportId"))
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dataExportResponse(export))
+}
+
+// DownloadDataExport sends a finished export as a ZIP archive (default) or, with
+// ?format=json, as a single JSON document
+func (h *AuthHandlers) DownloadDataExport(c *gin.Context) {
+	file, err := h.authService.DownloadDataExport(c.GetInt("userID"), c.Param("exportId"), c.Query("format"))
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Disposition", `attachment; filename="`+file.Name+`"`)
+	c.Data(http.StatusOK, file.ContentType, file.Data)
+}
+
+// PublishExportReady tells the notification service that a user's data export can be
+// downloaded; it is registered with AuthService.OnDataExportReady
+func (h *AuthHandlers) PublishExportReady(user *models.User, exportID string, link *service.EmailLink) {
+	if h.producer == nil {
+		return
+	}
+	if err := h.producer.ExportReady(context.Background(), user.ID, user.Email, user.Username, user.Timezone, exportID, link.Link, link.ExpiresAt); err != nil {
+		log.Printf("Failed to send user.export_ready event: %v", err)
+	}
+}
+
+// dataExportResponse adds the download URL to finished exports
+func dataExportResponse(export *models.DataExport) models.DataExportResponse {
+	resp := export.ToResponse()
+	if export.Status == models.DataExportReady {
+		resp.DownloadURL = "/users/profile/export/" + export.ID + "/download"
+	}
+	return resp
+}
@@ -2,14 +2,20 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/apperr"
 	"github.com/VerSysLabTin23/TodolistProject/auth/internal/events"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/lockout"
 	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/policy"
 	"github.com/VerSysLabTin23/TodolistProject/auth/internal/repository"
 	"github.com/VerSysLabTin23/TodolistProject/auth/internal/service"
 )
@@ -27,22 +33,21 @@ func NewAuthHandlers(authService *service.AuthService, userRepo repository.UserR
 
 func (h *AuthHandlers) HealthCheck(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"status": "ok"}) }
 
+// JWKS publishes the token verification keys; clients may cache the response briefly
+func (h *AuthHandlers) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.authService.JWKS())
+}
+
 func (h *AuthHandlers) Register(c *gin.Context) {
 	var req models.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", err.Error()))
+		apperr.Write(c, apperr.Validation(err))
 		return
 	}
 	user, err := h.authService.RegisterUser(req)
 	if err != nil {
-		switch err.Error() {
-		case "username already exists":
-			c.JSON(http.StatusConflict, errResp("CONFLICT", "Username already exists"))
-		case "email already exists":
-			c.JSON(http.StatusConflict, errResp("CONFLICT", "Email already exists"))
-		default:
-			c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "Failed to register user"))
-		}
+		apperr.Write(c, err)
 		return
 	}
 
@@ -57,53 +62,239 @@ func (h *AuthHandlers) Register(c *gin.Context) {
 	} else {
 		log.Printf("Kafka producer is nil, skipping user.created event")
 	}
+	h.sendVerification(user)
 
 	c.JSON(http.StatusCreated, user.ToUserResponse())
 }
 
+// VerifyEmail redeems the single-use token from a verification email
+func (h *AuthHandlers) VerifyEmail(c *gin.Context) {
+	var req models.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Write(c, apperr.Validation(err))
+		return
+	}
+	user, err := h.authService.VerifyEmail(req.Token)
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user.ToUserResponse())
+}
+
+// ResendVerification always answers 202 so the endpoint cannot be used to probe
+// which addresses are registered or verified
+func (h *AuthHandlers) ResendVerification(c *gin.Context) {
+	var req models.ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Write(c, apperr.Validation(err))
+		return
+	}
+	user, v, err := h.authService.ResendVerification(req.Email)
+	if err != nil {
+		log.Printf("Failed to resend verification: %v", err)
+	} else if user != nil {
+		h.publishVerification(user, v)
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the address belongs to an unverified account, a verification email has been sent"})
+}
+
+// ForgotPassword always answers 202 so the endpoint cannot be used to find out
+// whether an account exists for the address
+func (h *AuthHandlers) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Write(c, apperr.Validation(err))
+		return
+	}
+	user, link, err := h.authService.RequestPasswordReset(req.Email)
+	if err != nil {
+		log.Printf("Failed to issue password reset: %v", err)
+	} else if user != nil && h.producer != nil {
+		if err := h.producer.PasswordResetRequested(context.Background(), user.ID, user.Email, user.Username, user.Timezone, link.Link, link.ExpiresAt); err != nil {
+			log.Printf("Failed to send user.password_reset_requested event: %v", err)
+		}
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "If an account exists for this address, a password reset email has been sent"})
+}
+
+// ResetPassword sets a new password with the token from a reset email; all of the
+// user's sessions are revoked
+func (h *AuthHandlers) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Write(c, apperr.Validation(err))
+		return
+	}
+	user, err := h.authService.ResetPassword(req.Token, req.NewPassword)
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	h.publishPasswordChanged(context.Background(), user.ToUserResponse(), user.ID, "reset")
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset; please log in again"})
+}
+
+// sendVerification issues a verification token and publishes user.verification_requested;
+// failures are logged since the user can always ask for a new link
+func (h *AuthHandlers) sendVerification(user *models.User) {
+	v, err := h.authService.IssueEmailVerification(user)
+	if err != nil {
+		log.Printf("Failed to issue email verification for user %d: %v", user.ID, err)
+		return
+	}
+	h.publishVerification(user, v)
+}
+
+// changeEmail sets the user's email to newEmail. A new address has to be verified
+// again, whoever changed it; the caller stores the user and then calls sendVerification
+// if it changed. Reset links go to the email, so it is a credential like the password
+// and cannot be changed while impersonating. It answers the request when ok is false.
+func (h *AuthHandlers) changeEmail(c *gin.Context, user *models.User, newEmail string) (changed, ok bool) {
+	if newEmail == user.Email {
+		return false, true
+	}
+	if c.GetInt("impersonatorID") != 0 {
+		apperr.Respond(c, http.StatusForbidden, "IMPERSONATION_FORBIDDEN", "The email address cannot be changed while impersonating a user")
+		return false, false
+	}
+	if exists, err := h.userRepo.ExistsByEmail(newEmail); err != nil {
+		apperr.Write(c, apperr.Wrap(err, "Failed to check email"))
+		return false, false
+	} else if exists {
+		apperr.Write(c, service.ErrEmailTaken)
+		return false, false
+	}
+	user.Email = newEmail
+	user.EmailVerified = false
+	user.EmailVerifiedAt = nil
+	return true, true
+}
+
+func (h *AuthHandlers) publishVerification(user *models.User, v *service.EmailLink) {
+	if h.producer == nil {
+		return
+	}
+	if err := h.producer.VerificationRequested(context.Background(), user.ID, user.Email, user.Username, user.Timezone, v.Link, v.ExpiresAt); err != nil {
+		log.Printf("Failed to send user.verification_requested event: %v", err)
+	}
+}
+
 func (h *AuthHandlers) Login(c *gin.Context) {
 	var req models.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", err.Error()))
+		apperr.Write(c, apperr.Validation(err))
 		return
 	}
-	resp, err := h.authService.AuthenticateUser(req)
+	resp, challenge, err := h.authService.AuthenticateUser(req, clientInfo(c))
 	if err != nil {
-		switch err.Error() {
-		case "invalid credentials":
-			c.JSON(http.StatusUnauthorized, errResp("UNAUTHORIZED", "Invalid credentials"))
-		case "user account is deactivated":
-			c.JSON(http.StatusUnauthorized, errResp("UNAUTHORIZED", "User account is deactivated"))
-		default:
-			c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "Authentication failed"))
+		if loginThrottled(c, err) {
+			return
 		}
+		apperr.Write(c, err)
+		return
+	}
+	if challenge != nil {
+		// The password was correct but a second factor is needed before tokens are issued
+		c.JSON(http.StatusOK, challenge)
 		return
 	}
 	c.JSON(http.StatusOK, resp)
 }
 
+// PublishUserLocked is registered with the auth service to announce account lockouts
+func (h *AuthHandlers) PublishUserLocked(user *models.User, until time.Time, ip string) {
+	if h.producer == nil {
+		return
+	}
+	if err := h.producer.UserLocked(context.Background(), user.ID, user.Email, user.Username, user.Timezone, until, ip); err != nil {
+		log.Printf("Failed to send user.locked event: %v", err)
+	}
+}
+
+// PublishTokensRevoked is registered with the auth service to tell the other services
+// that a user's tokens were revoked
+func (h *AuthHandlers) PublishTokensRevoked(userID, version int, reason string) {
+	if h.producer == nil {
+		return
+	}
+	if err := h.producer.TokensRevoked(context.Background(), userID, version, reason); err != nil {
+		log.Printf("Failed to send user.tokens_revoked event: %v", err)
+	}
+}
+
+// PublishSessionRevoked is registered with the auth service to tell the other services
+// that one of a user's sessions was ended
+func (h *AuthHandlers) PublishSessionRevoked(userID int, sessionID string, until time.Time) {
+	if h.producer == nil {
+		return
+	}
+	if err := h.producer.SessionRevoked(context.Background(), userID, sessionID, until); err != nil {
+		log.Printf("Failed to send user.session_revoked event: %v", err)
+	}
+}
+
+// UnlockUser lifts a login lock or backoff caused by failed attempts (admin only)
+func (h *AuthHandlers) UnlockUser(c *gin.Context) {
+	targetID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "Invalid user ID")
+		return
+	}
+	if denied(c, policy.CanUnlockUser(actor(c))) {
+		return
+	}
+	user, err := h.userRepo.GetByID(targetID)
+	if err != nil {
+		apperr.Write(c, service.ErrUserNotFound)
+		return
+	}
+	if h.deniedTarget(c, user) {
+		return
+	}
+	if err := h.authService.UnlockUser(targetID); err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
 func (h *AuthHandlers) Refresh(c *gin.Context) {
 	var req models.RefreshRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", err.Error()))
+		apperr.Write(c, apperr.Validation(err))
 		return
 	}
-	resp, err := h.authService.RefreshToken(req.RefreshToken)
+	resp, err := h.authService.RefreshToken(req.RefreshToken, clientInfo(c))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, errResp("UNAUTHORIZED", "Invalid refresh token"))
+		apperr.Write(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, resp)
 }
 
 func (h *AuthHandlers) Logout(c *gin.Context) {
+	var req models.LogoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Write(c, apperr.Validation(err))
+		return
+	}
+	if err := h.authService.Logout(req.RefreshToken, req.AllSessions); err != nil {
+		apperr.Write(c, err)
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Logout successful"})
 }
 
+// ListUsers returns a page of users, filtered and sorted as described by
+// models.UserFilters; nextCursor is set when another page follows
 func (h *AuthHandlers) ListUsers(c *gin.Context) {
+	if denied(c, policy.CanListUsers(actor(c))) {
+		return
+	}
 	var filters models.UserFilters
 	if err := c.ShouldBindQuery(&filters); err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", err.Error()))
+		apperr.Write(c, apperr.Validation(err))
 		return
 	}
 	if filters.Limit <= 0 {
@@ -112,67 +303,140 @@ func (h *AuthHandlers) ListUsers(c *gin.Context) {
 	if filters.Limit > 200 {
 		filters.Limit = 200
 	}
-	if filters.Offset < 0 {
-		filters.Offset = 0
+	order, ok := models.ParseUserSort(filters.Sort)
+	if !ok {
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "sort must be createdAt, username or email, prefixed with - for descending order")
+		return
+	}
+	filters.Order = order
+	if filters.Cursor != "" {
+		if filters.After, ok = models.DecodeUserCursor(filters.Cursor, order); !ok {
+			apperr.Respond(c, http.StatusBadRequest, "INVALID_CURSOR", "The cursor is invalid or was issued for another sort order")
+			return
+		}
 	}
-	users, total, err := h.userRepo.List(filters)
+	users, more, err := h.userRepo.List(filters)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "Failed to list users"))
+		apperr.Write(c, apperr.Wrap(err, "Failed to list users"))
 		return
 	}
 	responses := make([]models.UserResponse, 0, len(users))
 	for _, u := range users {
 		responses = append(responses, u.ToUserResponse())
 	}
-	c.JSON(http.StatusOK, gin.H{"users": responses, "total": total, "limit": filters.Limit, "offset": filters.Offset})
+	page := gin.H{"users": responses, "limit": filters.Limit, "sort": order.String()}
+	if more {
+		page["nextCursor"] = order.CursorAfter(&users[len(users)-1])
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// SearchUsers finds active users by the start of their username or name for
+// typeahead pickers. Any signed-in user may search; only public fields are returned.
+func (h *AuthHandlers) SearchUsers(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if len(query) < 2 {
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "q must have at least 2 characters")
+		return
+	}
+	limit := 10
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > 25 {
+			apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "limit must be between 1 and 25")
+			return
+		}
+		limit = n
+	}
+	users, err := h.userRepo.Search(query, limit)
+	if err != nil {
+		apperr.Write(c, apperr.Wrap(err, "Failed to search users"))
+		return
+	}
+	summaries := make([]models.UserSummary, 0, len(users))
+	for i := range users {
+		summaries = append(summaries, users[i].ToSummary())
+	}
+	c.JSON(http.StatusOK, gin.H{"users": summaries})
 }
 
 func (h *AuthHandlers) CreateUser(c *gin.Context) {
+	if denied(c, policy.CanCreateUser(actor(c))) {
+		return
+	}
 	var req models.CreateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", err.Error()))
+		apperr.Write(c, apperr.Validation(err))
+		return
+	}
+	if denied(c, policy.CanAssignRole(actor(c), req.Role)) || !h.validRole(c, req.Role) || h.deniedRole(c, req.Role) {
 		return
 	}
 	exists, err := h.userRepo.ExistsByUsername(req.Username)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "Failed to check username"))
+		apperr.Write(c, apperr.Wrap(err, "Failed to check username"))
 		return
 	}
 	if exists {
-		c.JSON(http.StatusConflict, errResp("CONFLICT", "Username already exists"))
+		apperr.Write(c, service.ErrUsernameTaken)
 		return
 	}
 	exists, err = h.userRepo.ExistsByEmail(req.Email)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "Failed to check email"))
+		apperr.Write(c, apperr.Wrap(err, "Failed to check email"))
 		return
 	}
 	if exists {
-		c.JSON(http.StatusConflict, errResp("CONFLICT", "Email already exists"))
+		apperr.Write(c, service.ErrEmailTaken)
 		return
 	}
 	hash, err := h.authService.HashPassword(req.Password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "Failed to hash password"))
+		apperr.Write(c, apperr.Wrap(err, "Failed to hash password"))
 		return
 	}
-	newUser := &models.User{Username: req.Username, Email: req.Email, PasswordHash: hash, FirstName: req.FirstName, LastName: req.LastName, Role: req.Role, IsActive: true}
+	// Accounts created by an admin are trusted and skip email verification
+	newUser := &models.User{Username: req.Username, Email: req.Email, PasswordHash: hash, FirstName: req.FirstName, LastName: req.LastName, Role: req.Role, IsActive: true, EmailVerified: true}
 	if err := h.userRepo.Create(newUser); err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "Failed to create user"))
+		apperr.Write(c, apperr.Wrap(err, "Failed to create user"))
 		return
 	}
+	if h.producer != nil {
+		if err := h.producer.UserCreated(eventContext(c), newUser.ID, newUser.Email, newUser.Username); err != nil {
+			log.Printf("Failed to send user.created event: %v", err)
+		}
+	}
 	c.JSON(http.StatusCreated, newUser.ToUserResponse())
 }
 
 func (h *AuthHandlers) GetUser(c *gin.Context) {
 	targetID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "Invalid user ID"))
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "Invalid user ID")
+		return
+	}
+	if denied(c, policy.CanViewUser(actor(c), targetID)) {
+		return
+	}
+	targetUser, err := h.userRepo.GetByID(targetID)
+	if err != nil {
+		apperr.Write(c, service.ErrUserNotFound)
+		return
+	}
+	c.JSON(http.StatusOK, targetUser.ToUserResponse())
+}
+
+// GetInternalUser serves user lookups for other backend services (e.g. notification);
+// the route is guarded by RequireService
+func (h *AuthHandlers) GetInternalUser(c *gin.Context) {
+	targetID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "Invalid user ID")
 		return
 	}
 	targetUser, err := h.userRepo.GetByID(targetID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, errResp("NOT_FOUND", "User not found"))
+		apperr.Write(c, service.ErrUserNotFound)
 		return
 	}
 	c.JSON(http.StatusOK, targetUser.ToUserResponse())
@@ -181,42 +445,47 @@ func (h *AuthHandlers) GetUser(c *gin.Context) {
 func (h *AuthHandlers) UpdateUser(c *gin.Context) {
 	targetID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "Invalid user ID"))
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "Invalid user ID")
 		return
 	}
 	var req models.UpdateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", err.Error()))
+		apperr.Write(c, apperr.Validation(err))
+		return
+	}
+	if denied(c, policy.CanUpdateUser(actor(c), targetID, req)) {
+		return
+	}
+	if req.Role != nil && (!h.validRole(c, *req.Role) || h.deniedRole(c, *req.Role)) {
 		return
 	}
 	user, err := h.userRepo.GetByID(targetID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, errResp("NOT_FOUND", "User not found"))
+		apperr.Write(c, service.ErrUserNotFound)
 		return
 	}
+	if h.deniedTarget(c, user) {
+		return
+	}
+	before := user.ToUserResponse()
 	if req.Username != nil {
 		if *req.Username != user.Username {
 			if exists, err := h.userRepo.ExistsByUsername(*req.Username); err != nil {
-				c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "Failed to check username"))
+				apperr.Write(c, apperr.Wrap(err, "Failed to check username"))
 				return
 			} else if exists {
-				c.JSON(http.StatusConflict, errResp("CONFLICT", "Username already exists"))
+				apperr.Write(c, service.ErrUsernameTaken)
 				return
 			}
 		}
 		user.Username = *req.Username
 	}
+	emailChanged := false
 	if req.Email != nil {
-		if *req.Email != user.Email {
-			if exists, err := h.userRepo.ExistsByEmail(*req.Email); err != nil {
-				c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "Failed to check email"))
-				return
-			} else if exists {
-				c.JSON(http.StatusConflict, errResp("CONFLICT", "Email already exists"))
-				return
-			}
+		var ok bool
+		if emailChanged, ok = h.changeEmail(c, user, *req.Email); !ok {
+			return
 		}
-		user.Email = *req.Email
 	}
 	if req.FirstName != nil {
 		user.FirstName = *req.FirstName
@@ -224,6 +493,7 @@ func (h *AuthHandlers) UpdateUser(c *gin.Context) {
 	if req.LastName != nil {
 		user.LastName = *req.LastName
 	}
+	roleChanged := req.Role != nil && *req.Role != user.Role
 	if req.Role != nil {
 		user.Role = *req.Role
 	}
@@ -231,22 +501,52 @@ func (h *AuthHandlers) UpdateUser(c *gin.Context) {
 		user.IsActive = *req.IsActive
 	}
 	if err := h.userRepo.Update(user); err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "Failed to update user"))
+		apperr.Write(c, apperr.Wrap(err, "Failed to update user"))
 		return
 	}
+	// A deactivated user is signed out everywhere at once, not when their tokens expire.
+	// After a role change, tokens still carrying the old role are revoked as well.
+	if req.IsActive != nil && !*req.IsActive {
+		if err := h.authService.RevokeAllSessions(user.ID, models.RevokeReasonDeactivated); err != nil {
+			apperr.Write(c, apperr.Wrap(err, "User was deactivated but their sessions could not be revoked"))
+			return
+		}
+	} else if roleChanged {
+		if err := h.authService.RevokeAllSessions(user.ID, models.RevokeReasonRoleChanged); err != nil {
+			apperr.Write(c, apperr.Wrap(err, "Role was changed but the user's sessions could not be revoked"))
+			return
+		}
+	}
+	if emailChanged {
+		h.sendVerification(user)
+	}
+	h.publishUserChanges(eventContext(c), before, user.ToUserResponse(), c.GetInt("userID"))
 	c.JSON(http.StatusOK, user.ToUserResponse())
 }
 
 func (h *AuthHandlers) DeleteUser(c *gin.Context) {
 	targetID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "Invalid user ID"))
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "Invalid user ID")
+		return
+	}
+	if denied(c, policy.CanDeleteUser(actor(c), targetID)) {
+		return
+	}
+	user, err := h.userRepo.GetByID(targetID)
+	if err != nil {
+		apperr.Write(c, service.ErrUserNotFound)
+		return
+	}
+	if h.deniedTarget(c, user) {
 		return
 	}
-	if err := h.userRepo.Delete(targetID); err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "Failed to delete user"))
+	// The account is soft-deleted; its data is erased after the grace period
+	if _, err := h.authService.DeleteAccount(targetID, c.GetInt("userID")); err != nil {
+		apperr.Write(c, err)
 		return
 	}
+	h.publishDeletionScheduled(eventContext(c), user.ToUserResponse(), c.GetInt("userID"))
 	c.Status(http.StatusNoContent)
 }
 
@@ -254,7 +554,7 @@ func (h *AuthHandlers) GetProfile(c *gin.Context) {
 	userID := c.GetInt("userID")
 	user, err := h.userRepo.GetByID(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "Failed to get user"))
+		apperr.Write(c, apperr.Wrap(err, "Failed to get user"))
 		return
 	}
 	c.JSON(http.StatusOK, user.ToUserResponse())
@@ -264,36 +564,54 @@ func (h *AuthHandlers) UpdateProfile(c *gin.Context) {
 	userID := c.GetInt("userID")
 	var req models.UpdateProfileRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", err.Error()))
+		apperr.Write(c, apperr.Validation(err))
 		return
 	}
 	user, err := h.userRepo.GetByID(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "Failed to get user"))
+		apperr.Write(c, apperr.Wrap(err, "Failed to get user"))
 		return
 	}
+	before := user.ToUserResponse()
 	if req.FirstName != nil {
 		user.FirstName = *req.FirstName
 	}
 	if req.LastName != nil {
 		user.LastName = *req.LastName
 	}
-	if req.Email != nil {
-		if *req.Email != user.Email {
-			if exists, err := h.userRepo.ExistsByEmail(*req.Email); err != nil {
-				c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "Failed to check email"))
-				return
-			} else if exists {
-				c.JSON(http.StatusConflict, errResp("CONFLICT", "Email already exists"))
+	if req.Locale != nil {
+		user.Locale = ""
+		if *req.Locale != "" {
+			locale, ok := models.NormalizeLocale(*req.Locale)
+			if !ok {
+				apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "locale must be a language tag such as en or de-DE")
 				return
 			}
+			user.Locale = locale
+		}
+	}
+	if req.Timezone != nil {
+		if *req.Timezone != "" && !models.ValidTimezone(*req.Timezone) {
+			apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "timezone must be an IANA timezone such as Europe/Berlin")
+			return
+		}
+		user.Timezone = *req.Timezone
+	}
+	emailChanged := false
+	if req.Email != nil {
+		var ok bool
+		if emailChanged, ok = h.changeEmail(c, user, *req.Email); !ok {
+			return
 		}
-		user.Email = *req.Email
 	}
 	if err := h.userRepo.Update(user); err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "Failed to update profile"))
+		apperr.Write(c, apperr.Wrap(err, "Failed to update profile"))
 		return
 	}
+	if emailChanged {
+		h.sendVerification(user)
+	}
+	h.publishUserChanges(eventContext(c), before, user.ToUserResponse(), userID)
 	c.JSON(http.StatusOK, user.ToUserResponse())
 }
 
@@ -301,21 +619,102 @@ func (h *AuthHandlers) ChangePassword(c *gin.Context) {
 	userID := c.GetInt("userID")
 	var req models.ChangePasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", err.Error()))
+		apperr.Write(c, apperr.Validation(err))
 		return
 	}
-	if err := h.authService.ChangePassword(userID, req); err != nil {
-		switch err.Error() {
-		case "user not found":
-			c.JSON(http.StatusNotFound, errResp("NOT_FOUND", "User not found"))
-		case "current password is incorrect":
-			c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "Current password is incorrect"))
-		default:
-			c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "Failed to change password"))
-		}
+	resp, err := h.authService.ChangePassword(userID, req, clientInfo(c))
+	if err != nil {
+		apperr.Write(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
+	h.publishPasswordChanged(eventContext(c), resp.User, userID, "change")
+	// All previous tokens are revoked; the caller continues with the new pair
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully", "accessToken": resp.AccessToken, "refreshToken": resp.RefreshToken})
+}
+
+// actor builds the policy subject from the identity RequireAuth put into the context
+func actor(c *gin.Context) policy.Actor {
+	return policy.Actor{UserID: c.GetInt("userID"), Role: c.GetString("userRole"), Permissions: c.GetStringSlice("permissions")}
+}
+
+// deniedTarget answers 403 when the caller may not act on target because its role
+// grants permissions the caller lacks (see policy.CanActOn) and reports whether it did
+func (h *AuthHandlers) deniedTarget(c *gin.Context, target *models.User) bool {
+	v, err := h.targetViolation(c, target)
+	if err != nil {
+		apperr.Write(c, apperr.Wrap(err, "Failed to load role"))
+		return true
+	}
+	return denied(c, v)
 }
 
-func errResp(code, message string) gin.H { return gin.H{"code": code, "message": message} }
+// targetViolation is the policy.CanActOn verdict for the caller acting on target
+func (h *AuthHandlers) targetViolation(c *gin.Context, target *models.User) (*policy.Violation, error) {
+	if target.ID == c.GetInt("userID") {
+		return nil, nil
+	}
+	perms, err := h.authService.RolePermissions(target.Role)
+	if err != nil {
+		return nil, err
+	}
+	return policy.CanActOn(actor(c), target.ID, perms), nil
+}
+
+// deniedRole answers 403 when role grants permissions the caller lacks (see
+// policy.CanGrantRole) and reports whether it did
+func (h *AuthHandlers) deniedRole(c *gin.Context, role string) bool {
+	perms, err := h.authService.RolePermissions(role)
+	if err != nil {
+		apperr.Write(c, apperr.Wrap(err, "Failed to load role"))
+		return true
+	}
+	return denied(c, policy.CanGrantRole(actor(c), perms))
+}
+
+// validRole answers 400 unless role exists and reports whether it does
+func (h *AuthHandlers) validRole(c *gin.Context, role string) bool {
+	exists, err := h.authService.RoleExists(role)
+	if err != nil {
+		apperr.Write(c, apperr.Wrap(err, "Failed to check role"))
+		return false
+	}
+	if !exists {
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "Invalid role")
+		return false
+	}
+	return true
+}
+
+// denied writes a 403 problem for a policy violation and reports whether it did
+func denied(c *gin.Context, v *policy.Violation) bool {
+	if v == nil {
+		return false
+	}
+	ext := gin.H{"reason": v.Reason, "fields": v.Fields}
+	if v.Permission != "" {
+		ext["permission"] = v.Permission
+	}
+	apperr.RespondWith(c, http.StatusForbidden, "FORBIDDEN", v.Message, ext)
+	return true
+}
+
+// loginThrottled answers 429 with Retry-After when err is a lockout error and reports whether it did
+func loginThrottled(c *gin.Context, err error) bool {
+	var le *lockout.Error
+	if !errors.As(err, &le) {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(int(le.RetryAfter.Seconds())+1))
+	if le.Locked {
+		apperr.Respond(c, http.StatusTooManyRequests, "ACCOUNT_LOCKED", "Account temporarily locked after too many failed logins")
+	} else {
+		apperr.Respond(c, http.StatusTooManyRequests, "LOGIN_THROTTLED", "Too many failed logins, try again later")
+	}
+	return true
+}
+
+// clientInfo extracts the device details stored alongside issued refresh tokens
+func clientInfo(c *gin.Context) models.ClientInfo {
+	return models.ClientInfo{UserAgent: c.Request.UserAgent(), IPAddress: c.ClientIP()}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// forbiddenBody is the part of a policy 403 the tests look at
+type forbiddenBody struct {
+	Status     int      `json:"status"`
+	Code       string   `json:"code"`
+	Reason     string   `json:"reason"`
+	Permission string   `json:"permission"`
+	Fields     []string `json:"fields"`
+}
+
+// serveAs sends a request to handler as the given caller, like the JWT middleware would
+// after validating their token. The handlers only get that far when the policy denies
+// the request before any repository is used, so they need no dependencies.
+func serveAs(handler gin.HandlerFunc, route, method, path, body string, userID int, role string, perms ...string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Handle(method, route, func(c *gin.Context) {
+		c.Set("userID", userID)
+		c.Set("userRole", role)
+		c.Set("permissions", perms)
+	}, handler)
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestPolicyDenials(t *testing.T) {
+	h := &AuthHandlers{}
+	tests := []struct {
+		name    string
+		handler gin.HandlerFunc
+		route   string
+		method  string
+		path    string
+		body    string
+		perms   []string
+		want    forbiddenBody
+	}{
+		{"list users", h.ListUsers, "/users", http.MethodGet, "/users", "", []string{models.PermTeamsCreate},
+			forbiddenBody{Reason: "PERMISSION_REQUIRED", Permission: models.PermUsersRead}},
+		{"get other user", h.GetUser, "/users/:id", http.MethodGet, "/users/1", "", nil,
+			forbiddenBody{Reason: "NOT_SELF_OR_ADMIN", Permission: models.PermUsersRead}},
+		{"update other user", h.UpdateUser, "/users/:id", http.MethodPut, "/users/1", `{"firstName": "Eve"}`, nil,
+			forbiddenBody{Reason: "NOT_SELF_OR_ADMIN", Permission: models.PermUsersWrite}},
+		{"update own role", h.UpdateUser, "/users/:id", http.MethodPut, "/users/2", `{"role": "admin", "isActive": true}`, nil,
+			forbiddenBody{Reason: "FIELD_RESTRICTED", Fields: []string{"role", "isActive"}}},
+		{"deactivate self", h.UpdateUser, "/users/:id", http.MethodPut, "/users/2", `{"isActive": false}`,
+			[]string{models.PermUsersWrite}, forbiddenBody{Reason: "SELF_LOCKOUT", Fields: []string{"isActive"}}},
+		{"delete user", h.DeleteUser, "/users/:id", http.MethodDelete, "/users/1", "", []string{models.PermUsersWrite},
+			forbiddenBody{Reason: "PERMISSION_REQUIRED", Permission: models.PermUsersDelete}},
+		{"delete self", h.DeleteUser, "/users/:id", http.MethodDelete, "/users/2", "", []string{models.PermUsersDelete},
+			forbiddenBody{Reason: "SELF_LOCKOUT"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveAs(tt.handler, tt.route, tt.method, tt.path, tt.body, 2, models.RoleUser, tt.perms...)
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("status = %d, want 403; body %s", w.Code, w.Body.String())
+			}
+			if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/problem+json") {
+				t.Errorf("Content-Type = %q, want application/problem+json", ct)
+			}
+			var got forbiddenBody
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("invalid body %s: %v", w.Body.String(), err)
+			}
+			tt.want.Status, tt.want.Code = http.StatusForbidden, "FORBIDDEN"
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("body = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/apperr"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/policy"
+)
+
+// Impersonate issues a short-lived access token for acting as another user (users.impersonate)
+func (h *AuthHandlers) Impersonate(c *gin.Context) {
+	targetID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "Invalid user ID")
+		return
+	}
+	if denied(c, policy.CanImpersonate(actor(c))) {
+		return
+	}
+	var req models.ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Write(c, apperr.Validation(err))
+		return
+	}
+	resp, err := h.authService.Impersonate(c.GetInt("userID"), c.GetStringSlice("permissions"), targetID, req.Reason)
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+// ListImpersonations lists who impersonated a user, newest first (users.read)
+func (h *AuthHandlers) ListImpersonations(c *gin.Context) {
+	targetID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "Invalid user ID")
+		return
+	}
+	if denied(c, policy.CanViewImpersonations(actor(c))) {
+		return
+	}
+	impersonations, err := h.authService.ListImpersonations(targetID)
+	if err != nil {
+		apperr.Write(c, apperr.Wrap(err, "Failed to list impersonations"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"impersonations": impersonations})
+}
+
+// PublishImpersonation is registered with the auth service to announce issued
+// impersonation tokens
+func (h *AuthHandlers) PublishImpersonation(impersonation *models.Impersonation) {
+	if h.producer == nil {
+		return
+	}
+	if err := h.producer.UserImpersonated(context.Background(), impersonation.UserID, impersonation.ActorID, impersonation.Reason, impersonation.ExpiresAt); err != nil {
+		log.Printf("Failed to send user.impersonated event: %v", err)
+	}
+}
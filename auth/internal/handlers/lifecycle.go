@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"log"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/events"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// eventContext returns the context to publish events of the request with; events of
+// impersonated requests carry the real actor as impersonatorId
+func eventContext(c *gin.Context) context.Context {
+	return events.WithImpersonator(context.Background(), c.GetInt("impersonatorID"))
+}
+
+// publishUserChanges announces an edit of a user made by actorID: user.updated when
+// profile fields or preferences changed, plus user.role_changed, user.deactivated or user.reactivated
+func (h *AuthHandlers) publishUserChanges(ctx context.Context, before, after models.UserResponse, actorID int) {
+	if h.producer == nil {
+		return
+	}
+	if before.Username != after.Username || before.Email != after.Email || before.FirstName != after.FirstName ||
+		before.LastName != after.LastName || before.EmailVerified != after.EmailVerified ||
+		before.Locale != after.Locale || before.Timezone != after.Timezone || before.AvatarURL != after.AvatarURL {
+		if err := h.producer.UserUpdated(ctx, after.ID, actorID, before, after); err != nil {
+			log.Printf("Failed to send user.updated event: %v", err)
+		}
+	}
+	if before.Role != after.Role {
+		if err := h.producer.UserRoleChanged(ctx, after.ID, actorID, before.Role, after.Role); err != nil {
+			log.Printf("Failed to send user.role_changed event: %v", err)
+		}
+	}
+	if before.IsActive && !after.IsActive {
+		if err := h.producer.UserDeactivated(ctx, after.ID, actorID); err != nil {
+			log.Printf("Failed to send user.deactivated event: %v", err)
+		}
+	} else if !before.IsActive && after.IsActive {
+		if err := h.producer.UserReactivated(ctx, after.ID, actorID); err != nil {
+			log.Printf("Failed to send user.reactivated event: %v", err)
+		}
+	}
+}
+
+// publishDeletionScheduled announces a soft-deleted user as deactivated. The deletion can
+// still be undone, so user.deleted, on which other services drop the user's memberships
+// and assignments, is only published once the grace period is over.
+func (h *AuthHandlers) publishDeletionScheduled(ctx context.Context, before models.UserResponse, actorID int) {
+	if h.producer == nil || !before.IsActive {
+		return
+	}
+	if err := h.producer.UserDeactivated(ctx, before.ID, actorID); err != nil {
+		log.Printf("Failed to send user.deactivated event: %v", err)
+	}
+}
+
+// publishUserDeleted announces a deleted user so the team and task services drop
+// their memberships and assignments
+func (h *AuthHandlers) publishUserDeleted(ctx context.Context, userID, actorID int) {
+	if h.producer == nil {
+		return
+	}
+	if err := h.producer.UserDeleted(ctx, userID, actorID); err != nil {
+		log.Printf("Failed to send user.deleted event: %v", err)
+	}
+}
+
+// publishPasswordChanged announces a new password; method is "change" or "reset"
+func (h *AuthHandlers) publishPasswordChanged(ctx context.Context, user models.UserResponse, actorID int, method string) {
+	if h.producer == nil {
+		return
+	}
+	if err := h.producer.PasswordChanged(ctx, user.ID, actorID, user.Email, user.Username, method); err != nil {
+		log.Printf("Failed to send user.password_changed event: %v", err)
+	}
+}
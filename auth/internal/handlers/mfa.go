@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/apperr"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/policy"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/service"
+)
+
+// LoginMFA completes a login that answered with a challenge token
+func (h *AuthHandlers) LoginMFA(c *gin.Context) {
+	var req models.MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Write(c, apperr.Validation(err))
+		return
+	}
+	resp, err := h.authService.CompleteMFALogin(req.ChallengeToken, req.Code, clientInfo(c))
+	if err != nil {
+		if loginThrottled(c, err) {
+			return
+		}
+		apperr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// LoginMFAEnroll returns a new TOTP secret for a user whose role requires 2FA but who
+// has not set it up yet; the first code is then submitted to /auth/login/mfa
+func (h *AuthHandlers) LoginMFAEnroll(c *gin.Context) {
+	var req models.MFAChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Write(c, apperr.Validation(err))
+		return
+	}
+	resp, err := h.authService.BeginChallengeEnrollment(req.ChallengeToken)
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *AuthHandlers) EnrollMFA(c *gin.Context) {
+	resp, err := h.authService.StartMFAEnrollment(c.GetInt("userID"))
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *AuthHandlers) ConfirmMFA(c *gin.Context) {
+	var req models.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Write(c, apperr.Validation(err))
+		return
+	}
+	codes, err := h.authService.ConfirmMFAEnrollment(c.GetInt("userID"), req.Code)
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (h *AuthHandlers) DisableMFA(c *gin.Context) {
+	var req models.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Write(c, apperr.Validation(err))
+		return
+	}
+	if err := h.authService.DisableMFA(c.GetInt("userID"), req.Code); err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AuthHandlers) RegenerateRecoveryCodes(c *gin.Context) {
+	var req models.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Write(c, apperr.Validation(err))
+		return
+	}
+	codes, err := h.authService.RegenerateRecoveryCodes(c.GetInt("userID"), req.Code)
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// ResetUserMFA lets an admin switch off 2FA for a user who lost their authenticator.
+// If the user's role requires 2FA they are asked to enroll again at the next login.
+func (h *AuthHandlers) ResetUserMFA(c *gin.Context) {
+	targetID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "Invalid user ID")
+		return
+	}
+	if denied(c, policy.CanResetMFA(actor(c))) {
+		return
+	}
+	user, err := h.userRepo.GetByID(targetID)
+	if err != nil {
+		apperr.Write(c, service.ErrUserNotFound)
+		return
+	}
+	if h.deniedTarget(c, user) {
+		return
+	}
+	if err := h.authService.ResetMFA(targetID); err != nil {
+		apperr.Write(c, apperr.Wrap(err, "Failed to reset 2FA"))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AuthHandlers) GetMFAPolicy(c *gin.Context) {
+	p, err := h.authService.MFAPolicy()
+	if err != nil {
+		apperr.Write(c, apperr.Wrap(err, "Failed to load MFA policy"))
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+func (h *AuthHandlers) SetMFAPolicy(c *gin.Context) {
+	var req models.MFAPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Write(c, apperr.Validation(err))
+		return
+	}
+	p, err := h.authService.SetMFAPolicy(req)
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
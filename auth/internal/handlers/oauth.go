@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/apperr"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/policy"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/service"
+)
+
+// Token is the OAuth 2.0 token endpoint: client credentials (service and registered
+// clients), authorization code with PKCE, and refresh token grants. Errors use the
+// RFC 6749 format so standard OAuth client libraries understand them.
+func (h *AuthHandlers) Token(c *gin.Context) {
+	var req models.TokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": err.Error()})
+		return
+	}
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		req.ClientID, req.ClientSecret = id, secret
+	}
+	var resp *models.TokenResponse
+	var err error
+	switch req.GrantType {
+	case models.GrantClientCredentials:
+		resp, err = h.authService.ClientCredentialsToken(req.ClientID, req.ClientSecret, req.Scope)
+	case models.GrantAuthorizationCode:
+		resp, err = h.authService.AuthorizationCodeToken(req.ClientID, req.ClientSecret, req.Code, req.RedirectURI, req.CodeVerifier, clientInfo(c))
+	case models.GrantRefreshToken:
+		resp, err = h.authService.RefreshClientToken(req.ClientID, req.ClientSecret, req.RefreshToken, clientInfo(c))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_grant_type"})
+		return
+	}
+	if err != nil {
+		oauthTokenError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, resp)
+}
+
+// Introspect is the RFC 7662 token introspection endpoint for resource servers and clients
+func (h *AuthHandlers) Introspect(c *gin.Context) {
+	var req models.IntrospectionRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": err.Error()})
+		return
+	}
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		req.ClientID, req.ClientSecret = id, secret
+	}
+	resp, err := h.authService.Introspect(req.ClientID, req.ClientSecret, req.Token)
+	if err != nil {
+		oauthTokenError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, resp)
+}
+
+// oauthTokenError writes an RFC 6749 section 5.2 error response
+func oauthTokenError(c *gin.Context, err error) {
+	var oe *service.OAuthError
+	if !errors.As(err, &oe) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	body := gin.H{"error": oe.Code}
+	if oe.Description != "" {
+		body["error_description"] = oe.Description
+	}
+	if oe.Code == "invalid_client" {
+		c.Header("WWW-Authenticate", `Basic realm="auth-service"`)
+		c.JSON(http.StatusUnauthorized, body)
+		return
+	}
+	c.JSON(http.StatusBadRequest, body)
+}
+
+// Authorize validates an authorization request for the frontend's consent page and
+// tells it which scopes to show and whether the user already approved them
+func (h *AuthHandlers) Authorize(c *gin.Context) {
+	var req models.AuthorizeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		apperr.Write(c, apperr.Validation(err))
+		return
+	}
+	resp, err := h.authService.PrepareAuthorization(c.GetInt("userID"), req)
+	if err != nil {
+		authorizeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// AuthorizeDecision records the user's approval or denial and returns the client
+// redirect the browser should follow
+func (h *AuthHandlers) AuthorizeDecision(c *gin.Context) {
+	var req models.AuthorizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Write(c, apperr.Validation(err))
+		return
+	}
+	resp, err := h.authService.AuthorizeDecision(c.GetInt("userID"), req)
+	if err != nil {
+		authorizeError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, resp)
+}
+
+// authorizeError reports an invalid authorization request. Errors the client should
+// learn about carry the redirect; a bad client or redirect URI is only shown to the user.
+func authorizeError(c *gin.Context, err error) {
+	var oe *service.OAuthError
+	if errors.As(err, &oe) {
+		apperr.RespondWith(c, http.StatusBadRequest, "OAUTH_ERROR", oe.Description, gin.H{"error": oe.Code, "redirectTo": oe.RedirectTo})
+		return
+	}
+	apperr.Write(c, err)
+}
+
+// ListOAuthClients lists the OAuth clients the current user registered
+func (h *AuthHandlers) ListOAuthClients(c *gin.Context) {
+	clients, err := h.authService.ListOAuthClients(c.GetInt("userID"))
+	if err != nil {
+		apperr.Write(c, apperr.Wrap(err, "Failed to list clients"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"clients": clients})
+}
+
+// CreateOAuthClient registers a third-party application owned by the current user;
+// the client secret is only included in this response
+func (h *AuthHandlers) CreateOAuthClient(c *gin.Context) {
+	var req models.CreateOAuthClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Write(c, apperr.Validation(err))
+		return
+	}
+	client, err := h.authService.RegisterOAuthClient(c.GetInt("userID"), req)
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, client)
+}
+
+// DeleteOAuthClient removes a client and revokes its refresh tokens (owner or admin)
+func (h *AuthHandlers) DeleteOAuthClient(c *gin.Context) {
+	client, err := h.authService.GetOAuthClient(c.Param("clientId"))
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	if denied(c, policy.CanManageOAuthClient(actor(c), client.OwnerID)) {
+		return
+	}
+	if err := h.authService.DeleteOAuthClient(client.ClientID); err != nil {
+		apperr.Write(c, apperr.Wrap(err, "Failed to delete client"))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListAuthorizations lists the applications the current user has granted access to
+func (h *AuthHandlers) ListAuthorizations(c *gin.Context) {
+	authorizations, err := h.authService.ListAuthorizations(c.GetInt("userID"))
+	if err != nil {
+		apperr.Write(c, apperr.Wrap(err, "Failed to list authorizations"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"authorizations": authorizations})
+}
+
+// RevokeAuthorization withdraws the current user's consent for an application
+func (h *AuthHandlers) RevokeAuthorization(c *gin.Context) {
+	if err := h.authService.RevokeAuthorization(c.GetInt("userID"), c.Param("clientId")); err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
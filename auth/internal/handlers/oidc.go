@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/apperr"
+)
+
+// OIDCProviders lists the external identity providers users can sign in with
+func (h *AuthHandlers) OIDCProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": h.authService.OIDCProviders()})
+}
+
+// OIDCLogin redirects the browser to the provider's login page
+func (h *AuthHandlers) OIDCLogin(c *gin.Context) {
+	authURL, err := h.authService.StartOIDCLogin(c.Param("provider"))
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback completes the login when the provider redirects back. It answers
+// like /auth/login: a LoginResponse, or an MFA challenge if a second factor is needed.
+func (h *AuthHandlers) OIDCCallback(c *gin.Context) {
+	if e := c.Query("error"); e != "" {
+		apperr.RespondWith(c, http.StatusBadRequest, "OIDC_ERROR", "Identity provider returned an error", gin.H{"error": e, "errorDescription": c.Query("error_description")})
+		return
+	}
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "code and state are required")
+		return
+	}
+	result, err := h.authService.CompleteOIDCLogin(c.Param("provider"), code, state, clientInfo(c))
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+
+	if user := result.Provisioned; user != nil {
+		if h.producer != nil {
+			if err := h.producer.UserCreated(context.Background(), user.ID, user.Email, user.Username); err != nil {
+				log.Printf("Failed to send user.created event: %v", err)
+			}
+		}
+		if !user.EmailVerified {
+			h.sendVerification(user)
+		}
+	}
+	c.Header("Cache-Control", "no-store")
+	if result.Challenge != nil {
+		c.JSON(http.StatusOK, result.Challenge)
+		return
+	}
+	c.JSON(http.StatusOK, result.Login)
+}
+
+// ListIdentities shows the external accounts linked to the current user
+func (h *AuthHandlers) ListIdentities(c *gin.Context) {
+	identities, err := h.authService.LinkedIdentities(c.GetInt("userID"))
+	if err != nil {
+		apperr.Write(c, apperr.Wrap(err, "Failed to list identities"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"identities": identities})
+}
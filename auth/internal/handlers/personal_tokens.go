@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/apperr"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// ListPersonalAccessTokens lists the current user's personal access tokens
+func (h *AuthHandlers) ListPersonalAccessTokens(c *gin.Context) {
+	tokens, err := h.authService.ListPersonalAccessTokens(c.GetInt("userID"))
+	if err != nil {
+		apperr.Write(c, apperr.Wrap(err, "Failed to list tokens"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tokens": tokens})
+}
+
+// CreatePersonalAccessToken creates a personal access token for the current user;
+// the token itself is only included in this response
+func (h *AuthHandlers) CreatePersonalAccessToken(c *gin.Context) {
+	var req models.CreatePersonalAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Write(c, apperr.Validation(err))
+		return
+	}
+	token, err := h.authService.CreatePersonalAccessToken(c.GetInt("userID"), req)
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, token)
+}
+
+// DeletePersonalAccessToken revokes one of the current user's personal access tokens
+func (h *AuthHandlers) DeletePersonalAccessToken(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "Invalid token ID")
+		return
+	}
+	if err := h.authService.DeletePersonalAccessToken(c.GetInt("userID"), id); err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/apperr"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// ListPermissions lists every permission a role can grant
+func (h *AuthHandlers) ListPermissions(c *gin.Context) {
+	permissions := make([]gin.H, 0, len(models.PermissionDescriptions))
+	for name, description := range models.PermissionDescriptions {
+		permissions = append(permissions, gin.H{"name": name, "description": description})
+	}
+	sort.Slice(permissions, func(i, j int) bool { return permissions[i]["name"].(string) < permissions[j]["name"].(string) })
+	c.JSON(http.StatusOK, gin.H{"permissions": permissions})
+}
+
+// ListRoles lists all roles with their permissions
+func (h *AuthHandlers) ListRoles(c *gin.Context) {
+	roles, err := h.authService.ListRoles()
+	if err != nil {
+		apperr.Write(c, apperr.Wrap(err, "Failed to list roles"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+// GetRole returns a single role
+func (h *AuthHandlers) GetRole(c *gin.Context) {
+	role, err := h.authService.GetRole(c.Param("name"))
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, role)
+}
+
+// CreateRole adds a role
+func (h *AuthHandlers) CreateRole(c *gin.Context) {
+	var req models.CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Write(c, apperr.Validation(err))
+		return
+	}
+	role, err := h.authService.CreateRole(req)
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, role)
+}
+
+// UpdateRole changes a role's description or replaces its permissions
+func (h *AuthHandlers) UpdateRole(c *gin.Context) {
+	var req models.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Write(c, apperr.Validation(err))
+		return
+	}
+	role, err := h.authService.UpdateRole(c.Param("name"), req)
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, role)
+}
+
+// DeleteRole removes a role that no user holds
+func (h *AuthHandlers) DeleteRole(c *gin.Context) {
+	if err := h.authService.DeleteRole(c.Param("name")); err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,530 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/apperr"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/policy"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/scim"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/service"
+)
+
+// scimMaxResults caps the page size of SCIM list requests
+const scimMaxResults = 200
+
+// scimUserAttributes maps the filterable SCIM user attributes to their columns
+var scimUserAttributes = map[string]string{"username": "username", "emails": "email", "emails.value": "email", "externalid": "external_id"}
+
+// scimGroupAttributes maps the filterable SCIM group attributes to their columns
+var scimGroupAttributes = map[string]string{"displayname": "display_name", "externalid": "external_id"}
+
+// SCIMServiceProviderConfig describes which SCIM features are supported
+func (h *AuthHandlers) SCIMServiceProviderConfig(c *gin.Context) {
+	scimJSON(c, http.StatusOK, gin.H{
+		"schemas":          []string{scim.SchemaServiceProviderConfig},
+		"documentationUri": "/scim/v2",
+		"patch":            gin.H{"supported": true},
+		"bulk":             gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":           gin.H{"supported": true, "maxResults": scimMaxResults},
+		"changePassword":   gin.H{"supported": true},
+		"sort":             gin.H{"supported": false},
+		"etag":             gin.H{"supported": false},
+		"authenticationSchemes": []gin.H{{
+			"type": "oauthbearertoken", "name": "Bearer token", "primary": true,
+			"description": "A first-party access token or a personal access token with the scim scope, of a user with users.write",
+		}},
+	})
+}
+
+// SCIMResourceTypes lists the provisioned resource types
+func (h *AuthHandlers) SCIMResourceTypes(c *gin.Context) {
+	types := []gin.H{
+		{"schemas": []string{scim.SchemaResourceType}, "id": "User", "name": "User", "endpoint": "/Users", "schema": scim.SchemaUser},
+		{"schemas": []string{scim.SchemaResourceType}, "id": "Group", "name": "Group", "endpoint": "/Groups", "schema": scim.SchemaGroup},
+	}
+	scimJSON(c, http.StatusOK, scim.NewListResponse(types, len(types), int64(len(types)), 1))
+}
+
+// ListSCIMUsers returns a page of users (startIndex, count), optionally filtered by
+// userName, emails or externalId
+func (h *AuthHandlers) ListSCIMUsers(c *gin.Context) {
+	column, value, ok := scimFilter(c, scimUserAttributes)
+	if !ok {
+		return
+	}
+	start, count, err := scim.Paging(c.Query("startIndex"), c.Query("count"), scimMaxResults)
+	if err != nil {
+		scimError(c, http.StatusBadRequest, scim.ErrInvalidValue, err.Error())
+		return
+	}
+	users, total, err := h.userRepo.ListByAttribute(column, value, start-1, count)
+	if err != nil {
+		scimError(c, http.StatusInternalServerError, "", "Failed to list users")
+		return
+	}
+	resources := make([]scim.User, 0, len(users))
+	for i := range users {
+		resources = append(resources, scimUser(&users[i]))
+	}
+	scimJSON(c, http.StatusOK, scim.NewListResponse(resources, len(resources), total, start))
+}
+
+// GetSCIMUser returns a user
+func (h *AuthHandlers) GetSCIMUser(c *gin.Context) {
+	user, ok := h.scimTargetUser(c)
+	if !ok {
+		return
+	}
+	scimJSON(c, http.StatusOK, scimUser(user))
+}
+
+// CreateSCIMUser provisions a user pushed by a directory. Like accounts an admin
+// creates, it has the default role and a verified email; without a password the user
+// sets one through the password reset.
+func (h *AuthHandlers) CreateSCIMUser(c *gin.Context) {
+	var in scim.User
+	if err := c.ShouldBindJSON(&in); err != nil {
+		scimError(c, http.StatusBadRequest, scim.ErrInvalidSyntax, "Invalid user: "+err.Error())
+		return
+	}
+	if !h.validSCIMUser(c, &in, nil) {
+		return
+	}
+	user := &models.User{Role: models.RoleUser, IsActive: true, EmailVerified: true}
+	applySCIMUser(user, &in)
+	if in.Password != "" {
+		hash, err := h.authService.HashPassword(in.Password)
+		if err != nil {
+			scimError(c, http.StatusInternalServerError, "", "Failed to hash password")
+			return
+		}
+		user.PasswordHash = hash
+	}
+	if err := h.userRepo.Create(user); err != nil {
+		scimError(c, http.StatusInternalServerError, "", "Failed to create user")
+		return
+	}
+	if h.producer != nil {
+		if err := h.producer.UserCreated(eventContext(c), user.ID, user.Email, user.Username); err != nil {
+			log.Printf("Failed to send user.created event: %v", err)
+		}
+	}
+	c.Header("Location", "/scim/v2/Users/"+strconv.Itoa(user.ID))
+	scimJSON(c, http.StatusCreated, scimUser(user))
+}
+
+// ReplaceSCIMUser replaces a user's userName, name, emails, externalId and active state
+func (h *AuthHandlers) ReplaceSCIMUser(c *gin.Context) {
+	user, ok := h.scimTargetUser(c)
+	if !ok {
+		return
+	}
+	var in scim.User
+	if err := c.ShouldBindJSON(&in); err != nil {
+		scimError(c, http.StatusBadRequest, scim.ErrInvalidSyntax, "Invalid user: "+err.Error())
+		return
+	}
+	h.saveSCIMUser(c, user, &in)
+}
+
+// PatchSCIMUser modifies single attributes of a user, e.g. active to (de)provision it
+func (h *AuthHandlers) PatchSCIMUser(c *gin.Context) {
+	user, ok := h.scimTargetUser(c)
+	if !ok {
+		return
+	}
+	var req scim.PatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		scimError(c, http.StatusBadRequest, scim.ErrInvalidSyntax, "Invalid patch: "+err.Error())
+		return
+	}
+	in := scimUser(user)
+	if err := scim.ApplyUserPatch(&in, req.Operations); err != nil {
+		var pe *scim.PatchError
+		if errors.As(err, &pe) {
+			scimError(c, http.StatusBadRequest, pe.ScimType, pe.Detail)
+			return
+		}
+		scimError(c, http.StatusBadRequest, scim.ErrInvalidValue, err.Error())
+		return
+	}
+	h.saveSCIMUser(c, user, &in)
+}
+
+// DeleteSCIMUser deletes a user like DELETE /users/:id; it is erased after the grace period
+func (h *AuthHandlers) DeleteSCIMUser(c *gin.Context) {
+	user, ok := h.scimTargetUser(c)
+	if !ok {
+		return
+	}
+	if scimDenied(c, policy.CanDeleteUser(actor(c), user.ID)) || h.scimDeniedTarget(c, user) {
+		return
+	}
+	before := user.ToUserResponse()
+	actorID := c.GetInt("userID")
+	if _, err := h.authService.DeleteAccount(user.ID, actorID); err != nil {
+		scimError(c, http.StatusInternalServerError, "", "Failed to delete user")
+		return
+	}
+	h.publishDeletionScheduled(eventContext(c), before, actorID)
+	c.Status(http.StatusNoContent)
+}
+
+// saveSCIMUser validates and stores a user's new SCIM representation. Deactivated
+// users and users given a new password are signed out everywhere, and a new email
+// has to be verified again.
+func (h *AuthHandlers) saveSCIMUser(c *gin.Context, user *models.User, in *scim.User) {
+	if !h.validSCIMUser(c, in, user) || h.scimDeniedTarget(c, user) {
+		return
+	}
+	before := user.ToUserResponse()
+	wasActive := user.IsActive
+	applySCIMUser(user, in)
+	if scimDenied(c, policy.CanUpdateUser(actor(c), user.ID, models.UpdateUserRequest{IsActive: &user.IsActive})) {
+		return
+	}
+	emailChanged := user.Email != before.Email
+	if emailChanged {
+		user.EmailVerified = false
+		user.EmailVerifiedAt = nil
+	}
+	if err := h.userRepo.Update(user); err != nil {
+		scimError(c, http.StatusInternalServerError, "", "Failed to update user")
+		return
+	}
+	if emailChanged {
+		h.sendVerification(user)
+	}
+	actorID := c.GetInt("userID")
+	ctx := eventContext(c)
+	if in.Password != "" {
+		hash, err := h.authService.HashPassword(in.Password)
+		if err != nil {
+			scimError(c, http.StatusInternalServerError, "", "Failed to hash password")
+			return
+		}
+		if err := h.userRepo.UpdatePasswordHash(user.ID, hash); err != nil {
+			scimError(c, http.StatusInternalServerError, "", "Failed to update password")
+			return
+		}
+		if err := h.authService.RevokeAllSessions(user.ID, models.RevokeReasonPasswordReset); err != nil {
+			scimError(c, http.StatusInternalServerError, "", "Password was changed but the user's sessions could not be revoked")
+			return
+		}
+		h.publishPasswordChanged(ctx, user.ToUserResponse(), actorID, "reset")
+	} else if wasActive && !user.IsActive {
+		if err := h.authService.RevokeAllSessions(user.ID, models.RevokeReasonDeactivated); err != nil {
+			scimError(c, http.StatusInternalServerError, "", "User was deactivated but their sessions could not be revoked")
+			return
+		}
+	}
+	h.publishUserChanges(ctx, before, user.ToUserResponse(), actorID)
+	scimJSON(c, http.StatusOK, scimUser(user))
+}
+
+// validSCIMUser answers 400 or 409 unless in can be stored for existing (nil for a new user)
+func (h *AuthHandlers) validSCIMUser(c *gin.Context, in *scim.User, existing *models.User) bool {
+	in.UserName = strings.TrimSpace(in.UserName)
+	email := strings.TrimSpace(in.PrimaryEmail())
+	if n := utf8.RuneCountInString(in.UserName); n < 3 || n > 50 {
+		scimError(c, http.StatusBadRequest, scim.ErrInvalidValue, "userName must have 3 to 50 characters")
+		return false
+	}
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		scimError(c, http.StatusBadRequest, scim.ErrInvalidValue, "A valid primary email is required")
+		return false
+	}
+	if in.Password != "" && len(in.Password) < 6 {
+		scimError(c, http.StatusBadRequest, scim.ErrInvalidValue, "password must have at least 6 characters")
+		return false
+	}
+	if existing == nil || !strings.EqualFold(existing.Username, in.UserName) {
+		exists, err := h.userRepo.ExistsByUsername(in.UserName)
+		if err != nil {
+			scimError(c, http.StatusInternalServerError, "", "Failed to check userName")
+			return false
+		}
+		if exists {
+			scimError(c, http.StatusConflict, scim.ErrUniqueness, "userName already exists")
+			return false
+		}
+	}
+	if existing == nil || !strings.EqualFold(existing.Email, email) {
+		exists, err := h.userRepo.ExistsByEmail(email)
+		if err != nil {
+			scimError(c, http.StatusInternalServerError, "", "Failed to check email")
+			return false
+		}
+		if exists {
+			scimError(c, http.StatusConflict, scim.ErrUniqueness, "email already exists")
+			return false
+		}
+	}
+	return true
+}
+
+// scimTargetUser loads the user of the :id parameter, answering 404 if there is none
+func (h *AuthHandlers) scimTargetUser(c *gin.Context) (*models.User, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err == nil {
+		var user *models.User
+		if user, err = h.userRepo.GetByID(id); err == nil {
+			return user, true
+		}
+	}
+	scimError(c, http.StatusNotFound, "", "User not found")
+	return nil, false
+}
+
+// ListSCIMGroups returns a page of groups (startIndex, count), optionally filtered by
+// displayName or externalId
+func (h *AuthHandlers) ListSCIMGroups(c *gin.Context) {
+	column, value, ok := scimFilter(c, scimGroupAttributes)
+	if !ok {
+		return
+	}
+	start, count, err := scim.Paging(c.Query("startIndex"), c.Query("count"), scimMaxResults)
+	if err != nil {
+		scimError(c, http.StatusBadRequest, scim.ErrInvalidValue, err.Error())
+		return
+	}
+	groups, total, err := h.authService.SCIMGroups(column, value, start-1, count)
+	if err != nil {
+		scimError(c, http.StatusInternalServerError, "", "Failed to list groups")
+		return
+	}
+	resources := make([]scim.Group, 0, len(groups))
+	for i := range groups {
+		resources = append(resources, scimGroup(&groups[i]))
+	}
+	scimJSON(c, http.StatusOK, scim.NewListResponse(resources, len(resources), total, start))
+}
+
+// GetSCIMGroup returns a group with its members
+func (h *AuthHandlers) GetSCIMGroup(c *gin.Context) {
+	group, err := h.authService.SCIMGroup(c.Param("id"))
+	if err != nil {
+		scimGroupError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, scimGroup(group))
+}
+
+// CreateSCIMGroup creates a group and a team for it owned by the caller; the members
+// join the team
+func (h *AuthHandlers) CreateSCIMGroup(c *gin.Context) {
+	var in scim.Group
+	if err := c.ShouldBindJSON(&in); err != nil {
+		scimError(c, http.StatusBadRequest, scim.ErrInvalidSyntax, "Invalid group: "+err.Error())
+		return
+	}
+	members, ok := scimMemberIDs(c, in.Members)
+	if !ok {
+		return
+	}
+	group, err := h.authService.CreateSCIMGroup(c.GetInt("userID"), in.DisplayName, in.ExternalID, members)
+	if err != nil {
+		scimGroupError(c, err)
+		return
+	}
+	c.Header("Location", "/scim/v2/Groups/"+group.ID)
+	scimJSON(c, http.StatusCreated, scimGroup(group))
+}
+
+// ReplaceSCIMGroup replaces a group's name, externalId and members
+func (h *AuthHandlers) ReplaceSCIMGroup(c *gin.Context) {
+	var in scim.Group
+	if err := c.ShouldBindJSON(&in); err != nil {
+		scimError(c, http.StatusBadRequest, scim.ErrInvalidSyntax, "Invalid group: "+err.Error())
+		return
+	}
+	h.saveSCIMGroup(c, &in)
+}
+
+// PatchSCIMGroup renames a group or adds and removes members
+func (h *AuthHandlers) PatchSCIMGroup(c *gin.Context) {
+	var req scim.PatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		scimError(c, http.StatusBadRequest, scim.ErrInvalidSyntax, "Invalid patch: "+err.Error())
+		return
+	}
+	group, err := h.authService.SCIMGroup(c.Param("id"))
+	if err != nil {
+		scimGroupError(c, err)
+		return
+	}
+	in := scimGroup(group)
+	if err := scim.ApplyGroupPatch(&in, req.Operations); err != nil {
+		var pe *scim.PatchError
+		if errors.As(err, &pe) {
+			scimError(c, http.StatusBadRequest, pe.ScimType, pe.Detail)
+			return
+		}
+		scimError(c, http.StatusBadRequest, scim.ErrInvalidValue, err.Error())
+		return
+	}
+	h.saveSCIMGroup(c, &in)
+}
+
+// DeleteSCIMGroup deletes a group and its team
+func (h *AuthHandlers) DeleteSCIMGroup(c *gin.Context) {
+	if err := h.authService.DeleteSCIMGroup(c.GetInt("userID"), c.Param("id")); err != nil {
+		scimGroupError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// saveSCIMGroup stores the new state of the group of the :id parameter
+func (h *AuthHandlers) saveSCIMGroup(c *gin.Context, in *scim.Group) {
+	members, ok := scimMemberIDs(c, in.Members)
+	if !ok {
+		return
+	}
+	group, err := h.authService.ReplaceSCIMGroup(c.GetInt("userID"), c.Param("id"), in.DisplayName, in.ExternalID, members)
+	if err != nil {
+		scimGroupError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, scimGroup(group))
+}
+
+// scimGroupError answers with the SCIM error for an error of a group operation
+func scimGroupError(c *gin.Context, err error) {
+	var e *apperr.Error
+	if !errors.As(err, &e) {
+		log.Printf("request %s: SCIM group operation failed: %v", apperr.RequestIDFrom(c), err)
+		scimError(c, http.StatusInternalServerError, "", "Failed to update group")
+		return
+	}
+	scimType := ""
+	switch {
+	case errors.Is(err, service.ErrGroupNameTaken):
+		scimType = scim.ErrUniqueness
+	case errors.Is(err, service.ErrGroupNameRequired), errors.Is(err, service.ErrMemberNotFound):
+		scimType = scim.ErrInvalidValue
+	}
+	scimError(c, e.Kind.Status(), scimType, e.Message)
+}
+
+// scimMemberIDs parses the user IDs of group members, answering 400 if one is invalid
+func scimMemberIDs(c *gin.Context, members []scim.MemberRef) ([]int, bool) {
+	ids := make([]int, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.Atoi(m.Value)
+		if err != nil {
+			scimError(c, http.StatusBadRequest, scim.ErrInvalidValue, "Members must be existing users")
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
+
+// scimFilter parses the filter parameter into a column and value; attributes maps the
+// supported lowercased attributes to columns. An empty column means no filter.
+func scimFilter(c *gin.Context, attributes map[string]string) (string, string, bool) {
+	filter, err := scim.ParseFilter(c.Query("filter"))
+	if err != nil {
+		scimError(c, http.StatusBadRequest, scim.ErrInvalidFilter, err.Error())
+		return "", "", false
+	}
+	if filter == nil {
+		return "", "", true
+	}
+	column, ok := attributes[strings.ToLower(filter.Attribute)]
+	if !ok {
+		scimError(c, http.StatusBadRequest, scim.ErrInvalidFilter, "Filtering by "+filter.Attribute+" is not supported")
+		return "", "", false
+	}
+	return column, filter.Value, true
+}
+
+// applySCIMUser copies the attributes of a SCIM user to a user; active is only changed when given
+func applySCIMUser(user *models.User, in *scim.User) {
+	user.Username = in.UserName
+	user.Email = strings.TrimSpace(in.PrimaryEmail())
+	user.ExternalID = in.ExternalID
+	user.FirstName, user.LastName = "", ""
+	if in.Name != nil {
+		user.FirstName, user.LastName = in.Name.GivenName, in.Name.FamilyName
+	}
+	if in.Active != nil {
+		user.IsActive = *in.Active
+	}
+}
+
+// scimUser converts a user to its SCIM representation
+func scimUser(user *models.User) scim.User {
+	active := user.IsActive
+	displayName := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if displayName == "" {
+		displayName = user.Username
+	}
+	id := strconv.Itoa(user.ID)
+	return scim.User{
+		Schemas:     []string{scim.SchemaUser},
+		ID:          id,
+		ExternalID:  user.ExternalID,
+		UserName:    user.Username,
+		Name:        &scim.Name{Formatted: strings.TrimSpace(user.FirstName + " " + user.LastName), GivenName: user.FirstName, FamilyName: user.LastName},
+		DisplayName: displayName,
+		Emails:      []scim.Email{{Value: user.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Meta:        &scim.Meta{ResourceType: "User", Created: user.CreatedAt, LastModified: user.UpdatedAt, Location: "/scim/v2/Users/" + id},
+	}
+}
+
+// scimGroup converts a group to its SCIM representation
+func scimGroup(group *models.SCIMGroup) scim.Group {
+	members := make([]scim.MemberRef, 0, len(group.Members))
+	for _, userID := range group.Members {
+		id := strconv.Itoa(userID)
+		members = append(members, scim.MemberRef{Value: id, Ref: "/scim/v2/Users/" + id})
+	}
+	return scim.Group{
+		Schemas:     []string{scim.SchemaGroup},
+		ID:          group.ID,
+		ExternalID:  group.ExternalID,
+		DisplayName: group.DisplayName,
+		Members:     members,
+		Meta:        &scim.Meta{ResourceType: "Group", Created: group.CreatedAt, LastModified: group.UpdatedAt, Location: "/scim/v2/Groups/" + group.ID},
+	}
+}
+
+// scimDenied answers 403 with a SCIM error if v is a violation and reports whether it did
+func scimDenied(c *gin.Context, v *policy.Violation) bool {
+	if v == nil {
+		return false
+	}
+	scimError(c, http.StatusForbidden, "", v.Message)
+	return true
+}
+
+// scimDeniedTarget is deniedTarget with SCIM errors
+func (h *AuthHandlers) scimDeniedTarget(c *gin.Context, target *models.User) bool {
+	v, err := h.targetViolation(c, target)
+	if err != nil {
+		scimError(c, http.StatusInternalServerError, "", "Failed to load role")
+		return true
+	}
+	return scimDenied(c, v)
+}
+
+func scimError(c *gin.Context, status int, scimType, detail string) {
+	scimJSON(c, status, scim.NewError(status, scimType, detail))
+}
+
+func scimJSON(c *gin.Context, status int, body interface{}) {
+	c.Header("Content-Type", scim.ContentType)
+	c.JSON(status, body)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/apperr"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/policy"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/service"
+)
+
+// ListSessions lists the devices the current user is logged in on
+func (h *AuthHandlers) ListSessions(c *gin.Context) {
+	h.listSessions(c, c.GetInt("userID"))
+}
+
+// RevokeSession logs the current user out on one device
+func (h *AuthHandlers) RevokeSession(c *gin.Context) {
+	h.revokeSession(c, c.GetInt("userID"))
+}
+
+// ListUserSessions lists a user's sessions (self or admin)
+func (h *AuthHandlers) ListUserSessions(c *gin.Context) {
+	targetID, ok := h.sessionTarget(c)
+	if !ok {
+		return
+	}
+	h.listSessions(c, targetID)
+}
+
+// RevokeUserSession ends one of a user's sessions (self or admin)
+func (h *AuthHandlers) RevokeUserSession(c *gin.Context) {
+	targetID, ok := h.sessionTarget(c)
+	if !ok {
+		return
+	}
+	h.revokeSession(c, targetID)
+}
+
+// RevokeUserSessions signs a user out on every device (self or admin)
+func (h *AuthHandlers) RevokeUserSessions(c *gin.Context) {
+	targetID, ok := h.sessionTarget(c)
+	if !ok {
+		return
+	}
+	if err := h.authService.RevokeAllSessions(targetID, models.RevokeReasonSessionsRevoked); err != nil {
+		apperr.Write(c, apperr.Wrap(err, "Failed to revoke sessions"))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// sessionTarget parses the user ID of a /users/:id/sessions route and applies the
+// session policy; it has already responded when ok is false
+func (h *AuthHandlers) sessionTarget(c *gin.Context) (int, bool) {
+	targetID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "Invalid user ID")
+		return 0, false
+	}
+	if denied(c, policy.CanManageSessions(actor(c), targetID)) {
+		return 0, false
+	}
+	if targetID != c.GetInt("userID") {
+		user, err := h.userRepo.GetByID(targetID)
+		if err != nil {
+			apperr.Write(c, service.ErrUserNotFound)
+			return 0, false
+		}
+		if h.deniedTarget(c, user) {
+			return 0, false
+		}
+	}
+	return targetID, true
+}
+
+func (h *AuthHandlers) listSessions(c *gin.Context, userID int) {
+	sessions, err := h.authService.ListSessions(userID, c.GetString("sessionID"))
+	if err != nil {
+		apperr.Write(c, apperr.Wrap(err, "Failed to list sessions"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+func (h *AuthHandlers) revokeSession(c *gin.Context, userID int) {
+	if err := h.authService.RevokeSession(userID, c.Param("sessionId")); err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
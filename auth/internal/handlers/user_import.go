@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/apperr"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/policy"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/service"
+)
+
+// maxUserImportBytes bounds the size of an uploaded import
+const maxUserImportBytes = 5 << 20
+
+// ImportUsers starts a bulk import of the users in the request body, a CSV document
+// (text/csv) or a JSON array (application/json). With ?dryRun=true the rows are only
+// validated. The job runs in the background; its report is polled with GetUserImport.
+func (h *AuthHandlers) ImportUsers(c *gin.Context) {
+	if denied(c, policy.CanImportUsers(actor(c))) {
+		return
+	}
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dryRun", "false"))
+	if err != nil {
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "dryRun must be true or false")
+		return
+	}
+	var format string
+	switch c.ContentType() {
+	case "text/csv":
+		format = "csv"
+	case "application/json":
+		format = "json"
+	default:
+		apperr.Respond(c, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE", "Send the users as text/csv or application/json")
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxUserImportBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			apperr.Respond(c, http.StatusRequestEntityTooLarge, "IMPORT_TOO_LARGE", "An import may have at most "+strconv.Itoa(maxUserImportBytes)+" bytes")
+			return
+		}
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "Failed to read the import")
+		return
+	}
+	rows, err := service.ParseUserImport(format, data)
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	// Every role handed out must be assignable by the caller, as with CreateUser
+	checked := map[string]bool{}
+	for _, row := range rows {
+		role := strings.TrimSpace(row.Role)
+		if role == "" {
+			role = models.RoleUser
+		}
+		if checked[role] {
+			continue
+		}
+		checked[role] = true
+		if denied(c, policy.CanAssignRole(actor(c), role)) || h.deniedRole(c, role) {
+			return
+		}
+	}
+	imp, err := h.authService.StartUserImport(c.GetInt("userID"), rows, dryRun)
+	if err != nil {
+		apperr.Write(c, apperr.Wrap(err, "Failed to start the import"))
+		return
+	}
+	c.Header("Location", "/users/import/"+imp.ID)
+	c.JSON(http.StatusAccepted, imp.ToResponse())
+}
+
+// GetUserImport reports the status of an import and, once it completed, the outcome of every row
+func (h *AuthHandlers) GetUserImport(c *gin.Context) {
+	if denied(c, policy.CanImportUsers(actor(c))) {
+		return
+	}
+	imp, err := h.authService.UserImport(c.Param("importId"))
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, imp.ToResponse())
+}
+
+// PublishUserImported announces a user created by a bulk import with user.created, like
+// users created one at a time; it is registered with AuthService.OnUserImported
+func (h *AuthHandlers) PublishUserImported(user *models.User) {
+	if h.producer == nil {
+		return
+	}
+	if err := h.producer.UserCreated(context.Background(), user.ID, user.Email, user.Username); err != nil {
+		log.Printf("Failed to send user.created event: %v", err)
+	}
+}
@@ -0,0 +1,461 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is one asymmetric key pair identified by its kid.
+// RetiredAt is set once the key stopped signing; it stays published in the JWKS
+// until RetiredAt+retention so tokens it signed remain verifiable.
+type SigningKey struct {
+	KID       string
+	Method    jwt.SigningMethod
+	Private   crypto.Signer
+	CreatedAt time.Time
+	RetiredAt *time.Time
+}
+
+// Public returns the verification key matching Private
+func (k *SigningKey) Public() crypto.PublicKey { return k.Private.Public() }
+
+// Manager holds the active signing key plus previous keys still valid for verification
+type Manager struct {
+	mu          sync.RWMutex
+	active      *SigningKey
+	keys        map[string]*SigningKey
+	retention   time.Duration
+	alg         string
+	dir         string
+	pinned      string
+	rotateEvery time.Duration
+}
+
+// PublishDelay is how long a key added to JWT_KEYS_DIR is only published before it signs
+// tokens, so every replica (re-reading the directory each ReloadInterval) and every
+// JWKS cache knows it first.
+const PublishDelay = 5 * time.Minute
+
+// ReloadInterval is how often JWT_KEYS_DIR is re-read for keys added by other replicas
+const ReloadInterval = time.Minute
+
+// NewManagerFromEnv loads PEM keys from JWT_KEYS_DIR, generating the first one of type
+// JWT_SIGNING_ALG (RS256 or EdDSA) when the directory is empty. The signing key is
+// JWT_ACTIVE_KID, or the lexicographically last kid that has been published for
+// PublishDelay. JWT_KEY_ROTATION_INTERVAL adds a new key to the directory on that
+// interval. Without a directory the service refuses to start unless
+// JWT_EPHEMERAL_KEYS=true asks for a throwaway key (local development only).
+// retention is how long a superseded key stays verifiable and should cover the
+// longest token TTL.
+func NewManagerFromEnv(retention time.Duration) (*Manager, error) {
+	alg := os.Getenv("JWT_SIGNING_ALG")
+	if alg == "" {
+		alg = "RS256"
+	}
+	m := &Manager{keys: map[string]*SigningKey{}, retention: retention, alg: alg,
+		dir: os.Getenv("JWT_KEYS_DIR"), pinned: os.Getenv("JWT_ACTIVE_KID")}
+	if s := os.Getenv("JWT_KEY_ROTATION_INTERVAL"); s != "" {
+		interval, err := time.ParseDuration(s)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid JWT_KEY_ROTATION_INTERVAL %q", s)
+		}
+		m.rotateEvery = interval
+	}
+	if m.dir == "" {
+		if os.Getenv("JWT_EPHEMERAL_KEYS") != "true" {
+			return nil, errors.New("JWT_KEYS_DIR is not set; set it to a persistent directory, or JWT_EPHEMERAL_KEYS=true for local development")
+		}
+		log.Printf("WARNING: using an ephemeral %s signing key; tokens will not survive a restart and replicas will not share keys", alg)
+		if err := m.Rotate(); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	if m.rotateEvery > 0 && m.pinned != "" {
+		return nil, errors.New("JWT_KEY_ROTATION_INTERVAL cannot be combined with JWT_ACTIVE_KID, which pins the signing key")
+	}
+	if m.rotateEvery > 0 && m.rotateEvery <= PublishDelay {
+		return nil, fmt.Errorf("JWT_KEY_ROTATION_INTERVAL must be longer than %s", PublishDelay)
+	}
+	if err := os.MkdirAll(m.dir, 0o700); err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(m.dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 && m.pinned == "" {
+		log.Printf("No keys in %s, generating the first %s signing key", m.dir, alg)
+		if err := m.Rotate(); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	if err := m.reload(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// reload reads every *.pem file in the key directory as a PKCS#8 private key named
+// <kid>.pem and picks the signing key. Unless a kid is pinned, keys sorting before the
+// signing key count as retired from the moment their successor could sign, so they stop
+// verifying after the retention period. The current keys are kept if reading fails.
+func (m *Manager) reload() error {
+	files, err := filepath.Glob(filepath.Join(m.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no *.pem keys found in %s", m.dir)
+	}
+	sort.Strings(files)
+	loaded := make([]*SigningKey, 0, len(files))
+	for _, f := range files {
+		key, err := loadPEM(f, strings.TrimSuffix(filepath.Base(f), ".pem"))
+		if err != nil {
+			return err
+		}
+		loaded = append(loaded, key)
+	}
+	active := -1
+	now := time.Now()
+	for i, k := range loaded {
+		if m.pinned != "" {
+			if k.KID == m.pinned {
+				active = i
+			}
+		} else if now.Sub(k.CreatedAt) >= PublishDelay {
+			active = i
+		}
+	}
+	if m.pinned != "" && active < 0 {
+		return fmt.Errorf("JWT_ACTIVE_KID %q not found in %s", m.pinned, m.dir)
+	}
+	if active < 0 {
+		// Only new keys, e.g. on the first start: nobody can have tokens signed by another one
+		active = len(loaded) - 1
+	}
+	keys := make(map[string]*SigningKey, len(loaded))
+	for i, k := range loaded {
+		if m.pinned == "" && i < active {
+			retired := loaded[i+1].CreatedAt.Add(PublishDelay)
+			k.RetiredAt = &retired
+		}
+		keys[k.KID] = k
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys = keys
+	m.active = loaded[active]
+	return nil
+}
+
+// loadPEM reads a key file; its modification time is taken as the key's creation time
+func loadPEM(path, kid string) (*SigningKey, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &SigningKey{KID: kid, Method: jwt.SigningMethodRS256, Private: k, CreatedAt: info.ModTime()}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{KID: kid, Method: jwt.SigningMethodEdDSA, Private: k, CreatedAt: info.ModTime()}, nil
+	default:
+		return nil, fmt.Errorf("%s: unsupported key type %T", path, parsed)
+	}
+}
+
+// writePEM stores key as <kid>.pem in dir. The file is written under a temporary name
+// first so replicas re-reading the directory never see it half-written.
+func writePEM(dir string, key *SigningKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key.Private)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".new-key-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := pem.Encode(tmp, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, key.KID+".pem"))
+}
+
+// Rotate generates a new signing key. Without a key directory it signs right away and the
+// current key is retired; it keeps verifying tokens for the retention period, so rotation
+// never logs users out. With a directory the key is written there as
+// <UTC timestamp>-<thumbprint>.pem and signs once it has been published for PublishDelay;
+// key files retired for longer than the retention period are deleted.
+func (m *Manager) Rotate() error {
+	key, err := generate(m.alg)
+	if err != nil {
+		return err
+	}
+	if m.dir != "" {
+		key.KID = time.Now().UTC().Format("20060102T150405Z") + "-" + key.KID
+		if err := writePEM(m.dir, key); err != nil {
+			return err
+		}
+		m.removeExpired()
+		return m.reload()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if m.active != nil {
+		m.active.RetiredAt = &now
+	}
+	m.keys[key.KID] = key
+	m.active = key
+	for kid, k := range m.keys {
+		if k.RetiredAt != nil && now.Sub(*k.RetiredAt) > m.retention {
+			delete(m.keys, kid)
+		}
+	}
+	return nil
+}
+
+// removeExpired deletes the files of keys that no longer verify anything
+func (m *Manager) removeExpired() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for kid, k := range m.keys {
+		if k.RetiredAt != nil && time.Since(*k.RetiredAt) > m.retention {
+			if err := os.Remove(filepath.Join(m.dir, kid+".pem")); err != nil && !os.IsNotExist(err) {
+				log.Printf("failed to remove expired JWT signing key %s: %v", kid, err)
+			}
+		}
+	}
+}
+
+// Run keeps the keys current until stop is closed. A key directory is re-read every
+// ReloadInterval so keys added by other replicas or operators are picked up, and gets a
+// new key once its newest one is older than JWT_KEY_ROTATION_INTERVAL. An ephemeral key
+// is simply replaced on that interval.
+func (m *Manager) Run(stop <-chan struct{}) {
+	interval := ReloadInterval
+	if m.dir == "" {
+		if m.rotateEvery == 0 {
+			return
+		}
+		interval = m.rotateEvery
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := m.refresh(); err != nil {
+				log.Printf("JWT signing key refresh failed: %v", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (m *Manager) refresh() error {
+	if m.dir == "" {
+		return m.Rotate()
+	}
+	if err := m.reload(); err != nil {
+		return err
+	}
+	if m.rotateEvery > 0 && time.Since(m.newest()) >= m.rotateEvery {
+		return m.Rotate()
+	}
+	return nil
+}
+
+// newest returns when the most recent key was created
+func (m *Manager) newest() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var t time.Time
+	for _, k := range m.keys {
+		if k.CreatedAt.After(t) {
+			t = k.CreatedAt
+		}
+	}
+	return t
+}
+
+func generate(alg string) (*SigningKey, error) {
+	var key *SigningKey
+	switch alg {
+	case "RS256":
+		k, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		key = &SigningKey{Method: jwt.SigningMethodRS256, Private: k}
+	case "EdDSA":
+		_, k, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		key = &SigningKey{Method: jwt.SigningMethodEdDSA, Private: k}
+	default:
+		return nil, fmt.Errorf("unsupported JWT_SIGNING_ALG %q", alg)
+	}
+	key.CreatedAt = time.Now()
+	kid, err := thumbprint(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	key.KID = kid
+	return key, nil
+}
+
+// Active returns the key new tokens are signed with
+func (m *Manager) Active() *SigningKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.active
+}
+
+// Lookup returns the verification key for a kid, if it is still published
+func (m *Manager) Lookup(kid string) (*SigningKey, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	k, ok := m.keys[kid]
+	if !ok {
+		return nil, false
+	}
+	if k.RetiredAt != nil && time.Since(*k.RetiredAt) > m.retention {
+		return nil, false
+	}
+	return k, true
+}
+
+// Keyfunc resolves the verification key for jwt.Parse from the token's kid header
+// and rejects tokens whose alg does not match that key.
+func (m *Manager) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("missing kid header")
+	}
+	key, ok := m.Lookup(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.Public(), nil
+}
+
+// Sign signs the claims with the active key and sets the kid header
+func (m *Manager) Sign(claims jwt.Claims) (string, error) {
+	key := m.Active()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.KID
+	return token.SignedString(key.Private)
+}
+
+// JWK is a single public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns every published public key, active key first
+func (m *Manager) JWKS() JWKS {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	set := JWKS{Keys: []JWK{}}
+	kids := make([]string, 0, len(m.keys))
+	for kid := range m.keys {
+		kids = append(kids, kid)
+	}
+	sort.Slice(kids, func(i, j int) bool {
+		if kids[i] == m.active.KID {
+			return true
+		}
+		if kids[j] == m.active.KID {
+			return false
+		}
+		return kids[i] < kids[j]
+	})
+	for _, kid := range kids {
+		k := m.keys[kid]
+		if k.RetiredAt != nil && time.Since(*k.RetiredAt) > m.retention {
+			continue
+		}
+		if jwk, err := toJWK(k); err == nil {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+func toJWK(k *SigningKey) (JWK, error) {
+	b64 := base64.RawURLEncoding
+	switch pub := k.Public().(type) {
+	case *rsa.PublicKey:
+		return JWK{Kty: "RSA", Kid: k.KID, Use: "sig", Alg: k.Method.Alg(), N: b64.EncodeToString(pub.N.Bytes()), E: b64.EncodeToString(big.NewInt(int64(pub.E)).Bytes())}, nil
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Kid: k.KID, Use: "sig", Alg: k.Method.Alg(), Crv: "Ed25519", X: b64.EncodeToString(pub)}, nil
+	default:
+		return JWK{}, fmt.Errorf("unsupported public key type %T", pub)
+	}
+}
+
+// thumbprint derives a kid from the public key (truncated SHA-256 over its DER encoding)
+func thumbprint(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:12]), nil
+}
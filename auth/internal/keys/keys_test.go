@@ -0,0 +1,133 @@
+package keys
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// addKey writes a new Ed25519 key named <kid>.pem to dir, created age ago
+func addKey(t *testing.T, dir, kid string, age time.Duration) {
+	t.Helper()
+	key, err := generate("EdDSA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key.KID = kid
+	if err := writePEM(dir, key); err != nil {
+		t.Fatal(err)
+	}
+	created := time.Now().Add(-age)
+	if err := os.Chtimes(filepath.Join(dir, kid+".pem"), created, created); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newManager(t *testing.T, dir string, retention time.Duration) *Manager {
+	t.Helper()
+	m := &Manager{keys: map[string]*SigningKey{}, retention: retention, alg: "EdDSA", dir: dir}
+	if err := m.reload(); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func sign(t *testing.T, m *Manager) string {
+	t.Helper()
+	token, err := m.Sign(jwt.MapClaims{"sub": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// verifies reports whether m still verifies token
+func verifies(m *Manager, token string) bool {
+	_, err := jwt.Parse(token, m.Keyfunc)
+	return err == nil
+}
+
+func published(m *Manager, kid string) bool {
+	for _, k := range m.JWKS().Keys {
+		if k.Kid == kid {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRotationAfterPublishDelay(t *testing.T) {
+	dir := t.TempDir()
+	addKey(t, dir, "a", time.Hour)
+	m := newManager(t, dir, time.Hour)
+	old := sign(t, m)
+
+	addKey(t, dir, "b", time.Minute)
+	if err := m.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if kid := m.Active().KID; kid != "a" {
+		t.Fatalf("active key during the publish delay = %s, want a", kid)
+	}
+	if !published(m, "b") {
+		t.Error("new key not published during the publish delay")
+	}
+
+	addKey(t, dir, "b", PublishDelay+time.Minute)
+	if err := m.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if kid := m.Active().KID; kid != "b" {
+		t.Fatalf("active key after the publish delay = %s, want b", kid)
+	}
+	if !verifies(m, old) || !published(m, "a") {
+		t.Error("retired key stopped verifying within the retention period")
+	}
+	if !verifies(m, sign(t, m)) {
+		t.Error("token of the new key does not verify")
+	}
+}
+
+func TestRetiredKeyExpires(t *testing.T) {
+	dir := t.TempDir()
+	addKey(t, dir, "a", 3*time.Hour)
+	m := newManager(t, dir, time.Hour)
+	old := sign(t, m)
+
+	// b started signing about 1h55m ago, so a retired longer than the retention period ago
+	addKey(t, dir, "b", 2*time.Hour)
+	if err := m.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if verifies(m, old) || published(m, "a") {
+		t.Error("key retired longer than the retention period still verifies")
+	}
+}
+
+func TestFirstKeysSignRightAway(t *testing.T) {
+	dir := t.TempDir()
+	addKey(t, dir, "a", 0)
+	addKey(t, dir, "b", 0)
+	if kid := newManager(t, dir, time.Hour).Active().KID; kid != "b" {
+		t.Fatalf("active key = %s, want b", kid)
+	}
+}
+
+func TestPinnedKey(t *testing.T) {
+	dir := t.TempDir()
+	addKey(t, dir, "a", 3*time.Hour)
+	addKey(t, dir, "b", 2*time.Hour)
+	m := &Manager{keys: map[string]*SigningKey{}, retention: time.Hour, alg: "EdDSA", dir: dir, pinned: "a"}
+	if err := m.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if kid := m.Active().KID; kid != "a" {
+		t.Fatalf("active key = %s, want the pinned a", kid)
+	}
+	if !published(m, "b") {
+		t.Error("keys are not retired while a kid is pinned")
+	}
+}
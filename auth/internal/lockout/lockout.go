@@ -0,0 +1,187 @@
+// Package lockout slows down password guessing. Failed logins are counted per username
+// and per client IP; after a few free attempts every further try has to wait
+// exponentially longer, and too many failures lock the username (or block the IP)
+// for a while. Counters live in a pluggable Store so several auth-service replicas
+// can share them.
+package lockout
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Counter is the failure state stored for one key
+type Counter struct {
+	Failures    int
+	LastFailure time.Time
+}
+
+// Store keeps failure counters. Counters expire ttl after their last failure.
+type Store interface {
+	Get(key string) (Counter, error)
+	Increment(key string, now time.Time, ttl time.Duration) (Counter, error)
+	Reset(key string) error
+}
+
+// Config controls backoff and lockout thresholds
+type Config struct {
+	FreeAttempts    int           // failures before backoff starts (LOGIN_BACKOFF_FREE_ATTEMPTS)
+	IPFreeAttempts  int           // the same per client IP, higher for shared NAT addresses (LOGIN_IP_BACKOFF_FREE_ATTEMPTS)
+	BaseDelay       time.Duration // first backoff delay, doubled per failure (LOGIN_BACKOFF_BASE)
+	MaxDelay        time.Duration // upper bound of the backoff delay (LOGIN_BACKOFF_MAX)
+	MaxFailures     int           // failures that lock a username (LOGIN_MAX_FAILURES)
+	IPMaxFailures   int           // failures that block a client IP (LOGIN_IP_MAX_FAILURES)
+	LockoutDuration time.Duration // how long a lock or block lasts (LOGIN_LOCKOUT_DURATION)
+}
+
+// ConfigFromEnv returns the lockout settings with their defaults
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{FreeAttempts: 3, IPFreeAttempts: 20, BaseDelay: time.Second, MaxDelay: time.Minute, MaxFailures: 10, IPMaxFailures: 100, LockoutDuration: 15 * time.Minute}
+	ints := map[string]*int{"LOGIN_BACKOFF_FREE_ATTEMPTS": &cfg.FreeAttempts, "LOGIN_IP_BACKOFF_FREE_ATTEMPTS": &cfg.IPFreeAttempts, "LOGIN_MAX_FAILURES": &cfg.MaxFailures, "LOGIN_IP_MAX_FAILURES": &cfg.IPMaxFailures}
+	for name, dst := range ints {
+		if s := os.Getenv(name); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				return cfg, fmt.Errorf("invalid %s %q", name, s)
+			}
+			*dst = n
+		}
+	}
+	durations := map[string]*time.Duration{"LOGIN_BACKOFF_BASE": &cfg.BaseDelay, "LOGIN_BACKOFF_MAX": &cfg.MaxDelay, "LOGIN_LOCKOUT_DURATION": &cfg.LockoutDuration}
+	for name, dst := range durations {
+		if s := os.Getenv(name); s != "" {
+			d, err := time.ParseDuration(s)
+			if err != nil || d < 0 {
+				return cfg, fmt.Errorf("invalid %s %q", name, s)
+			}
+			*dst = d
+		}
+	}
+	return cfg, nil
+}
+
+// Error is returned while a login attempt has to wait
+type Error struct {
+	// Locked is set when the username is locked (as opposed to backoff or an IP block)
+	Locked     bool
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.Locked {
+		return "account locked"
+	}
+	return "too many failed attempts"
+}
+
+// Guard applies Config to the counters in a Store
+type Guard struct {
+	cfg   Config
+	store Store
+	now   func() time.Time
+}
+
+// NewGuard creates a guard; a nil store falls back to NewMemoryStore
+func NewGuard(cfg Config, store Store) *Guard {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	return &Guard{cfg: cfg, store: store, now: time.Now}
+}
+
+func userKey(username string) string { return "user:" + strings.ToLower(username) }
+func ipKey(ip string) string         { return "ip:" + ip }
+
+// Check returns an *Error if a login for username from ip must not be attempted now
+func (g *Guard) Check(username, ip string) error {
+	now := g.now()
+	user, err := g.store.Get(userKey(username))
+	if err != nil {
+		return err
+	}
+	if wait := g.wait(user, g.cfg.FreeAttempts, g.cfg.MaxFailures, now); wait > 0 {
+		return &Error{Locked: g.cfg.MaxFailures > 0 && user.Failures >= g.cfg.MaxFailures, RetryAfter: wait}
+	}
+	if ip == "" {
+		return nil
+	}
+	addr, err := g.store.Get(ipKey(ip))
+	if err != nil {
+		return err
+	}
+	if wait := g.wait(addr, g.cfg.IPFreeAttempts, g.cfg.IPMaxFailures, now); wait > 0 {
+		return &Error{RetryAfter: wait}
+	}
+	return nil
+}
+
+// Failure records a failed attempt. It reports whether this failure locked the
+// username and until when.
+func (g *Guard) Failure(username, ip string) (bool, time.Time, error) {
+	now := g.now()
+	ttl := g.cfg.LockoutDuration
+	if g.cfg.MaxDelay > ttl {
+		ttl = g.cfg.MaxDelay
+	}
+	if ip != "" {
+		if _, err := g.store.Increment(ipKey(ip), now, ttl); err != nil {
+			return false, time.Time{}, err
+		}
+	}
+	user, err := g.store.Increment(userKey(username), now, ttl)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	// Check refuses attempts while locked, so every failure past the limit starts a new lock
+	if g.cfg.MaxFailures > 0 && user.Failures >= g.cfg.MaxFailures {
+		return true, now.Add(g.cfg.LockoutDuration), nil
+	}
+	return false, time.Time{}, nil
+}
+
+// Success clears the username's counter. The IP counter is kept so that one known
+// password cannot be used to reset the budget for guessing others.
+func (g *Guard) Success(username string) error {
+	return g.store.Reset(userKey(username))
+}
+
+// Unlock lifts a lock or backoff on the username (admin action)
+func (g *Guard) Unlock(username string) error {
+	return g.store.Reset(userKey(username))
+}
+
+// LockedUntil returns when the username's lock ends, or the zero time if it is not locked
+func (g *Guard) LockedUntil(username string) (time.Time, error) {
+	c, err := g.store.Get(userKey(username))
+	if err != nil || g.cfg.MaxFailures == 0 || c.Failures < g.cfg.MaxFailures {
+		return time.Time{}, err
+	}
+	until := c.LastFailure.Add(g.cfg.LockoutDuration)
+	if !until.After(g.now()) {
+		return time.Time{}, nil
+	}
+	return until, nil
+}
+
+// wait returns how long the next attempt for a counter has to wait
+func (g *Guard) wait(c Counter, freeAttempts, maxFailures int, now time.Time) time.Duration {
+	var delay time.Duration
+	switch {
+	case maxFailures > 0 && c.Failures >= maxFailures:
+		delay = g.cfg.LockoutDuration
+	case c.Failures > freeAttempts:
+		delay = g.cfg.BaseDelay
+		for i := freeAttempts + 1; i < c.Failures && delay < g.cfg.MaxDelay; i++ {
+			delay *= 2
+		}
+		if delay > g.cfg.MaxDelay {
+			delay = g.cfg.MaxDelay
+		}
+	default:
+		return 0
+	}
+	return c.LastFailure.Add(delay).Sub(now)
+}
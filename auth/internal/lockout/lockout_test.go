@@ -0,0 +1,150 @@
+package lockout
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var testConfig = Config{FreeAttempts: 3, IPFreeAttempts: 5, BaseDelay: time.Second, MaxDelay: 4 * time.Second,
+	MaxFailures: 8, IPMaxFailures: 10, LockoutDuration: time.Minute}
+
+// newTestGuard returns a guard with an in-memory store and a clock the test advances
+func newTestGuard(cfg Config) (*Guard, *time.Time) {
+	g := NewGuard(cfg, nil)
+	now := time.Now()
+	g.now = func() time.Time { return now }
+	return g, &now
+}
+
+func fail(t *testing.T, g *Guard, username, ip string, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if _, _, err := g.Failure(username, ip); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// checkWait asserts that Check answers with the given lock state and wait; a zero wait
+// means the attempt is allowed
+func checkWait(t *testing.T, g *Guard, username, ip string, locked bool, wait time.Duration) {
+	t.Helper()
+	err := g.Check(username, ip)
+	if wait == 0 {
+		if err != nil {
+			t.Fatalf("Check = %v, want allowed", err)
+		}
+		return
+	}
+	var le *Error
+	if !errors.As(err, &le) {
+		t.Fatalf("Check = %v, want *Error", err)
+	}
+	if le.Locked != locked || le.RetryAfter != wait {
+		t.Fatalf("Check = {Locked: %v, RetryAfter: %s}, want {Locked: %v, RetryAfter: %s}", le.Locked, le.RetryAfter, locked, wait)
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	g, now := newTestGuard(testConfig)
+	fail(t, g, "jane", "", 3)
+	checkWait(t, g, "jane", "", false, 0)
+
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		fail(t, g, "jane", "", 1)
+		checkWait(t, g, "jane", "", false, want)
+		*now = now.Add(want)
+		checkWait(t, g, "jane", "", false, 0)
+	}
+}
+
+func TestLockout(t *testing.T) {
+	g, now := newTestGuard(testConfig)
+	fail(t, g, "jane", "", 7)
+	if until, _ := g.LockedUntil("jane"); !until.IsZero() {
+		t.Fatalf("locked until %s before reaching MaxFailures", until)
+	}
+	locked, until, err := g.Failure("jane", "")
+	if err != nil || !locked || !until.Equal(now.Add(time.Minute)) {
+		t.Fatalf("Failure = (%v, %s, %v), want locked until %s", locked, until, err, now.Add(time.Minute))
+	}
+	checkWait(t, g, "jane", "", true, time.Minute)
+	if got, _ := g.LockedUntil("jane"); !got.Equal(until) {
+		t.Errorf("LockedUntil = %s, want %s", got, until)
+	}
+
+	*now = now.Add(time.Minute)
+	if got, _ := g.LockedUntil("jane"); !got.IsZero() {
+		t.Errorf("LockedUntil = %s after the lockout duration, want zero", got)
+	}
+	checkWait(t, g, "jane", "", false, 0)
+}
+
+func TestUnlockAndSuccess(t *testing.T) {
+	g, _ := newTestGuard(testConfig)
+	fail(t, g, "jane", "", 8)
+	if err := g.Unlock("jane"); err != nil {
+		t.Fatal(err)
+	}
+	checkWait(t, g, "jane", "", false, 0)
+
+	fail(t, g, "jane", "10.0.0.1", 4)
+	if err := g.Success("jane"); err != nil {
+		t.Fatal(err)
+	}
+	checkWait(t, g, "jane", "10.0.0.1", false, 0)
+	if c, _ := g.store.Get(ipKey("10.0.0.1")); c.Failures != 4 {
+		t.Errorf("IP failures after a success = %d, want 4", c.Failures)
+	}
+}
+
+func TestUsernamesIgnoreCase(t *testing.T) {
+	g, _ := newTestGuard(testConfig)
+	fail(t, g, "Jane", "", 4)
+	checkWait(t, g, "jane", "", false, time.Second)
+}
+
+func TestIPBackoff(t *testing.T) {
+	g, now := newTestGuard(testConfig)
+	// Guessing across usernames is throttled by the IP counter
+	for _, username := range []string{"a", "b", "c", "d", "e", "f"} {
+		fail(t, g, username, "10.0.0.1", 1)
+	}
+	checkWait(t, g, "g", "10.0.0.1", false, time.Second)
+	checkWait(t, g, "g", "10.0.0.2", false, 0)
+	checkWait(t, g, "g", "", false, 0)
+
+	*now = now.Add(time.Second)
+	checkWait(t, g, "g", "10.0.0.1", false, 0)
+	fail(t, g, "g", "10.0.0.1", 4)
+	// An IP block never reports the username as locked
+	checkWait(t, g, "h", "10.0.0.1", false, time.Minute)
+}
+
+func TestDisabledLockout(t *testing.T) {
+	cfg := testConfig
+	cfg.MaxFailures = 0
+	g, _ := newTestGuard(cfg)
+	fail(t, g, "jane", "", 50)
+	checkWait(t, g, "jane", "", false, 4*time.Second)
+	if until, _ := g.LockedUntil("jane"); !until.IsZero() {
+		t.Errorf("LockedUntil = %s without MaxFailures, want zero", until)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("LOGIN_MAX_FAILURES", "5")
+	t.Setenv("LOGIN_BACKOFF_BASE", "500ms")
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxFailures != 5 || cfg.BaseDelay != 500*time.Millisecond || cfg.FreeAttempts != 3 {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+	t.Setenv("LOGIN_BACKOFF_MAX", "-1s")
+	if _, err := ConfigFromEnv(); err == nil {
+		t.Error("negative LOGIN_BACKOFF_MAX accepted")
+	}
+}
@@ -0,0 +1,67 @@
+package lockout
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps counters in process memory. It is the default; with several
+// replicas each one counts separately, so use a shared store (LOGIN_COUNTER_STORE=database).
+type MemoryStore struct {
+	mu       sync.Mutex
+	counters map[string]*memoryCounter
+	swept    time.Time
+}
+
+type memoryCounter struct {
+	Counter
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: map[string]*memoryCounter{}, swept: time.Now()}
+}
+
+// Get returns the counter for key, or a zero counter if it does not exist or expired
+func (s *MemoryStore) Get(key string) (Counter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.counters[key]
+	if !ok || time.Now().After(c.expiresAt) {
+		return Counter{}, nil
+	}
+	return c.Counter, nil
+}
+
+// Increment adds a failure to key and returns the updated counter
+func (s *MemoryStore) Increment(key string, now time.Time, ttl time.Duration) (Counter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.swept) > time.Minute {
+		// Drop expired counters so the map does not grow with every username ever tried
+		for k, c := range s.counters {
+			if now.After(c.expiresAt) {
+				delete(s.counters, k)
+			}
+		}
+		s.swept = now
+	}
+	c, ok := s.counters[key]
+	if !ok || now.After(c.expiresAt) {
+		c = &memoryCounter{}
+		s.counters[key] = c
+	}
+	c.Failures++
+	c.LastFailure = now
+	c.expiresAt = now.Add(ttl)
+	return c.Counter, nil
+}
+
+// Reset removes the counter for key
+func (s *MemoryStore) Reset(key string) error {
+	s.mu.Lock()
+	delete(s.counters, key)
+	s.mu.Unlock()
+	return nil
+}
@@ -4,6 +4,9 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/apperr"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/policy"
 	"github.com/VerSysLabTin23/TodolistProject/auth/internal/service"
 	"github.com/gin-gonic/gin"
 )
@@ -17,58 +20,142 @@ func NewJWTMiddleware(authService *service.AuthService) *JWTMiddleware {
 
 // RequireAuth validates the Authorization header and verifies the JWT:
 // - expects header format: "Authorization: Bearer <token>"
-// - extracts token and delegates validation to AuthService.ValidateToken
-// - on success, puts user information (id, username, role) into the request context
+// - extracts token and delegates validation to AuthService.ValidateToken (access tokens and personal access tokens only)
+// - on success, puts user information (id, username, role and permissions, OAuth clientID and scope, session ID, impersonator ID, timezone) into the request context
 // - on failure, responds 401 and aborts the request
 func (m *JWTMiddleware) RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"code": "UNAUTHORIZED", "message": "Missing authorization header"})
+			apperr.Respond(c, http.StatusUnauthorized, "UNAUTHORIZED", "Missing authorization header")
 			c.Abort()
 			return
 		}
 		if !strings.HasPrefix(authHeader, "Bearer ") {
-			c.JSON(http.StatusUnauthorized, gin.H{"code": "UNAUTHORIZED", "message": "Invalid authorization header format"})
+			apperr.Respond(c, http.StatusUnauthorized, "UNAUTHORIZED", "Invalid authorization header format")
 			c.Abort()
 			return
 		}
 		token := strings.TrimPrefix(authHeader, "Bearer ")
 		claims, err := m.authService.ValidateToken(token) // validate token: parse token and check if it is valid
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"code": "UNAUTHORIZED", "message": "Invalid or expired token"})
+			apperr.Respond(c, http.StatusUnauthorized, "UNAUTHORIZED", "Invalid or expired token")
 			c.Abort()
 			return
 		}
 		c.Set("userID", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("userRole", claims.Role)
+		c.Set("permissions", claims.Permissions)
+		c.Set("tokenType", claims.TokenType)
+		c.Set("restricted", claims.Restricted)
+		c.Set("clientID", claims.ClientID)
+		c.Set("scope", claims.Scope)
+		c.Set("sessionID", claims.SessionID)
+		c.Set("impersonatorID", claims.ImpersonatorID)
+		c.Set("timezone", claims.Timezone)
 		c.Next()
 	}
 }
 
-func (m *JWTMiddleware) RequireRole(requiredRole string) gin.HandlerFunc {
+// RequireFirstParty must run after RequireAuth; it rejects access tokens issued to
+// OAuth clients and personal access tokens. Their scopes only cover the task and team
+// APIs, so applications and scripts cannot manage accounts, change passwords or mint
+// further tokens.
+func (m *JWTMiddleware) RequireFirstParty() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		m.RequireAuth()(c)
-		if c.IsAborted() {
+		if c.GetString("clientID") != "" || c.GetString("tokenType") == models.TokenTypePersonal {
+			c.Header("WWW-Authenticate", `Bearer error="insufficient_scope"`)
+			apperr.Respond(c, http.StatusForbidden, "INSUFFICIENT_SCOPE", "Tokens issued to OAuth clients and personal access tokens cannot use this endpoint")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireScope must run after RequireAuth; tokens issued to OAuth clients and personal
+// access tokens need scope, first-party tokens are not limited
+func (m *JWTMiddleware) RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("clientID") == "" && c.GetString("tokenType") != models.TokenTypePersonal {
+			c.Next()
+			return
+		}
+		for _, s := range strings.Fields(c.GetString("scope")) {
+			if s == scope {
+				c.Next()
+				return
+			}
+		}
+		c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+		apperr.Respond(c, http.StatusForbidden, "INSUFFICIENT_SCOPE", "This endpoint requires the "+scope+" scope")
+		c.Abort()
+	}
+}
+
+// RequireRealUser must run after RequireAuth; it rejects impersonation tokens, so an
+// admin acting as a user cannot change the user's password, 2FA or credentials
+func (m *JWTMiddleware) RequireRealUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetInt("impersonatorID") != 0 {
+			apperr.Respond(c, http.StatusForbidden, "IMPERSONATION_FORBIDDEN", "This endpoint cannot be used while impersonating a user")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireVerifiedEmail must run after RequireAuth; it rejects restricted tokens, i.e.
+// those of users who have not verified their email under the "restricted" policy
+func (m *JWTMiddleware) RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool("restricted") {
+			apperr.Respond(c, http.StatusForbidden, "EMAIL_NOT_VERIFIED", "Verify your email address to use this endpoint")
+			c.Abort()
 			return
 		}
-		userRole, exists := c.Get("userRole")
-		if !exists {
-			c.JSON(http.StatusInternalServerError, gin.H{"code": "INTERNAL_ERROR", "message": "User role not found in context"})
+		c.Next()
+	}
+}
+
+// RequireService accepts only service tokens obtained through the client credentials
+// grant (POST /oauth/token) and stores the calling client's ID as "serviceClient".
+// User access tokens are rejected, so /internal routes are not reachable from browsers.
+func (m *JWTMiddleware) RequireService() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			apperr.Respond(c, http.StatusUnauthorized, "UNAUTHORIZED", "Missing or invalid service credentials")
 			c.Abort()
 			return
 		}
-		if userRole != requiredRole {
-			c.JSON(http.StatusForbidden, gin.H{"code": "FORBIDDEN", "message": "Insufficient permissions"})
+		claims, err := m.authService.ValidateServiceToken(strings.TrimPrefix(authHeader, "Bearer "))
+		if err != nil {
+			apperr.Respond(c, http.StatusUnauthorized, "UNAUTHORIZED", "Invalid or expired service token")
 			c.Abort()
 			return
 		}
+		c.Set("serviceClient", claims.ClientID)
 		c.Next()
 	}
 }
 
-func (m *JWTMiddleware) RequireAdmin() gin.HandlerFunc { return m.RequireRole("admin") }
+// RequirePermission must run after RequireAuth; it rejects callers whose token does not
+// carry permission (granted by their role) with the same structured 403 as the policy
+// checks in the handlers
+func (m *JWTMiddleware) RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		a := policy.Actor{UserID: c.GetInt("userID"), Role: c.GetString("userRole"), Permissions: c.GetStringSlice("permissions")}
+		if v := policy.Require(a, permission, "This endpoint requires the "+permission+" permission"); v != nil {
+			apperr.RespondWith(c, http.StatusForbidden, "FORBIDDEN", v.Message, gin.H{"reason": v.Reason, "permission": v.Permission})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
 
 func GetUserIDFromContext(c *gin.Context) (int, bool) {
 	v, ok := c.Get("userID")
@@ -100,3 +187,12 @@ func GetUserRoleFromContext(c *gin.Context) (string, bool) {
 	}
 	return "", false
 }
+func GetPermissionsFromContext(c *gin.Context) []string {
+	return c.GetStringSlice("permissions")
+}
+
+// GetImpersonatorIDFromContext returns the admin acting as the user, or 0 if the
+// request was not made with an impersonation token
+func GetImpersonatorIDFromContext(c *gin.Context) int {
+	return c.GetInt("impersonatorID")
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/apperr"
+)
+
+// RateLimiter is a fixed-window, in-memory limiter keyed by client IP and route.
+// It protects unauthenticated endpoints that send email or redeem emailed tokens;
+// with several replicas each instance counts separately.
+type RateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+	swept   time.Time
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// NewRateLimiter allows limit requests per client and route within each window
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{limit: limit, window: window, windows: map[string]*rateWindow{}, swept: time.Now()}
+}
+
+// Limit responds 429 with Retry-After once a client exceeds the limit for the route
+func (rl *RateLimiter) Limit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		retryAfter, ok := rl.allow(c.ClientIP() + " " + c.FullPath())
+		if !ok {
+			c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			apperr.Respond(c, http.StatusTooManyRequests, "RATE_LIMITED", "Too many requests, try again later")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func (rl *RateLimiter) allow(key string) (time.Duration, bool) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	now := time.Now()
+	if now.Sub(rl.swept) > rl.window {
+		// Drop finished windows so the map does not grow with every client ever seen
+		for k, w := range rl.windows {
+			if now.Sub(w.start) >= rl.window {
+				delete(rl.windows, k)
+			}
+		}
+		rl.swept = now
+	}
+	w, ok := rl.windows[key]
+	if !ok || now.Sub(w.start) >= rl.window {
+		rl.windows[key] = &rateWindow{start: now, count: 1}
+		return 0, true
+	}
+	if w.count >= rl.limit {
+		return rl.window - now.Sub(w.start), false
+	}
+	w.count++
+	return 0, true
+}
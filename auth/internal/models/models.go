@@ -1,23 +1,52 @@
 package models
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
 // User represents a user in the system
 type User struct {
-	ID           int       `json:"id" gorm:"primaryKey"`
-	Username     string    `json:"username" gorm:"size:255;uniqueIndex;not null"`
-	Email        string    `json:"email" gorm:"size:255;uniqueIndex;not null"`
-	PasswordHash string    `json:"-" gorm:"column:password_hash;not null"`
-	FirstName    string    `json:"firstName" gorm:"column:first_name"`
-	LastName     string    `json:"lastName" gorm:"column:last_name"`
-	Role         string    `json:"role" gorm:"not null;default:'user'"`
-	IsActive     bool      `json:"isActive" gorm:"column:is_active;not null;default:true"`
-	CreatedAt    time.Time `json:"createdAt" gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
-	UpdatedAt    time.Time `json:"updatedAt" gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"`
+	ID           int    `json:"id" gorm:"primaryKey"`
+	Username     string `json:"username" gorm:"size:255;uniqueIndex;not null"`
+	Email        string `json:"email" gorm:"size:255;uniqueIndex;not null"`
+	PasswordHash string `json:"-" gorm:"column:password_hash;not null"`
+	FirstName    string `json:"firstName" gorm:"column:first_name"`
+	LastName     string `json:"lastName" gorm:"column:last_name"`
+	Role         string `json:"role" gorm:"not null;default:'user'"`
+	IsActive     bool   `json:"isActive" gorm:"column:is_active;not null;default:true"`
+	// EmailVerified is false for self-registered accounts until POST /auth/verify-email
+	EmailVerified   bool       `json:"emailVerified" gorm:"column:email_verified;not null;default:false"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt,omitempty" gorm:"column:email_verified_at"`
+	// MFAEnabled is set once a TOTP enrollment has been confirmed. TOTPSecret may hold
+	// a pending secret while MFAEnabled is still false; it is encrypted when
+	// MFA_ENCRYPTION_KEY is configured.
+	MFAEnabled   bool   `json:"mfaEnabled" gorm:"column:mfa_enabled;not null;default:false"`
+	TOTPSecret   string `json:"-" gorm:"column:totp_secret"`
+	TOTPLastStep int64  `json:"-" gorm:"column:totp_last_step;not null;default:0"`
+	// TokenVersion is embedded in every access and refresh token; tokens carrying an
+	// older version are rejected, so bumping it revokes them all at once
+	TokenVersion int `json:"-" gorm:"column:token_version;not null;default:0"`
+	// Locale (BCP 47, e.g. "de-DE") and Timezone (IANA, e.g. "Europe/Berlin") are display
+	// preferences; empty means unset, which clients treat as "en" and UTC
+	Locale   string `json:"locale" gorm:"column:locale;size:35;not null;default:''"`
+	Timezone string `json:"timezone" gorm:"column:timezone;size:64;not null;default:''"`
+	// AvatarKey names the thumbnails of the current avatar in the avatar store; it changes
+	// with every upload so avatar URLs can be cached forever
+	AvatarKey string `json:"-" gorm:"column:avatar_key;size:32;not null;default:''"`
+	// ExternalID identifies the user in an external directory that provisions it via SCIM
+	ExternalID string `json:"-" gorm:"column:external_id;size:255;not null;default:''"`
+	// DeletedAt marks a deleted account during its grace period; GORM leaves such
+	// users out of all queries unless Unscoped is used
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"column:deleted_at;index"`
+	CreatedAt time.Time      `json:"createdAt" gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time      `json:"updatedAt" gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"`
 }
 
 // TableName specifies the table name for User
@@ -25,15 +54,681 @@ func (User) TableName() string {
 	return "users"
 }
 
+// RefreshToken is a persisted, hashed refresh token. Tokens issued from the same
+// login share a FamilyID; rotating a token marks the old row with RotatedAt.
+// Tokens issued to an OAuth client carry its ClientID and the granted Scope.
+type RefreshToken struct {
+	ID        int64      `gorm:"primaryKey"`
+	UserID    int        `gorm:"column:user_id;not null"`
+	FamilyID  string     `gorm:"column:family_id;size:36;not null"`
+	ClientID  string     `gorm:"column:client_id;size:64;not null"`
+	Scope     string     `gorm:"column:scope;size:255;not null"`
+	TokenHash string     `gorm:"column:token_hash;size:64;uniqueIndex;not null"`
+	UserAgent string     `gorm:"column:user_agent;size:255"`
+	IPAddress string     `gorm:"column:ip_address;size:45"`
+	ExpiresAt time.Time  `gorm:"column:expires_at;not null"`
+	RotatedAt *time.Time `gorm:"column:rotated_at"`
+	RevokedAt *time.Time `gorm:"column:revoked_at"`
+	CreatedAt time.Time  `gorm:"column:created_at;autoCreateTime"`
+}
+
+// TableName specifies the table name for RefreshToken
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// AccountErasure tracks the erasure of a deleted account. Until EraseAfter an admin
+// can restore the account; then the user is anonymized and every service in Steps is
+// asked to erase its data and report back.
+type AccountErasure struct {
+	UserID          int                  `gorm:"column:user_id;primaryKey;autoIncrement:false"`
+	RequestedBy     int                  `gorm:"column:requested_by;not null"`
+	EraseAfter      time.Time            `gorm:"column:erase_after;not null"`
+	StartedAt       *time.Time           `gorm:"column:started_at"`
+	LastRequestedAt *time.Time           `gorm:"column:last_requested_at"`
+	CompletedAt     *time.Time           `gorm:"column:completed_at"`
+	CreatedAt       time.Time            `gorm:"column:created_at;autoCreateTime"`
+	Steps           []AccountErasureStep `gorm:"foreignKey:UserID;references:UserID"`
+}
+
+// TableName specifies the table name for AccountErasure
+func (AccountErasure) TableName() string {
+	return "account_erasures"
+}
+
+// AccountErasureStep is one service's part of an erasure
+type AccountErasureStep struct {
+	UserID      int        `gorm:"column:user_id;primaryKey;autoIncrement:false"`
+	Service     string     `gorm:"column:service;primaryKey;size:32"`
+	CompletedAt *time.Time `gorm:"column:completed_at"`
+}
+
+// TableName specifies the table name for AccountErasureStep
+func (AccountErasureStep) TableName() string {
+	return "account_erasure_steps"
+}
+
+// ToResponse converts an AccountErasure to its API representation
+func (e *AccountErasure) ToResponse() AccountErasureResponse {
+	resp := AccountErasureResponse{UserID: e.UserID, RequestedBy: e.RequestedBy, EraseAfter: e.EraseAfter, StartedAt: e.StartedAt, CompletedAt: e.CompletedAt, Services: map[string]*time.Time{}}
+	for _, step := range e.Steps {
+		resp.Services[step.Service] = step.CompletedAt
+	}
+	return resp
+}
+
+// Session is a first-party login on one device. Its ID is the family ID of the
+// refresh tokens issued for the login; the row is deleted when the session is revoked.
+type Session struct {
+	ID         string    `gorm:"column:id;primaryKey;size:36"`
+	UserID     int       `gorm:"column:user_id;not null"`
+	Device     string    `gorm:"column:device;size:100;not null"`
+	UserAgent  string    `gorm:"column:user_agent;size:255"`
+	IPAddress  string    `gorm:"column:ip_address;size:45"`
+	CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime"`
+	LastSeenAt time.Time `gorm:"column:last_seen_at;not null"`
+	ExpiresAt  time.Time `gorm:"column:expires_at;not null"`
+}
+
+// TableName specifies the table name for Session
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// ToResponse converts a Session to its API representation; current marks the
+// session the request was made from
+func (s *Session) ToResponse(current bool) SessionResponse {
+	return SessionResponse{
+		ID:         s.ID,
+		Device:     s.Device,
+		IPAddress:  s.IPAddress,
+		UserAgent:  s.UserAgent,
+		CreatedAt:  s.CreatedAt,
+		LastSeenAt: s.LastSeenAt,
+		ExpiresAt:  s.ExpiresAt,
+		Current:    current,
+	}
+}
+
+// Purposes of one-time tokens
+const (
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposePasswordReset     = "password_reset"
+)
+
+// OneTimeToken is a hashed, expiring, single-use token sent to the user by email
+type OneTimeToken struct {
+	ID        int64      `gorm:"primaryKey"`
+	UserID    int        `gorm:"column:user_id;not null"`
+	Purpose   string     `gorm:"column:purpose;size:32;not null"`
+	TokenHash string     `gorm:"column:token_hash;size:64;uniqueIndex;not null"`
+	ExpiresAt time.Time  `gorm:"column:expires_at;not null"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+	CreatedAt time.Time  `gorm:"column:created_at;autoCreateTime"`
+}
+
+// TableName specifies the table name for OneTimeToken
+func (OneTimeToken) TableName() string {
+	return "one_time_tokens"
+}
+
+// MFARecoveryCode is a hashed single-use code that replaces a TOTP code once
+type MFARecoveryCode struct {
+	ID        int64      `gorm:"primaryKey"`
+	UserID    int        `gorm:"column:user_id;not null"`
+	CodeHash  string     `gorm:"column:code_hash;size:64;not null"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+	CreatedAt time.Time  `gorm:"column:created_at;autoCreateTime"`
+}
+
+// TableName specifies the table name for MFARecoveryCode
+func (MFARecoveryCode) TableName() string {
+	return "mfa_recovery_codes"
+}
+
+// Setting is a runtime setting managed by admins
+type Setting struct {
+	Name      string    `gorm:"column:name;primaryKey;size:64"`
+	Value     string    `gorm:"column:value;not null"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// TableName specifies the table name for Setting
+func (Setting) TableName() string {
+	return "settings"
+}
+
+// LoginFailure is a failed-login counter shared by all auth-service replicas
+// (LOGIN_COUNTER_STORE=database). Keys are "user:<username>" or "ip:<address>".
+type LoginFailure struct {
+	CounterKey    string    `gorm:"column:counter_key;primaryKey;size:191"`
+	Failures      int       `gorm:"column:failures;not null"`
+	LastFailureAt time.Time `gorm:"column:last_failure_at;not null"`
+	ExpiresAt     time.Time `gorm:"column:expires_at;not null"`
+}
+
+// TableName specifies the table name for LoginFailure
+func (LoginFailure) TableName() string {
+	return "login_failures"
+}
+
+// UserIdentity links an account at an external OIDC provider to a local user
+type UserIdentity struct {
+	ID          int64      `json:"id" gorm:"primaryKey"`
+	UserID      int        `json:"-" gorm:"column:user_id;not null"`
+	Provider    string     `json:"provider" gorm:"column:provider;size:64;not null"`
+	Subject     string     `json:"subject" gorm:"column:subject;size:255;not null"`
+	Email       string     `json:"email" gorm:"column:email"`
+	CreatedAt   time.Time  `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+	LastLoginAt *time.Time `json:"lastLoginAt,omitempty" gorm:"column:last_login_at"`
+}
+
+// TableName specifies the table name for UserIdentity
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
+
+// OIDCLoginState is a pending OIDC login between the redirect to the provider and
+// its callback. Only the hash of the state parameter is stored.
+type OIDCLoginState struct {
+	StateHash    string    `gorm:"column:state_hash;primaryKey;size:64"`
+	Provider     string    `gorm:"column:provider;size:64;not null"`
+	CodeVerifier string    `gorm:"column:code_verifier;not null"`
+	Nonce        string    `gorm:"column:nonce;not null"`
+	ExpiresAt    time.Time `gorm:"column:expires_at;not null"`
+	CreatedAt    time.Time `gorm:"column:created_at;autoCreateTime"`
+}
+
+// TableName specifies the table name for OIDCLoginState
+func (OIDCLoginState) TableName() string {
+	return "oidc_login_states"
+}
+
+// OIDCProviderInfo describes a configured external identity provider
+type OIDCProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+	LoginURL    string `json:"loginUrl"`
+}
+
+// OAuth scopes third-party clients can request. Access tokens without a client_id
+// (first-party logins) are not limited by scopes.
+const (
+	ScopeTasksRead  = "tasks:read"
+	ScopeTasksWrite = "tasks:write"
+	ScopeTeamsRead  = "teams:read"
+	ScopeTeamsWrite = "teams:write"
+	ScopeTeamsAdmin = "teams:admin"
+	ScopeSCIM       = "scim"
+)
+
+// ScopeDescriptions explains each scope on the consent screen
+var ScopeDescriptions = map[string]string{
+	ScopeTasksRead:  "View tasks in your teams",
+	ScopeTasksWrite: "Create, edit, assign and delete tasks in your teams",
+	ScopeTeamsRead:  "View the members of your teams",
+	ScopeTeamsWrite: "Edit teams you belong to",
+	ScopeTeamsAdmin: "Manage team members and delete teams you own",
+	ScopeSCIM:       "Provision users and groups through SCIM (needs users.write)",
+}
+
+// OAuth grant types a registered client may use
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantRefreshToken      = "refresh_token"
+	GrantClientCredentials = "client_credentials"
+)
+
+// OAuthClient is a third-party application registered by a user. Confidential clients
+// authenticate with a secret (only its hash is stored); public clients have none and
+// rely on PKCE. Lists are stored space-separated. Client credentials tokens act as the owner.
+type OAuthClient struct {
+	ID               int64     `gorm:"primaryKey"`
+	ClientID         string    `gorm:"column:client_id;size:64;uniqueIndex;not null"`
+	ClientSecretHash string    `gorm:"column:client_secret_hash;size:64;not null"`
+	Name             string    `gorm:"column:name;size:100;not null"`
+	RedirectURIs     string    `gorm:"column:redirect_uris;not null"`
+	Scopes           string    `gorm:"column:scopes;size:255;not null"`
+	GrantTypes       string    `gorm:"column:grant_types;size:100;not null"`
+	OwnerID          int       `gorm:"column:owner_id;not null"`
+	CreatedAt        time.Time `gorm:"column:created_at;autoCreateTime"`
+}
+
+// TableName specifies the table name for OAuthClient
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// IsPublic reports whether the client has no secret (browser or native apps)
+func (c *OAuthClient) IsPublic() bool { return c.ClientSecretHash == "" }
+
+// AllowsGrant reports whether the client was registered for a grant type
+func (c *OAuthClient) AllowsGrant(grantType string) bool {
+	return containsField(c.GrantTypes, grantType)
+}
+
+// HasRedirectURI reports whether uri exactly matches a registered redirect URI
+func (c *OAuthClient) HasRedirectURI(uri string) bool {
+	return containsField(c.RedirectURIs, uri)
+}
+
+// ToResponse converts an OAuthClient to its API representation
+func (c *OAuthClient) ToResponse() OAuthClientResponse {
+	return OAuthClientResponse{
+		ClientID:     c.ClientID,
+		Name:         c.Name,
+		RedirectURIs: strings.Fields(c.RedirectURIs),
+		Scopes:       strings.Fields(c.Scopes),
+		GrantTypes:   strings.Fields(c.GrantTypes),
+		Public:       c.IsPublic(),
+		OwnerID:      c.OwnerID,
+		CreatedAt:    c.CreatedAt,
+	}
+}
+
+func containsField(list, value string) bool {
+	for _, f := range strings.Fields(list) {
+		if f == value {
+			return true
+		}
+	}
+	return false
+}
+
+// OAuthConsent records the scopes a user has granted to a client
+type OAuthConsent struct {
+	UserID    int       `gorm:"column:user_id;primaryKey"`
+	ClientID  string    `gorm:"column:client_id;primaryKey;size:64"`
+	Scope     string    `gorm:"column:scope;size:255;not null"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// TableName specifies the table name for OAuthConsent
+func (OAuthConsent) TableName() string {
+	return "oauth_consents"
+}
+
+// OAuthAuthorizationCode is an issued authorization code awaiting redemption. Only
+// the code's hash is stored, together with the PKCE challenge it was bound to.
+type OAuthAuthorizationCode struct {
+	CodeHash      string    `gorm:"column:code_hash;primaryKey;size:64"`
+	ClientID      string    `gorm:"column:client_id;size:64;not null"`
+	UserID        int       `gorm:"column:user_id;not null"`
+	RedirectURI   string    `gorm:"column:redirect_uri;not null"`
+	Scope         string    `gorm:"column:scope;size:255;not null"`
+	CodeChallenge string    `gorm:"column:code_challenge;size:128;not null"`
+	ExpiresAt     time.Time `gorm:"column:expires_at;not null"`
+	CreatedAt     time.Time `gorm:"column:created_at;autoCreateTime"`
+}
+
+// TableName specifies the table name for OAuthAuthorizationCode
+func (OAuthAuthorizationCode) TableName() string {
+	return "oauth_authorization_codes"
+}
+
+// PersonalAccessTokenPrefix starts every personal access token, so they can be told
+// apart from JWTs without parsing and are easy to spot in leaked-secret scans
+const PersonalAccessTokenPrefix = "pat_"
+
+// PersonalAccessToken is a long-lived, scoped token a user creates for scripts and CI
+// jobs. Only the token's hash is stored; TokenPrefix identifies it in listings.
+type PersonalAccessToken struct {
+	ID          int64      `gorm:"primaryKey"`
+	UserID      int        `gorm:"column:user_id;not null"`
+	Name        string     `gorm:"column:name;size:100;not null"`
+	TokenHash   string     `gorm:"column:token_hash;size:64;uniqueIndex;not null"`
+	TokenPrefix string     `gorm:"column:token_prefix;size:16;not null"`
+	Scope       string     `gorm:"column:scope;size:255;not null"`
+	ExpiresAt   *time.Time `gorm:"column:expires_at"`
+	LastUsedAt  *time.Time `gorm:"column:last_used_at"`
+	CreatedAt   time.Time  `gorm:"column:created_at;autoCreateTime"`
+}
+
+// TableName specifies the table name for PersonalAccessToken
+func (PersonalAccessToken) TableName() string {
+	return "personal_access_tokens"
+}
+
+// ToResponse converts a PersonalAccessToken to its API representation
+func (t *PersonalAccessToken) ToResponse() PersonalAccessTokenResponse {
+	return PersonalAccessTokenResponse{
+		ID:          t.ID,
+		Name:        t.Name,
+		TokenPrefix: t.TokenPrefix,
+		Scopes:      strings.Fields(t.Scope),
+		ExpiresAt:   t.ExpiresAt,
+		LastUsedAt:  t.LastUsedAt,
+		CreatedAt:   t.CreatedAt,
+	}
+}
+
+// Data export states
+const (
+	DataExportPending = "pending"
+	DataExportRunning = "running"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+)
+
+// DataExport is an asynchronous export of a user's data. Archive holds the finished
+// DataExportArchive as JSON until ExpiresAt.
+type DataExport struct {
+	ID          string     `gorm:"column:id;primaryKey;size:36"`
+	UserID      int        `gorm:"column:user_id;not null"`
+	Status      string     `gorm:"column:status;size:16;not null"`
+	Error       string     `gorm:"column:error;size:255;not null"`
+	Archive     []byte     `gorm:"column:archive"`
+	CreatedAt   time.Time  `gorm:"column:created_at;autoCreateTime"`
+	StartedAt   *time.Time `gorm:"column:started_at"`
+	CompletedAt *time.Time `gorm:"column:completed_at"`
+	ExpiresAt   *time.Time `gorm:"column:expires_at"`
+}
+
+// TableName specifies the table name for DataExport
+func (DataExport) TableName() string {
+	return "data_exports"
+}
+
+// ToResponse converts a DataExport to its API representation
+func (e *DataExport) ToResponse() DataExportResponse {
+	return DataExportResponse{ID: e.ID, Status: e.Status, Error: e.Error, CreatedAt: e.CreatedAt, CompletedAt: e.CompletedAt, ExpiresAt: e.ExpiresAt}
+}
+
+// DataExportArchive is everything the system holds about a user. Teams and Tasks are
+// passed through as returned by the team and task services.
+type DataExportArchive struct {
+	ExportedAt           time.Time                     `json:"exportedAt"`
+	Profile              UserResponse                  `json:"profile"`
+	Identities           []UserIdentity                `json:"identities"`
+	Sessions             []SessionResponse             `json:"sessions"`
+	PersonalAccessTokens []PersonalAccessTokenResponse `json:"personalAccessTokens"`
+	Authorizations       []OAuthAuthorization          `json:"authorizations"`
+	OAuthClients         []OAuthClientResponse         `json:"oauthClients"`
+	Teams                json.RawMessage               `json:"teams"`
+	Tasks                json.RawMessage               `json:"tasks"`
+}
+
+// Status of a bulk user import
+const (
+	UserImportPending   = "pending"
+	UserImportRunning   = "running"
+	UserImportCompleted = "completed"
+	UserImportFailed    = "failed"
+)
+
+// UserImport is a bulk import of users run in the background. Rows holds the parsed
+// input until the import finished, Report the result of every row, both as JSON. A dry
+// run only validates.
+type UserImport struct {
+	ID          string     `gorm:"column:id;primaryKey;size:36"`
+	ActorID     int        `gorm:"column:actor_id;not null"`
+	DryRun      bool       `gorm:"column:dry_run;not null"`
+	Status      string     `gorm:"column:status;size:16;not null"`
+	Total       int        `gorm:"column:total;not null"`
+	Succeeded   int        `gorm:"column:succeeded;not null"`
+	Failed      int        `gorm:"column:failed;not null"`
+	Error       string     `gorm:"column:error;size:255;not null"`
+	Rows        []byte     `gorm:"column:input"`
+	Report      []byte     `gorm:"column:report"`
+	CreatedAt   time.Time  `gorm:"column:created_at;autoCreateTime"`
+	StartedAt   *time.Time `gorm:"column:started_at"`
+	CompletedAt *time.Time `gorm:"column:completed_at"`
+}
+
+// TableName specifies the table name for UserImport
+func (UserImport) TableName() string {
+	return "user_imports"
+}
+
+// ImportUserRow is one user of a bulk import, a CSV line or a JSON object. Role
+// defaults to "user"; without a password the user sets one through the password reset.
+type ImportUserRow struct {
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	Password  string `json:"password,omitempty"`
+	FirstName string `json:"firstName,omitempty"`
+	LastName  string `json:"lastName,omitempty"`
+	Role      string `json:"role,omitempty"`
+}
+
+// Outcome of a row of a bulk import
+const (
+	ImportRowCreated = "created"
+	ImportRowValid   = "valid" // dry run: the row would have been created
+	ImportRowFailed  = "failed"
+)
+
+// ImportRowResult reports what happened to a row; Row counts from 1 and leaves out
+// the CSV header
+type ImportRowResult struct {
+	Row      int      `json:"row"`
+	Username string   `json:"username"`
+	Status   string   `json:"status"`
+	UserID   int      `json:"userId,omitempty"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// UserImportResponse reports the state of an import; Rows is set once it completed
+type UserImportResponse struct {
+	ID          string            `json:"id"`
+	Status      string            `json:"status"`
+	DryRun      bool              `json:"dryRun"`
+	Total       int               `json:"total"`
+	Succeeded   int               `json:"succeeded"`
+	Failed      int               `json:"failed"`
+	Error       string            `json:"error,omitempty"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	CompletedAt *time.Time        `json:"completedAt,omitempty"`
+	Rows        []ImportRowResult `json:"rows,omitempty"`
+}
+
+// ToResponse converts a UserImport to its API representation
+func (i *UserImport) ToResponse() UserImportResponse {
+	resp := UserImportResponse{ID: i.ID, Status: i.Status, DryRun: i.DryRun, Total: i.Total, Succeeded: i.Succeeded, Failed: i.Failed, Error: i.Error, CreatedAt: i.CreatedAt, CompletedAt: i.CompletedAt}
+	if len(i.Report) > 0 {
+		_ = json.Unmarshal(i.Report, &resp.Rows)
+	}
+	return resp
+}
+
+// SCIMGroup is a group an external directory pushed through SCIM. Every group is
+// backed by a team in the team service, owned by the user whose token created it.
+type SCIMGroup struct {
+	ID          string    `gorm:"column:id;primaryKey;size:36"`
+	DisplayName string    `gorm:"column:display_name;size:255;not null"`
+	ExternalID  string    `gorm:"column:external_id;size:255;not null"`
+	TeamID      int       `gorm:"column:team_id;not null"`
+	OwnerID     int       `gorm:"column:owner_id;not null"`
+	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt   time.Time `gorm:"column:updated_at;autoUpdateTime"`
+	// Members holds the IDs of the group's users when they were loaded
+	Members []int `gorm:"-"`
+}
+
+// TableName specifies the table name for SCIMGroup
+func (SCIMGroup) TableName() string {
+	return "scim_groups"
+}
+
+// SCIMGroupMember is a user in a SCIM group, and so in the group's team
+type SCIMGroupMember struct {
+	GroupID string `gorm:"column:group_id;primaryKey;size:36"`
+	UserID  int    `gorm:"column:user_id;primaryKey"`
+}
+
+// TableName specifies the table name for SCIMGroupMember
+func (SCIMGroupMember) TableName() string {
+	return "scim_group_members"
+}
+
+// Permissions a role can grant; they are embedded in access tokens ("perms" claim) and
+// checked by the auth, task and team services
+const (
+	PermUsersRead          = "users.read"
+	PermUsersWrite         = "users.write"
+	PermUsersDelete        = "users.delete"
+	PermUsersImpersonate   = "users.impersonate"
+	PermRolesManage        = "roles.manage"
+	PermSettingsManage     = "settings.manage"
+	PermOAuthClientsManage = "oauth_clients.manage"
+	PermTeamsCreate        = "teams.create"
+	PermTasksDeleteAny     = "tasks.delete_any"
+)
+
+// PermissionDescriptions lists every known permission
+var PermissionDescriptions = map[string]string{
+	PermUsersRead:          "View any user and the erasure of deleted accounts",
+	PermUsersWrite:         "Create and edit users, unlock accounts, reset 2FA and manage other users' sessions",
+	PermUsersDelete:        "Delete and restore users",
+	PermUsersImpersonate:   "Act as another user with a short-lived token, e.g. for support",
+	PermRolesManage:        "Create, change and delete roles and assign them to users",
+	PermSettingsManage:     "Change runtime settings such as the 2FA policy",
+	PermOAuthClientsManage: "Remove OAuth clients registered by other users",
+	PermTeamsCreate:        "Create teams",
+	PermTasksDeleteAny:     "Delete tasks in any team",
+}
+
+// Built-in roles; RoleUser is given to new accounts and RoleAdmin always holds every permission
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Role is a named set of permissions assigned to users
+type Role struct {
+	Name        string           `gorm:"column:name;primaryKey;size:50"`
+	Description string           `gorm:"column:description;size:255;not null"`
+	BuiltIn     bool             `gorm:"column:built_in;not null;default:false"`
+	Permissions []RolePermission `gorm:"foreignKey:Role;references:Name"`
+	CreatedAt   time.Time        `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt   time.Time        `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// TableName specifies the table name for Role
+func (Role) TableName() string {
+	return "roles"
+}
+
+// PermissionNames returns the role's permissions in alphabetical order
+func (r *Role) PermissionNames() []string {
+	names := make([]string, 0, len(r.Permissions))
+	for _, p := range r.Permissions {
+		names = append(names, p.Permission)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ToResponse converts a Role to its API representation
+func (r *Role) ToResponse() RoleResponse {
+	return RoleResponse{Name: r.Name, Description: r.Description, BuiltIn: r.BuiltIn, Permissions: r.PermissionNames(), CreatedAt: r.CreatedAt, UpdatedAt: r.UpdatedAt}
+}
+
+// RolePermission grants one permission to a role
+type RolePermission struct {
+	Role       string `gorm:"column:role;primaryKey;size:50"`
+	Permission string `gorm:"column:permission;primaryKey;size:64"`
+}
+
+// TableName specifies the table name for RolePermission
+func (RolePermission) TableName() string {
+	return "role_permissions"
+}
+
+// RoleResponse represents a role in API responses
+type RoleResponse struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	BuiltIn     bool      `json:"builtIn"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// CreateRoleRequest represents the request body for creating a role
+type CreateRoleRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// UpdateRoleRequest replaces a role's description and/or permissions
+type UpdateRoleRequest struct {
+	Description *string  `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// Impersonation records an impersonation token issued to ActorID for acting as UserID
+type Impersonation struct {
+	ID        int64     `gorm:"primaryKey"`
+	ActorID   int       `gorm:"column:actor_id;not null"`
+	UserID    int       `gorm:"column:user_id;not null"`
+	Reason    string    `gorm:"column:reason;size:255;not null"`
+	TokenID   string    `gorm:"column:token_id;size:32;uniqueIndex;not null"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
+	ExpiresAt time.Time `gorm:"column:expires_at;not null"`
+}
+
+// TableName specifies the table name for Impersonation
+func (Impersonation) TableName() string {
+	return "impersonations"
+}
+
+// ToResponse converts an Impersonation to its API representation
+func (i *Impersonation) ToResponse() ImpersonationResponse {
+	return ImpersonationResponse{ID: i.ID, ActorID: i.ActorID, UserID: i.UserID, Reason: i.Reason, CreatedAt: i.CreatedAt, ExpiresAt: i.ExpiresAt}
+}
+
+// ImpersonationResponse represents an impersonation in the audit trail
+type ImpersonationResponse struct {
+	ID        int64     `json:"id"`
+	ActorID   int       `json:"actorId"`
+	UserID    int       `json:"userId"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// ImpersonateRequest starts an impersonation; the reason is kept in the audit trail
+type ImpersonateRequest struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+// ImpersonationTokenResponse carries a short-lived access token for the impersonated
+// user; there is no refresh token
+type ImpersonationTokenResponse struct {
+	AccessToken    string       `json:"accessToken"`
+	TokenType      string       `json:"tokenType"`
+	ExpiresIn      int          `json:"expiresIn"`
+	ExpiresAt      time.Time    `json:"expiresAt"`
+	ImpersonatorID int          `json:"impersonatorId"`
+	User           UserResponse `json:"user"`
+}
+
+// ClientInfo describes the device a login or refresh request came from
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
 // UserResponse represents the user data sent in API responses
 type UserResponse struct {
-	ID        int       `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	FirstName string    `json:"firstName"`
-	LastName  string    `json:"lastName"`
-	Role      string    `json:"role"`
-	IsActive  bool      `json:"isActive"`
+	ID        int    `json:"id"`
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Role      string `json:"role"`
+	IsActive  bool   `json:"isActive"`
+	// EmailVerified reports whether the user confirmed their email address
+	EmailVerified bool   `json:"emailVerified"`
+	MFAEnabled    bool   `json:"mfaEnabled"`
+	Locale        string `json:"locale"`
+	Timezone      string `json:"timezone"`
+	// AvatarURL is relative to the auth service, e.g. /avatars/<key>?size=64; empty
+	// without an avatar
+	AvatarURL string    `json:"avatarUrl,omitempty"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
@@ -58,6 +753,56 @@ type LoginResponse struct {
 	AccessToken  string       `json:"accessToken"`
 	RefreshToken string       `json:"refreshToken"`
 	User         UserResponse `json:"user"`
+	// RecoveryCodes is only set when the login completed a forced 2FA enrollment
+	RecoveryCodes []string `json:"recoveryCodes,omitempty"`
+}
+
+// MFA challenge stages: "verify" expects a code from an enrolled authenticator,
+// "enroll" means the user's role requires 2FA and an authenticator must be set up first
+const (
+	MFAStageVerify = "verify"
+	MFAStageEnroll = "enroll"
+)
+
+// MFAChallengeResponse is returned by /auth/login instead of tokens when a second
+// factor is needed. ChallengeToken is redeemed at /auth/login/mfa.
+type MFAChallengeResponse struct {
+	MFARequired    bool   `json:"mfaRequired"`
+	Stage          string `json:"stage"`
+	ChallengeToken string `json:"challengeToken"`
+	ExpiresIn      int    `json:"expiresIn"`
+}
+
+// MFALoginRequest completes a two-step login with a TOTP or recovery code
+type MFALoginRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// MFAChallengeRequest identifies a pending login by its challenge token
+type MFAChallengeRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+}
+
+// MFAEnrollmentResponse carries the new TOTP secret and its otpauth:// URI
+type MFAEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauthUri"`
+}
+
+// MFACodeRequest carries a TOTP (or, where allowed, recovery) code
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// RecoveryCodesResponse lists freshly generated recovery codes; they are shown only once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// MFAPolicy lists the roles whose members must use 2FA
+type MFAPolicy struct {
+	RequiredRoles []string `json:"requiredRoles"`
 }
 
 // RefreshRequest represents the request body for token refresh
@@ -65,6 +810,215 @@ type RefreshRequest struct {
 	RefreshToken string `json:"refreshToken" binding:"required"`
 }
 
+// LogoutRequest represents the request body for logout. AllSessions revokes
+// every refresh token of the user instead of only the presented token's family.
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+	AllSessions  bool   `json:"allSessions"`
+}
+
+// TokenRequest is an OAuth 2.0 token request (RFC 6749): the client credentials,
+// authorization code (with PKCE) or refresh token grant. Client credentials may be
+// sent in the body or via HTTP Basic authentication.
+type TokenRequest struct {
+	GrantType    string `form:"grant_type" json:"grant_type" binding:"required"`
+	ClientID     string `form:"client_id" json:"client_id"`
+	ClientSecret string `form:"client_secret" json:"client_secret"`
+	Scope        string `form:"scope" json:"scope"`
+	Code         string `form:"code" json:"code"`
+	RedirectURI  string `form:"redirect_uri" json:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier" json:"code_verifier"`
+	RefreshToken string `form:"refresh_token" json:"refresh_token"`
+}
+
+// TokenResponse is the OAuth 2.0 token response. Service tokens have no refresh
+// token or scope.
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// IntrospectionRequest asks whether a token is active (RFC 7662). The caller
+// authenticates like at the token endpoint.
+type IntrospectionRequest struct {
+	Token        string `form:"token" json:"token" binding:"required"`
+	ClientID     string `form:"client_id" json:"client_id"`
+	ClientSecret string `form:"client_secret" json:"client_secret"`
+}
+
+// IntrospectionResponse describes an access token; only Active is set for tokens
+// that are expired, revoked or not ours
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Aud       string `json:"aud,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+}
+
+// CreateOAuthClientRequest registers a third-party application. GrantTypes defaults
+// to authorization_code and refresh_token; Public clients get no secret.
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required,max=100"`
+	RedirectURIs []string `json:"redirectUris"`
+	Scopes       []string `json:"scopes" binding:"required,min=1"`
+	GrantTypes   []string `json:"grantTypes"`
+	Public       bool     `json:"public"`
+}
+
+// OAuthClientResponse represents a registered client. ClientSecret is only returned
+// once, when the client is created.
+type OAuthClientResponse struct {
+	ClientID     string    `json:"clientId"`
+	ClientSecret string    `json:"clientSecret,omitempty"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirectUris"`
+	Scopes       []string  `json:"scopes"`
+	GrantTypes   []string  `json:"grantTypes"`
+	Public       bool      `json:"public"`
+	OwnerID      int       `json:"ownerId"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// AuthorizeRequest carries the parameters of an OAuth 2.0 authorization request. The
+// frontend's consent page forwards them from its query string; Approve is only read
+// when the user submits the decision.
+type AuthorizeRequest struct {
+	ResponseType        string `form:"response_type" json:"response_type"`
+	ClientID            string `form:"client_id" json:"client_id" binding:"required"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri"`
+	Scope               string `form:"scope" json:"scope"`
+	State               string `form:"state" json:"state"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method"`
+	Approve             bool   `form:"approve" json:"approve"`
+}
+
+// ScopeInfo describes a scope on the consent screen
+type ScopeInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// AuthorizeResponse tells the consent page what to show. ConsentRequired is false
+// when the user already granted every requested scope to the client.
+type AuthorizeResponse struct {
+	ClientID        string      `json:"clientId"`
+	ClientName      string      `json:"clientName"`
+	RedirectURI     string      `json:"redirectUri"`
+	Scopes          []ScopeInfo `json:"scopes"`
+	ConsentRequired bool        `json:"consentRequired"`
+}
+
+// AuthorizeRedirect is where the browser goes after the decision: the client's
+// redirect URI with a code, or with an error
+type AuthorizeRedirect struct {
+	RedirectTo string `json:"redirectTo"`
+}
+
+// OAuthAuthorization is a client the user has granted access to
+type OAuthAuthorization struct {
+	ClientID   string    `json:"clientId"`
+	ClientName string    `json:"clientName"`
+	Scopes     []string  `json:"scopes"`
+	GrantedAt  time.Time `json:"grantedAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// CreatePersonalAccessTokenRequest creates a personal access token; without
+// ExpiresAt the token is valid until it is deleted
+type CreatePersonalAccessTokenRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// PersonalAccessTokenResponse represents a personal access token. Token is only
+// returned when the token is created.
+type PersonalAccessTokenResponse struct {
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	Token       string     `json:"token,omitempty"`
+	TokenPrefix string     `json:"tokenPrefix"`
+	Scopes      []string   `json:"scopes"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+	LastUsedAt  *time.Time `json:"lastUsedAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+// SessionResponse represents a login session. LastSeenAt is the latest login or
+// token refresh from the device.
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	Device     string    `json:"device"`
+	IPAddress  string    `json:"ipAddress"`
+	UserAgent  string    `json:"userAgent"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Current    bool      `json:"current"`
+}
+
+// AccountErasureResponse reports the state of an account erasure; Services maps each
+// service to the time it reported its data erased (null while pending)
+type AccountErasureResponse struct {
+	UserID      int                   `json:"userId"`
+	RequestedBy int                   `json:"requestedBy"`
+	EraseAfter  time.Time             `json:"eraseAfter"`
+	StartedAt   *time.Time            `json:"startedAt,omitempty"`
+	CompletedAt *time.Time            `json:"completedAt,omitempty"`
+	Services    map[string]*time.Time `json:"services"`
+}
+
+// DataExportResponse reports the state of a data export; the archive can be
+// downloaded once Status is "ready"
+type DataExportResponse struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	DownloadURL string     `json:"downloadUrl,omitempty"`
+}
+
+// DeleteAccountRequest confirms the deletion of the caller's own account. Password is
+// required for accounts that have one; accounts created through an external provider
+// confirm with their username instead.
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+	Confirm  string `json:"confirm"`
+}
+
+// VerifyEmailRequest redeems the token from a verification email
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ResendVerificationRequest asks for a new verification email
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ForgotPasswordRequest asks for a password reset email
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest sets a new password using the token from a reset email
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required,min=6"`
+}
+
 // CreateUserRequest represents the request body for creating a user (admin only)
 type CreateUserRequest struct {
 	Username  string `json:"username" binding:"required,min=3,max=50"`
@@ -72,7 +1026,7 @@ type CreateUserRequest struct {
 	Password  string `json:"password" binding:"required,min=6"`
 	FirstName string `json:"firstName"`
 	LastName  string `json:"lastName"`
-	Role      string `json:"role" binding:"required,oneof=user admin"`
+	Role      string `json:"role" binding:"required"`
 }
 
 // UpdateUserRequest represents the request body for updating a user
@@ -90,6 +1044,9 @@ type UpdateProfileRequest struct {
 	FirstName *string `json:"firstName"`
 	LastName  *string `json:"lastName"`
 	Email     *string `json:"email"`
+	// Locale and Timezone are cleared with an empty string
+	Locale   *string `json:"locale"`
+	Timezone *string `json:"timezone"`
 }
 
 // ChangePasswordRequest represents the request body for changing password
@@ -98,43 +1055,286 @@ type ChangePasswordRequest struct {
 	NewPassword     string `json:"newPassword" binding:"required,min=6"`
 }
 
-// UserFilters represents filters for user listing
+// UserFilters represents filters, sort order and keyset pagination for user listing
 type UserFilters struct {
-	Query  string `form:"q"`
-	Limit  int    `form:"limit,default=10"`
-	Offset int    `form:"offset,default=0"`
+	// Query matches the start of the username, email, first or last name
+	Query    string `form:"q"`
+	Role     string `form:"role"`
+	IsActive *bool  `form:"isActive"`
+	// CreatedAfter (inclusive) and CreatedBefore (exclusive) are RFC 3339 times
+	CreatedAfter  *time.Time `form:"createdAfter"`
+	CreatedBefore *time.Time `form:"createdBefore"`
+	Sort          string     `form:"sort,default=-createdAt"`
+	Cursor        string     `form:"cursor"`
+	Limit         int        `form:"limit,default=10"`
+
+	// Order and After are parsed from Sort and Cursor by the handler
+	Order UserSort    `form:"-"`
+	After *UserCursor `form:"-"`
+}
+
+// userSortColumns maps the fields users can be sorted by to their columns; all are
+// NOT NULL so that keyset comparisons work
+var userSortColumns = map[string]string{"createdAt": "created_at", "username": "username", "email": "email"}
+
+// UserSort is a sort order of the user listing; the user ID breaks ties
+type UserSort struct {
+	Field string
+	Desc  bool
+}
+
+// ParseUserSort parses a sort parameter such as "username" or "-createdAt" (descending)
+func ParseUserSort(s string) (UserSort, bool) {
+	order := UserSort{Field: strings.TrimPrefix(s, "-"), Desc: strings.HasPrefix(s, "-")}
+	_, ok := userSortColumns[order.Field]
+	return order, ok
+}
+
+// Column returns the column the sort field is stored in
+func (s UserSort) Column() string {
+	return userSortColumns[s.Field]
+}
+
+func (s UserSort) String() string {
+	if s.Desc {
+		return "-" + s.Field
+	}
+	return s.Field
+}
+
+// UserCursor is the position after the last user of a page: the sort it was issued
+// for, that user's value of the sort field and their ID
+type UserCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// CursorAfter returns the opaque cursor that continues the listing after u
+func (s UserSort) CursorAfter(u *User) string {
+	c := UserCursor{Sort: s.String(), ID: u.ID}
+	switch s.Field {
+	case "createdAt":
+		c.Value = u.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case "username":
+		c.Value = u.Username
+	case "email":
+		c.Value = u.Email
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeUserCursor parses a cursor; it is only valid for the sort it was issued for
+func DecodeUserCursor(token string, order UserSort) (*UserCursor, bool) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, false
+	}
+	var c UserCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != order.String() || c.ID <= 0 {
+		return nil, false
+	}
+	if order.Field == "createdAt" {
+		if _, err := time.Parse(time.RFC3339Nano, c.Value); err != nil {
+			return nil, false
+		}
+	}
+	return &c, true
+}
+
+// SortValue returns the cursor's value of the sort field as it is compared in queries
+func (c *UserCursor) SortValue() interface{} {
+	if strings.TrimPrefix(c.Sort, "-") == "createdAt" {
+		t, _ := time.Parse(time.RFC3339Nano, c.Value)
+		return t
+	}
+	return c.Value
 }
 
-// Error represents an error response
-type Error struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
+// UserSummary is the public view of a user returned by the user search, e.g. for
+// picking an assignee; it leaves out email, role and account state
+type UserSummary struct {
+	ID        int    `json:"id"`
+	Username  string `json:"username"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	AvatarURL string `json:"avatarUrl,omitempty"`
 }
 
+// Token types carried in the "typ" claim
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+	TokenTypeService = "service"
+	TokenTypeMFA     = "mfa"
+	// TokenTypePersonal is reported for personal access tokens, which are opaque
+	// rather than JWTs and only validated by the auth service
+	TokenTypePersonal = "personal"
+)
+
+// Reasons reported with a user.tokens_revoked event
+const (
+	RevokeReasonLogoutAll       = "logout_all"
+	RevokeReasonSessionsRevoked = "sessions_revoked"
+	RevokeReasonPasswordChanged = "password_changed"
+	RevokeReasonPasswordReset   = "password_reset"
+	RevokeReasonDeactivated     = "deactivated"
+	RevokeReasonRoleChanged     = "role_changed"
+	RevokeReasonDeleted         = "deleted"
+)
+
+// Token audiences: access tokens are for the API services, refresh tokens can only
+// be redeemed at the auth service itself, service tokens only open /internal routes.
+const (
+	AudienceAPI      = "todolist-api"
+	AudienceAuth     = "auth-service"
+	AudienceInternal = "todolist-internal"
+)
+
 // Claims represents JWT claims
 type Claims struct {
 	UserID   int    `json:"user_id"`
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	// Permissions are those of Role when the token was issued
+	Permissions []string `json:"perms,omitempty"`
+	TokenType   string   `json:"typ"`
+	ClientID    string   `json:"client_id,omitempty"`
+	// Scope is the space-separated scope granted to an OAuth client or a personal
+	// access token; it is only set, and only enforced, on those tokens
+	Scope string `json:"scope,omitempty"`
+	// Restricted marks access tokens of unverified users under the "restricted"
+	// email verification policy; such tokens may only read, not modify, resources
+	Restricted bool `json:"restricted,omitempty"`
+	// SessionID is the login session (refresh token family) a first-party access token belongs to
+	SessionID string `json:"sid,omitempty"`
+	// TokenVersion is the user's token version when the token was issued
+	TokenVersion int `json:"ver,omitempty"`
+	// MFAStage is only set on MFA challenge tokens
+	MFAStage string `json:"mfa_stage,omitempty"`
+	// Timezone is the user's IANA timezone when the token was issued, so other services
+	// can tell what "today" is for the user
+	Timezone string `json:"tz,omitempty"`
+	// ImpersonatorID and Act are set on impersonation tokens: UserID is the impersonated
+	// user, the impersonator is the user who actually acts (RFC 8693 "act" claim)
+	ImpersonatorID int          `json:"impersonator_id,omitempty"`
+	Act            *ActorClaims `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// ActorClaims identifies the acting party of an impersonation token
+type ActorClaims struct {
+	Subject  string `json:"sub"`
+	Username string `json:"username,omitempty"`
+}
+
+// IsImpersonation reports whether the token was issued to someone acting as the user
+func (c *Claims) IsImpersonation() bool { return c.ImpersonatorID != 0 }
+
 // ToUserResponse converts a User to UserResponse
 func (u *User) ToUserResponse() UserResponse {
 	return UserResponse{
-		ID:        u.ID,
-		Username:  u.Username,
-		Email:     u.Email,
-		FirstName: u.FirstName,
-		LastName:  u.LastName,
-		Role:      u.Role,
-		IsActive:  u.IsActive,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		ID:            u.ID,
+		Username:      u.Username,
+		Email:         u.Email,
+		FirstName:     u.FirstName,
+		LastName:      u.LastName,
+		Role:          u.Role,
+		IsActive:      u.IsActive,
+		EmailVerified: u.EmailVerified,
+		MFAEnabled:    u.MFAEnabled,
+		Locale:        u.Locale,
+		Timezone:      u.Timezone,
+		AvatarURL:     u.AvatarURL(),
+		CreatedAt:     u.CreatedAt,
+		UpdatedAt:     u.UpdatedAt,
 	}
 }
 
-// ValidateRole validates if the role is valid
-func ValidateRole(role string) bool {
-	return role == "user" || role == "admin"
+// ToSummary converts User to UserSummary
+func (u *User) ToSummary() UserSummary {
+	return UserSummary{ID: u.ID, Username: u.Username, FirstName: u.FirstName, LastName: u.LastName, AvatarURL: u.AvatarURL()}
+}
+
+// AvatarURL returns the path the user's avatar is served at, or "" without an avatar
+func (u *User) AvatarURL() string {
+	if u.AvatarKey == "" {
+		return ""
+	}
+	return "/avatars/" + u.AvatarKey
+}
+
+// Location returns the user's timezone, UTC when none is set
+func (u *User) Location() *time.Location {
+	if loc, err := time.LoadLocation(u.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// NormalizeLocale checks a BCP 47 language tag of the form language[-Script][-REGION]
+// (e.g. "en", "de-DE", "zh-Hant-TW", "es-419") and returns it in canonical case
+func NormalizeLocale(tag string) (string, bool) {
+	parts := strings.Split(tag, "-")
+	if len(parts) > 3 || !isAlpha(parts[0], 2, 3) {
+		return "", false
+	}
+	out := []string{strings.ToLower(parts[0])}
+	rest := parts[1:]
+	if len(rest) > 0 && isAlpha(rest[0], 4, 4) {
+		out = append(out, strings.ToUpper(rest[0][:1])+strings.ToLower(rest[0][1:]))
+		rest = rest[1:]
+	}
+	if len(rest) > 0 {
+		switch {
+		case isAlpha(rest[0], 2, 2):
+			out = append(out, strings.ToUpper(rest[0]))
+		case len(rest[0]) == 3 && strings.Trim(rest[0], "0123456789") == "":
+			out = append(out, rest[0])
+		default:
+			return "", false
+		}
+		rest = rest[1:]
+	}
+	if len(rest) > 0 {
+		return "", false
+	}
+	return strings.Join(out, "-"), true
+}
+
+// ValidTimezone reports whether name is an IANA timezone such as "Europe/Berlin" or "UTC"
+func ValidTimezone(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+func isAlpha(s string, min, max int) bool {
+	if len(s) < min || len(s) > max {
+		return false
+	}
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidRoleName reports whether name can be used for a role: 2-50 lowercase letters,
+// digits, "_" or "-", starting with a letter. Whether the role exists is up to the
+// roles table.
+func ValidRoleName(name string) bool {
+	if len(name) < 2 || len(name) > 50 || name[0] < 'a' || name[0] > 'z' {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '_' && r != '-' {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,56 @@
+package oidc
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// ProvidersFromEnv reads OIDC_PROVIDERS (comma-separated names) and, per provider,
+// OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _SCOPES, _DISPLAY_NAME and
+// _TRUST_EMAIL. Callback URLs are AUTH_PUBLIC_URL + /auth/oidc/<name>/callback.
+func ProvidersFromEnv() (map[string]*Provider, error) {
+	providers := map[string]*Provider{}
+	list := os.Getenv("OIDC_PROVIDERS")
+	if list == "" {
+		return providers, nil
+	}
+	publicURL := strings.TrimSuffix(os.Getenv("AUTH_PUBLIC_URL"), "/")
+	if publicURL == "" {
+		publicURL = "http://localhost:8084"
+	}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !namePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid OIDC provider name %q", name)
+		}
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		cfg := Config{
+			Name:         name,
+			DisplayName:  os.Getenv(prefix + "DISPLAY_NAME"),
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+			RedirectURL:  publicURL + "/auth/oidc/" + name + "/callback",
+			TrustEmail:   os.Getenv(prefix+"TRUST_EMAIL") == "true",
+		}
+		if cfg.Issuer == "" || cfg.ClientID == "" {
+			return nil, fmt.Errorf("%sISSUER and %sCLIENT_ID must be set", prefix, prefix)
+		}
+		if cfg.DisplayName == "" {
+			cfg.DisplayName = name
+		}
+		if len(cfg.Scopes) == 0 {
+			cfg.Scopes = []string{"openid", "email", "profile"}
+		}
+		providers[name] = NewProvider(cfg)
+	}
+	return providers, nil
+}
@@ -0,0 +1,185 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// keySet caches a provider's JWKS. It is refetched when stale or when an ID token
+// references an unknown kid (providers rotate keys without notice).
+type keySet struct {
+	url        string
+	httpClient *http.Client
+	cacheTTL   time.Duration
+	minRefresh time.Duration
+
+	mu        sync.RWMutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+func newKeySet(url string, c *http.Client) *keySet {
+	return &keySet{url: url, httpClient: c, cacheTTL: time.Hour, minRefresh: 30 * time.Second, keys: map[string]interface{}{}}
+}
+
+// get returns the key for kid if its type fits alg. Providers with a single key may
+// omit kid, in which case the only key of a matching type is used.
+func (s *keySet) get(kid, alg string) (interface{}, error) {
+	s.mu.RLock()
+	key, ok := s.lookup(kid, alg)
+	age := time.Since(s.fetchedAt)
+	s.mu.RUnlock()
+	if ok && age < s.cacheTTL {
+		return key, nil
+	}
+	if !ok && age < s.minRefresh {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if err := s.refresh(); err != nil {
+		if ok {
+			return key, nil
+		}
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if key, ok := s.lookup(kid, alg); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (s *keySet) lookup(kid, alg string) (interface{}, bool) {
+	if kid != "" {
+		key, ok := s.keys[kid]
+		return key, ok && fits(key, alg)
+	}
+	var found interface{}
+	for _, key := range s.keys {
+		if fits(key, alg) {
+			if found != nil {
+				return nil, false // ambiguous without a kid
+			}
+			found = key
+		}
+	}
+	return found, found != nil
+}
+
+// fits reports whether a key type can verify the JWS algorithm
+func fits(key interface{}, alg string) bool {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return strings.HasPrefix(alg, "RS")
+	case *ecdsa.PublicKey:
+		return strings.HasPrefix(alg, "ES")
+	case ed25519.PublicKey:
+		return alg == "EdDSA"
+	}
+	return false
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (s *keySet) refresh() error {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := getJSON(s.httpClient, s.url, &set); err != nil {
+		return fmt.Errorf("failed to fetch provider jwks: %w", err)
+	}
+	keys := make(map[string]interface{}, len(set.Keys))
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		kid := k.Kid
+		if kid == "" {
+			kid = fmt.Sprintf("#%d", i)
+		}
+		keys[kid] = pub
+	}
+	s.mu.Lock()
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	b64 := base64.RawURLEncoding
+	switch k.Kty {
+	case "RSA":
+		n, err := b64.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := b64.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := b64.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := b64.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, errors.New("EC point is not on the curve")
+		}
+		return pub, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := b64.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
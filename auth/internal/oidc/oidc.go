@@ -0,0 +1,339 @@
+// Package oidc implements the relying-party side of OpenID Connect: provider discovery,
+// the authorization code flow with PKCE (S256) and ID token verification against the
+// provider's published keys.
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Config describes one external identity provider
+type Config struct {
+	Name         string // used in URLs: /auth/oidc/<name>/login
+	DisplayName  string // shown on login buttons
+	Issuer       string // discovery happens at Issuer + /.well-known/openid-configuration
+	ClientID     string
+	ClientSecret string   // empty for public clients
+	Scopes       []string // "openid" is always requested
+	RedirectURL  string
+	// TrustEmail links a first login to an existing local account with the same,
+	// provider-verified email. Only enable it for providers that own the addresses
+	// they assert (e.g. a company IdP).
+	TrustEmail bool
+}
+
+// Identity is what a successful login tells us about the external user
+type Identity struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	GivenName         string
+	FamilyName        string
+}
+
+// metadata is the subset of the discovery document we use
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider talks to one identity provider. Discovery and keys are fetched lazily
+// and cached, so the auth service starts even while a provider is unreachable.
+type Provider struct {
+	cfg        Config
+	httpClient *http.Client
+
+	mu     sync.Mutex
+	meta   *metadata
+	metaAt time.Time
+	keys   *keySet
+}
+
+// discoveryTTL is how long a discovery document is cached
+const discoveryTTL = time.Hour
+
+// NewProvider creates a provider client
+func NewProvider(cfg Config) *Provider {
+	return &Provider{cfg: cfg, httpClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Config returns the provider's configuration
+func (p *Provider) Config() Config { return p.cfg }
+
+// NewVerifier returns a random PKCE code verifier (RFC 7636)
+func NewVerifier() (string, error) {
+	return randomString(32)
+}
+
+// NewNonce returns a random value for the state and nonce parameters
+func NewNonce() (string, error) {
+	return randomString(24)
+}
+
+// challenge derives the S256 code challenge from a verifier
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the provider URL the browser is sent to
+func (p *Provider) AuthCodeURL(state, nonce, verifier string) (string, error) {
+	meta, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(p.scopes(), " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", challenge(verifier))
+	q.Set("code_challenge_method", "S256")
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange redeems an authorization code and returns the verified identity. nonce
+// must be the value sent with the authorization request.
+func (p *Provider) Exchange(code, verifier, nonce string) (*Identity, error) {
+	meta, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("code_verifier", verifier)
+	if p.cfg.ClientSecret == "" {
+		// Public clients identify themselves in the body; PKCE protects the code
+		form.Set("client_id", p.cfg.ClientID)
+	}
+	req, err := http.NewRequest(http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		// client_secret_basic requires form-encoding the credentials (RFC 6749 section 2.3.1)
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		AccessToken      string `json:"access_token"`
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %d: %s %s", resp.StatusCode, body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+	id, err := p.verifyIDToken(meta, body.IDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+	if id.Email == "" && meta.UserinfoEndpoint != "" && body.AccessToken != "" {
+		// Some providers only put profile claims into the userinfo response
+		if err := p.fillFromUserinfo(meta, body.AccessToken, id); err != nil {
+			return nil, err
+		}
+	}
+	return id, nil
+}
+
+// idClaims are the ID token claims we read
+type idClaims struct {
+	Nonce             string      `json:"nonce"`
+	AuthorizedParty   string      `json:"azp"`
+	Email             string      `json:"email"`
+	EmailVerified     interface{} `json:"email_verified"`
+	PreferredUsername string      `json:"preferred_username"`
+	GivenName         string      `json:"given_name"`
+	FamilyName        string      `json:"family_name"`
+	jwt.RegisteredClaims
+}
+
+func (p *Provider) verifyIDToken(meta *metadata, raw, nonce string) (*Identity, error) {
+	claims := &idClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(kid, t.Method.Alg())
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithIssuer(meta.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute))
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+	if claims.Nonce != nonce {
+		return nil, errors.New("invalid id_token: nonce mismatch")
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.cfg.ClientID {
+		return nil, errors.New("invalid id_token: azp mismatch")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("invalid id_token: missing sub")
+	}
+	return &Identity{Subject: claims.Subject, Email: claims.Email, EmailVerified: truthy(claims.EmailVerified),
+		PreferredUsername: claims.PreferredUsername, GivenName: claims.GivenName, FamilyName: claims.FamilyName}, nil
+}
+
+func (p *Provider) fillFromUserinfo(meta *metadata, accessToken string, id *Identity) error {
+	req, err := http.NewRequest(http.MethodGet, meta.UserinfoEndpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("userinfo request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("userinfo endpoint returned status: %d", resp.StatusCode)
+	}
+	var info struct {
+		Sub               string      `json:"sub"`
+		Email             string      `json:"email"`
+		EmailVerified     interface{} `json:"email_verified"`
+		PreferredUsername string      `json:"preferred_username"`
+		GivenName         string      `json:"given_name"`
+		FamilyName        string      `json:"family_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return fmt.Errorf("failed to decode userinfo: %w", err)
+	}
+	// The userinfo response must describe the same subject as the ID token (OIDC Core 5.3.2)
+	if info.Sub != id.Subject {
+		return errors.New("userinfo subject does not match id_token")
+	}
+	id.Email, id.EmailVerified = info.Email, truthy(info.EmailVerified)
+	if id.PreferredUsername == "" {
+		id.PreferredUsername = info.PreferredUsername
+	}
+	if id.GivenName == "" {
+		id.GivenName = info.GivenName
+	}
+	if id.FamilyName == "" {
+		id.FamilyName = info.FamilyName
+	}
+	return nil
+}
+
+// discover returns the cached discovery document, fetching it when stale
+func (p *Provider) discover() (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil && time.Since(p.metaAt) < discoveryTTL {
+		return p.meta, nil
+	}
+	issuer := strings.TrimSuffix(p.cfg.Issuer, "/")
+	var meta metadata
+	if err := p.getJSON(issuer+"/.well-known/openid-configuration", &meta); err != nil {
+		if p.meta != nil {
+			// Keep using the last document while the provider is unreachable
+			return p.meta, nil
+		}
+		return nil, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+	if strings.TrimSuffix(meta.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer %q does not match configured %q", meta.Issuer, p.cfg.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: document is missing required endpoints")
+	}
+	if p.keys == nil || p.keys.url != meta.JWKSURI {
+		p.keys = newKeySet(meta.JWKSURI, p.httpClient)
+	}
+	p.meta, p.metaAt = &meta, time.Now()
+	return p.meta, nil
+}
+
+func (p *Provider) key(kid, alg string) (interface{}, error) {
+	p.mu.Lock()
+	keys := p.keys
+	p.mu.Unlock()
+	if keys == nil {
+		return nil, errors.New("provider keys not loaded")
+	}
+	return keys.get(kid, alg)
+}
+
+func (p *Provider) scopes() []string {
+	scopes := []string{"openid"}
+	for _, s := range p.cfg.Scopes {
+		if s != "openid" {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+func (p *Provider) getJSON(u string, v interface{}) error {
+	return getJSON(p.httpClient, u, v)
+}
+
+func getJSON(c *http.Client, u string, v interface{}) error {
+	resp, err := c.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status: %d", u, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// truthy accepts email_verified as a boolean or as the string "true" (some providers send strings)
+func truthy(v interface{}) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case string:
+		return b == "true"
+	}
+	return false
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
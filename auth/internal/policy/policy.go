@@ -0,0 +1,216 @@
+package policy
+
+import "github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+
+// Reasons reported in structured 403 responses
+const (
+	ReasonPermissionRequired = "PERMISSION_REQUIRED"
+	ReasonNotSelf            = "NOT_SELF_OR_ADMIN"
+	ReasonFieldRestricted    = "FIELD_RESTRICTED"
+	ReasonSelfLockout        = "SELF_LOCKOUT"
+	ReasonTargetPrivileged   = "TARGET_PRIVILEGED"
+	ReasonRoleExceeds        = "ROLE_EXCEEDS_PERMISSIONS"
+)
+
+// Actor is the authenticated caller a decision is made for; Permissions are those
+// embedded in the caller's access token
+type Actor struct {
+	UserID      int
+	Role        string
+	Permissions []string
+}
+
+// Can reports whether the actor's role grants permission
+func (a Actor) Can(permission string) bool {
+	for _, p := range a.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Violation describes why an action was denied; Permission names the permission
+// that would have allowed it
+type Violation struct {
+	Reason     string   `json:"reason"`
+	Message    string   `json:"message"`
+	Permission string   `json:"permission,omitempty"`
+	Fields     []string `json:"fields,omitempty"`
+}
+
+func (v *Violation) Error() string { return v.Message }
+
+// CanListUsers allows users with users.read to list the user directory
+func CanListUsers(a Actor) *Violation {
+	return Require(a, models.PermUsersRead, "You are not allowed to list users")
+}
+
+// CanCreateUser allows users with users.write to create users
+func CanCreateUser(a Actor) *Violation {
+	return Require(a, models.PermUsersWrite, "You are not allowed to create users")
+}
+
+// CanImportUsers allows users with users.write to create users in bulk; every role
+// in the import is also checked with CanAssignRole
+func CanImportUsers(a Actor) *Violation {
+	return Require(a, models.PermUsersWrite, "You are not allowed to import users")
+}
+
+// CanAssignRole lets users who can create users give new accounts the default role;
+// any other role needs roles.manage, so nobody can hand out more than they hold
+func CanAssignRole(a Actor, role string) *Violation {
+	if role == models.RoleUser || a.Can(models.PermRolesManage) {
+		return nil
+	}
+	return &Violation{Reason: ReasonFieldRestricted, Message: "You are not allowed to assign roles", Permission: models.PermRolesManage, Fields: []string{"role"}}
+}
+
+// CanGrantRole allows handing out a role only if it grants no permission the actor
+// lacks, so nobody can give an account more than they hold themselves
+func CanGrantRole(a Actor, rolePermissions []string) *Violation {
+	if p := missingPermission(a, rolePermissions); p != "" {
+		return &Violation{Reason: ReasonRoleExceeds, Message: "The role grants permissions you do not have", Permission: p, Fields: []string{"role"}}
+	}
+	return nil
+}
+
+// CanActOn allows changing, signing out, unlocking, deleting or restoring another user
+// only if the user's role grants no permission the actor lacks, the rule impersonation
+// follows too. Otherwise e.g. a holder of users.write could take over an admin by
+// changing their email and resetting the password. Users may always act on themselves.
+func CanActOn(a Actor, targetID int, targetPermissions []string) *Violation {
+	if a.UserID == targetID {
+		return nil
+	}
+	if p := missingPermission(a, targetPermissions); p != "" {
+		return &Violation{Reason: ReasonTargetPrivileged, Message: "The user holds permissions you do not have", Permission: p}
+	}
+	return nil
+}
+
+// CanDeleteUser allows users with users.delete to delete any user except themselves
+func CanDeleteUser(a Actor, targetID int) *Violation {
+	if v := Require(a, models.PermUsersDelete, "You are not allowed to delete users"); v != nil {
+		return v
+	}
+	if a.UserID == targetID {
+		return &Violation{Reason: ReasonSelfLockout, Message: "You cannot delete your own account"}
+	}
+	return nil
+}
+
+// CanDeleteOwnAccount allows users to delete their own account; those who manage roles
+// cannot, so the system is not left without an admin by accident
+func CanDeleteOwnAccount(a Actor) *Violation {
+	if a.Can(models.PermRolesManage) {
+		return &Violation{Reason: ReasonSelfLockout, Message: "Users who manage roles cannot delete their own account"}
+	}
+	return nil
+}
+
+// CanRestoreUser allows users with users.delete to restore a deleted account
+func CanRestoreUser(a Actor) *Violation {
+	return Require(a, models.PermUsersDelete, "You are not allowed to restore users")
+}
+
+// CanViewErasure allows users with users.read to follow the erasure of a deleted account
+func CanViewErasure(a Actor) *Violation {
+	return Require(a, models.PermUsersRead, "You are not allowed to view account erasures")
+}
+
+// CanViewUser allows users to read their own record and users with users.read to
+// read any record
+func CanViewUser(a Actor, targetID int) *Violation {
+	return requireSelfOr(a, targetID, models.PermUsersRead, "You can only view your own user")
+}
+
+// CanUpdateUser allows users to update themselves and users with users.write to update
+// anyone. Changing isActive needs users.write and assigning a role roles.manage; nobody
+// may change their own role or deactivate themselves.
+func CanUpdateUser(a Actor, targetID int, req models.UpdateUserRequest) *Violation {
+	if v := requireSelfOr(a, targetID, models.PermUsersWrite, "You can only update your own user"); v != nil {
+		return v
+	}
+	var restricted []string
+	if req.Role != nil && !a.Can(models.PermRolesManage) {
+		restricted = append(restricted, "role")
+	}
+	if req.IsActive != nil && !a.Can(models.PermUsersWrite) {
+		restricted = append(restricted, "isActive")
+	}
+	if len(restricted) > 0 {
+		return &Violation{Reason: ReasonFieldRestricted, Message: "You are not allowed to change these fields", Fields: restricted}
+	}
+	if a.UserID != targetID {
+		return nil
+	}
+	if req.Role != nil && *req.Role != a.Role {
+		restricted = append(restricted, "role")
+	}
+	if req.IsActive != nil && !*req.IsActive {
+		restricted = append(restricted, "isActive")
+	}
+	if len(restricted) > 0 {
+		return &Violation{Reason: ReasonSelfLockout, Message: "You cannot change your own role or deactivate yourself", Fields: restricted}
+	}
+	return nil
+}
+
+// CanUnlockUser allows users with users.write to lift a login lock
+func CanUnlockUser(a Actor) *Violation {
+	return Require(a, models.PermUsersWrite, "You are not allowed to unlock accounts")
+}
+
+// CanResetMFA allows users with users.write to switch off another user's 2FA (e.g. lost device)
+func CanResetMFA(a Actor) *Violation {
+	return Require(a, models.PermUsersWrite, "You are not allowed to reset two-factor authentication")
+}
+
+// CanManageOAuthClient allows users to remove the OAuth clients they registered and
+// users with oauth_clients.manage to remove any client
+func CanManageOAuthClient(a Actor, ownerID int) *Violation {
+	return requireSelfOr(a, ownerID, models.PermOAuthClientsManage, "You can only manage your own OAuth clients")
+}
+
+// CanManageSessions allows users to list and revoke their own login sessions and
+// users with users.write to do so for any user
+func CanManageSessions(a Actor, targetID int) *Violation {
+	return requireSelfOr(a, targetID, models.PermUsersWrite, "You can only manage your own sessions")
+}
+
+// CanImpersonate allows users with users.impersonate to act as another user
+func CanImpersonate(a Actor) *Violation {
+	return Require(a, models.PermUsersImpersonate, "You are not allowed to impersonate users")
+}
+
+// CanViewImpersonations allows users with users.read to see who impersonated a user
+func CanViewImpersonations(a Actor) *Violation {
+	return Require(a, models.PermUsersRead, "You are not allowed to view impersonations")
+}
+
+// Require allows the action only if the actor holds permission; the RequirePermission
+// middleware uses it for whole route groups
+func Require(a Actor, permission, message string) *Violation {
+	if a.Can(permission) {
+		return nil
+	}
+	return &Violation{Reason: ReasonPermissionRequired, Message: message, Permission: permission}
+}
+
+// missingPermission returns the first of perms the actor does not hold, or ""
+func missingPermission(a Actor, perms []string) string {
+	for _, p := range perms {
+		if !a.Can(p) {
+			return p
+		}
+	}
+	return ""
+}
+
+func requireSelfOr(a Actor, targetID int, permission, message string) *Violation {
+	if a.UserID == targetID || a.Can(permission) {
+		return nil
+	}
+	return &Violation{Reason: ReasonNotSelf, Message: message, Permission: permission}
+}
@@ -0,0 +1,178 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+var (
+	user     = Actor{UserID: 2, Role: models.RoleUser, Permissions: []string{models.PermTeamsCreate}}
+	helpdesk = Actor{UserID: 3, Role: "helpdesk", Permissions: []string{models.PermUsersRead, models.PermUsersWrite}}
+	admin    = Actor{UserID: 1, Role: models.RoleAdmin, Permissions: []string{models.PermUsersRead, models.PermUsersWrite,
+		models.PermUsersDelete, models.PermRolesManage, models.PermTeamsCreate}}
+)
+
+func ptr[T any](v T) *T { return &v }
+
+// check compares a decision with the expected violation; want nil means allowed
+func check(t *testing.T, got, want *Violation) {
+	t.Helper()
+	if want == nil {
+		if got != nil {
+			t.Fatalf("denied (%s: %s), want allowed", got.Reason, got.Message)
+		}
+		return
+	}
+	if got == nil {
+		t.Fatalf("allowed, want %s", want.Reason)
+	}
+	if got.Reason != want.Reason || got.Permission != want.Permission || !reflect.DeepEqual(got.Fields, want.Fields) {
+		t.Fatalf("got %s permission=%q fields=%v, want %s permission=%q fields=%v",
+			got.Reason, got.Permission, got.Fields, want.Reason, want.Permission, want.Fields)
+	}
+}
+
+func TestCanListUsers(t *testing.T) {
+	tests := []struct {
+		name  string
+		actor Actor
+		want  *Violation
+	}{
+		{"user", user, &Violation{Reason: ReasonPermissionRequired, Permission: models.PermUsersRead}},
+		{"users.read", helpdesk, nil},
+		{"admin", admin, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) { check(t, CanListUsers(tt.actor), tt.want) })
+	}
+}
+
+func TestCanViewUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		actor    Actor
+		targetID int
+		want     *Violation
+	}{
+		{"self", user, user.UserID, nil},
+		{"other without users.read", user, admin.UserID, &Violation{Reason: ReasonNotSelf, Permission: models.PermUsersRead}},
+		{"other with users.read", helpdesk, user.UserID, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) { check(t, CanViewUser(tt.actor, tt.targetID), tt.want) })
+	}
+}
+
+func TestCanUpdateUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		actor    Actor
+		targetID int
+		req      models.UpdateUserRequest
+		want     *Violation
+	}{
+		{"own name", user, user.UserID, models.UpdateUserRequest{FirstName: ptr("Jo")}, nil},
+		{"other without users.write", user, admin.UserID, models.UpdateUserRequest{FirstName: ptr("Jo")},
+			&Violation{Reason: ReasonNotSelf, Permission: models.PermUsersWrite}},
+		{"own role", user, user.UserID, models.UpdateUserRequest{Role: ptr(models.RoleAdmin)},
+			&Violation{Reason: ReasonFieldRestricted, Fields: []string{"role"}}},
+		{"own role and isActive", user, user.UserID, models.UpdateUserRequest{Role: ptr(models.RoleAdmin), IsActive: ptr(true)},
+			&Violation{Reason: ReasonFieldRestricted, Fields: []string{"role", "isActive"}}},
+		{"role without roles.manage", helpdesk, user.UserID, models.UpdateUserRequest{Role: ptr(models.RoleAdmin)},
+			&Violation{Reason: ReasonFieldRestricted, Fields: []string{"role"}}},
+		{"deactivate other", helpdesk, user.UserID, models.UpdateUserRequest{IsActive: ptr(false)}, nil},
+		{"deactivate self", admin, admin.UserID, models.UpdateUserRequest{IsActive: ptr(false)},
+			&Violation{Reason: ReasonSelfLockout, Fields: []string{"isActive"}}},
+		{"change own role", admin, admin.UserID, models.UpdateUserRequest{Role: ptr(models.RoleUser)},
+			&Violation{Reason: ReasonSelfLockout, Fields: []string{"role"}}},
+		{"keep own role", admin, admin.UserID, models.UpdateUserRequest{Role: ptr(models.RoleAdmin)}, nil},
+		{"role of other", admin, user.UserID, models.UpdateUserRequest{Role: ptr(models.RoleAdmin)}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) { check(t, CanUpdateUser(tt.actor, tt.targetID, tt.req), tt.want) })
+	}
+}
+
+func TestCanDeleteUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		actor    Actor
+		targetID int
+		want     *Violation
+	}{
+		{"without users.delete", helpdesk, user.UserID, &Violation{Reason: ReasonPermissionRequired, Permission: models.PermUsersDelete}},
+		{"self without users.delete", user, user.UserID, &Violation{Reason: ReasonPermissionRequired, Permission: models.PermUsersDelete}},
+		{"other", admin, user.UserID, nil},
+		{"self", admin, admin.UserID, &Violation{Reason: ReasonSelfLockout}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) { check(t, CanDeleteUser(tt.actor, tt.targetID), tt.want) })
+	}
+}
+
+func TestCanDeleteOwnAccount(t *testing.T) {
+	check(t, CanDeleteOwnAccount(user), nil)
+	check(t, CanDeleteOwnAccount(admin), &Violation{Reason: ReasonSelfLockout})
+}
+
+func TestCanAssignRole(t *testing.T) {
+	tests := []struct {
+		name  string
+		actor Actor
+		role  string
+		want  *Violation
+	}{
+		{"default role", helpdesk, models.RoleUser, nil},
+		{"other role without roles.manage", helpdesk, "helpdesk",
+			&Violation{Reason: ReasonFieldRestricted, Permission: models.PermRolesManage, Fields: []string{"role"}}},
+		{"other role with roles.manage", admin, models.RoleAdmin, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) { check(t, CanAssignRole(tt.actor, tt.role), tt.want) })
+	}
+}
+
+func TestCanGrantRole(t *testing.T) {
+	tests := []struct {
+		name  string
+		actor Actor
+		perms []string
+		want  *Violation
+	}{
+		{"no permissions", user, nil, nil},
+		{"subset", helpdesk, []string{models.PermUsersRead}, nil},
+		{"more than held", helpdesk, []string{models.PermUsersRead, models.PermUsersDelete},
+			&Violation{Reason: ReasonRoleExceeds, Permission: models.PermUsersDelete, Fields: []string{"role"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) { check(t, CanGrantRole(tt.actor, tt.perms), tt.want) })
+	}
+}
+
+func TestCanActOn(t *testing.T) {
+	tests := []struct {
+		name     string
+		actor    Actor
+		targetID int
+		perms    []string
+		want     *Violation
+	}{
+		{"self", helpdesk, helpdesk.UserID, admin.Permissions, nil},
+		{"permission the actor lacks", helpdesk, user.UserID, user.Permissions,
+			&Violation{Reason: ReasonTargetPrivileged, Permission: models.PermTeamsCreate}},
+		{"equally privileged", admin, 4, admin.Permissions, nil},
+		{"more privileged", helpdesk, admin.UserID, admin.Permissions,
+			&Violation{Reason: ReasonTargetPrivileged, Permission: models.PermUsersDelete}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) { check(t, CanActOn(tt.actor, tt.targetID, tt.perms), tt.want) })
+	}
+}
+
+func TestCanManageSessions(t *testing.T) {
+	check(t, CanManageSessions(user, user.UserID), nil)
+	check(t, CanManageSessions(user, admin.UserID), &Violation{Reason: ReasonNotSelf, Permission: models.PermUsersWrite})
+	check(t, CanManageSessions(helpdesk, admin.UserID), nil)
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// AccountErasureRepository defines data operations for account erasures
+type AccountErasureRepository interface {
+	Create(erasure *models.AccountErasure) error
+	Get(userID int) (*models.AccountErasure, error)
+	Cancel(userID int) (bool, error)
+	ListDue(now time.Time) ([]models.AccountErasure, error)
+	ListStalled(requestedBefore time.Time) ([]models.AccountErasure, error)
+	Start(userID int, services []string, at time.Time) (bool, error)
+	MarkRequested(userID int, at time.Time) error
+	CompleteStep(userID int, service string, at time.Time) (bool, error)
+}
+
+// GormAccountErasureRepository implements AccountErasureRepository using GORM
+type GormAccountErasureRepository struct {
+	db *gorm.DB
+}
+
+// NewAccountErasureRepository creates a new GORM-based account erasure repository
+func NewAccountErasureRepository(db *gorm.DB) AccountErasureRepository {
+	return &GormAccountErasureRepository{db: db}
+}
+
+// Create schedules an erasure
+func (r *GormAccountErasureRepository) Create(erasure *models.AccountErasure) error {
+	return r.db.Create(erasure).Error
+}
+
+// Get returns the erasure of a user with its steps, or nil if there is none
+func (r *GormAccountErasureRepository) Get(userID int) (*models.AccountErasure, error) {
+	var erasure models.AccountErasure
+	if err := r.db.Preload("Steps").Where("user_id = ?", userID).First(&erasure).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &erasure, nil
+}
+
+// Cancel removes an erasure that has not started yet and reports whether it did
+func (r *GormAccountErasureRepository) Cancel(userID int) (bool, error) {
+	res := r.db.Where("user_id = ? AND started_at IS NULL", userID).Delete(&models.AccountErasure{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// ListDue returns erasures whose grace period is over but that have not started
+func (r *GormAccountErasureRepository) ListDue(now time.Time) ([]models.AccountErasure, error) {
+	var erasures []models.AccountErasure
+	err := r.db.Where("started_at IS NULL AND erase_after <= ?", now).Order("erase_after").Find(&erasures).Error
+	return erasures, err
+}
+
+// ListStalled returns started erasures that are still waiting for a service and were
+// last requested before the given time
+func (r *GormAccountErasureRepository) ListStalled(requestedBefore time.Time) ([]models.AccountErasure, error) {
+	var erasures []models.AccountErasure
+	err := r.db.Preload("Steps").Where("started_at IS NOT NULL AND completed_at IS NULL AND last_requested_at < ?", requestedBefore).Find(&erasures).Error
+	return erasures, err
+}
+
+// Start claims a due erasure and creates a pending step per service. It reports false
+// if the erasure was cancelled or already started, e.g. by another instance.
+func (r *GormAccountErasureRepository) Start(userID int, services []string, at time.Time) (bool, error) {
+	started := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.AccountErasure{}).Where("user_id = ? AND started_at IS NULL", userID).
+			Updates(map[string]interface{}{"started_at": at, "last_requested_at": at})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		for _, service := range services {
+			if err := tx.Create(&models.AccountErasureStep{UserID: userID, Service: service}).Error; err != nil {
+				return err
+			}
+		}
+		started = true
+		return nil
+	})
+	return started, err
+}
+
+// MarkRequested records that the services were asked (again) to erase the user's data
+func (r *GormAccountErasureRepository) MarkRequested(userID int, at time.Time) error {
+	return r.db.Model(&models.AccountErasure{}).Where("user_id = ?", userID).Update("last_requested_at", at).Error
+}
+
+// CompleteStep records a service's report. It reports true when this was the last
+// pending step, marking the whole erasure completed.
+func (r *GormAccountErasureRepository) CompleteStep(userID int, service string, at time.Time) (bool, error) {
+	completed := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.AccountErasureStep{}).Where("user_id = ? AND service = ? AND completed_at IS NULL", userID, service).
+			Update("completed_at", at).Error; err != nil {
+			return err
+		}
+		var pending int64
+		if err := tx.Model(&models.AccountErasureStep{}).Where("user_id = ? AND completed_at IS NULL", userID).Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending > 0 {
+			return nil
+		}
+		res := tx.Model(&models.AccountErasure{}).Where("user_id = ? AND started_at IS NOT NULL AND completed_at IS NULL", userID).Update("completed_at", at)
+		completed = res.RowsAffected == 1
+		return res.Error
+	})
+	return completed, err
+}
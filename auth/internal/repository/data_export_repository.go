@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// DataExportRepository defines data operations for data exports
+type DataExportRepository interface {
+	Create(export *models.DataExport) error
+	Get(id string) (*models.DataExport, error)
+	GetActiveForUser(userID int) (*models.DataExport, error)
+	ListRunnable(staleBefore time.Time) ([]models.DataExport, error)
+	Claim(id string, at time.Time, staleBefore time.Time) (bool, error)
+	Complete(id string, archive []byte, at, expiresAt time.Time) error
+	Fail(id, reason string, at time.Time) error
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+// GormDataExportRepository implements DataExportRepository using GORM
+type GormDataExportRepository struct {
+	db *gorm.DB
+}
+
+// NewDataExportRepository creates a new GORM-based data export repository
+func NewDataExportRepository(db *gorm.DB) DataExportRepository {
+	return &GormDataExportRepository{db: db}
+}
+
+// Create stores a new export
+func (r *GormDataExportRepository) Create(export *models.DataExport) error {
+	return r.db.Create(export).Error
+}
+
+// Get returns an export with its archive, or nil if there is none
+func (r *GormDataExportRepository) Get(id string) (*models.DataExport, error) {
+	var export models.DataExport
+	if err := r.db.Where("id = ?", id).First(&export).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &export, nil
+}
+
+// GetActiveForUser returns a user's pending or running export, or nil if there is none
+func (r *GormDataExportRepository) GetActiveForUser(userID int) (*models.DataExport, error) {
+	var export models.DataExport
+	err := r.db.Omit("archive").Where("user_id = ? AND status IN ?", userID, []string{models.DataExportPending, models.DataExportRunning}).
+		Order("created_at DESC").First(&export).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &export, nil
+}
+
+// ListRunnable returns pending exports and running ones started before staleBefore,
+// whose instance presumably stopped, oldest first
+func (r *GormDataExportRepository) ListRunnable(staleBefore time.Time) ([]models.DataExport, error) {
+	var exports []models.DataExport
+	err := r.db.Omit("archive").Where("status = ? OR (status = ? AND started_at < ?)", models.DataExportPending, models.DataExportRunning, staleBefore).
+		Order("created_at").Find(&exports).Error
+	return exports, err
+}
+
+// Claim marks a runnable export as running and reports false if another instance got it first
+func (r *GormDataExportRepository) Claim(id string, at time.Time, staleBefore time.Time) (bool, error) {
+	res := r.db.Model(&models.DataExport{}).
+		Where("id = ? AND (status = ? OR (status = ? AND started_at < ?))", id, models.DataExportPending, models.DataExportRunning, staleBefore).
+		Updates(map[string]interface{}{"status": models.DataExportRunning, "started_at": at})
+	return res.RowsAffected > 0, res.Error
+}
+
+// Complete stores the finished archive
+func (r *GormDataExportRepository) Complete(id string, archive []byte, at, expiresAt time.Time) error {
+	return r.db.Model(&models.DataExport{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": models.DataExportReady, "archive": archive, "completed_at": at, "expires_at": expiresAt}).Error
+}
+
+// Fail records why an export could not be created
+func (r *GormDataExportRepository) Fail(id, reason string, at time.Time) error {
+	return r.db.Model(&models.DataExport{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": models.DataExportFailed, "error": reason, "completed_at": at}).Error
+}
+
+// DeleteExpired removes exports whose archive expired and failed exports older than a day
+func (r *GormDataExportRepository) DeleteExpired(now time.Time) (int64, error) {
+	res := r.db.Where("expires_at < ? OR (status = ? AND completed_at < ?)", now, models.DataExportFailed, now.Add(-24*time.Hour)).Delete(&models.DataExport{})
+	return res.RowsAffected, res.Error
+}
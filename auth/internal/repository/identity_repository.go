@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// IdentityRepository defines data operations for linked external OIDC accounts
+type IdentityRepository interface {
+	Get(provider, subject string) (*models.UserIdentity, error)
+	Create(identity *models.UserIdentity) error
+	CreateWithUser(user *models.User, identity *models.UserIdentity) error
+	ListForUser(userID int) ([]models.UserIdentity, error)
+	TouchLogin(id int64) error
+}
+
+// GormIdentityRepository implements IdentityRepository using GORM
+type GormIdentityRepository struct {
+	db *gorm.DB
+}
+
+// NewIdentityRepository creates a new GORM-based identity repository
+func NewIdentityRepository(db *gorm.DB) IdentityRepository {
+	return &GormIdentityRepository{db: db}
+}
+
+// Get returns the identity for a provider subject, or nil if it is not linked yet
+func (r *GormIdentityRepository) Get(provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	if err := r.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// Create links an identity to an existing user
+func (r *GormIdentityRepository) Create(identity *models.UserIdentity) error {
+	return r.db.Create(identity).Error
+}
+
+// CreateWithUser provisions a new user together with its first identity
+func (r *GormIdentityRepository) CreateWithUser(user *models.User, identity *models.UserIdentity) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		return tx.Create(identity).Error
+	})
+}
+
+// ListForUser returns the identities linked to a user
+func (r *GormIdentityRepository) ListForUser(userID int) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	err := r.db.Where("user_id = ?", userID).Order("created_at").Find(&identities).Error
+	return identities, err
+}
+
+// TouchLogin records a login through the identity
+func (r *GormIdentityRepository) TouchLogin(id int64) error {
+	return r.db.Model(&models.UserIdentity{}).Where("id = ?", id).Update("last_login_at", time.Now().UTC()).Error
+}
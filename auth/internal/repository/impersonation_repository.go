@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// ImpersonationRepository stores the audit trail of impersonation tokens
+type ImpersonationRepository interface {
+	Create(impersonation *models.Impersonation) error
+	ListForUser(userID int, limit int) ([]models.Impersonation, error)
+}
+
+// GormImpersonationRepository implements ImpersonationRepository using GORM
+type GormImpersonationRepository struct {
+	db *gorm.DB
+}
+
+// NewImpersonationRepository creates a new GORM-based impersonation repository
+func NewImpersonationRepository(db *gorm.DB) ImpersonationRepository {
+	return &GormImpersonationRepository{db: db}
+}
+
+// Create records an issued impersonation token
+func (r *GormImpersonationRepository) Create(impersonation *models.Impersonation) error {
+	return r.db.Create(impersonation).Error
+}
+
+// ListForUser returns the latest impersonations of a user, newest first
+func (r *GormImpersonationRepository) ListForUser(userID int, limit int) ([]models.Impersonation, error) {
+	var impersonations []models.Impersonation
+	err := r.db.Where("user_id = ?", userID).Order("id DESC").Limit(limit).Find(&impersonations).Error
+	return impersonations, err
+}
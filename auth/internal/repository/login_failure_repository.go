@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/lockout"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// LoginFailureRepository is a lockout.Store backed by the database, so that all
+// auth-service replicas see the same failure counters
+type LoginFailureRepository interface {
+	lockout.Store
+	DeleteExpired(before time.Time) (int64, error)
+}
+
+// GormLoginFailureRepository implements LoginFailureRepository using GORM
+type GormLoginFailureRepository struct {
+	db *gorm.DB
+}
+
+// NewLoginFailureRepository creates a new GORM-based login failure repository
+func NewLoginFailureRepository(db *gorm.DB) LoginFailureRepository {
+	return &GormLoginFailureRepository{db: db}
+}
+
+// Get returns the counter for key, or a zero counter if it does not exist or expired
+func (r *GormLoginFailureRepository) Get(key string) (lockout.Counter, error) {
+	var f models.LoginFailure
+	if err := r.db.Where("counter_key = ? AND expires_at > ?", key, time.Now().UTC()).First(&f).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return lockout.Counter{}, nil
+		}
+		return lockout.Counter{}, err
+	}
+	return lockout.Counter{Failures: f.Failures, LastFailure: f.LastFailureAt}, nil
+}
+
+// Increment adds a failure to key in a single upsert, so concurrent failures on
+// different replicas are all counted; an expired counter starts again at 1
+func (r *GormLoginFailureRepository) Increment(key string, now time.Time, ttl time.Duration) (lockout.Counter, error) {
+	now = now.UTC()
+	err := r.db.Exec(`INSERT INTO login_failures (counter_key, failures, last_failure_at, expires_at) VALUES (?, 1, ?, ?)
+		ON DUPLICATE KEY UPDATE failures = IF(expires_at <= VALUES(last_failure_at), 1, failures + 1),
+			last_failure_at = VALUES(last_failure_at), expires_at = VALUES(expires_at)`,
+		key, now, now.Add(ttl)).Error
+	if err != nil {
+		return lockout.Counter{}, err
+	}
+	var f models.LoginFailure
+	if err := r.db.Where("counter_key = ?", key).First(&f).Error; err != nil {
+		return lockout.Counter{}, err
+	}
+	return lockout.Counter{Failures: f.Failures, LastFailure: f.LastFailureAt}, nil
+}
+
+// Reset removes the counter for key
+func (r *GormLoginFailureRepository) Reset(key string) error {
+	return r.db.Where("counter_key = ?", key).Delete(&models.LoginFailure{}).Error
+}
+
+// DeleteExpired purges counters that expired before the given time
+func (r *GormLoginFailureRepository) DeleteExpired(before time.Time) (int64, error) {
+	res := r.db.Where("expires_at < ?", before).Delete(&models.LoginFailure{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// MFARecoveryCodeRepository defines data operations for hashed 2FA recovery codes
+type MFARecoveryCodeRepository interface {
+	ReplaceForUser(userID int, hashes []string) error
+	Consume(userID int, hash string) (bool, error)
+	DeleteForUser(userID int) error
+}
+
+// GormMFARecoveryCodeRepository implements MFARecoveryCodeRepository using GORM
+type GormMFARecoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+// NewMFARecoveryCodeRepository creates a new GORM-based recovery code repository
+func NewMFARecoveryCodeRepository(db *gorm.DB) MFARecoveryCodeRepository {
+	return &GormMFARecoveryCodeRepository{db: db}
+}
+
+// ReplaceForUser discards all existing codes of the user and stores the new set
+func (r *GormMFARecoveryCodeRepository) ReplaceForUser(userID int, hashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]models.MFARecoveryCode, 0, len(hashes))
+		for _, h := range hashes {
+			codes = append(codes, models.MFARecoveryCode{UserID: userID, CodeHash: h})
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// Consume marks an unused code as used; the conditional update makes each code single-use
+func (r *GormMFARecoveryCodeRepository) Consume(userID int, hash string) (bool, error) {
+	res := r.db.Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now().UTC())
+	return res.RowsAffected == 1, res.Error
+}
+
+// DeleteForUser removes all recovery codes of the user
+func (r *GormMFARecoveryCodeRepository) DeleteForUser(userID int) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error
+}
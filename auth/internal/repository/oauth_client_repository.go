@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// OAuthClientRepository defines data operations for registered OAuth clients
+type OAuthClientRepository interface {
+	Create(client *models.OAuthClient) error
+	Get(clientID string) (*models.OAuthClient, error)
+	ListByOwner(ownerID int) ([]models.OAuthClient, error)
+	Delete(clientID string) error
+}
+
+// GormOAuthClientRepository implements OAuthClientRepository using GORM
+type GormOAuthClientRepository struct {
+	db *gorm.DB
+}
+
+// NewOAuthClientRepository creates a new GORM-based OAuth client repository
+func NewOAuthClientRepository(db *gorm.DB) OAuthClientRepository {
+	return &GormOAuthClientRepository{db: db}
+}
+
+// Create registers a client
+func (r *GormOAuthClientRepository) Create(client *models.OAuthClient) error {
+	return r.db.Create(client).Error
+}
+
+// Get returns the client with the given client ID, or nil if there is none
+func (r *GormOAuthClientRepository) Get(clientID string) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	if err := r.db.Where("client_id = ?", clientID).First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &client, nil
+}
+
+// ListByOwner returns the clients a user registered, oldest first
+func (r *GormOAuthClientRepository) ListByOwner(ownerID int) ([]models.OAuthClient, error) {
+	var clients []models.OAuthClient
+	err := r.db.Where("owner_id = ?", ownerID).Order("id").Find(&clients).Error
+	return clients, err
+}
+
+// Delete removes a client; its consents and pending codes are removed by the database
+func (r *GormOAuthClientRepository) Delete(clientID string) error {
+	return r.db.Where("client_id = ?", clientID).Delete(&models.OAuthClient{}).Error
+}
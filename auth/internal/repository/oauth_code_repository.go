@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// OAuthCodeRepository stores issued authorization codes
+type OAuthCodeRepository interface {
+	Create(code *models.OAuthAuthorizationCode) error
+	Consume(hash string) (*models.OAuthAuthorizationCode, error)
+	DeleteExpired(before time.Time) (int64, error)
+}
+
+// GormOAuthCodeRepository implements OAuthCodeRepository using GORM
+type GormOAuthCodeRepository struct {
+	db *gorm.DB
+}
+
+// NewOAuthCodeRepository creates a new GORM-based authorization code repository
+func NewOAuthCodeRepository(db *gorm.DB) OAuthCodeRepository {
+	return &GormOAuthCodeRepository{db: db}
+}
+
+// Create stores an issued code
+func (r *GormOAuthCodeRepository) Create(code *models.OAuthAuthorizationCode) error {
+	return r.db.Create(code).Error
+}
+
+// Consume deletes and returns a live code, so each code is redeemed at most once.
+// Unknown, expired or already redeemed codes return ErrTokenNotUsable.
+func (r *GormOAuthCodeRepository) Consume(hash string) (*models.OAuthAuthorizationCode, error) {
+	var code models.OAuthAuthorizationCode
+	if err := r.db.Where("code_hash = ?", hash).First(&code).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTokenNotUsable
+		}
+		return nil, err
+	}
+	res := r.db.Where("code_hash = ?", hash).Delete(&models.OAuthAuthorizationCode{})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected != 1 || time.Now().After(code.ExpiresAt) {
+		return nil, ErrTokenNotUsable
+	}
+	return &code, nil
+}
+
+// DeleteExpired purges codes that were never redeemed
+func (r *GormOAuthCodeRepository) DeleteExpired(before time.Time) (int64, error) {
+	res := r.db.Where("expires_at < ?", before).Delete(&models.OAuthAuthorizationCode{})
+	return res.RowsAffected, res.Error
+}
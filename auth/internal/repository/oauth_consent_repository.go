@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// OAuthConsentRepository defines data operations for the scopes users granted to clients
+type OAuthConsentRepository interface {
+	Get(userID int, clientID string) (*models.OAuthConsent, error)
+	Save(userID int, clientID, scope string) error
+	ListForUser(userID int) ([]models.OAuthConsent, error)
+	Delete(userID int, clientID string) (bool, error)
+}
+
+// GormOAuthConsentRepository implements OAuthConsentRepository using GORM
+type GormOAuthConsentRepository struct {
+	db *gorm.DB
+}
+
+// NewOAuthConsentRepository creates a new GORM-based consent repository
+func NewOAuthConsentRepository(db *gorm.DB) OAuthConsentRepository {
+	return &GormOAuthConsentRepository{db: db}
+}
+
+// Get returns the user's consent for a client, or nil if none was given
+func (r *GormOAuthConsentRepository) Get(userID int, clientID string) (*models.OAuthConsent, error) {
+	var consent models.OAuthConsent
+	if err := r.db.Where("user_id = ? AND client_id = ?", userID, clientID).First(&consent).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &consent, nil
+}
+
+// Save records or replaces the granted scope in a single upsert
+func (r *GormOAuthConsentRepository) Save(userID int, clientID, scope string) error {
+	return r.db.Exec(`INSERT INTO oauth_consents (user_id, client_id, scope) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE scope = VALUES(scope)`, userID, clientID, scope).Error
+}
+
+// ListForUser returns every consent a user has given, oldest first
+func (r *GormOAuthConsentRepository) ListForUser(userID int) ([]models.OAuthConsent, error) {
+	var consents []models.OAuthConsent
+	err := r.db.Where("user_id = ?", userID).Order("created_at").Find(&consents).Error
+	return consents, err
+}
+
+// Delete withdraws a consent and reports whether there was one
+func (r *GormOAuthConsentRepository) Delete(userID int, clientID string) (bool, error) {
+	res := r.db.Where("user_id = ? AND client_id = ?", userID, clientID).Delete(&models.OAuthConsent{})
+	return res.RowsAffected > 0, res.Error
+}
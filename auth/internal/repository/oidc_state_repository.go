@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// OIDCStateRepository stores pending OIDC logins
+type OIDCStateRepository interface {
+	Create(state *models.OIDCLoginState) error
+	Consume(hash, provider string) (*models.OIDCLoginState, error)
+	DeleteExpired(before time.Time) (int64, error)
+}
+
+// GormOIDCStateRepository implements OIDCStateRepository using GORM
+type GormOIDCStateRepository struct {
+	db *gorm.DB
+}
+
+// NewOIDCStateRepository creates a new GORM-based OIDC state repository
+func NewOIDCStateRepository(db *gorm.DB) OIDCStateRepository {
+	return &GormOIDCStateRepository{db: db}
+}
+
+// Create stores a pending login
+func (r *GormOIDCStateRepository) Create(state *models.OIDCLoginState) error {
+	return r.db.Create(state).Error
+}
+
+// Consume deletes and returns a live state. Only the caller whose delete succeeds
+// gets the state, so a callback cannot be replayed. Unknown, expired or already
+// used states return ErrTokenNotUsable.
+func (r *GormOIDCStateRepository) Consume(hash, provider string) (*models.OIDCLoginState, error) {
+	var state models.OIDCLoginState
+	if err := r.db.Where("state_hash = ? AND provider = ?", hash, provider).First(&state).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTokenNotUsable
+		}
+		return nil, err
+	}
+	res := r.db.Where("state_hash = ?", hash).Delete(&models.OIDCLoginState{})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected != 1 || time.Now().After(state.ExpiresAt) {
+		return nil, ErrTokenNotUsable
+	}
+	return &state, nil
+}
+
+// DeleteExpired purges abandoned logins
+func (r *GormOIDCStateRepository) DeleteExpired(before time.Time) (int64, error) {
+	res := r.db.Where("expires_at < ?", before).Delete(&models.OIDCLoginState{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// ErrTokenNotUsable is returned when a one-time token is unknown, expired or already used
+var ErrTokenNotUsable = errors.New("token not usable")
+
+// OneTimeTokenRepository defines data operations for hashed single-use tokens
+// (e.g. email verification links)
+type OneTimeTokenRepository interface {
+	Create(token *models.OneTimeToken) error
+	Consume(hash, purpose string) (*models.OneTimeToken, error)
+	InvalidateForUser(userID int, purpose string) error
+	CountSince(userID int, purpose string, since time.Time) (int64, error)
+	DeleteExpired(before time.Time) (int64, error)
+}
+
+// GormOneTimeTokenRepository implements OneTimeTokenRepository using GORM
+type GormOneTimeTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewOneTimeTokenRepository creates a new GORM-based one-time token repository
+func NewOneTimeTokenRepository(db *gorm.DB) OneTimeTokenRepository {
+	return &GormOneTimeTokenRepository{db: db}
+}
+
+// Create stores a newly issued token
+func (r *GormOneTimeTokenRepository) Create(token *models.OneTimeToken) error {
+	return r.db.Create(token).Error
+}
+
+// Consume marks a live token as used and returns it. The update is conditional,
+// so a token can be redeemed exactly once even under concurrent requests.
+func (r *GormOneTimeTokenRepository) Consume(hash, purpose string) (*models.OneTimeToken, error) {
+	var token models.OneTimeToken
+	if err := r.db.Where("token_hash = ? AND purpose = ?", hash, purpose).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTokenNotUsable
+		}
+		return nil, err
+	}
+	now := time.Now().UTC()
+	res := r.db.Model(&models.OneTimeToken{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", token.ID, now).
+		Update("used_at", now)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected != 1 {
+		return nil, ErrTokenNotUsable
+	}
+	token.UsedAt = &now
+	return &token, nil
+}
+
+// InvalidateForUser marks all outstanding tokens of a user for the purpose as used
+func (r *GormOneTimeTokenRepository) InvalidateForUser(userID int, purpose string) error {
+	return r.db.Model(&models.OneTimeToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now().UTC()).Error
+}
+
+// CountSince counts tokens issued to a user for the purpose since the given time
+func (r *GormOneTimeTokenRepository) CountSince(userID int, purpose string, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.OneTimeToken{}).
+		Where("user_id = ? AND purpose = ? AND created_at >= ?", userID, purpose, since).
+		Count(&count).Error
+	return count, err
+}
+
+// DeleteExpired removes tokens that expired before the given time
+func (r *GormOneTimeTokenRepository) DeleteExpired(before time.Time) (int64, error) {
+	res := r.db.Where("expires_at < ?", before).Delete(&models.OneTimeToken{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// PersonalAccessTokenRepository defines data operations for personal access tokens
+type PersonalAccessTokenRepository interface {
+	Create(token *models.PersonalAccessToken) error
+	GetByHash(hash string) (*models.PersonalAccessToken, error)
+	ListForUser(userID int) ([]models.PersonalAccessToken, error)
+	Delete(userID int, id int64) (bool, error)
+	TouchLastUsed(id int64, at time.Time) error
+}
+
+// GormPersonalAccessTokenRepository implements PersonalAccessTokenRepository using GORM
+type GormPersonalAccessTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewPersonalAccessTokenRepository creates a new GORM-based personal access token repository
+func NewPersonalAccessTokenRepository(db *gorm.DB) PersonalAccessTokenRepository {
+	return &GormPersonalAccessTokenRepository{db: db}
+}
+
+// Create stores a new token
+func (r *GormPersonalAccessTokenRepository) Create(token *models.PersonalAccessToken) error {
+	return r.db.Create(token).Error
+}
+
+// GetByHash returns the token with the given hash, or nil if there is none
+func (r *GormPersonalAccessTokenRepository) GetByHash(hash string) (*models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+	if err := r.db.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ListForUser returns a user's tokens, newest first
+func (r *GormPersonalAccessTokenRepository) ListForUser(userID int) ([]models.PersonalAccessToken, error) {
+	var tokens []models.PersonalAccessToken
+	err := r.db.Where("user_id = ?", userID).Order("id DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// Delete removes one of a user's tokens and reports whether it existed
+func (r *GormPersonalAccessTokenRepository) Delete(userID int, id int64) (bool, error) {
+	res := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.PersonalAccessToken{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// TouchLastUsed records when a token was last used
+func (r *GormPersonalAccessTokenRepository) TouchLastUsed(id int64, at time.Time) error {
+	return r.db.Model(&models.PersonalAccessToken{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
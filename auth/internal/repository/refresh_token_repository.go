@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// RefreshTokenRepository defines data operations for persisted refresh tokens
+type RefreshTokenRepository interface {
+	Create(token *models.RefreshToken) error
+	GetByHash(hash string) (*models.RefreshToken, error)
+	MarkRotated(id int64) (bool, error)
+	RevokeFamily(familyID string) error
+	RevokeAllForUser(userID int) error
+	RevokeClient(clientID string) error
+	RevokeClientForUser(userID int, clientID string) error
+	DeleteExpired(before time.Time) (int64, error)
+}
+
+// GormRefreshTokenRepository implements RefreshTokenRepository using GORM
+type GormRefreshTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewRefreshTokenRepository creates a new GORM-based refresh token repository
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &GormRefreshTokenRepository{db: db}
+}
+
+// Create stores a newly issued refresh token
+func (r *GormRefreshTokenRepository) Create(token *models.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+// GetByHash retrieves a refresh token by the SHA-256 hash of its value
+func (r *GormRefreshTokenRepository) GetByHash(hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := r.db.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkRotated flags a token as used. It only succeeds for a token that is still
+// live, so two concurrent refreshes with the same token cannot both win.
+func (r *GormRefreshTokenRepository) MarkRotated(id int64) (bool, error) {
+	res := r.db.Model(&models.RefreshToken{}).
+		Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", id).
+		Update("rotated_at", time.Now().UTC())
+	return res.RowsAffected == 1, res.Error
+}
+
+// RevokeFamily revokes every token issued in the same login (device) family
+func (r *GormRefreshTokenRepository) RevokeFamily(familyID string) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now().UTC()).Error
+}
+
+// RevokeAllForUser revokes every refresh token of a user across all devices
+func (r *GormRefreshTokenRepository) RevokeAllForUser(userID int) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now().UTC()).Error
+}
+
+// RevokeClient revokes every refresh token issued to an OAuth client
+func (r *GormRefreshTokenRepository) RevokeClient(clientID string) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("client_id = ? AND revoked_at IS NULL", clientID).
+		Update("revoked_at", time.Now().UTC()).Error
+}
+
+// RevokeClientForUser revokes the refresh tokens a user's grant gave an OAuth client
+func (r *GormRefreshTokenRepository) RevokeClientForUser(userID int, clientID string) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND client_id = ? AND revoked_at IS NULL", userID, clientID).
+		Update("revoked_at", time.Now().UTC()).Error
+}
+
+// DeleteExpired removes tokens that expired before the given time
+func (r *GormRefreshTokenRepository) DeleteExpired(before time.Time) (int64, error) {
+	res := r.db.Where("expires_at < ?", before).Delete(&models.RefreshToken{})
+	return res.RowsAffected, res.Error
+}
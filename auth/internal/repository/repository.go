@@ -1,6 +1,11 @@
 package repository
 
 import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
 	"gorm.io/gorm"
 
 	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
@@ -13,8 +18,21 @@ type UserRepository interface {
 	GetByUsername(username string) (*models.User, error)
 	GetByEmail(email string) (*models.User, error)
 	Update(user *models.User) error
+	UpdatePasswordHash(id int, hash string) error
+	SetAvatarKey(id int, key string) error
+	MarkEmailVerified(id int) error
+	SetTOTPSecret(id int, secret string) error
+	EnableMFA(id int, step int64) error
+	DisableMFA(id int) error
+	AdvanceTOTPStep(id int, step int64) (bool, error)
+	BumpTokenVersion(id int) (int, error)
 	Delete(id int) error
-	List(filters models.UserFilters) ([]models.User, int64, error)
+	GetDeletedByID(id int) (*models.User, error)
+	Restore(id int) error
+	Anonymize(id int) error
+	List(filters models.UserFilters) ([]models.User, bool, error)
+	Search(query string, limit int) ([]models.User, error)
+	ListByAttribute(column, value string, offset, limit int) ([]models.User, int64, error)
 	ExistsByUsername(username string) (bool, error)
 	ExistsByEmail(email string) (bool, error)
 }
@@ -64,57 +82,202 @@ func (r *GormUserRepository) GetByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
-// Update updates an existing user
+// Update updates an existing user. The token version and deletion time are left alone
+// so that saving a stale copy cannot undo a revocation or a deletion.
 func (r *GormUserRepository) Update(user *models.User) error {
-	return r.db.Save(user).Error
+	return r.db.Select("*").Omit("token_version", "deleted_at").Save(user).Error
+}
+
+// UpdatePasswordHash replaces only the stored password hash of a user
+func (r *GormUserRepository) UpdatePasswordHash(id int, hash string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Update("password_hash", hash).Error
+}
+
+// SetAvatarKey points the user at another avatar; "" removes it
+func (r *GormUserRepository) SetAvatarKey(id int, key string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Update("avatar_key", key).Error
+}
+
+// MarkEmailVerified records that the user proved ownership of their email address
+func (r *GormUserRepository) MarkEmailVerified(id int) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"email_verified": true, "email_verified_at": time.Now().UTC()}).Error
 }
 
-// Delete deletes a user by ID
+// SetTOTPSecret stores a pending TOTP secret; 2FA stays off until EnableMFA
+func (r *GormUserRepository) SetTOTPSecret(id int, secret string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"totp_secret": secret, "mfa_enabled": false, "totp_last_step": 0}).Error
+}
+
+// EnableMFA turns on 2FA after the first code (at the given time step) was confirmed
+func (r *GormUserRepository) EnableMFA(id int, step int64) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"mfa_enabled": true, "totp_last_step": step}).Error
+}
+
+// DisableMFA turns off 2FA and forgets the TOTP secret
+func (r *GormUserRepository) DisableMFA(id int) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"mfa_enabled": false, "totp_secret": nil, "totp_last_step": 0}).Error
+}
+
+// AdvanceTOTPStep records the time step of an accepted code. It only succeeds for a
+// step later than the last accepted one, so a code cannot be replayed.
+func (r *GormUserRepository) AdvanceTOTPStep(id int, step int64) (bool, error) {
+	res := r.db.Model(&models.User{}).Where("id = ? AND totp_last_step < ?", id, step).Update("totp_last_step", step)
+	return res.RowsAffected == 1, res.Error
+}
+
+// BumpTokenVersion increments the user's token version and returns the new value
+func (r *GormUserRepository) BumpTokenVersion(id int) (int, error) {
+	var user models.User
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", id).Update("token_version", gorm.Expr("token_version + 1")).Error; err != nil {
+			return err
+		}
+		return tx.Select("token_version").Where("id = ?", id).First(&user).Error
+	})
+	return user.TokenVersion, err
+}
+
+// Delete soft-deletes a user by ID; the row stays until Erase
 func (r *GormUserRepository) Delete(id int) error {
 	return r.db.Delete(&models.User{}, id).Error
 }
 
-// List retrieves a list of users with filters and pagination
-func (r *GormUserRepository) List(filters models.UserFilters) ([]models.User, int64, error) {
-	var users []models.User
-	var total int64
+// GetDeletedByID returns a soft-deleted user, or nil if there is none with the ID
+func (r *GormUserRepository) GetDeletedByID(id int) (*models.User, error) {
+	var user models.User
+	if err := r.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Restore undoes a soft delete
+func (r *GormUserRepository) Restore(id int) error {
+	return r.db.Unscoped().Model(&models.User{}).Where("id = ?", id).Update("deleted_at", nil).Error
+}
 
+// Anonymize erases a deleted user's personal data: everything the user owns in this
+// service is removed and the row is kept as a tombstone without PII, so IDs recorded
+// elsewhere (e.g. erasure requests) still resolve
+func (r *GormUserRepository) Anonymize(id int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, owned := range []interface{}{&models.RefreshToken{}, &models.Session{}, &models.OneTimeToken{}, &models.MFARecoveryCode{},
+			&models.UserIdentity{}, &models.OAuthConsent{}, &models.OAuthAuthorizationCode{}, &models.PersonalAccessToken{}, &models.DataExport{}, &models.SCIMGroupMember{}} {
+			if err := tx.Where("user_id = ?", id).Delete(owned).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("owner_id = ?", id).Delete(&models.OAuthClient{}).Error; err != nil {
+			return err
+		}
+		tombstone := "deleted-user-" + strconv.Itoa(id)
+		return tx.Unscoped().Model(&models.User{}).Where("id = ? AND deleted_at IS NOT NULL", id).Updates(map[string]interface{}{
+			"username": tombstone, "email": tombstone + "@deleted.invalid", "password_hash": "", "first_name": "", "last_name": "",
+			"role": models.RoleUser, "is_active": false, "email_verified": false, "email_verified_at": nil,
+			"mfa_enabled": false, "totp_secret": "", "totp_last_step": 0, "locale": "", "timezone": "", "avatar_key": "", "external_id": "",
+		}).Error
+	})
+}
+
+// List returns a page of users matching filters in filters.Order, starting after
+// filters.After. It reads one user more than filters.Limit to report whether
+// another page follows.
+func (r *GormUserRepository) List(filters models.UserFilters) ([]models.User, bool, error) {
 	query := r.db.Model(&models.User{})
 
-	// Apply search filter
+	// Prefix matches can use the column indexes, unlike '%q%'
 	if filters.Query != "" {
-		query = query.Where("username LIKE ? OR email LIKE ? OR first_name LIKE ? OR last_name LIKE ?",
-			"%"+filters.Query+"%", "%"+filters.Query+"%", "%"+filters.Query+"%", "%"+filters.Query+"%")
+		prefix := escapeLike(filters.Query) + "%"
+		query = query.Where("username LIKE ? OR email LIKE ? OR first_name LIKE ? OR last_name LIKE ?", prefix, prefix, prefix, prefix)
+	}
+	if filters.Role != "" {
+		query = query.Where("role = ?", filters.Role)
+	}
+	if filters.IsActive != nil {
+		query = query.Where("is_active = ?", *filters.IsActive)
+	}
+	if filters.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *filters.CreatedAfter)
+	}
+	if filters.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *filters.CreatedBefore)
 	}
 
-	// Get total count
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
+	column, direction, cmp := filters.Order.Column(), "ASC", ">"
+	if filters.Order.Desc {
+		direction, cmp = "DESC", "<"
+	}
+	if filters.After != nil {
+		value := filters.After.SortValue()
+		query = query.Where(column+" "+cmp+" ? OR ("+column+" = ? AND id "+cmp+" ?)", value, value, filters.After.ID)
 	}
 
-	// Apply pagination
-	if filters.Limit > 0 {
-		query = query.Limit(filters.Limit)
+	var users []models.User
+	err := query.Order(column + " " + direction).Order("id " + direction).Limit(filters.Limit + 1).Find(&users).Error
+	if err != nil {
+		return nil, false, err
+	}
+	if len(users) > filters.Limit {
+		return users[:filters.Limit], true, nil
 	}
-	if filters.Offset > 0 {
-		query = query.Offset(filters.Offset)
+	return users, false, nil
+}
+
+// Search returns active users whose username, first or last name starts with query,
+// or whose first and last name start with the two words of a query like "jane sm"
+func (r *GormUserRepository) Search(query string, limit int) ([]models.User, error) {
+	prefix := escapeLike(query) + "%"
+	cond := r.db.Where("username LIKE ? OR first_name LIKE ? OR last_name LIKE ?", prefix, prefix, prefix)
+	if first, last, ok := strings.Cut(query, " "); ok && first != "" && strings.TrimSpace(last) != "" {
+		cond = cond.Or("first_name LIKE ? AND last_name LIKE ?", escapeLike(first)+"%", escapeLike(strings.TrimSpace(last))+"%")
 	}
+	var users []models.User
+	err := r.db.Where("is_active = ?", true).Where(cond).Order("username").Limit(limit).Find(&users).Error
+	return users, err
+}
 
-	// Execute query
-	err := query.Order("created_at DESC").Find(&users).Error
+// escapeLike escapes the LIKE wildcards in s so that it matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// ListByAttribute returns a page of users in ID order, optionally only those whose
+// column (username, email or external_id) equals value, and the total number of matches
+func (r *GormUserRepository) ListByAttribute(column, value string, offset, limit int) ([]models.User, int64, error) {
+	query := r.db.Model(&models.User{})
+	if column != "" {
+		query = query.Where(column+" = ?", value)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []models.User
+	if limit == 0 {
+		return users, total, nil
+	}
+	err := query.Order("id").Offset(offset).Limit(limit).Find(&users).Error
 	return users, total, err
 }
 
-// ExistsByUsername checks if a user with the given username exists
+// ExistsByUsername checks if a user with the given username exists; deleted accounts
+// keep their username until they are erased
 func (r *GormUserRepository) ExistsByUsername(username string) (bool, error) {
 	var count int64
-	err := r.db.Model(&models.User{}).Where("username = ?", username).Count(&count).Error
+	err := r.db.Unscoped().Model(&models.User{}).Where("username = ?", username).Count(&count).Error
 	return count > 0, err
 }
 
-// ExistsByEmail checks if a user with the given email exists
+// ExistsByEmail checks if a user with the given email exists, including deleted accounts
 func (r *GormUserRepository) ExistsByEmail(email string) (bool, error) {
 	var count int64
-	err := r.db.Model(&models.User{}).Where("email = ?", email).Count(&count).Error
+	err := r.db.Unscoped().Model(&models.User{}).Where("email = ?", email).Count(&count).Error
 	return count > 0, err
 }
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// RoleRepository defines data operations for roles and their permissions
+type RoleRepository interface {
+	List() ([]models.Role, error)
+	Get(name string) (*models.Role, error)
+	Create(role *models.Role) error
+	Update(role *models.Role) error
+	Delete(name string) error
+	CountUsers(name string) (int64, error)
+	Permissions(name string) ([]string, error)
+}
+
+// GormRoleRepository implements RoleRepository using GORM
+type GormRoleRepository struct {
+	db *gorm.DB
+}
+
+// NewRoleRepository creates a new GORM-based role repository
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return &GormRoleRepository{db: db}
+}
+
+// List returns all roles with their permissions, by name
+func (r *GormRoleRepository) List() ([]models.Role, error) {
+	var roles []models.Role
+	err := r.db.Preload("Permissions").Order("name").Find(&roles).Error
+	return roles, err
+}
+
+// Get returns a role with its permissions, or nil if there is none
+func (r *GormRoleRepository) Get(name string) (*models.Role, error) {
+	var role models.Role
+	if err := r.db.Preload("Permissions").Where("name = ?", name).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+// Create stores a new role together with its permissions
+func (r *GormRoleRepository) Create(role *models.Role) error {
+	return r.db.Create(role).Error
+}
+
+// Update saves the role's description and replaces its permissions
+func (r *GormRoleRepository) Update(role *models.Role) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Role{}).Where("name = ?", role.Name).Update("description", role.Description).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("role = ?", role.Name).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		if len(role.Permissions) == 0 {
+			return nil
+		}
+		return tx.Create(&role.Permissions).Error
+	})
+}
+
+// Delete removes a role; its permissions are removed by the foreign key
+func (r *GormRoleRepository) Delete(name string) error {
+	return r.db.Where("name = ?", name).Delete(&models.Role{}).Error
+}
+
+// CountUsers returns how many users, including deleted ones in their grace period,
+// hold the role
+func (r *GormRoleRepository) CountUsers(name string) (int64, error) {
+	var n int64
+	err := r.db.Unscoped().Model(&models.User{}).Where("role = ?", name).Count(&n).Error
+	return n, err
+}
+
+// Permissions returns the permissions of a role, sorted; unknown roles have none
+func (r *GormRoleRepository) Permissions(name string) ([]string, error) {
+	var perms []string
+	err := r.db.Model(&models.RolePermission{}).Where("role = ?", name).Order("permission").Pluck("permission", &perms).Error
+	return perms, err
+}
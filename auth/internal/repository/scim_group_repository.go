@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// SCIMGroupRepository defines data operations for groups provisioned through SCIM
+type SCIMGroupRepository interface {
+	Create(group *models.SCIMGroup) error
+	Get(id string) (*models.SCIMGroup, error)
+	List(column, value string, offset, limit int) ([]models.SCIMGroup, int64, error)
+	ExistsByDisplayName(name, exceptID string) (bool, error)
+	Update(group *models.SCIMGroup) error
+	Delete(id string) error
+	Members(groupID string) ([]int, error)
+	AddMember(groupID string, userID int) error
+	RemoveMember(groupID string, userID int) error
+	RemoveUser(userID int) error
+}
+
+// GormSCIMGroupRepository implements SCIMGroupRepository using GORM
+type GormSCIMGroupRepository struct {
+	db *gorm.DB
+}
+
+// NewSCIMGroupRepository creates a new GORM-based SCIM group repository
+func NewSCIMGroupRepository(db *gorm.DB) SCIMGroupRepository {
+	return &GormSCIMGroupRepository{db: db}
+}
+
+// Create stores a new group
+func (r *GormSCIMGroupRepository) Create(group *models.SCIMGroup) error {
+	return r.db.Create(group).Error
+}
+
+// Get returns a group, or nil if there is none
+func (r *GormSCIMGroupRepository) Get(id string) (*models.SCIMGroup, error) {
+	var group models.SCIMGroup
+	if err := r.db.Where("id = ?", id).First(&group).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+// List returns a page of groups in creation order, optionally only those whose column
+// (display_name or external_id) equals value, and the total number of matches
+func (r *GormSCIMGroupRepository) List(column, value string, offset, limit int) ([]models.SCIMGroup, int64, error) {
+	query := r.db.Model(&models.SCIMGroup{})
+	if column != "" {
+		query = query.Where(column+" = ?", value)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var groups []models.SCIMGroup
+	if limit == 0 {
+		return groups, total, nil
+	}
+	err := query.Order("created_at, id").Offset(offset).Limit(limit).Find(&groups).Error
+	return groups, total, err
+}
+
+// ExistsByDisplayName checks if another group than exceptID has the name
+func (r *GormSCIMGroupRepository) ExistsByDisplayName(name, exceptID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.SCIMGroup{}).Where("display_name = ? AND id <> ?", name, exceptID).Count(&count).Error
+	return count > 0, err
+}
+
+// Update saves a group's name and external ID
+func (r *GormSCIMGroupRepository) Update(group *models.SCIMGroup) error {
+	return r.db.Model(group).Select("display_name", "external_id").Updates(group).Error
+}
+
+// Delete removes a group with its memberships
+func (r *GormSCIMGroupRepository) Delete(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("group_id = ?", id).Delete(&models.SCIMGroupMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&models.SCIMGroup{}).Error
+	})
+}
+
+// Members returns the IDs of a group's members in ascending order
+func (r *GormSCIMGroupRepository) Members(groupID string) ([]int, error) {
+	var ids []int
+	err := r.db.Model(&models.SCIMGroupMember{}).Where("group_id = ?", groupID).Order("user_id").Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+// AddMember adds a user to a group; adding a member again does nothing
+func (r *GormSCIMGroupRepository) AddMember(groupID string, userID int) error {
+	return r.db.Where(models.SCIMGroupMember{GroupID: groupID, UserID: userID}).FirstOrCreate(&models.SCIMGroupMember{}).Error
+}
+
+// RemoveMember removes a user from a group
+func (r *GormSCIMGroupRepository) RemoveMember(groupID string, userID int) error {
+	return r.db.Where("group_id = ? AND user_id = ?", groupID, userID).Delete(&models.SCIMGroupMember{}).Error
+}
+
+// RemoveUser removes a user from every group
+func (r *GormSCIMGroupRepository) RemoveUser(userID int) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.SCIMGroupMember{}).Error
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// SessionRepository defines data operations for first-party login sessions
+type SessionRepository interface {
+	Create(session *models.Session) error
+	Get(id string) (*models.Session, error)
+	ListForUser(userID int, now time.Time) ([]models.Session, error)
+	Touch(id, ipAddress, userAgent string, seenAt, expiresAt time.Time) error
+	Delete(id string) error
+	DeleteForUser(userID int) error
+	DeleteExpired(before time.Time) (int64, error)
+}
+
+// GormSessionRepository implements SessionRepository using GORM
+type GormSessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository creates a new GORM-based session repository
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &GormSessionRepository{db: db}
+}
+
+// Create stores a new session
+func (r *GormSessionRepository) Create(session *models.Session) error {
+	return r.db.Create(session).Error
+}
+
+// Get returns the session with the given ID, or nil if there is none
+func (r *GormSessionRepository) Get(id string) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.Where("id = ?", id).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ListForUser returns a user's unexpired sessions, most recently seen first
+func (r *GormSessionRepository) ListForUser(userID int, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.Where("user_id = ? AND expires_at > ?", userID, now).Order("last_seen_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+// Touch records a token refresh: where the device was last seen and until when the
+// new refresh token is valid. Deleted (revoked) sessions are not recreated.
+func (r *GormSessionRepository) Touch(id, ipAddress, userAgent string, seenAt, expiresAt time.Time) error {
+	return r.db.Model(&models.Session{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"ip_address":   ipAddress,
+		"user_agent":   userAgent,
+		"last_seen_at": seenAt,
+		"expires_at":   expiresAt,
+	}).Error
+}
+
+// Delete removes a session
+func (r *GormSessionRepository) Delete(id string) error {
+	return r.db.Where("id = ?", id).Delete(&models.Session{}).Error
+}
+
+// DeleteForUser removes every session of a user
+func (r *GormSessionRepository) DeleteForUser(userID int) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.Session{}).Error
+}
+
+// DeleteExpired purges sessions whose last refresh token has expired
+func (r *GormSessionRepository) DeleteExpired(before time.Time) (int64, error) {
+	res := r.db.Where("expires_at < ?", before).Delete(&models.Session{})
+	return res.RowsAffected, res.Error
+}
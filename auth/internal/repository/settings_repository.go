@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// SettingsRepository stores runtime settings managed by admins
+type SettingsRepository interface {
+	Get(name string) (string, bool, error)
+	Set(name, value string) error
+}
+
+// GormSettingsRepository implements SettingsRepository using GORM
+type GormSettingsRepository struct {
+	db *gorm.DB
+}
+
+// NewSettingsRepository creates a new GORM-based settings repository
+func NewSettingsRepository(db *gorm.DB) SettingsRepository {
+	return &GormSettingsRepository{db: db}
+}
+
+// Get returns the value of a setting and whether it has been set
+func (r *GormSettingsRepository) Get(name string) (string, bool, error) {
+	var s models.Setting
+	if err := r.db.Where("name = ?", name).First(&s).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return s.Value, true, nil
+}
+
+// Set creates or overwrites a setting
+func (r *GormSettingsRepository) Set(name, value string) error {
+	return r.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&models.Setting{Name: name, Value: value}).Error
+}
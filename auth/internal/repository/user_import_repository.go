@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// UserImportRepository defines data operations for bulk user imports
+type UserImportRepository interface {
+	Create(imp *models.UserImport) error
+	Get(id string) (*models.UserImport, error)
+	ListPending() ([]models.UserImport, error)
+	Claim(id string, at time.Time) (bool, error)
+	Complete(id string, succeeded, failed int, report []byte, at time.Time) error
+	Fail(id, reason string, at time.Time) error
+	FailStale(staleBefore, at time.Time) (int64, error)
+	DeleteCompletedBefore(before time.Time) (int64, error)
+}
+
+// GormUserImportRepository implements UserImportRepository using GORM
+type GormUserImportRepository struct {
+	db *gorm.DB
+}
+
+// NewUserImportRepository creates a new GORM-based user import repository
+func NewUserImportRepository(db *gorm.DB) UserImportRepository {
+	return &GormUserImportRepository{db: db}
+}
+
+// Create stores a new import with its rows
+func (r *GormUserImportRepository) Create(imp *models.UserImport) error {
+	return r.db.Create(imp).Error
+}
+
+// Get returns an import with its rows and report, or nil if there is none
+func (r *GormUserImportRepository) Get(id string) (*models.UserImport, error) {
+	var imp models.UserImport
+	if err := r.db.Where("id = ?", id).First(&imp).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &imp, nil
+}
+
+// ListPending returns imports that have not been started, oldest first
+func (r *GormUserImportRepository) ListPending() ([]models.UserImport, error) {
+	var imps []models.UserImport
+	err := r.db.Omit("input", "report").Where("status = ?", models.UserImportPending).Order("created_at").Find(&imps).Error
+	return imps, err
+}
+
+// Claim marks a pending import as running and reports false if another instance got it first
+func (r *GormUserImportRepository) Claim(id string, at time.Time) (bool, error) {
+	res := r.db.Model(&models.UserImport{}).Where("id = ? AND status = ?", id, models.UserImportPending).
+		Updates(map[string]interface{}{"status": models.UserImportRunning, "started_at": at})
+	return res.RowsAffected > 0, res.Error
+}
+
+// Complete stores the report of a finished import and drops its rows, which may hold passwords
+func (r *GormUserImportRepository) Complete(id string, succeeded, failed int, report []byte, at time.Time) error {
+	return r.db.Model(&models.UserImport{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": models.UserImportCompleted, "succeeded": succeeded, "failed": failed, "report": report, "input": nil, "completed_at": at}).Error
+}
+
+// Fail records why an import could not run and drops its rows
+func (r *GormUserImportRepository) Fail(id, reason string, at time.Time) error {
+	return r.db.Model(&models.UserImport{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": models.UserImportFailed, "error": reason, "input": nil, "completed_at": at}).Error
+}
+
+// FailStale marks imports that are still running since before staleBefore as failed;
+// their instance presumably stopped. They are not restarted because some rows may
+// already have been created.
+func (r *GormUserImportRepository) FailStale(staleBefore, at time.Time) (int64, error) {
+	res := r.db.Model(&models.UserImport{}).Where("status = ? AND started_at < ?", models.UserImportRunning, staleBefore).
+		Updates(map[string]interface{}{"status": models.UserImportFailed, "error": "Interrupted; check which users were created before importing again", "input": nil, "completed_at": at})
+	return res.RowsAffected, res.Error
+}
+
+// DeleteCompletedBefore removes the reports of finished and failed imports older than before
+func (r *GormUserImportRepository) DeleteCompletedBefore(before time.Time) (int64, error) {
+	res := r.db.Where("status IN ? AND completed_at < ?", []string{models.UserImportCompleted, models.UserImportFailed}, before).Delete(&models.UserImport{})
+	return res.RowsAffected, res.Error
+}
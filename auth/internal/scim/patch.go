@@ -0,0 +1,231 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// PatchError is a patch operation that cannot be applied; ScimType is one of the Err* constants
+type PatchError struct {
+	ScimType string
+	Detail   string
+}
+
+func (e *PatchError) Error() string {
+	return e.Detail
+}
+
+func patchError(scimType, format string, args ...interface{}) error {
+	return &PatchError{ScimType: scimType, Detail: fmt.Sprintf(format, args...)}
+}
+
+// ApplyUserPatch applies patch operations to a user. Supported are userName,
+// externalId, active, password, name (and name.givenName, name.familyName), emails
+// (and emails[type eq "work"].value), and add or replace without a path, whose value
+// holds several of these.
+func ApplyUserPatch(u *User, ops []PatchOperation) error {
+	for _, op := range ops {
+		kind := strings.ToLower(op.Op)
+		if kind != "add" && kind != "replace" && kind != "remove" {
+			return patchError(ErrInvalidSyntax, "unsupported op %q", op.Op)
+		}
+		if op.Path == "" {
+			if kind == "remove" {
+				return patchError(ErrNoTarget, "remove needs a path")
+			}
+			values, ok := op.Value.(map[string]interface{})
+			if !ok {
+				return patchError(ErrInvalidValue, "an operation without a path needs an object value")
+			}
+			for path, value := range values {
+				if err := setUserAttribute(u, path, value); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if kind == "remove" {
+			if err := removeUserAttribute(u, op.Path); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := setUserAttribute(u, op.Path, op.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setUserAttribute(u *User, path string, value interface{}) error {
+	lower := strings.ToLower(path)
+	switch {
+	case lower == "username":
+		return setString(&u.UserName, path, value)
+	case lower == "externalid":
+		return setString(&u.ExternalID, path, value)
+	case lower == "password":
+		return setString(&u.Password, path, value)
+	case lower == "displayname":
+		return setString(&u.DisplayName, path, value)
+	case lower == "active":
+		active, err := ParseBool(value)
+		if err != nil {
+			return patchError(ErrInvalidValue, "active must be a boolean")
+		}
+		u.Active = &active
+	case lower == "name":
+		var name Name
+		if err := convert(value, &name); err != nil {
+			return patchError(ErrInvalidValue, "name must be an object")
+		}
+		u.Name = &name
+	case lower == "name.givenname" || lower == "name.familyname":
+		if u.Name == nil {
+			u.Name = &Name{}
+		}
+		if lower == "name.givenname" {
+			return setString(&u.Name.GivenName, path, value)
+		}
+		return setString(&u.Name.FamilyName, path, value)
+	case lower == "emails":
+		var emails []Email
+		if err := convert(value, &emails); err != nil {
+			return patchError(ErrInvalidValue, "emails must be a list of addresses")
+		}
+		u.Emails = emails
+	case strings.HasPrefix(lower, "emails[") && strings.HasSuffix(lower, "].value"):
+		var email string
+		if err := setString(&email, path, value); err != nil {
+			return err
+		}
+		u.Emails = []Email{{Value: email, Type: "work", Primary: true}}
+	default:
+		return patchError(ErrInvalidPath, "attribute %q is not supported", path)
+	}
+	return nil
+}
+
+func removeUserAttribute(u *User, path string) error {
+	switch strings.ToLower(path) {
+	case "externalid":
+		u.ExternalID = ""
+	case "name":
+		u.Name = nil
+	case "name.givenname":
+		if u.Name != nil {
+			u.Name.GivenName = ""
+		}
+	case "name.familyname":
+		if u.Name != nil {
+			u.Name.FamilyName = ""
+		}
+	case "displayname":
+		u.DisplayName = ""
+	default:
+		return patchError(ErrMutability, "attribute %q cannot be removed", path)
+	}
+	return nil
+}
+
+// ApplyGroupPatch applies patch operations to a group: replace displayName or
+// externalId, add, replace or remove members, and remove single members with
+// members[value eq "id"]
+func ApplyGroupPatch(g *Group, ops []PatchOperation) error {
+	for _, op := range ops {
+		kind := strings.ToLower(op.Op)
+		if kind != "add" && kind != "replace" && kind != "remove" {
+			return patchError(ErrInvalidSyntax, "unsupported op %q", op.Op)
+		}
+		if id, ok := ParseMemberPath(op.Path); ok {
+			if kind != "remove" {
+				return patchError(ErrInvalidPath, "members[value eq ...] can only be removed")
+			}
+			g.Members = withoutMembers(g.Members, map[string]bool{id: true})
+			continue
+		}
+		switch strings.ToLower(op.Path) {
+		case "":
+			if kind == "remove" {
+				return patchError(ErrNoTarget, "remove needs a path")
+			}
+			values, ok := op.Value.(map[string]interface{})
+			if !ok {
+				return patchError(ErrInvalidValue, "an operation without a path needs an object value")
+			}
+			for path, value := range values {
+				if err := ApplyGroupPatch(g, []PatchOperation{{Op: op.Op, Path: path, Value: value}}); err != nil {
+					return err
+				}
+			}
+		case "displayname":
+			if kind == "remove" {
+				return patchError(ErrMutability, "displayName cannot be removed")
+			}
+			if err := setString(&g.DisplayName, op.Path, op.Value); err != nil {
+				return err
+			}
+		case "externalid":
+			if kind == "remove" {
+				g.ExternalID = ""
+			} else if err := setString(&g.ExternalID, op.Path, op.Value); err != nil {
+				return err
+			}
+		case "members":
+			var members []MemberRef
+			if op.Value != nil {
+				if err := convert(op.Value, &members); err != nil {
+					return patchError(ErrInvalidValue, "members must be a list of {\"value\": id}")
+				}
+			}
+			switch kind {
+			case "add":
+				g.Members = append(g.Members, members...)
+			case "replace":
+				g.Members = members
+			case "remove":
+				if op.Value == nil {
+					g.Members = nil
+					break
+				}
+				ids := map[string]bool{}
+				for _, m := range members {
+					ids[m.Value] = true
+				}
+				g.Members = withoutMembers(g.Members, ids)
+			}
+		default:
+			return patchError(ErrInvalidPath, "attribute %q is not supported", op.Path)
+		}
+	}
+	return nil
+}
+
+func withoutMembers(members []MemberRef, ids map[string]bool) []MemberRef {
+	kept := members[:0:0]
+	for _, m := range members {
+		if !ids[m.Value] {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+func setString(dst *string, path string, value interface{}) error {
+	s, ok := value.(string)
+	if !ok {
+		return patchError(ErrInvalidValue, "%s must be a string", path)
+	}
+	*dst = s
+	return nil
+}
+
+// convert decodes a patch value into a typed attribute
+func convert(value interface{}, dst interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
@@ -0,0 +1,220 @@
+// Package scim holds the resource and message types of SCIM 2.0 (RFC 7643, RFC 7644)
+// used to provision users and groups from an external directory, and parses the small
+// subset of filters and patch paths the auth service supports.
+package scim
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of SCIM requests and responses
+const ContentType = "application/scim+json"
+
+// Schema URIs
+const (
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+)
+
+// Error types reported in scimType (RFC 7644 section 3.12)
+const (
+	ErrInvalidFilter = "invalidFilter"
+	ErrInvalidPath   = "invalidPath"
+	ErrInvalidValue  = "invalidValue"
+	ErrInvalidSyntax = "invalidSyntax"
+	ErrUniqueness    = "uniqueness"
+	ErrMutability    = "mutability"
+	ErrNoTarget      = "noTarget"
+)
+
+// User is a SCIM user. Password is only accepted, never returned.
+type User struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	UserName    string   `json:"userName"`
+	Name        *Name    `json:"name,omitempty"`
+	DisplayName string   `json:"displayName,omitempty"`
+	Emails      []Email  `json:"emails,omitempty"`
+	Active      *bool    `json:"active,omitempty"`
+	Password    string   `json:"password,omitempty"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+// Name is the name of a SCIM user
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// Email is an email address of a SCIM user
+type Email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// PrimaryEmail returns the address marked primary, or the first one
+func (u *User) PrimaryEmail() string {
+	for _, e := range u.Emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+	return ""
+}
+
+// Group is a SCIM group
+type Group struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id,omitempty"`
+	ExternalID  string      `json:"externalId,omitempty"`
+	DisplayName string      `json:"displayName"`
+	Members     []MemberRef `json:"members"`
+	Meta        *Meta       `json:"meta,omitempty"`
+}
+
+// MemberRef is a user in a group
+type MemberRef struct {
+	Value   string `json:"value"`
+	Ref     string `json:"$ref,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+// Meta describes a resource
+type Meta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location"`
+}
+
+// ListResponse is a page of resources; StartIndex counts from 1
+type ListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int64       `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// NewListResponse wraps a page of resources
+func NewListResponse(resources interface{}, count int, total int64, startIndex int) ListResponse {
+	return ListResponse{Schemas: []string{SchemaListResponse}, TotalResults: total, StartIndex: startIndex, ItemsPerPage: count, Resources: resources}
+}
+
+// Error is a SCIM error response; Status is the HTTP status as a string
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+// NewError builds an error response; scimType may be empty
+func NewError(status int, scimType, detail string) Error {
+	return Error{Schemas: []string{SchemaError}, Status: strconv.Itoa(status), ScimType: scimType, Detail: detail}
+}
+
+// PatchRequest modifies a resource with a list of operations
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// PatchOperation is one change of a PatchRequest; Op is add, remove or replace
+// (compared case-insensitively, as some directories send "Replace")
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Filter is an "attribute eq value" filter, the only kind that is supported
+type Filter struct {
+	Attribute string
+	Value     string
+}
+
+var filterPattern = regexp.MustCompile(`^\s*([A-Za-z][A-Za-z0-9.]*)\s+(?i:eq)\s+"((?:[^"\\]|\\.)*)"\s*$`)
+
+// ParseFilter parses an "attribute eq "value"" filter; an empty filter matches everything
+// and returns nil. Attributes are compared case-insensitively, as the RFC requires, and
+// returned as given.
+func ParseFilter(filter string) (*Filter, error) {
+	if strings.TrimSpace(filter) == "" {
+		return nil, nil
+	}
+	m := filterPattern.FindStringSubmatch(filter)
+	if m == nil {
+		return nil, errors.New(`only filters of the form attribute eq "value" are supported`)
+	}
+	value, err := strconv.Unquote(`"` + m[2] + `"`)
+	if err != nil {
+		return nil, errors.New("invalid filter value")
+	}
+	return &Filter{Attribute: m[1], Value: value}, nil
+}
+
+var memberPathPattern = regexp.MustCompile(`^\s*(?i:members)\s*\[\s*(?i:value)\s+(?i:eq)\s+"([^"]*)"\s*\]\s*$`)
+
+// ParseMemberPath returns the member ID of a "members[value eq "id"]" patch path
+func ParseMemberPath(path string) (string, bool) {
+	m := memberPathPattern.FindStringSubmatch(path)
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// ParseBool reads a boolean patch value; some directories send "True" or "False" strings
+func ParseBool(v interface{}) (bool, error) {
+	switch b := v.(type) {
+	case bool:
+		return b, nil
+	case string:
+		return strconv.ParseBool(b)
+	}
+	return false, errors.New("not a boolean")
+}
+
+// Paging returns the 1-based start index and the page size of a list request;
+// count defaults to and is capped at max
+func Paging(startIndex, count string, max int) (int, int, error) {
+	start, size := 1, max
+	if startIndex != "" {
+		n, err := strconv.Atoi(startIndex)
+		if err != nil {
+			return 0, 0, errors.New("startIndex must be a number")
+		}
+		if n > 1 {
+			start = n
+		}
+	}
+	if count != "" {
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return 0, 0, errors.New("count must be a number")
+		}
+		if n < 0 {
+			n = 0
+		}
+		if n < max {
+			size = n
+		}
+	}
+	return start, size, nil
+}
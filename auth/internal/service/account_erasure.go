@@ -0,0 +1,240 @@
+package service
+
+import (
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// ErasureStepAuth is the erasure step of this service: anonymizing the user
+const ErasureStepAuth = "auth"
+
+// erasureRetryInterval is how long services get to report before they are asked again
+const erasureRetryInterval = time.Hour
+
+// AccountErasureConfig is read from ACCOUNT_DELETION_GRACE and ERASURE_SERVICES
+type AccountErasureConfig struct {
+	// Grace is how long a deleted account can be restored before its data is erased
+	Grace time.Duration
+	// Services must each report the user's data erased (user.erasure_completed)
+	Services []string
+}
+
+// AccountErasureConfigFromEnv returns the erasure settings with their defaults
+// (30 days; the team and task services)
+func AccountErasureConfigFromEnv() AccountErasureConfig {
+	cfg := AccountErasureConfig{Grace: 30 * 24 * time.Hour, Services: []string{"team", "task"}}
+	if s := os.Getenv("ACCOUNT_DELETION_GRACE"); s != "" {
+		if grace, err := time.ParseDuration(s); err == nil && grace >= 0 {
+			cfg.Grace = grace
+		}
+	}
+	if s, ok := os.LookupEnv("ERASURE_SERVICES"); ok {
+		cfg.Services = strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+	}
+	return cfg
+}
+
+// OnErasureRequested registers a callback that asks the other services to erase a
+// user's data, e.g. by publishing user.erasure_requested
+func (s *AuthService) OnErasureRequested(fn func(userID int)) {
+	s.onErasureRequested = fn
+}
+
+// OnAccountErased registers a callback that announces a deleted user whose grace period
+// is over, e.g. by publishing user.deleted. Other services drop the user's memberships
+// and assignments only then, so restoring an account within the grace period keeps them.
+// actorID requested the deletion.
+func (s *AuthService) OnAccountErased(fn func(userID, actorID int)) {
+	s.onAccountErased = fn
+}
+
+// DeleteAccount soft-deletes a user on behalf of actorID: all of their tokens are
+// revoked at once and the erasure of their data is scheduled after the grace period.
+func (s *AuthService) DeleteAccount(userID, actorID int) (*models.AccountErasure, error) {
+	if _, err := s.repo.GetByID(userID); err != nil {
+		return nil, ErrUserNotFound
+	}
+	if err := s.RevokeAllSessions(userID, models.RevokeReasonDeleted); err != nil {
+		return nil, err
+	}
+	// The erasure is recorded first: one whose user was never deleted is dropped when it
+	// falls due, while a deleted user without an erasure would never be erased
+	erasure, err := s.erasures.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if erasure == nil {
+		erasure = &models.AccountErasure{UserID: userID, RequestedBy: actorID, EraseAfter: time.Now().UTC().Add(s.erasure.Grace)}
+		if err := s.erasures.Create(erasure); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.repo.Delete(userID); err != nil {
+		return nil, err
+	}
+	return erasure, nil
+}
+
+// DeleteOwnAccount deletes the caller's account after they confirmed it with their
+// password, or with their username if the account has no password (external login)
+func (s *AuthService) DeleteOwnAccount(userID int, req models.DeleteAccountRequest) (*models.AccountErasure, error) {
+	user, err := s.repo.GetByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.PasswordHash != "" {
+		if req.Password == "" || !s.VerifyPassword(req.Password, user.PasswordHash) {
+			return nil, ErrIncorrectPassword
+		}
+	} else if req.Confirm != user.Username {
+		return nil, ErrConfirmationRequired
+	}
+	return s.DeleteAccount(userID, userID)
+}
+
+// RestoreAccount undoes the deletion of an account whose erasure has not started yet.
+// Tokens revoked by the deletion stay revoked; the user has to log in again.
+func (s *AuthService) RestoreAccount(userID int) (*models.User, error) {
+	user, err := s.repo.GetDeletedByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	cancelled, err := s.erasures.Cancel(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !cancelled {
+		if erasure, err := s.erasures.Get(userID); err != nil {
+			return nil, err
+		} else if erasure != nil {
+			return nil, ErrErasureStarted
+		}
+	}
+	if err := s.repo.Restore(userID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(userID)
+}
+
+// AccountErasure returns the state of a user's erasure
+func (s *AuthService) AccountErasure(userID int) (*models.AccountErasureResponse, error) {
+	erasure, err := s.erasures.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if erasure == nil {
+		return nil, ErrErasureNotFound
+	}
+	resp := erasure.ToResponse()
+	return &resp, nil
+}
+
+// ProcessErasures starts the erasures whose grace period is over and asks services
+// that have not reported within erasureRetryInterval again. Starting an erasure
+// anonymizes the user in this service.
+func (s *AuthService) ProcessErasures(now time.Time) {
+	due, err := s.erasures.ListDue(now)
+	if err != nil {
+		log.Printf("failed to list due account erasures: %v", err)
+		return
+	}
+	for _, erasure := range due {
+		s.startErasure(erasure.UserID, erasure.RequestedBy, now)
+	}
+	stalled, err := s.erasures.ListStalled(now.Add(-erasureRetryInterval))
+	if err != nil {
+		log.Printf("failed to list stalled account erasures: %v", err)
+		return
+	}
+	for _, erasure := range stalled {
+		log.Printf("account erasure of user %d still pending, requesting again", erasure.UserID)
+		for _, step := range erasure.Steps {
+			if step.Service == ErasureStepAuth && step.CompletedAt == nil {
+				s.eraseUser(erasure.UserID)
+			}
+		}
+		if err := s.erasures.MarkRequested(erasure.UserID, now); err != nil {
+			log.Printf("failed to record erasure request for user %d: %v", erasure.UserID, err)
+		}
+		if s.onErasureRequested != nil {
+			s.onErasureRequested(erasure.UserID)
+		}
+	}
+}
+
+// startErasure claims a due erasure, anonymizes the user and asks the other services to follow
+func (s *AuthService) startErasure(userID, actorID int, now time.Time) {
+	if _, err := s.repo.GetByID(userID); err == nil {
+		// The account was restored or never deleted
+		if _, err := s.erasures.Cancel(userID); err != nil {
+			log.Printf("failed to drop erasure of active user %d: %v", userID, err)
+		}
+		return
+	}
+	user, err := s.repo.GetDeletedByID(userID)
+	if err != nil {
+		log.Printf("failed to load deleted user %d: %v", userID, err)
+		return
+	}
+	started, err := s.erasures.Start(userID, append([]string{ErasureStepAuth}, s.erasure.Services...), now)
+	if err != nil || !started {
+		if err != nil {
+			log.Printf("failed to start erasure of user %d: %v", userID, err)
+		}
+		return
+	}
+	if user != nil {
+		if err := s.guard.Unlock(user.Username); err != nil {
+			log.Printf("failed to clear login failures of erased user %d: %v", userID, err)
+		}
+	}
+	// Group memberships stay during the grace period, like the teams backing the groups
+	if err := s.RemoveUserFromSCIMGroups(userID); err != nil {
+		log.Printf("failed to remove erased user %d from SCIM groups: %v", userID, err)
+	}
+	if user != nil && s.onAccountErased != nil {
+		s.onAccountErased(userID, actorID)
+	}
+	s.eraseUser(userID)
+	if s.onErasureRequested != nil {
+		s.onErasureRequested(userID)
+	}
+}
+
+// eraseUser anonymizes the user and completes this service's erasure step
+func (s *AuthService) eraseUser(userID int) {
+	user, err := s.repo.GetDeletedByID(userID)
+	if err != nil {
+		log.Printf("failed to load user %d for erasure: %v", userID, err)
+		return
+	}
+	if err := s.repo.Anonymize(userID); err != nil {
+		log.Printf("failed to erase user %d: %v", userID, err)
+		return
+	}
+	if user != nil {
+		s.deleteAvatar(user.AvatarKey)
+	}
+	if err := s.CompleteErasureStep(userID, ErasureStepAuth); err != nil {
+		log.Printf("failed to record erasure of user %d: %v", userID, err)
+	}
+}
+
+// CompleteErasureStep records that a service erased the user's data
+func (s *AuthService) CompleteErasureStep(userID int, service string) error {
+	completed, err := s.erasures.CompleteStep(userID, service, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if completed {
+		log.Printf("account erasure of user %d completed", userID)
+	}
+	return nil
+}
@@ -1,33 +1,128 @@
 package service
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"fmt"
+	"log"
+	"net/url"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt" // Keep for backward compatibility
 
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/avatars"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/keys"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/lockout"
 	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/oidc"
 	"github.com/VerSysLabTin23/TodolistProject/auth/internal/repository"
 )
 
 // AuthService handles authentication logic
 type AuthService struct {
-	repo       repository.UserRepository
-	jwtSecret  []byte
-	accessTTL  time.Duration
-	refreshTTL time.Duration
+	repo          repository.UserRepository
+	tokens        repository.RefreshTokenRepository
+	oneTimeTokens repository.OneTimeTokenRepository
+	recoveryCodes repository.MFARecoveryCodeRepository
+	settings      repository.SettingsRepository
+	roles         repository.RoleRepository
+	hasher        PasswordHasher
+	keys          *keys.Manager
+	accessTTL     time.Duration
+	refreshTTL    time.Duration
+
+	serviceClients map[string]string // client ID -> SHA-256 of its secret
+	serviceTTL     time.Duration
+
+	verification  EmailVerificationConfig
+	passwordReset time.Duration
+	appBaseURL    string
+	mfa           MFAConfig
+
+	guard    *lockout.Guard
+	onLocked func(user *models.User, until time.Time, ip string)
+
+	onTokensRevoked  func(userID, version int, reason string)
+	onSessionRevoked func(userID int, sessionID string, until time.Time)
+
+	erasures           repository.AccountErasureRepository
+	erasure            AccountErasureConfig
+	onErasureRequested func(userID int)
+	onAccountErased    func(userID, actorID int)
+
+	identities    repository.IdentityRepository
+	oidcStates    repository.OIDCStateRepository
+	oidcProviders map[string]*oidc.Provider
+
+	oauthClients   repository.OAuthClientRepository
+	oauthConsents  repository.OAuthConsentRepository
+	oauthCodes     repository.OAuthCodeRepository
+	oauthAccessTTL time.Duration
+
+	personalTokens repository.PersonalAccessTokenRepository
+	sessions       repository.SessionRepository
+
+	impersonations   repository.ImpersonationRepository
+	impersonationTTL time.Duration
+	onImpersonation  func(impersonation *models.Impersonation)
+
+	exports       repository.DataExportRepository
+	exportSources ExportSources
+	exportTTL     time.Duration
+	onExportReady func(user *models.User, exportID string, link *EmailLink)
+
+	imports        repository.UserImportRepository
+	onUserImported func(user *models.User)
+
+	groups repository.SCIMGroupRepository
+	teams  TeamDirectory
+
+	avatars        avatars.Store
+	avatarMaxBytes int64
 }
 
-// TODO: Secret key should be stored in a secure way, use env variable or secret manager
-func NewAuthService(repo repository.UserRepository) *AuthService {
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "your-secret-key-change-in-production"
-	}
+// Repositories bundles the stores the service works with
+type Repositories struct {
+	Users          repository.UserRepository
+	RefreshTokens  repository.RefreshTokenRepository
+	OneTimeTokens  repository.OneTimeTokenRepository
+	RecoveryCodes  repository.MFARecoveryCodeRepository
+	Settings       repository.SettingsRepository
+	Roles          repository.RoleRepository
+	Identities     repository.IdentityRepository
+	OIDCStates     repository.OIDCStateRepository
+	OAuthClients   repository.OAuthClientRepository
+	OAuthConsents  repository.OAuthConsentRepository
+	OAuthCodes     repository.OAuthCodeRepository
+	PersonalTokens repository.PersonalAccessTokenRepository
+	Sessions       repository.SessionRepository
+	Erasures       repository.AccountErasureRepository
+	Exports        repository.DataExportRepository
+	Impersonations repository.ImpersonationRepository
+	Imports        repository.UserImportRepository
+	SCIMGroups     repository.SCIMGroupRepository
+}
+
+// NewAuthService wires the service; tokens are signed with the manager's active key
+func NewAuthService(repos Repositories, hasher PasswordHasher, keyManager *keys.Manager, mfa MFAConfig, guard *lockout.Guard, providers map[string]*oidc.Provider) *AuthService {
+	accessTTL, refreshTTL := TokenTTLsFromEnv()
+	return &AuthService{repo: repos.Users, tokens: repos.RefreshTokens, oneTimeTokens: repos.OneTimeTokens, recoveryCodes: repos.RecoveryCodes, settings: repos.Settings, roles: repos.Roles,
+		hasher: hasher, keys: keyManager, accessTTL: accessTTL, refreshTTL: refreshTTL,
+		serviceClients: ServiceClientsFromEnv(), serviceTTL: ServiceTokenTTLFromEnv(),
+		verification: EmailVerificationConfigFromEnv(), passwordReset: PasswordResetTTLFromEnv(), appBaseURL: AppBaseURLFromEnv(), mfa: mfa, guard: guard,
+		identities: repos.Identities, oidcStates: repos.OIDCStates, oidcProviders: providers,
+		oauthClients: repos.OAuthClients, oauthConsents: repos.OAuthConsents, oauthCodes: repos.OAuthCodes, oauthAccessTTL: OAuthAccessTTLFromEnv(),
+		personalTokens: repos.PersonalTokens, sessions: repos.Sessions, erasures: repos.Erasures, erasure: AccountErasureConfigFromEnv(),
+		exports: repos.Exports, exportTTL: DataExportTTLFromEnv(), impersonations: repos.Impersonations, impersonationTTL: ImpersonationTTLFromEnv(),
+		imports: repos.Imports, groups: repos.SCIMGroups}
+}
+
+// TokenTTLsFromEnv returns the access and refresh token lifetimes (JWT_ACCESS_TTL, JWT_REFRESH_TTL)
+func TokenTTLsFromEnv() (time.Duration, time.Duration) {
 	accessTTL := 15 * time.Hour
 	refreshTTL := 7 * 24 * time.Hour
 	if s := os.Getenv("JWT_ACCESS_TTL"); s != "" {
@@ -40,14 +135,7 @@ func NewAuthService(repo repository.UserRepository) *AuthService {
 			refreshTTL = ttl
 		}
 	}
-	return &AuthService{repo: repo, jwtSecret: []byte(jwtSecret), accessTTL: accessTTL, refreshTTL: refreshTTL}
-}
-
-// hashPassword hashes a password with SHA256
-func (s *AuthService) hashPassword(password string) string {
-	hash := sha256.New()
-	hash.Write([]byte(password))
-	return hex.EncodeToString(hash.Sum(nil))
+	return accessTTL, refreshTTL
 }
 
 func (s *AuthService) RegisterUser(req models.RegisterRequest) (*models.User, error) {
@@ -56,149 +144,441 @@ func (s *AuthService) RegisterUser(req models.RegisterRequest) (*models.User, er
 		return nil, err
 	}
 	if exists {
-		return nil, errors.New("username already exists")
+		return nil, ErrUsernameTaken
 	}
 	exists, err = s.repo.ExistsByEmail(req.Email)
 	if err != nil {
 		return nil, err
 	}
 	if exists {
-		return nil, errors.New("email already exists")
+		return nil, ErrEmailTaken
 	}
 
-	// Hash password with SHA256
-	hashedPassword := s.hashPassword(req.Password)
+	hashedPassword, err := s.hasher.Hash(req.Password)
+	if err != nil {
+		return nil, err
+	}
 
-	user := &models.User{Username: req.Username, Email: req.Email, PasswordHash: hashedPassword, FirstName: req.FirstName, LastName: req.LastName, Role: "user", IsActive: true}
+	// Self-registered accounts start unverified; see IssueEmailVerification
+	user := &models.User{Username: req.Username, Email: req.Email, PasswordHash: hashedPassword, FirstName: req.FirstName, LastName: req.LastName, Role: models.RoleUser, IsActive: true, EmailVerified: false}
 	if err := s.repo.Create(user); err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func (s *AuthService) AuthenticateUser(req models.LoginRequest) (*models.LoginResponse, error) {
+// AuthenticateUser checks the password. Users with 2FA, or whose role requires it,
+// get an MFA challenge instead of tokens and finish the login at CompleteMFALogin.
+// Failed attempts are counted per username and client IP; while backing off or
+// locked it returns a *lockout.Error without checking the password.
+func (s *AuthService) AuthenticateUser(req models.LoginRequest, client models.ClientInfo) (*models.LoginResponse, *models.MFAChallengeResponse, error) {
+	if err := s.checkLoginAllowed(req.Username, client); err != nil {
+		return nil, nil, err
+	}
 	user, err := s.repo.GetByUsername(req.Username)
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		s.loginFailed(req.Username, nil, client)
+		return nil, nil, ErrInvalidCredentials
 	}
 	if !user.IsActive {
-		return nil, errors.New("user account is deactivated")
+		return nil, nil, ErrAccountDeactivated
 	}
 	if !s.VerifyPassword(req.Password, user.PasswordHash) {
-		return nil, errors.New("invalid credentials")
+		s.loginFailed(req.Username, user, client)
+		return nil, nil, ErrInvalidCredentials
+	}
+	if !user.EmailVerified && s.verification.Policy == EmailVerificationRequired {
+		return nil, nil, ErrEmailNotVerified
+	}
+	s.rehashIfNeeded(user, req.Password)
+	return s.finishLogin(user, client)
+}
+
+// finishLogin runs after the first factor succeeded (password or external provider).
+// Users with 2FA, or whose role requires it, get an MFA challenge instead of tokens.
+func (s *AuthService) finishLogin(user *models.User, client models.ClientInfo) (*models.LoginResponse, *models.MFAChallengeResponse, error) {
+	if user.MFAEnabled {
+		challenge, err := s.issueMFAChallenge(user, models.MFAStageVerify)
+		return nil, challenge, err
+	}
+	required, err := s.mfaRequiredFor(user.Role)
+	if err != nil {
+		return nil, nil, err
+	}
+	if required {
+		challenge, err := s.issueMFAChallenge(user, models.MFAStageEnroll)
+		return nil, challenge, err
+	}
+	resp, err := s.startSession(user, client)
+	return resp, nil, err
+}
+
+// startSession opens a new refresh token family for a fully authenticated user,
+// records it as a session of the device and clears the failed-login counter of the username
+func (s *AuthService) startSession(user *models.User, client models.ClientInfo) (*models.LoginResponse, error) {
+	if err := s.guard.Success(user.Username); err != nil {
+		log.Printf("failed to reset login failures for user %d: %v", user.ID, err)
 	}
-	accessToken, err := s.generateAccessToken(user)
+	familyID, err := newFamilyID()
 	if err != nil {
 		return nil, err
 	}
-	refreshToken, err := s.generateRefreshToken(user)
+	if err := s.createSession(user.ID, familyID, client); err != nil {
+		return nil, err
+	}
+	return s.issueTokens(user, familyID, client)
+}
+
+// RefreshToken rotates a refresh token: the presented token is marked as used and a
+// new one is issued in the same family. Presenting a token that was already rotated
+// is treated as theft and revokes the whole family.
+func (s *AuthService) RefreshToken(refreshToken string, client models.ClientInfo) (*models.LoginResponse, error) {
+	stored, err := s.lookupRefreshToken(refreshToken)
 	if err != nil {
 		return nil, err
 	}
-	return &models.LoginResponse{AccessToken: accessToken, RefreshToken: refreshToken, User: user.ToUserResponse()}, nil
+	// Tokens issued to OAuth clients are only redeemed at /oauth/token, where they keep their scope
+	if stored.ClientID != "" {
+		return nil, ErrInvalidRefreshToken
+	}
+	user, err := s.rotateRefreshToken(stored)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.issueTokens(user, stored.FamilyID, client)
+	if err != nil {
+		return nil, err
+	}
+	s.touchSession(stored.FamilyID, client)
+	return resp, nil
 }
 
-func (s *AuthService) RefreshToken(refreshToken string) (*models.LoginResponse, error) {
-	claims, err := s.parseToken(refreshToken)
+// rotateRefreshToken marks a stored refresh token as used and returns its user.
+// Reuse of a rotated token revokes the whole family.
+func (s *AuthService) rotateRefreshToken(stored *models.RefreshToken) (*models.User, error) {
+	if stored.RotatedAt != nil {
+		s.revokeFamily(stored.FamilyID)
+		return nil, ErrRefreshTokenReused
+	}
+	if stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+	rotated, err := s.tokens.MarkRotated(stored.ID)
 	if err != nil {
-		return nil, errors.New("invalid refresh token")
+		return nil, err
 	}
-	user, err := s.repo.GetByID(claims.UserID)
+	if !rotated {
+		// Lost a race against another refresh with the same token
+		s.revokeFamily(stored.FamilyID)
+		return nil, ErrRefreshTokenReused
+	}
+	user, err := s.repo.GetByID(stored.UserID)
 	if err != nil {
-		return nil, errors.New("user not found")
+		return nil, ErrInvalidRefreshToken
 	}
 	if !user.IsActive {
-		return nil, errors.New("user account is deactivated")
+		s.revokeFamily(stored.FamilyID)
+		return nil, ErrAccountDeactivated
 	}
-	accessToken, err := s.generateAccessToken(user)
+	return user, nil
+}
+
+// Logout revokes the session the refresh token belongs to, or every session of
+// its user when allSessions is set. OAuth clients may only end their own grant.
+func (s *AuthService) Logout(refreshToken string, allSessions bool) error {
+	stored, err := s.lookupRefreshToken(refreshToken)
+	if err != nil {
+		return err
+	}
+	if allSessions && stored.ClientID == "" {
+		return s.RevokeAllSessions(stored.UserID, models.RevokeReasonLogoutAll)
+	}
+	return s.revokeSession(stored.FamilyID)
+}
+
+// lookupRefreshToken verifies the token signature and loads its stored row
+func (s *AuthService) lookupRefreshToken(refreshToken string) (*models.RefreshToken, error) {
+	if _, err := s.parseToken(refreshToken, models.TokenTypeRefresh); err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	stored, err := s.tokens.GetByHash(hashToken(refreshToken))
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	return stored, nil
+}
+
+// revokeFamily revokes a refresh token family and ends its session, logging failures
+func (s *AuthService) revokeFamily(familyID string) {
+	if err := s.revokeSession(familyID); err != nil {
+		log.Printf("failed to revoke refresh token family %s: %v", familyID, err)
+	}
+}
+
+// issueTokens creates an access token and a persisted refresh token in the given family
+func (s *AuthService) issueTokens(user *models.User, familyID string, client models.ClientInfo) (*models.LoginResponse, error) {
+	accessToken, err := s.generateAccessToken(user, familyID)
 	if err != nil {
 		return nil, err
 	}
-	newRefreshToken, err := s.generateRefreshToken(user)
+	refreshToken, err := s.storeRefreshToken(&models.RefreshToken{UserID: user.ID, FamilyID: familyID}, user, client)
 	if err != nil {
 		return nil, err
 	}
-	return &models.LoginResponse{AccessToken: accessToken, RefreshToken: newRefreshToken, User: user.ToUserResponse()}, nil
+	return &models.LoginResponse{AccessToken: accessToken, RefreshToken: refreshToken, User: user.ToUserResponse()}, nil
+}
+
+// storeRefreshToken signs a refresh token for user and persists its hash in row
+func (s *AuthService) storeRefreshToken(row *models.RefreshToken, user *models.User, client models.ClientInfo) (string, error) {
+	refreshToken, expiresAt, err := s.generateRefreshToken(user)
+	if err != nil {
+		return "", err
+	}
+	row.TokenHash = hashToken(refreshToken)
+	row.UserAgent = truncate(client.UserAgent, 255)
+	row.IPAddress = truncate(client.IPAddress, 45)
+	row.ExpiresAt = expiresAt.UTC()
+	if err := s.tokens.Create(row); err != nil {
+		return "", err
+	}
+	return refreshToken, nil
+}
+
+// issueOneTimeToken creates a single-use token for an email link, superseding any
+// outstanding token of the same purpose. Only the token's hash is stored.
+func (s *AuthService) issueOneTimeToken(userID int, purpose string, ttl time.Duration) (string, time.Time, error) {
+	if err := s.oneTimeTokens.InvalidateForUser(userID, purpose); err != nil {
+		return "", time.Time{}, err
+	}
+	token, err := randomHex(32)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := time.Now().Add(ttl)
+	row := &models.OneTimeToken{UserID: userID, Purpose: purpose, TokenHash: hashToken(token), ExpiresAt: expiresAt.UTC()}
+	if err := s.oneTimeTokens.Create(row); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// AppBaseURLFromEnv returns the frontend base URL used in emailed links (APP_BASE_URL)
+func AppBaseURLFromEnv() string {
+	if s := os.Getenv("APP_BASE_URL"); s != "" {
+		return strings.TrimRight(s, "/")
+	}
+	return "http://localhost"
+}
+
+// appLink builds a frontend link carrying a one-time token
+func (s *AuthService) appLink(path, token string) string {
+	return fmt.Sprintf("%s%s?token=%s", s.appBaseURL, path, url.QueryEscape(token))
 }
 
-// ValidateToken verifies a JWT (access or refresh) and returns its claims if valid.
-// It ensures:
-// - the token is well-formed and signed using an HMAC algorithm (e.g., HS256)
-// - the signature matches the configured JWT secret
+// ValidateToken verifies an access JWT and returns its claims if valid. Personal
+// access tokens (models.PersonalAccessTokenPrefix) are looked up in the database instead.
+// For JWTs it ensures:
+// - the token is well-formed and carries the kid of a published signing key
+// - the signature matches that key and its algorithm (RS256 or EdDSA)
 // - standard registered claims (exp, iat, etc.) are valid
+// - the token is an access token (typ "access", aud "todolist-api"); refresh tokens are rejected
 // Returns an error if any validation step fails.
 func (s *AuthService) ValidateToken(tokenString string) (*models.Claims, error) {
-	return s.parseToken(tokenString)
+	if strings.HasPrefix(tokenString, models.PersonalAccessTokenPrefix) {
+		return s.validatePersonalAccessToken(tokenString)
+	}
+	return s.parseToken(tokenString, models.TokenTypeAccess)
 }
 
-func (s *AuthService) ChangePassword(userID int, req models.ChangePasswordRequest) error {
+// ChangePassword replaces the user's password and revokes all of their tokens and
+// sessions. The caller is signed in again with a new session for client.
+func (s *AuthService) ChangePassword(userID int, req models.ChangePasswordRequest, client models.ClientInfo) (*models.LoginResponse, error) {
 	user, err := s.repo.GetByID(userID)
 	if err != nil {
-		return errors.New("user not found")
+		return nil, ErrUserNotFound
 	}
 	if !s.VerifyPassword(req.CurrentPassword, user.PasswordHash) {
-		return errors.New("current password is incorrect")
+		return nil, ErrIncorrectPassword
 	}
 
-	// Hash new password with SHA256
-	hashedPassword := s.hashPassword(req.NewPassword)
-	user.PasswordHash = hashedPassword
-
-	return s.repo.Update(user)
+	hashedPassword, err := s.hasher.Hash(req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdatePasswordHash(user.ID, hashedPassword); err != nil {
+		return nil, err
+	}
+	if err := s.RevokeAllSessions(user.ID, models.RevokeReasonPasswordChanged); err != nil {
+		return nil, err
+	}
+	// Reload so the new session carries the bumped token version
+	if user, err = s.repo.GetByID(userID); err != nil {
+		return nil, err
+	}
+	return s.startSession(user, client)
 }
 
-func (s *AuthService) generateAccessToken(user *models.User) (string, error) {
-	claims := &models.Claims{UserID: user.ID, Username: user.Username, Role: user.Role, RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.accessTTL)), IssuedAt: jwt.NewNumericDate(time.Now()), Issuer: "auth-service"}}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.jwtSecret)
+func (s *AuthService) generateAccessToken(user *models.User, sessionID string) (string, error) {
+	perms, err := s.permissionsFor(user.Role)
+	if err != nil {
+		return "", err
+	}
+	claims := &models.Claims{UserID: user.ID, Username: user.Username, Role: user.Role, Permissions: perms, TokenType: models.TokenTypeAccess, Restricted: s.isRestricted(user), SessionID: sessionID, TokenVersion: user.TokenVersion, Timezone: user.Timezone, RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.accessTTL)), IssuedAt: jwt.NewNumericDate(time.Now()), Issuer: "auth-service", Audience: jwt.ClaimStrings{models.AudienceAPI}}}
+	return s.keys.Sign(claims)
 }
 
-func (s *AuthService) generateRefreshToken(user *models.User) (string, error) {
-	claims := &models.Claims{UserID: user.ID, Username: user.Username, Role: user.Role, RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.refreshTTL)), IssuedAt: jwt.NewNumericDate(time.Now()), Issuer: "auth-service"}}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.jwtSecret)
+func (s *AuthService) generateRefreshToken(user *models.User) (string, time.Time, error) {
+	jti, err := randomHex(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	now := time.Now()
+	expiresAt := now.Add(s.refreshTTL)
+	claims := &models.Claims{UserID: user.ID, Username: user.Username, Role: user.Role, TokenType: models.TokenTypeRefresh, TokenVersion: user.TokenVersion, RegisteredClaims: jwt.RegisteredClaims{ID: jti, ExpiresAt: jwt.NewNumericDate(expiresAt), IssuedAt: jwt.NewNumericDate(now), Issuer: "auth-service", Audience: jwt.ClaimStrings{models.AudienceAuth}}}
+	signed, err := s.keys.Sign(claims)
+	return signed, expiresAt, err
 }
 
-// parseToken parses and validates a JWT string.
+// parseToken parses and validates a JWT string of the expected token type.
 // Flow:
-//  1. jwt.ParseWithClaims decodes the token and verifies the signature via the key func below
-//  2. Only HMAC signing methods are accepted; other algorithms are rejected
-//  3. The key func returns the server's JWT secret used to verify the signature
-//  4. On success, the library checks standard registered claims and sets token.Valid
-//  5. We assert the claims to our typed struct and return them if token.Valid is true
-func (s *AuthService) parseToken(tokenString string) (*models.Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &models.Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return s.jwtSecret, nil
-	})
+//  1. jwt.ParseWithClaims decodes the token and verifies the signature via keys.Manager.Keyfunc
+//  2. The key func picks the public key by the "kid" header and rejects a mismatching alg
+//  3. Only RS256/EdDSA are accepted, so HMAC tokens signed with a public key cannot pass
+//  4. On success, the library checks standard registered claims (including the audience
+//     matching the token type) and sets token.Valid
+//  5. We assert the claims to our typed struct, check the "typ" claim and return them
+//  6. Access and refresh tokens must also carry the user's current token version
+//  7. Access tokens of a login session are only valid while the session exists
+func (s *AuthService) parseToken(tokenString, tokenType string) (*models.Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &models.Claims{}, s.keys.Keyfunc,
+		jwt.WithValidMethods([]string{"RS256", "EdDSA"}), jwt.WithAudience(audienceFor(tokenType)), jwt.WithIssuer("auth-service"))
 	// ParseWithClaims:
 	// 1. parse tokenstring to header, payload, signature and store header and payload in models.Claims
-	// 2. func return the secretKey
-	// 3. secretKey + header.payload -> expected signature
-	// 4. signature == expected signature? token.valid=true :token.valid=false
+	// 2. Keyfunc returns the public key for the kid in the header
+	// 3. public key verifies the signature over header.payload
+	// 4. signature valid and registered claims ok? token.valid=true :token.valid=false
 	if err != nil {
 		return nil, err
 	}
 	if claims, ok := token.Claims.(*models.Claims); ok && token.Valid {
+		if claims.TokenType != tokenType {
+			return nil, errors.New("unexpected token type")
+		}
+		if tokenType == models.TokenTypeAccess || tokenType == models.TokenTypeRefresh {
+			if err := s.checkTokenVersion(claims); err != nil {
+				return nil, err
+			}
+			if err := s.checkImpersonator(claims); err != nil {
+				return nil, err
+			}
+		}
+		if tokenType == models.TokenTypeAccess && claims.SessionID != "" {
+			if err := s.checkSession(claims); err != nil {
+				return nil, err
+			}
+		}
 		return claims, nil
 	}
 	return nil, errors.New("invalid token")
 }
 
+// checkTokenVersion rejects tokens issued before the user's tokens were last revoked
+// (see RevokeAllSessions), and tokens of users that no longer exist
+func (s *AuthService) checkTokenVersion(claims *models.Claims) error {
+	user, err := s.repo.GetByID(claims.UserID)
+	if err != nil {
+		return errors.New("invalid token")
+	}
+	if claims.TokenVersion < user.TokenVersion {
+		return errors.New("token revoked")
+	}
+	return nil
+}
+
+// checkSession rejects access tokens of a session that was ended (see RevokeSession)
+// or expired, so signing out on one device also invalidates its access token
+func (s *AuthService) checkSession(claims *models.Claims) error {
+	session, err := s.sessions.Get(claims.SessionID)
+	if err != nil || session == nil || session.UserID != claims.UserID {
+		return errors.New("session revoked")
+	}
+	return nil
+}
+
+// audienceFor returns the audience a token of the given type must be issued for
+func audienceFor(tokenType string) string {
+	switch tokenType {
+	case models.TokenTypeRefresh, models.TokenTypeMFA:
+		return models.AudienceAuth
+	case models.TokenTypeService:
+		return models.AudienceInternal
+	default:
+		return models.AudienceAPI
+	}
+}
+
 func (s *AuthService) HashPassword(password string) (string, error) {
-	return s.hashPassword(password), nil
+	return s.hasher.Hash(password)
 }
 
 func (s *AuthService) VerifyPassword(password, hash string) bool {
-	// Check if this is a SHA256 hash (hex string of length 64)
-	if len(hash) == 64 {
-		// New SHA256 hash
-		computedHash := s.hashPassword(password)
-		return computedHash == hash
-	} else {
-		// Legacy bcrypt hash - try to verify with bcrypt
-		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	ok, err := s.hasher.Verify(password, hash)
+	return err == nil && ok
+}
+
+// rehashIfNeeded transparently upgrades a stored hash (legacy SHA-256, bcrypt, or
+// argon2id with weaker parameters) after the plaintext password has been verified.
+// Failures are logged and never block the login.
+func (s *AuthService) rehashIfNeeded(user *models.User, password string) {
+	if !s.hasher.NeedsRehash(user.PasswordHash) {
+		return
 	}
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		log.Printf("password rehash for user %d failed: %v", user.ID, err)
+		return
+	}
+	if err := s.repo.UpdatePasswordHash(user.ID, hash); err != nil {
+		log.Printf("storing upgraded password hash for user %d failed: %v", user.ID, err)
+		return
+	}
+	user.PasswordHash = hash
+}
+
+// hashToken returns the hex SHA-256 of a token; refresh tokens are high-entropy so a
+// fast hash is sufficient and lets us look them up by value.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// newFamilyID returns a random RFC 4122 version 4 UUID
+func newFamilyID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// JWKS returns the public keys other services use to verify tokens offline
+func (s *AuthService) JWKS() keys.JWKS {
+	return s.keys.JWKS()
 }
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"log"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/avatars"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// SetAvatarStore sets where avatar thumbnails are kept and the largest accepted
+// upload; without a store uploads fail
+func (s *AuthService) SetAvatarStore(store avatars.Store, maxBytes int64) {
+	s.avatars = store
+	s.avatarMaxBytes = maxBytes
+}
+
+// AvatarMaxBytes returns the largest accepted avatar upload
+func (s *AuthService) AvatarMaxBytes() int64 {
+	return s.avatarMaxBytes
+}
+
+// UploadAvatar replaces the user's avatar with thumbnails of the uploaded image.
+// Every upload gets a new key, so clients can cache avatar URLs indefinitely.
+func (s *AuthService) UploadAvatar(userID int, data []byte) (*models.User, error) {
+	if s.avatars == nil {
+		return nil, ErrAvatarsDisabled
+	}
+	user, err := s.repo.GetByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	thumbnails, err := avatars.Thumbnails(data)
+	if err != nil {
+		return nil, err
+	}
+	key, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.avatars.Save(key, thumbnails); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetAvatarKey(userID, key); err != nil {
+		s.deleteAvatar(key)
+		return nil, err
+	}
+	s.deleteAvatar(user.AvatarKey)
+	user.AvatarKey = key
+	return user, nil
+}
+
+// RemoveAvatar deletes the user's avatar
+func (s *AuthService) RemoveAvatar(userID int) (*models.User, error) {
+	user, err := s.repo.GetByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.AvatarKey == "" {
+		return user, nil
+	}
+	if err := s.repo.SetAvatarKey(userID, ""); err != nil {
+		return nil, err
+	}
+	s.deleteAvatar(user.AvatarKey)
+	user.AvatarKey = ""
+	return user, nil
+}
+
+// AvatarThumbnail returns the PNG thumbnail of an avatar in one of avatars.Sizes
+func (s *AuthService) AvatarThumbnail(key string, size int) ([]byte, error) {
+	if s.avatars == nil || !avatars.ValidSize(size) {
+		return nil, ErrAvatarNotFound
+	}
+	data, err := s.avatars.Open(key, size)
+	if err != nil {
+		return nil, ErrAvatarNotFound
+	}
+	return data, nil
+}
+
+// deleteAvatar removes replaced thumbnails; failures only leave orphaned files behind
+func (s *AuthService) deleteAvatar(key string) {
+	if key == "" || s.avatars == nil {
+		return
+	}
+	if err := s.avatars.Delete(key); err != nil {
+		log.Printf("failed to delete avatar %s: %v", key, err)
+	}
+}
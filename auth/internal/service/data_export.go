@@ -0,0 +1,241 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// selfClientID is the client ID of service tokens the auth service issues to itself
+const selfClientID = "auth"
+
+// dataExportStaleAfter is how long a running export may take before it is considered
+// abandoned (e.g. the instance stopped) and started again
+const dataExportStaleAfter = 10 * time.Minute
+
+// ExportSources provides the data other services hold about a user; see clients.ServiceClient
+type ExportSources interface {
+	UserMemberships(userID int, serviceToken string) (json.RawMessage, error)
+	UserTasks(userID int, serviceToken string) (json.RawMessage, error)
+}
+
+// ExportFile is a downloadable data export
+type ExportFile struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// DataExportTTLFromEnv returns how long a finished export can be downloaded
+// (DATA_EXPORT_TTL, default 7 days)
+func DataExportTTLFromEnv() time.Duration {
+	if s := os.Getenv("DATA_EXPORT_TTL"); s != "" {
+		if ttl, err := time.ParseDuration(s); err == nil && ttl > 0 {
+			return ttl
+		}
+		log.Printf("invalid DATA_EXPORT_TTL %q, using 168h", s)
+	}
+	return 7 * 24 * time.Hour
+}
+
+// SetExportSources sets where team memberships and tasks are read from for data exports
+func (s *AuthService) SetExportSources(sources ExportSources) {
+	s.exportSources = sources
+}
+
+// OnDataExportReady registers a callback that runs when an export can be downloaded,
+// e.g. to publish a user.export_ready event
+func (s *AuthService) OnDataExportReady(fn func(user *models.User, exportID string, link *EmailLink)) {
+	s.onExportReady = fn
+}
+
+// RequestDataExport starts an asynchronous export of everything the system holds about
+// the user. If one is already pending or running, that one is returned and created is false.
+func (s *AuthService) RequestDataExport(userID int) (export *models.DataExport, created bool, err error) {
+	active, err := s.exports.GetActiveForUser(userID)
+	if err != nil {
+		return nil, false, err
+	}
+	if active != nil {
+		return active, false, nil
+	}
+	id, err := newFamilyID()
+	if err != nil {
+		return nil, false, err
+	}
+	export = &models.DataExport{ID: id, UserID: userID, Status: models.DataExportPending}
+	if err := s.exports.Create(export); err != nil {
+		return nil, false, err
+	}
+	go s.runDataExport(id, userID)
+	return export, true, nil
+}
+
+// DataExport returns one of the user's exports; expired exports are not found
+func (s *AuthService) DataExport(userID int, id string) (*models.DataExport, error) {
+	export, err := s.exports.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if export == nil || export.UserID != userID || (export.ExpiresAt != nil && time.Now().After(*export.ExpiresAt)) {
+		return nil, ErrExportNotFound
+	}
+	return export, nil
+}
+
+// DownloadDataExport returns a finished export as a single JSON document ("json") or as
+// a ZIP archive with one JSON file per section ("zip")
+func (s *AuthService) DownloadDataExport(userID int, id, format string) (*ExportFile, error) {
+	export, err := s.DataExport(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if export.Status != models.DataExportReady {
+		return nil, ErrExportNotReady
+	}
+	name := "data-export-" + export.CreatedAt.UTC().Format("20060102") + "-" + export.ID[:8]
+	switch format {
+	case "json":
+		return &ExportFile{Name: name + ".json", ContentType: "application/json", Data: export.Archive}, nil
+	case "zip", "":
+		data, err := zipArchive(export.Archive)
+		if err != nil {
+			return nil, err
+		}
+		return &ExportFile{Name: name + ".zip", ContentType: "application/zip", Data: data}, nil
+	default:
+		return nil, ErrInvalidExportFormat
+	}
+}
+
+// ProcessDataExports runs exports that are pending or were abandoned by a stopped instance
+func (s *AuthService) ProcessDataExports(now time.Time) {
+	exports, err := s.exports.ListRunnable(now.Add(-dataExportStaleAfter))
+	if err != nil {
+		log.Printf("failed to list data exports: %v", err)
+		return
+	}
+	for _, export := range exports {
+		s.runDataExport(export.ID, export.UserID)
+	}
+}
+
+// runDataExport claims an export, collects the user's data and stores the archive
+func (s *AuthService) runDataExport(id string, userID int) {
+	now := time.Now().UTC()
+	claimed, err := s.exports.Claim(id, now, now.Add(-dataExportStaleAfter))
+	if err != nil || !claimed {
+		if err != nil {
+			log.Printf("failed to claim data export %s: %v", id, err)
+		}
+		return
+	}
+	user, err := s.repo.GetByID(userID)
+	if err != nil {
+		s.failDataExport(id, "User not found", err)
+		return
+	}
+	archive, reason, err := s.collectUserData(user)
+	if err != nil {
+		s.failDataExport(id, reason, err)
+		return
+	}
+	data, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		s.failDataExport(id, "Could not create the archive", err)
+		return
+	}
+	completedAt := time.Now().UTC()
+	expiresAt := completedAt.Add(s.exportTTL)
+	if err := s.exports.Complete(id, data, completedAt, expiresAt); err != nil {
+		log.Printf("failed to store data export %s: %v", id, err)
+		return
+	}
+	log.Printf("data export %s of user %d ready (%d bytes)", id, userID, len(data))
+	if s.onExportReady != nil {
+		s.onExportReady(user, id, &EmailLink{Link: s.appBaseURL + "/account/exports/" + id, ExpiresAt: expiresAt})
+	}
+}
+
+// collectUserData gathers the user's data from this and the other services. On error
+// it also returns a reason that can be shown to the user.
+func (s *AuthService) collectUserData(user *models.User) (*models.DataExportArchive, string, error) {
+	archive := &models.DataExportArchive{ExportedAt: time.Now().UTC(), Profile: user.ToUserResponse()}
+	var err error
+	if archive.Identities, err = s.identities.ListForUser(user.ID); err != nil {
+		return nil, "Could not read linked accounts", err
+	}
+	if archive.Sessions, err = s.ListSessions(user.ID, ""); err != nil {
+		return nil, "Could not read sessions", err
+	}
+	if archive.PersonalAccessTokens, err = s.ListPersonalAccessTokens(user.ID); err != nil {
+		return nil, "Could not read personal access tokens", err
+	}
+	if archive.Authorizations, err = s.ListAuthorizations(user.ID); err != nil {
+		return nil, "Could not read authorized applications", err
+	}
+	if archive.OAuthClients, err = s.ListOAuthClients(user.ID); err != nil {
+		return nil, "Could not read registered applications", err
+	}
+	if s.exportSources == nil {
+		return nil, "Team and task data are not available", errors.New("no export sources configured")
+	}
+	token, err := s.signServiceToken(selfClientID)
+	if err != nil {
+		return nil, "Could not authenticate with the other services", err
+	}
+	if archive.Teams, err = s.exportSources.UserMemberships(user.ID, token.AccessToken); err != nil {
+		return nil, "Could not read team memberships", err
+	}
+	if archive.Tasks, err = s.exportSources.UserTasks(user.ID, token.AccessToken); err != nil {
+		return nil, "Could not read tasks", err
+	}
+	return archive, "", nil
+}
+
+// failDataExport records a failed export; reason is shown to the user, err is only logged
+func (s *AuthService) failDataExport(id, reason string, err error) {
+	log.Printf("data export %s failed: %s: %v", id, reason, err)
+	if err := s.exports.Fail(id, reason, time.Now().UTC()); err != nil {
+		log.Printf("failed to record failure of data export %s: %v", id, err)
+	}
+}
+
+// zipArchive splits an export's JSON document into one file per section
+func zipArchive(archive []byte) ([]byte, error) {
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(archive, &sections); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(sections))
+	for name := range sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, sections[name], "", "  "); err != nil {
+			return nil, err
+		}
+		f, err := zw.Create(name + ".json")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Write(indented.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/repository"
+)
+
+// Email verification policies (EMAIL_VERIFICATION_POLICY) decide what unverified users may do
+const (
+	// EmailVerificationOff gives unverified users full access
+	EmailVerificationOff = "off"
+	// EmailVerificationRestricted lets unverified users log in with read-only access tokens
+	EmailVerificationRestricted = "restricted"
+	// EmailVerificationRequired refuses to log in unverified users
+	EmailVerificationRequired = "required"
+)
+
+// verificationResendLimit caps verification emails per user and hour
+const verificationResendLimit = 3
+
+// EmailLink is a link carrying a one-time token that has to be emailed to the user
+type EmailLink struct {
+	Link      string
+	ExpiresAt time.Time
+}
+
+// EmailVerificationConfig is read from EMAIL_VERIFICATION_POLICY and EMAIL_VERIFICATION_TTL
+type EmailVerificationConfig struct {
+	Policy string
+	TTL    time.Duration
+}
+
+// EmailVerificationConfigFromEnv returns the verification settings with their defaults
+func EmailVerificationConfigFromEnv() EmailVerificationConfig {
+	cfg := EmailVerificationConfig{Policy: EmailVerificationRestricted, TTL: 24 * time.Hour}
+	switch p := os.Getenv("EMAIL_VERIFICATION_POLICY"); p {
+	case EmailVerificationOff, EmailVerificationRestricted, EmailVerificationRequired:
+		cfg.Policy = p
+	case "":
+	default:
+		log.Printf("unknown EMAIL_VERIFICATION_POLICY %q, using %q", p, cfg.Policy)
+	}
+	if s := os.Getenv("EMAIL_VERIFICATION_TTL"); s != "" {
+		if ttl, err := time.ParseDuration(s); err == nil && ttl > 0 {
+			cfg.TTL = ttl
+		}
+	}
+	return cfg
+}
+
+// IssueEmailVerification creates a new verification token for the user, invalidating
+// any earlier ones, and returns the link to send. Only the token's hash is stored.
+func (s *AuthService) IssueEmailVerification(user *models.User) (*EmailLink, error) {
+	token, expiresAt, err := s.issueOneTimeToken(user.ID, models.TokenPurposeEmailVerification, s.verification.TTL)
+	if err != nil {
+		return nil, err
+	}
+	return &EmailLink{Link: s.appLink("/verify-email", token), ExpiresAt: expiresAt}, nil
+}
+
+// VerifyEmail redeems a verification token and marks the owner's email as verified
+func (s *AuthService) VerifyEmail(token string) (*models.User, error) {
+	stored, err := s.oneTimeTokens.Consume(hashToken(token), models.TokenPurposeEmailVerification)
+	if err != nil {
+		if errors.Is(err, repository.ErrTokenNotUsable) {
+			return nil, ErrInvalidVerificationToken
+		}
+		return nil, err
+	}
+	if err := s.repo.MarkEmailVerified(stored.UserID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(stored.UserID)
+}
+
+// ResendVerification issues a new verification link for the account with the given
+// email. It returns a nil user when there is nothing to send (unknown address, already
+// verified, or too many recent requests) so callers can answer identically in all cases.
+func (s *AuthService) ResendVerification(email string) (*models.User, *EmailLink, error) {
+	user, err := s.repo.GetByEmail(email)
+	if err != nil || user.EmailVerified || !user.IsActive {
+		return nil, nil, nil
+	}
+	recent, err := s.oneTimeTokens.CountSince(user.ID, models.TokenPurposeEmailVerification, time.Now().Add(-time.Hour).UTC())
+	if err != nil {
+		return nil, nil, err
+	}
+	if recent >= verificationResendLimit {
+		log.Printf("verification resend limit reached for user %d", user.ID)
+		return nil, nil, nil
+	}
+	v, err := s.IssueEmailVerification(user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, v, nil
+}
+
+// isRestricted reports whether tokens issued to the user must be marked restricted
+func (s *AuthService) isRestricted(user *models.User) bool {
+	return !user.EmailVerified && s.verification.Policy == EmailVerificationRestricted
+}
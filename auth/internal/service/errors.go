@@ -0,0 +1,93 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/apperr"
+)
+
+// Errors returned by AuthService. Handlers compare them with errors.Is and write them
+// with apperr.Write; their codes are part of the API and must not change.
+var (
+	// Users and sign-in
+	ErrUserNotFound             = apperr.New(apperr.NotFound, "USER_NOT_FOUND", "User not found")
+	ErrUsernameTaken            = apperr.New(apperr.Conflict, "USERNAME_TAKEN", "Username already exists")
+	ErrEmailTaken               = apperr.New(apperr.Conflict, "EMAIL_TAKEN", "Email already exists")
+	ErrInvalidCredentials       = apperr.New(apperr.Unauthorized, "INVALID_CREDENTIALS", "Invalid credentials")
+	ErrAccountDeactivated       = apperr.New(apperr.Unauthorized, "ACCOUNT_DEACTIVATED", "User account is deactivated")
+	ErrEmailNotVerified         = apperr.New(apperr.Forbidden, "EMAIL_NOT_VERIFIED", "Verify your email address before logging in")
+	ErrIncorrectPassword        = apperr.New(apperr.Invalid, "INCORRECT_PASSWORD", "Current password is incorrect")
+	ErrInvalidRefreshToken      = apperr.New(apperr.Unauthorized, "INVALID_REFRESH_TOKEN", "Invalid refresh token")
+	ErrRefreshTokenReused       = apperr.New(apperr.Unauthorized, "TOKEN_REUSED", "Refresh token was already used; the session has been revoked")
+	ErrInvalidVerificationToken = apperr.New(apperr.Invalid, "INVALID_TOKEN", "Verification token is invalid, expired or already used")
+	ErrInvalidResetToken        = apperr.New(apperr.Invalid, "INVALID_TOKEN", "Reset token is invalid, expired or already used")
+	ErrSessionNotFound          = apperr.New(apperr.NotFound, "SESSION_NOT_FOUND", "Session not found")
+
+	// Two-factor authentication
+	ErrInvalidMFAChallenge     = apperr.New(apperr.Unauthorized, "INVALID_MFA_CHALLENGE", "Invalid or expired MFA challenge")
+	ErrInvalidMFACode          = apperr.New(apperr.Unauthorized, "INVALID_MFA_CODE", "Invalid authentication code")
+	ErrMFAAlreadyEnabled       = apperr.New(apperr.Conflict, "MFA_ALREADY_ENABLED", "Two-factor authentication is already enabled")
+	ErrMFANotEnabled           = apperr.New(apperr.Conflict, "MFA_NOT_ENABLED", "Two-factor authentication is not enabled")
+	ErrMFAEnrollmentNotStarted = apperr.New(apperr.Conflict, "MFA_ENROLLMENT_NOT_STARTED", "Start the 2FA enrollment first")
+	ErrMFARequired             = apperr.New(apperr.Forbidden, "MFA_REQUIRED", "Two-factor authentication is required for your role")
+	ErrInvalidMFAPolicyRole    = apperr.New(apperr.Invalid, "INVALID_ROLE", "Invalid role")
+
+	// Roles
+	ErrRoleNotFound      = apperr.New(apperr.NotFound, "ROLE_NOT_FOUND", "Role not found")
+	ErrInvalidRoleName   = apperr.New(apperr.Invalid, "INVALID_ROLE_NAME", "Role names are 2-50 lowercase letters, digits, '_' or '-' and start with a letter")
+	ErrUnknownPermission = apperr.New(apperr.Invalid, "UNKNOWN_PERMISSION", "Unknown permission requested; see GET /roles/permissions")
+	ErrRoleExists        = apperr.New(apperr.Conflict, "ROLE_EXISTS", "Role already exists")
+	ErrRoleLocked        = apperr.New(apperr.Conflict, "ROLE_LOCKED", "The admin role always holds every permission")
+	ErrRoleBuiltIn       = apperr.New(apperr.Conflict, "ROLE_BUILT_IN", "Built-in roles cannot be deleted")
+	ErrRoleInUse         = apperr.New(apperr.Conflict, "ROLE_IN_USE", "The role is still assigned to users")
+
+	// Impersonation
+	ErrReasonRequired          = apperr.New(apperr.Invalid, "REASON_REQUIRED", "A reason is required")
+	ErrSelfImpersonation       = apperr.New(apperr.Invalid, "SELF_IMPERSONATION", "You cannot impersonate yourself")
+	ErrUserInactive            = apperr.New(apperr.Conflict, "USER_INACTIVE", "Inactive users cannot be impersonated")
+	ErrInsufficientPermissions = apperr.New(apperr.Forbidden, "INSUFFICIENT_PERMISSIONS", "The user holds permissions you do not have")
+
+	// Account deletion
+	ErrConfirmationRequired = apperr.New(apperr.Invalid, "CONFIRMATION_REQUIRED", "Set confirm to your username to delete your account")
+	ErrErasureStarted       = apperr.New(apperr.Conflict, "ERASURE_STARTED", "The user's data has already been erased")
+	ErrErasureNotFound      = apperr.New(apperr.NotFound, "ERASURE_NOT_FOUND", "User has no pending or completed erasure")
+
+	// OAuth clients and personal access tokens
+	ErrInvalidScope             = apperr.New(apperr.Invalid, "INVALID_SCOPE", "Unknown scope requested")
+	ErrInvalidGrantType         = apperr.New(apperr.Invalid, "INVALID_GRANT_TYPE", "grantTypes may only contain authorization_code, refresh_token and client_credentials")
+	ErrPublicClientCredentials  = apperr.New(apperr.Invalid, "INVALID_GRANT_TYPE", "Public clients cannot use the client credentials grant")
+	ErrRedirectURIRequired      = apperr.New(apperr.Invalid, "INVALID_REDIRECT_URI", "At least one redirect URI is required for the authorization code grant")
+	ErrInvalidRedirectURI       = apperr.New(apperr.Invalid, "INVALID_REDIRECT_URI", "Redirect URIs must be absolute https URLs (http only for localhost) without a fragment")
+	ErrRedirectURINotRegistered = apperr.New(apperr.Invalid, "INVALID_REDIRECT_URI", "redirect_uri is not registered for this client")
+	ErrUnknownClient            = apperr.New(apperr.Invalid, "INVALID_CLIENT", "Unknown client")
+	ErrClientNotFound           = apperr.New(apperr.NotFound, "CLIENT_NOT_FOUND", "Client not found")
+	ErrAuthorizationNotFound    = apperr.New(apperr.NotFound, "AUTHORIZATION_NOT_FOUND", "Authorization not found")
+	ErrExpiryInPast             = apperr.New(apperr.Invalid, "INVALID_EXPIRY", "expiresAt must be in the future")
+	ErrPersonalTokenNotFound    = apperr.New(apperr.NotFound, "TOKEN_NOT_FOUND", "Token not found")
+
+	// Sign-in with an identity provider
+	ErrUnknownProvider     = apperr.New(apperr.NotFound, "UNKNOWN_PROVIDER", "Unknown identity provider")
+	ErrProviderUnavailable = apperr.New(apperr.BadGateway, "OIDC_PROVIDER_ERROR", "Identity provider is unavailable")
+	ErrInvalidOIDCState    = apperr.New(apperr.Invalid, "INVALID_STATE", "Login expired or was already completed, please start again")
+	ErrOIDCExchangeFailed  = apperr.New(apperr.BadGateway, "OIDC_PROVIDER_ERROR", "Could not verify the login with the identity provider")
+	ErrOIDCEmailMissing    = apperr.New(apperr.Invalid, "OIDC_EMAIL_MISSING", "Identity provider did not share an email address")
+	ErrEmailRegistered     = apperr.New(apperr.Conflict, "EMAIL_TAKEN", "An account with this email already exists; sign in with your password")
+
+	// Avatars, data exports and imports
+	ErrAvatarsDisabled     = apperr.New(apperr.NotFound, "AVATARS_DISABLED", "Avatars are not enabled")
+	ErrAvatarNotFound      = apperr.New(apperr.NotFound, "AVATAR_NOT_FOUND", "Avatar not found")
+	ErrExportNotFound      = apperr.New(apperr.NotFound, "EXPORT_NOT_FOUND", "Export not found or expired")
+	ErrExportNotReady      = apperr.New(apperr.Conflict, "EXPORT_NOT_READY", "The export is not ready for download")
+	ErrInvalidExportFormat = apperr.New(apperr.Invalid, "INVALID_FORMAT", "format must be zip or json")
+	ErrImportNotFound      = apperr.New(apperr.NotFound, "IMPORT_NOT_FOUND", "Import not found")
+
+	// SCIM groups
+	ErrGroupNotFound          = apperr.New(apperr.NotFound, "GROUP_NOT_FOUND", "Group not found")
+	ErrGroupNameRequired      = apperr.New(apperr.Invalid, "GROUP_NAME_REQUIRED", "displayName is required")
+	ErrGroupNameTaken         = apperr.New(apperr.Conflict, "GROUP_NAME_TAKEN", "displayName already exists")
+	ErrMemberNotFound         = apperr.New(apperr.Invalid, "MEMBER_NOT_FOUND", "Members must be existing users")
+	ErrTeamServiceUnavailable = apperr.New(apperr.BadGateway, "TEAM_SERVICE_UNAVAILABLE", "The team service could not be updated; retry the request")
+)
+
+// errInvalidServiceClient is mapped to the OAuth invalid_client error by the token endpoint
+var errInvalidServiceClient = errors.New("invalid client")
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// impersonationHistoryLimit caps how many past impersonations of a user are listed
+const impersonationHistoryLimit = 100
+
+// ImpersonationTTLFromEnv returns the lifetime of impersonation tokens (IMPERSONATION_TTL, default 15m)
+func ImpersonationTTLFromEnv() time.Duration {
+	if s := os.Getenv("IMPERSONATION_TTL"); s != "" {
+		if ttl, err := time.ParseDuration(s); err == nil && ttl > 0 {
+			return ttl
+		}
+		log.Printf("invalid IMPERSONATION_TTL %q, using 15m", s)
+	}
+	return 15 * time.Minute
+}
+
+// OnImpersonation registers a callback that runs after an impersonation token was
+// issued, e.g. to publish a user.impersonated event
+func (s *AuthService) OnImpersonation(fn func(impersonation *models.Impersonation)) {
+	s.onImpersonation = fn
+}
+
+// Impersonate issues a short-lived access token that lets actorID act as targetID.
+// The token carries the target's identity and permissions plus the impersonator in
+// impersonator_id and act. actorPermissions are those of the caller's token: users
+// whose role grants a permission the caller lacks cannot be impersonated, so nobody
+// gains permissions by impersonating. No refresh token or session is created.
+func (s *AuthService) Impersonate(actorID int, actorPermissions []string, targetID int, reason string) (*models.ImpersonationTokenResponse, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrReasonRequired
+	}
+	if actorID == targetID {
+		return nil, ErrSelfImpersonation
+	}
+	actor, err := s.repo.GetByID(actorID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	target, err := s.repo.GetByID(targetID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if !target.IsActive {
+		return nil, ErrUserInactive
+	}
+	perms, err := s.permissionsFor(target.Role)
+	if err != nil {
+		return nil, err
+	}
+	if !containsAll(actorPermissions, perms) {
+		return nil, ErrInsufficientPermissions
+	}
+	jti, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	expiresAt := now.Add(s.impersonationTTL)
+	claims := &models.Claims{UserID: target.ID, Username: target.Username, Role: target.Role, Permissions: perms, TokenType: models.TokenTypeAccess, Restricted: s.isRestricted(target), TokenVersion: target.TokenVersion, Timezone: target.Timezone,
+		ImpersonatorID: actor.ID, Act: &models.ActorClaims{Subject: strconv.Itoa(actor.ID), Username: actor.Username},
+		RegisteredClaims: jwt.RegisteredClaims{ID: jti, Subject: strconv.Itoa(target.ID), ExpiresAt: jwt.NewNumericDate(expiresAt), IssuedAt: jwt.NewNumericDate(now), Issuer: "auth-service", Audience: jwt.ClaimStrings{models.AudienceAPI}}}
+	token, err := s.keys.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+	record := &models.Impersonation{ActorID: actor.ID, UserID: target.ID, Reason: truncate(reason, 255), TokenID: jti, ExpiresAt: expiresAt.UTC()}
+	if err := s.impersonations.Create(record); err != nil {
+		return nil, err
+	}
+	if s.onImpersonation != nil {
+		s.onImpersonation(record)
+	}
+	return &models.ImpersonationTokenResponse{AccessToken: token, TokenType: "Bearer", ExpiresIn: int(s.impersonationTTL.Seconds()), ExpiresAt: expiresAt.UTC(),
+		ImpersonatorID: actor.ID, User: target.ToUserResponse()}, nil
+}
+
+// ListImpersonations returns the latest impersonations of a user
+func (s *AuthService) ListImpersonations(userID int) ([]models.ImpersonationResponse, error) {
+	rows, err := s.impersonations.ListForUser(userID, impersonationHistoryLimit)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]models.ImpersonationResponse, 0, len(rows))
+	for i := range rows {
+		responses = append(responses, rows[i].ToResponse())
+	}
+	return responses, nil
+}
+
+// checkImpersonator rejects impersonation tokens once the impersonator was
+// deactivated or deleted, without waiting for the token to expire
+func (s *AuthService) checkImpersonator(claims *models.Claims) error {
+	if !claims.IsImpersonation() {
+		return nil
+	}
+	impersonator, err := s.repo.GetByID(claims.ImpersonatorID)
+	if err != nil || !impersonator.IsActive {
+		return errors.New("token revoked")
+	}
+	return nil
+}
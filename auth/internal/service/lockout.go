@@ -0,0 +1,58 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/lockout"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// OnAccountLocked registers a callback that runs when failed logins lock an existing
+// account, e.g. to publish a user.locked event
+func (s *AuthService) OnAccountLocked(fn func(user *models.User, until time.Time, ip string)) {
+	s.onLocked = fn
+}
+
+// checkLoginAllowed returns a *lockout.Error while the username or client is backing off
+func (s *AuthService) checkLoginAllowed(username string, client models.ClientInfo) error {
+	err := s.guard.Check(username, client.IPAddress)
+	var le *lockout.Error
+	if err != nil && !errors.As(err, &le) {
+		// A broken counter store must not take logins down with it
+		log.Printf("login guard check failed: %v", err)
+		return nil
+	}
+	return err
+}
+
+// loginFailed counts a failed password or 2FA code; user is nil for unknown usernames
+func (s *AuthService) loginFailed(username string, user *models.User, client models.ClientInfo) {
+	locked, until, err := s.guard.Failure(username, client.IPAddress)
+	if err != nil {
+		log.Printf("failed to record login failure for %q: %v", username, err)
+		return
+	}
+	if !locked {
+		return
+	}
+	log.Printf("login for %q locked until %s after repeated failures (last from %s)", username, until.Format(time.RFC3339), client.IPAddress)
+	if user != nil && s.onLocked != nil {
+		s.onLocked(user, until, client.IPAddress)
+	}
+}
+
+// LoginLockedUntil returns when the user's login lock ends, or the zero time
+func (s *AuthService) LoginLockedUntil(user *models.User) (time.Time, error) {
+	return s.guard.LockedUntil(user.Username)
+}
+
+// UnlockUser lifts a login lock or backoff on the user (admin action)
+func (s *AuthService) UnlockUser(userID int) error {
+	user, err := s.repo.GetByID(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	return s.guard.Unlock(user.Username)
+}
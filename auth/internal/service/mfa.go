@@ -0,0 +1,410 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/totp"
+)
+
+const (
+	// settingMFARequiredRoles holds a comma-separated list of roles that must use 2FA
+	settingMFARequiredRoles = "mfa_required_roles"
+	// recoveryCodeCount is how many recovery codes a user gets per generation
+	recoveryCodeCount = 10
+	// totpSkew accepts codes from one step before and after the current one
+	totpSkew = 1
+	// sealedSecretPrefix marks TOTP secrets encrypted with MFA_ENCRYPTION_KEY
+	sealedSecretPrefix = "enc:v1:"
+)
+
+// MFAConfig holds the 2FA settings read from the environment
+type MFAConfig struct {
+	Issuer       string        // shown in authenticator apps (MFA_ISSUER)
+	ChallengeTTL time.Duration // lifetime of MFA challenge tokens (MFA_CHALLENGE_TTL)
+	// DefaultRequiredRoles applies until an admin stores a policy (MFA_REQUIRED_ROLES)
+	DefaultRequiredRoles []string
+	aead                 cipher.AEAD
+}
+
+// MFAConfigFromEnv reads MFA_ISSUER, MFA_CHALLENGE_TTL, MFA_REQUIRED_ROLES and
+// MFA_ENCRYPTION_KEY (base64, 32 bytes, AES-256-GCM for stored TOTP secrets).
+// Without an encryption key secrets are stored unencrypted, which is only suitable
+// for local development.
+func MFAConfigFromEnv() (MFAConfig, error) {
+	cfg := MFAConfig{Issuer: "Todo App", ChallengeTTL: 5 * time.Minute}
+	if s := os.Getenv("MFA_ISSUER"); s != "" {
+		cfg.Issuer = s
+	}
+	if s := os.Getenv("MFA_CHALLENGE_TTL"); s != "" {
+		ttl, err := time.ParseDuration(s)
+		if err != nil || ttl <= 0 {
+			return cfg, fmt.Errorf("invalid MFA_CHALLENGE_TTL %q", s)
+		}
+		cfg.ChallengeTTL = ttl
+	}
+	cfg.DefaultRequiredRoles = splitRoles(os.Getenv("MFA_REQUIRED_ROLES"))
+	for _, role := range cfg.DefaultRequiredRoles {
+		if !models.ValidRoleName(role) {
+			return cfg, fmt.Errorf("invalid role %q in MFA_REQUIRED_ROLES", role)
+		}
+	}
+	key := os.Getenv("MFA_ENCRYPTION_KEY")
+	if key == "" {
+		log.Printf("WARNING: MFA_ENCRYPTION_KEY not set; TOTP secrets are stored unencrypted")
+		return cfg, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(raw) != 32 {
+		return cfg, errors.New("MFA_ENCRYPTION_KEY must be 32 bytes, base64 encoded")
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.aead, err = cipher.NewGCM(block)
+	return cfg, err
+}
+
+// seal encrypts a TOTP secret for storage when an encryption key is configured
+func (c MFAConfig) seal(secret string) (string, error) {
+	if c.aead == nil {
+		return secret, nil
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(secret), nil)
+	return sealedSecretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// open reverses seal; unencrypted secrets are returned unchanged
+func (c MFAConfig) open(stored string) (string, error) {
+	if !strings.HasPrefix(stored, sealedSecretPrefix) {
+		return stored, nil
+	}
+	if c.aead == nil {
+		return "", errors.New("TOTP secret is encrypted but MFA_ENCRYPTION_KEY is not set")
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, sealedSecretPrefix))
+	if err != nil || len(raw) < c.aead.NonceSize() {
+		return "", errors.New("corrupt TOTP secret")
+	}
+	nonce, ct := raw[:c.aead.NonceSize()], raw[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, ct, nil)
+	if err != nil {
+		return "", errors.New("corrupt TOTP secret")
+	}
+	return string(plain), nil
+}
+
+// issueMFAChallenge signs a short-lived token (typ "mfa", aud "auth-service") that
+// proves the password step succeeded and can only be redeemed at /auth/login/mfa
+func (s *AuthService) issueMFAChallenge(user *models.User, stage string) (*models.MFAChallengeResponse, error) {
+	jti, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	claims := &models.Claims{UserID: user.ID, Username: user.Username, Role: user.Role, TokenType: models.TokenTypeMFA, MFAStage: stage, RegisteredClaims: jwt.RegisteredClaims{ID: jti, ExpiresAt: jwt.NewNumericDate(now.Add(s.mfa.ChallengeTTL)), IssuedAt: jwt.NewNumericDate(now), Issuer: "auth-service", Audience: jwt.ClaimStrings{models.AudienceAuth}}}
+	signed, err := s.keys.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+	return &models.MFAChallengeResponse{MFARequired: true, Stage: stage, ChallengeToken: signed, ExpiresIn: int(s.mfa.ChallengeTTL.Seconds())}, nil
+}
+
+// challengeUser resolves a challenge token to its (still active) user
+func (s *AuthService) challengeUser(challengeToken string) (*models.User, string, error) {
+	claims, err := s.parseToken(challengeToken, models.TokenTypeMFA)
+	if err != nil {
+		return nil, "", ErrInvalidMFAChallenge
+	}
+	user, err := s.repo.GetByID(claims.UserID)
+	if err != nil {
+		return nil, "", ErrInvalidMFAChallenge
+	}
+	if !user.IsActive {
+		return nil, "", ErrAccountDeactivated
+	}
+	return user, claims.MFAStage, nil
+}
+
+// CompleteMFALogin finishes a two-step login. For the "verify" stage the code may be
+// a TOTP or a recovery code; for the "enroll" stage it confirms the authenticator set
+// up via BeginChallengeEnrollment and the response carries the new recovery codes.
+func (s *AuthService) CompleteMFALogin(challengeToken, code string, client models.ClientInfo) (*models.LoginResponse, error) {
+	user, stage, err := s.challengeUser(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+	// Wrong codes count as failed logins, so guessing codes is throttled like passwords
+	if err := s.checkLoginAllowed(user.Username, client); err != nil {
+		return nil, err
+	}
+	switch stage {
+	case models.MFAStageVerify:
+		if !user.MFAEnabled {
+			return nil, ErrInvalidMFAChallenge
+		}
+		if err := s.verifySecondFactor(user, code, true); err != nil {
+			if errors.Is(err, ErrInvalidMFACode) {
+				s.loginFailed(user.Username, user, client)
+			}
+			return nil, err
+		}
+		return s.startSession(user, client)
+	case models.MFAStageEnroll:
+		codes, err := s.confirmEnrollment(user, code)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s.startSession(user, client)
+		if err != nil {
+			return nil, err
+		}
+		resp.RecoveryCodes = codes
+		return resp, nil
+	default:
+		return nil, ErrInvalidMFAChallenge
+	}
+}
+
+// BeginChallengeEnrollment starts the forced enrollment of a user whose role requires 2FA
+func (s *AuthService) BeginChallengeEnrollment(challengeToken string) (*models.MFAEnrollmentResponse, error) {
+	user, stage, err := s.challengeUser(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+	if stage != models.MFAStageEnroll {
+		return nil, ErrInvalidMFAChallenge
+	}
+	return s.beginEnrollment(user)
+}
+
+// StartMFAEnrollment generates a new pending TOTP secret for a logged-in user
+func (s *AuthService) StartMFAEnrollment(userID int) (*models.MFAEnrollmentResponse, error) {
+	user, err := s.repo.GetByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	return s.beginEnrollment(user)
+}
+
+// ConfirmMFAEnrollment enables 2FA once the first code from the authenticator matches
+func (s *AuthService) ConfirmMFAEnrollment(userID int, code string) ([]string, error) {
+	user, err := s.repo.GetByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	return s.confirmEnrollment(user, code)
+}
+
+func (s *AuthService) beginEnrollment(user *models.User) (*models.MFAEnrollmentResponse, error) {
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	stored, err := s.mfa.seal(secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetTOTPSecret(user.ID, stored); err != nil {
+		return nil, err
+	}
+	return &models.MFAEnrollmentResponse{Secret: secret, OTPAuthURI: totp.URI(s.mfa.Issuer, user.Username, secret)}, nil
+}
+
+func (s *AuthService) confirmEnrollment(user *models.User, code string) ([]string, error) {
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFAEnrollmentNotStarted
+	}
+	secret, err := s.mfa.open(user.TOTPSecret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := totp.Validate(secret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+	if err := s.repo.EnableMFA(user.ID, step); err != nil {
+		return nil, err
+	}
+	user.MFAEnabled = true
+	return s.newRecoveryCodes(user.ID)
+}
+
+// DisableMFA switches 2FA off after checking a current TOTP or recovery code.
+// Users whose role requires 2FA cannot disable it.
+func (s *AuthService) DisableMFA(userID int, code string) error {
+	user, err := s.repo.GetByID(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if !user.MFAEnabled {
+		return ErrMFANotEnabled
+	}
+	required, err := s.mfaRequiredFor(user.Role)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrMFARequired
+	}
+	if err := s.verifySecondFactor(user, code, true); err != nil {
+		return err
+	}
+	return s.ResetMFA(user.ID)
+}
+
+// ResetMFA removes the TOTP secret and recovery codes without a code (admin action)
+func (s *AuthService) ResetMFA(userID int) error {
+	if err := s.repo.DisableMFA(userID); err != nil {
+		return err
+	}
+	return s.recoveryCodes.DeleteForUser(userID)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a TOTP code
+func (s *AuthService) RegenerateRecoveryCodes(userID int, code string) ([]string, error) {
+	user, err := s.repo.GetByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if !user.MFAEnabled {
+		return nil, ErrMFANotEnabled
+	}
+	if err := s.verifySecondFactor(user, code, false); err != nil {
+		return nil, err
+	}
+	return s.newRecoveryCodes(user.ID)
+}
+
+// verifySecondFactor accepts a TOTP code (each time step only once) or, if allowed,
+// an unused recovery code
+func (s *AuthService) verifySecondFactor(user *models.User, code string, allowRecovery bool) error {
+	secret, err := s.mfa.open(user.TOTPSecret)
+	if err != nil {
+		return err
+	}
+	if step, ok := totp.Validate(secret, code, time.Now(), totpSkew); ok {
+		advanced, err := s.repo.AdvanceTOTPStep(user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !advanced {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+	if allowRecovery {
+		used, err := s.recoveryCodes.Consume(user.ID, hashToken(normalizeRecoveryCode(code)))
+		if err != nil {
+			return err
+		}
+		if used {
+			log.Printf("user %d logged in with a recovery code", user.ID)
+			return nil
+		}
+	}
+	return ErrInvalidMFACode
+}
+
+// newRecoveryCodes generates and stores a fresh set of recovery codes (XXXXX-XXXXX,
+// 50 bits each) and returns the plaintext codes, which are never stored
+func (s *AuthService) newRecoveryCodes(userID int) ([]string, error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := enc.EncodeToString(b)[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, hashToken(raw))
+	}
+	if err := s.recoveryCodes.ReplaceForUser(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// MFAPolicy returns the roles that currently require 2FA
+func (s *AuthService) MFAPolicy() (models.MFAPolicy, error) {
+	value, ok, err := s.settings.Get(settingMFARequiredRoles)
+	if err != nil {
+		return models.MFAPolicy{}, err
+	}
+	if !ok {
+		return models.MFAPolicy{RequiredRoles: append([]string{}, s.mfa.DefaultRequiredRoles...)}, nil
+	}
+	return models.MFAPolicy{RequiredRoles: splitRoles(value)}, nil
+}
+
+// SetMFAPolicy stores the roles that require 2FA. Members of those roles without 2FA
+// are asked to enroll at their next login.
+func (s *AuthService) SetMFAPolicy(p models.MFAPolicy) (models.MFAPolicy, error) {
+	roles := make([]string, 0, len(p.RequiredRoles))
+	for _, role := range p.RequiredRoles {
+		exists, err := s.RoleExists(role)
+		if err != nil {
+			return models.MFAPolicy{}, err
+		}
+		if !exists {
+			return models.MFAPolicy{}, ErrInvalidMFAPolicyRole
+		}
+		roles = append(roles, role)
+	}
+	if err := s.settings.Set(settingMFARequiredRoles, strings.Join(roles, ",")); err != nil {
+		return models.MFAPolicy{}, err
+	}
+	return models.MFAPolicy{RequiredRoles: roles}, nil
+}
+
+func (s *AuthService) mfaRequiredFor(role string) (bool, error) {
+	p, err := s.MFAPolicy()
+	if err != nil {
+		return false, err
+	}
+	for _, r := range p.RequiredRoles {
+		if r == role {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func splitRoles(s string) []string {
+	roles := []string{}
+	for _, r := range strings.Split(s, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			roles = append(roles, r)
+		}
+	}
+	return roles
+}
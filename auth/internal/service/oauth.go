@@ -0,0 +1,538 @@
+package service
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/repository"
+)
+
+// oauthCodeTTL bounds the time between the user's consent and the client redeeming the code
+const oauthCodeTTL = time.Minute
+
+// codeVerifierPattern is the PKCE code_verifier syntax (RFC 7636 section 4.1)
+var codeVerifierPattern = regexp.MustCompile(`^[A-Za-z0-9._~-]{43,128}$`)
+
+// OAuthError is an OAuth 2.0 error (RFC 6749 sections 4.1.2.1 and 5.2). RedirectTo is
+// set for authorization errors that are reported back to the client's redirect URI.
+type OAuthError struct {
+	Code        string
+	Description string
+	RedirectTo  string
+}
+
+func (e *OAuthError) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+func oauthError(code, description string) error {
+	return &OAuthError{Code: code, Description: description}
+}
+
+// OAuthAccessTTLFromEnv returns the lifetime of access tokens issued to OAuth clients
+// (OAUTH_ACCESS_TTL, default 1h)
+func OAuthAccessTTLFromEnv() time.Duration {
+	if s := os.Getenv("OAUTH_ACCESS_TTL"); s != "" {
+		if ttl, err := time.ParseDuration(s); err == nil && ttl > 0 {
+			return ttl
+		}
+	}
+	return time.Hour
+}
+
+// RegisterOAuthClient registers a third-party application owned by ownerID. The
+// secret of a confidential client is returned once and only its hash is stored.
+func (s *AuthService) RegisterOAuthClient(ownerID int, req models.CreateOAuthClientRequest) (*models.OAuthClientResponse, error) {
+	scopes, err := parseScopes(strings.Join(req.Scopes, " "))
+	if err != nil || len(scopes) == 0 {
+		return nil, ErrInvalidScope
+	}
+	grants := req.GrantTypes
+	if len(grants) == 0 {
+		grants = []string{models.GrantAuthorizationCode, models.GrantRefreshToken}
+	}
+	grants = uniqueFields(grants)
+	for _, g := range grants {
+		switch g {
+		case models.GrantAuthorizationCode, models.GrantRefreshToken:
+		case models.GrantClientCredentials:
+			if req.Public {
+				return nil, ErrPublicClientCredentials
+			}
+		default:
+			return nil, ErrInvalidGrantType
+		}
+	}
+	redirectURIs := uniqueFields(req.RedirectURIs)
+	if containsString(grants, models.GrantAuthorizationCode) && len(redirectURIs) == 0 {
+		return nil, ErrRedirectURIRequired
+	}
+	for _, uri := range redirectURIs {
+		if !validRedirectURI(uri) {
+			return nil, ErrInvalidRedirectURI
+		}
+	}
+
+	clientID, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	client := &models.OAuthClient{ClientID: clientID, Name: req.Name, RedirectURIs: strings.Join(redirectURIs, " "), Scopes: strings.Join(scopes, " "), GrantTypes: strings.Join(grants, " "), OwnerID: ownerID}
+	var secret string
+	if !req.Public {
+		if secret, err = randomHex(32); err != nil {
+			return nil, err
+		}
+		client.ClientSecretHash = hashToken(secret)
+	}
+	if err := s.oauthClients.Create(client); err != nil {
+		return nil, err
+	}
+	resp := client.ToResponse()
+	resp.ClientSecret = secret
+	return &resp, nil
+}
+
+// ListOAuthClients returns the clients a user registered
+func (s *AuthService) ListOAuthClients(ownerID int) ([]models.OAuthClientResponse, error) {
+	clients, err := s.oauthClients.ListByOwner(ownerID)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]models.OAuthClientResponse, 0, len(clients))
+	for i := range clients {
+		responses = append(responses, clients[i].ToResponse())
+	}
+	return responses, nil
+}
+
+// GetOAuthClient returns a registered client
+func (s *AuthService) GetOAuthClient(clientID string) (*models.OAuthClient, error) {
+	client, err := s.oauthClients.Get(clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, ErrClientNotFound
+	}
+	return client, nil
+}
+
+// DeleteOAuthClient revokes every refresh token issued to a client and removes it
+// together with its consents. Access tokens already issued are not revoked; they expire
+// after OAUTH_ACCESS_TTL, and Introspect reports them inactive in the meantime.
+func (s *AuthService) DeleteOAuthClient(clientID string) error {
+	if err := s.tokens.RevokeClient(clientID); err != nil {
+		return err
+	}
+	return s.oauthClients.Delete(clientID)
+}
+
+// PrepareAuthorization validates an authorization request for the consent page and
+// reports whether the user still has to approve the requested scopes
+func (s *AuthService) PrepareAuthorization(userID int, req models.AuthorizeRequest) (*models.AuthorizeResponse, error) {
+	client, scopes, err := s.validateAuthorizeRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	consent, err := s.oauthConsents.Get(userID, client.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]models.ScopeInfo, 0, len(scopes))
+	for _, scope := range scopes {
+		infos = append(infos, models.ScopeInfo{Name: scope, Description: models.ScopeDescriptions[scope]})
+	}
+	return &models.AuthorizeResponse{
+		ClientID:        client.ClientID,
+		ClientName:      client.Name,
+		RedirectURI:     req.RedirectURI,
+		Scopes:          infos,
+		ConsentRequired: consent == nil || !containsAll(strings.Fields(consent.Scope), scopes),
+	}, nil
+}
+
+// AuthorizeDecision records the user's answer on the consent page. An approval
+// extends the stored consent and issues a single-use code bound to the PKCE
+// challenge; either way the browser is sent back to the client's redirect URI.
+func (s *AuthService) AuthorizeDecision(userID int, req models.AuthorizeRequest) (*models.AuthorizeRedirect, error) {
+	client, scopes, err := s.validateAuthorizeRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if !req.Approve {
+		return &models.AuthorizeRedirect{RedirectTo: redirectWith(req.RedirectURI, url.Values{"error": {"access_denied"}}, req.State)}, nil
+	}
+	consent, err := s.oauthConsents.Get(userID, client.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	granted := scopes
+	if consent != nil {
+		granted = uniqueFields(append(strings.Fields(consent.Scope), scopes...))
+	}
+	if err := s.oauthConsents.Save(userID, client.ClientID, strings.Join(granted, " ")); err != nil {
+		return nil, err
+	}
+	code, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	row := &models.OAuthAuthorizationCode{CodeHash: hashToken(code), ClientID: client.ClientID, UserID: userID, RedirectURI: req.RedirectURI, Scope: strings.Join(scopes, " "), CodeChallenge: req.CodeChallenge, ExpiresAt: time.Now().Add(oauthCodeTTL).UTC()}
+	if err := s.oauthCodes.Create(row); err != nil {
+		return nil, err
+	}
+	return &models.AuthorizeRedirect{RedirectTo: redirectWith(req.RedirectURI, url.Values{"code": {code}}, req.State)}, nil
+}
+
+// validateAuthorizeRequest checks an authorization request. An unknown client or
+// redirect URI is a plain error that must not redirect (RFC 6749 section 4.1.2.1);
+// every other problem is an *OAuthError that is sent back to the redirect URI.
+func (s *AuthService) validateAuthorizeRequest(req models.AuthorizeRequest) (*models.OAuthClient, []string, error) {
+	client, err := s.oauthClients.Get(req.ClientID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if client == nil {
+		return nil, nil, ErrUnknownClient
+	}
+	if !client.HasRedirectURI(req.RedirectURI) {
+		return nil, nil, ErrRedirectURINotRegistered
+	}
+	fail := func(code, description string) error {
+		return &OAuthError{Code: code, Description: description, RedirectTo: redirectWith(req.RedirectURI, url.Values{"error": {code}, "error_description": {description}}, req.State)}
+	}
+	if req.ResponseType != "code" {
+		return nil, nil, fail("unsupported_response_type", "Only response_type=code is supported")
+	}
+	if !client.AllowsGrant(models.GrantAuthorizationCode) {
+		return nil, nil, fail("unauthorized_client", "Client is not registered for the authorization code grant")
+	}
+	if req.CodeChallengeMethod != "S256" || len(req.CodeChallenge) != 43 {
+		return nil, nil, fail("invalid_request", "PKCE with code_challenge_method=S256 is required")
+	}
+	scopes, err := requestedScopes(client, req.Scope)
+	if err != nil {
+		return nil, nil, fail("invalid_scope", "Requested scope is unknown or not registered for this client")
+	}
+	return client, scopes, nil
+}
+
+// ClientCredentialsToken implements the client credentials grant. Backend services
+// from SERVICE_CLIENTS get a service token; registered OAuth clients get an access
+// token that acts as their owner, limited to the requested (default: registered) scopes.
+func (s *AuthService) ClientCredentialsToken(clientID, clientSecret, scope string) (*models.TokenResponse, error) {
+	if _, ok := s.serviceClients[clientID]; ok {
+		resp, err := s.IssueServiceToken(clientID, clientSecret)
+		if err != nil && errors.Is(err, errInvalidServiceClient) {
+			return nil, oauthError("invalid_client", "")
+		}
+		return resp, err
+	}
+	client, err := s.authenticateClient(clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !client.AllowsGrant(models.GrantClientCredentials) {
+		return nil, oauthError("unauthorized_client", "Client is not registered for the client credentials grant")
+	}
+	scopes, err := requestedScopes(client, scope)
+	if err != nil {
+		return nil, oauthError("invalid_scope", "Requested scope is unknown or not registered for this client")
+	}
+	owner, err := s.repo.GetByID(client.OwnerID)
+	if err != nil || !owner.IsActive {
+		return nil, oauthError("invalid_grant", "Client owner is deactivated")
+	}
+	return s.issueClientTokens(owner, client, scopes, "", models.ClientInfo{})
+}
+
+// AuthorizationCodeToken redeems an authorization code. The code must have been
+// issued to the same client and redirect URI, and the verifier must match its PKCE
+// challenge. Clients allowed the refresh_token grant also get a refresh token.
+func (s *AuthService) AuthorizationCodeToken(clientID, clientSecret, code, redirectURI, verifier string, info models.ClientInfo) (*models.TokenResponse, error) {
+	client, err := s.authenticateClient(clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !client.AllowsGrant(models.GrantAuthorizationCode) {
+		return nil, oauthError("unauthorized_client", "Client is not registered for the authorization code grant")
+	}
+	if code == "" || !codeVerifierPattern.MatchString(verifier) {
+		return nil, oauthError("invalid_request", "code and a valid code_verifier are required")
+	}
+	stored, err := s.oauthCodes.Consume(hashToken(code))
+	if err != nil {
+		if errors.Is(err, repository.ErrTokenNotUsable) {
+			return nil, oauthError("invalid_grant", "Authorization code is invalid or expired")
+		}
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	if stored.ClientID != client.ClientID || stored.RedirectURI != redirectURI ||
+		subtle.ConstantTimeCompare([]byte(challenge), []byte(stored.CodeChallenge)) != 1 {
+		return nil, oauthError("invalid_grant", "Authorization code was not issued for this request")
+	}
+	user, err := s.repo.GetByID(stored.UserID)
+	if err != nil || !user.IsActive {
+		return nil, oauthError("invalid_grant", "User account is deactivated")
+	}
+	var familyID string
+	if client.AllowsGrant(models.GrantRefreshToken) {
+		if familyID, err = newFamilyID(); err != nil {
+			return nil, err
+		}
+	}
+	return s.issueClientTokens(user, client, strings.Fields(stored.Scope), familyID, info)
+}
+
+// RefreshClientToken rotates a refresh token issued to an OAuth client. The new
+// tokens keep the originally granted scope.
+func (s *AuthService) RefreshClientToken(clientID, clientSecret, refreshToken string, info models.ClientInfo) (*models.TokenResponse, error) {
+	client, err := s.authenticateClient(clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !client.AllowsGrant(models.GrantRefreshToken) {
+		return nil, oauthError("unauthorized_client", "Client is not registered for the refresh token grant")
+	}
+	stored, err := s.lookupRefreshToken(refreshToken)
+	if err != nil || stored.ClientID != client.ClientID {
+		return nil, oauthError("invalid_grant", "Refresh token is invalid")
+	}
+	user, err := s.rotateRefreshToken(stored)
+	if err != nil {
+		return nil, oauthError("invalid_grant", err.Error())
+	}
+	return s.issueClientTokens(user, client, strings.Fields(stored.Scope), stored.FamilyID, info)
+}
+
+// Introspect reports whether an access token is active (RFC 7662). Callers
+// authenticate as a service client or a registered OAuth client. Unlike offline
+// verification, tokens of deactivated users and deleted clients are reported inactive.
+func (s *AuthService) Introspect(callerID, callerSecret, token string) (*models.IntrospectionResponse, error) {
+	if !s.authenticateServiceClient(callerID, callerSecret) {
+		if _, err := s.authenticateClient(callerID, callerSecret); err != nil {
+			return nil, err
+		}
+	}
+	inactive := &models.IntrospectionResponse{Active: false}
+	claims, err := s.ValidateToken(token)
+	if err != nil {
+		return inactive, nil
+	}
+	user, err := s.repo.GetByID(claims.UserID)
+	if err != nil || !user.IsActive {
+		return inactive, nil
+	}
+	if claims.ClientID != "" {
+		client, err := s.oauthClients.Get(claims.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		if client == nil {
+			return inactive, nil
+		}
+	}
+	resp := &models.IntrospectionResponse{Active: true, Scope: claims.Scope, ClientID: claims.ClientID, Username: claims.Username, TokenType: "Bearer",
+		Sub: strconv.Itoa(claims.UserID), Aud: models.AudienceAPI, Iss: claims.Issuer}
+	if claims.ExpiresAt != nil {
+		resp.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		resp.Iat = claims.IssuedAt.Unix()
+	}
+	return resp, nil
+}
+
+// ListAuthorizations returns the clients a user has granted access to
+func (s *AuthService) ListAuthorizations(userID int) ([]models.OAuthAuthorization, error) {
+	consents, err := s.oauthConsents.ListForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	authorizations := make([]models.OAuthAuthorization, 0, len(consents))
+	for _, consent := range consents {
+		client, err := s.oauthClients.Get(consent.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		if client == nil {
+			continue
+		}
+		authorizations = append(authorizations, models.OAuthAuthorization{ClientID: client.ClientID, ClientName: client.Name, Scopes: strings.Fields(consent.Scope), GrantedAt: consent.CreatedAt, UpdatedAt: consent.UpdatedAt})
+	}
+	return authorizations, nil
+}
+
+// RevokeAuthorization withdraws a user's consent for a client and revokes the
+// refresh tokens it holds for the user. Like with DeleteOAuthClient, access tokens
+// already issued stay valid until they expire (OAUTH_ACCESS_TTL).
+func (s *AuthService) RevokeAuthorization(userID int, clientID string) error {
+	deleted, err := s.oauthConsents.Delete(userID, clientID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrAuthorizationNotFound
+	}
+	return s.tokens.RevokeClientForUser(userID, clientID)
+}
+
+// authenticateClient checks a registered client's credentials. Public clients must
+// not send a secret; confidential clients must send theirs.
+func (s *AuthService) authenticateClient(clientID, clientSecret string) (*models.OAuthClient, error) {
+	if clientID == "" {
+		return nil, oauthError("invalid_client", "Client authentication is required")
+	}
+	client, err := s.oauthClients.Get(clientID)
+	if err != nil {
+		return nil, err
+	}
+	expected := hashToken("")
+	if client != nil && !client.IsPublic() {
+		expected = client.ClientSecretHash
+	}
+	// Always compare so timing does not reveal which client IDs exist
+	match := subtle.ConstantTimeCompare([]byte(hashToken(clientSecret)), []byte(expected)) == 1
+	if client == nil || (!client.IsPublic() && !match) || (client.IsPublic() && clientSecret != "") {
+		return nil, oauthError("invalid_client", "")
+	}
+	return client, nil
+}
+
+// issueClientTokens signs an access token for user on behalf of an OAuth client.
+// A refresh token in familyID is added when familyID is set.
+func (s *AuthService) issueClientTokens(user *models.User, client *models.OAuthClient, scopes []string, familyID string, info models.ClientInfo) (*models.TokenResponse, error) {
+	scope := strings.Join(scopes, " ")
+	jti, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	perms, err := s.permissionsFor(user.Role)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	claims := &models.Claims{UserID: user.ID, Username: user.Username, Role: user.Role, Permissions: perms, TokenType: models.TokenTypeAccess, ClientID: client.ClientID, Scope: scope, Restricted: s.isRestricted(user), TokenVersion: user.TokenVersion, Timezone: user.Timezone, RegisteredClaims: jwt.RegisteredClaims{ID: jti, Subject: strconv.Itoa(user.ID), ExpiresAt: jwt.NewNumericDate(now.Add(s.oauthAccessTTL)), IssuedAt: jwt.NewNumericDate(now), Issuer: "auth-service", Audience: jwt.ClaimStrings{models.AudienceAPI}}}
+	accessToken, err := s.keys.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+	resp := &models.TokenResponse{AccessToken: accessToken, TokenType: "Bearer", ExpiresIn: int(s.oauthAccessTTL.Seconds()), Scope: scope}
+	if familyID != "" {
+		resp.RefreshToken, err = s.storeRefreshToken(&models.RefreshToken{UserID: user.ID, FamilyID: familyID, ClientID: client.ClientID, Scope: scope}, user, info)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// requestedScopes returns the scopes of a request, defaulting to everything the
+// client registered; scopes the client did not register are rejected
+func requestedScopes(client *models.OAuthClient, scope string) ([]string, error) {
+	if strings.TrimSpace(scope) == "" {
+		return strings.Fields(client.Scopes), nil
+	}
+	scopes, err := parseScopes(scope)
+	if err != nil {
+		return nil, err
+	}
+	if !containsAll(strings.Fields(client.Scopes), scopes) {
+		return nil, errors.New("scope not registered")
+	}
+	return scopes, nil
+}
+
+// parseScopes splits a space-separated scope, dropping duplicates and rejecting unknown scopes
+func parseScopes(scope string) ([]string, error) {
+	scopes := uniqueFields(strings.Fields(scope))
+	for _, s := range scopes {
+		if _, ok := models.ScopeDescriptions[s]; !ok {
+			return nil, errors.New("unknown scope " + s)
+		}
+	}
+	return scopes, nil
+}
+
+// validRedirectURI accepts absolute https URIs, and http only on loopback hosts for
+// local development. Fragments are not allowed (RFC 6749 section 3.1.2).
+func validRedirectURI(raw string) bool {
+	if strings.ContainsAny(raw, " \t\r\n") {
+		return false
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || u.Fragment != "" || strings.Contains(raw, "#") {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := u.Hostname()
+		return host == "localhost" || host == "127.0.0.1" || host == "::1"
+	}
+	return false
+}
+
+// redirectWith adds params and, if set, state to a redirect URI's query
+func redirectWith(redirectURI string, params url.Values, state string) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	if state != "" {
+		q.Set("state", state)
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func uniqueFields(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v != "" && !containsString(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func containsAll(have, want []string) bool {
+	for _, w := range want {
+		if !containsString(have, w) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
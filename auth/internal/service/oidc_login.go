@@ -0,0 +1,197 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/oidc"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/repository"
+)
+
+// oidcStateTTL bounds the time a user may spend at the provider's login page
+const oidcStateTTL = 10 * time.Minute
+
+var usernameUnsafe = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// OIDCLoginResult is the outcome of an OIDC callback: tokens or an MFA challenge, and
+// the user if this login provisioned a new account
+type OIDCLoginResult struct {
+	Login       *models.LoginResponse
+	Challenge   *models.MFAChallengeResponse
+	Provisioned *models.User
+}
+
+// OIDCProviders lists the configured external identity providers
+func (s *AuthService) OIDCProviders() []models.OIDCProviderInfo {
+	infos := make([]models.OIDCProviderInfo, 0, len(s.oidcProviders))
+	for name, p := range s.oidcProviders {
+		infos = append(infos, models.OIDCProviderInfo{Name: name, DisplayName: p.Config().DisplayName, LoginURL: "/auth/oidc/" + name + "/login"})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// StartOIDCLogin stores a pending login (state, nonce and PKCE verifier) and returns
+// the provider URL to redirect the browser to
+func (s *AuthService) StartOIDCLogin(providerName string) (string, error) {
+	p, ok := s.oidcProviders[providerName]
+	if !ok {
+		return "", ErrUnknownProvider
+	}
+	state, err := oidc.NewNonce()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := oidc.NewNonce()
+	if err != nil {
+		return "", err
+	}
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		return "", err
+	}
+	authURL, err := p.AuthCodeURL(state, nonce, verifier)
+	if err != nil {
+		log.Printf("oidc provider %s unavailable: %v", providerName, err)
+		return "", ErrProviderUnavailable
+	}
+	pending := &models.OIDCLoginState{StateHash: hashToken(state), Provider: providerName, CodeVerifier: verifier, Nonce: nonce, ExpiresAt: time.Now().Add(oidcStateTTL).UTC()}
+	if err := s.oidcStates.Create(pending); err != nil {
+		return "", err
+	}
+	return authURL, nil
+}
+
+// CompleteOIDCLogin handles the provider callback: it redeems the state, exchanges the
+// code, finds or provisions the local user and then logs them in like a password login
+func (s *AuthService) CompleteOIDCLogin(providerName, code, state string, client models.ClientInfo) (*OIDCLoginResult, error) {
+	p, ok := s.oidcProviders[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	pending, err := s.oidcStates.Consume(hashToken(state), providerName)
+	if err != nil {
+		if errors.Is(err, repository.ErrTokenNotUsable) {
+			return nil, ErrInvalidOIDCState
+		}
+		return nil, err
+	}
+	id, err := p.Exchange(code, pending.CodeVerifier, pending.Nonce)
+	if err != nil {
+		log.Printf("oidc login with %s failed: %v", providerName, err)
+		return nil, ErrOIDCExchangeFailed
+	}
+	user, provisioned, err := s.resolveOIDCUser(p.Config(), id)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, ErrAccountDeactivated
+	}
+	resp, challenge, err := s.finishLogin(user, client)
+	if err != nil {
+		return nil, err
+	}
+	result := &OIDCLoginResult{Login: resp, Challenge: challenge}
+	if provisioned {
+		result.Provisioned = user
+	}
+	return result, nil
+}
+
+// LinkedIdentities lists the external accounts linked to a user
+func (s *AuthService) LinkedIdentities(userID int) ([]models.UserIdentity, error) {
+	return s.identities.ListForUser(userID)
+}
+
+// resolveOIDCUser returns the local user for an external identity. Known subjects map
+// to their linked user; otherwise a verified email links to an existing account (only
+// for providers with TrustEmail) or a new user with the "user" role is provisioned.
+func (s *AuthService) resolveOIDCUser(cfg oidc.Config, id *oidc.Identity) (*models.User, bool, error) {
+	linked, err := s.identities.Get(cfg.Name, id.Subject)
+	if err != nil {
+		return nil, false, err
+	}
+	if linked != nil {
+		user, err := s.repo.GetByID(linked.UserID)
+		if err != nil {
+			return nil, false, err
+		}
+		if err := s.identities.TouchLogin(linked.ID); err != nil {
+			log.Printf("failed to record login for identity %d: %v", linked.ID, err)
+		}
+		return user, false, nil
+	}
+
+	if id.Email == "" {
+		return nil, false, ErrOIDCEmailMissing
+	}
+	identity := &models.UserIdentity{Provider: cfg.Name, Subject: id.Subject, Email: id.Email}
+	if existing, err := s.repo.GetByEmail(id.Email); err == nil {
+		if !cfg.TrustEmail || !id.EmailVerified {
+			return nil, false, ErrEmailRegistered
+		}
+		identity.UserID = existing.ID
+		if err := s.identities.Create(identity); err != nil {
+			return nil, false, err
+		}
+		log.Printf("linked %s identity to existing user %d by verified email", cfg.Name, existing.ID)
+		return existing, false, nil
+	}
+
+	username, err := s.availableUsername(id)
+	if err != nil {
+		return nil, false, err
+	}
+	now := time.Now().UTC()
+	identity.LastLoginAt = &now
+	// Provisioned users have no local password; they can set one via the reset flow once
+	// their email is verified (or with EMAIL_VERIFICATION_POLICY=off)
+	user := &models.User{Username: username, Email: id.Email, FirstName: id.GivenName, LastName: id.FamilyName, Role: models.RoleUser, IsActive: true, EmailVerified: id.EmailVerified}
+	if id.EmailVerified {
+		user.EmailVerifiedAt = &now
+	}
+	if err := s.identities.CreateWithUser(user, identity); err != nil {
+		return nil, false, err
+	}
+	log.Printf("provisioned user %d from %s identity", user.ID, cfg.Name)
+	return user, true, nil
+}
+
+// availableUsername derives a free username from the provider's preferred username or
+// the email's local part, adding a numeric suffix when it is taken
+func (s *AuthService) availableUsername(id *oidc.Identity) (string, error) {
+	base := id.PreferredUsername
+	if base == "" || strings.Contains(base, "@") {
+		base = strings.SplitN(id.Email, "@", 2)[0]
+	}
+	base = strings.Trim(usernameUnsafe.ReplaceAllString(base, "_"), "_.-")
+	if len(base) < 3 {
+		base = "user_" + base
+	}
+	if len(base) > 40 {
+		base = base[:40]
+	}
+	candidate := base
+	for i := 0; i < 10; i++ {
+		exists, err := s.repo.ExistsByUsername(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
+		suffix, err := randomHex(3)
+		if err != nil {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s_%s", base, suffix)
+	}
+	return "", errors.New("no free username")
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordHasher hashes and verifies passwords stored in a self-describing encoded format.
+// NeedsRehash reports whether an encoded hash was produced by a weaker or different
+// configuration and should be replaced on the next successful login.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(password, encoded string) (bool, error)
+	NeedsRehash(encoded string) bool
+}
+
+var errUnknownHashFormat = errors.New("unknown password hash format")
+
+// Bounds for argon2id parameters, from the environment or from stored hashes. argon2.IDKey
+// panics on zero iterations or parallelism, and huge values would let a tampered hash
+// exhaust memory or CPU on login.
+const (
+	maxArgon2Memory     = 4 << 20 // KiB, 4 GiB
+	maxArgon2Iterations = 100
+	minArgon2SaltLength = 8
+	minArgon2KeyLength  = 16
+	maxArgon2KeyLength  = 1024
+)
+
+// Argon2idHasher produces PHC-style strings:
+// $argon2id$v=19$m=<memory KiB>,t=<iterations>,p=<parallelism>$<salt>$<key>
+type Argon2idHasher struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+	b64 := base64.RawStdEncoding
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Memory, h.Iterations, h.Parallelism, b64.EncodeToString(salt), b64.EncodeToString(key)), nil
+}
+
+func (h *Argon2idHasher) Verify(password, encoded string) (bool, error) {
+	p, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	computed := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(computed, key) == 1, nil
+}
+
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	p, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return p.Memory < h.Memory || p.Iterations < h.Iterations || p.Parallelism < h.Parallelism ||
+		uint32(len(salt)) < h.SaltLength || uint32(len(key)) < h.KeyLength
+}
+
+// validate rejects parameters argon2.IDKey cannot work with or that are out of range
+func (h *Argon2idHasher) validate() error {
+	switch {
+	case h.Iterations < 1 || h.Iterations > maxArgon2Iterations:
+		return fmt.Errorf("argon2id iterations must be between 1 and %d", maxArgon2Iterations)
+	case h.Parallelism < 1:
+		return errors.New("argon2id parallelism must be between 1 and 255")
+	case h.Memory < 8*uint32(h.Parallelism) || h.Memory > maxArgon2Memory:
+		return fmt.Errorf("argon2id memory must be between 8*parallelism (%d) and %d KiB", 8*uint32(h.Parallelism), maxArgon2Memory)
+	case h.SaltLength < minArgon2SaltLength:
+		return fmt.Errorf("argon2id salt must be at least %d bytes", minArgon2SaltLength)
+	case h.KeyLength < minArgon2KeyLength || h.KeyLength > maxArgon2KeyLength:
+		return fmt.Errorf("argon2id key length must be between %d and %d bytes", minArgon2KeyLength, maxArgon2KeyLength)
+	}
+	return nil
+}
+
+// decodeArgon2id parses a PHC-style argon2id string into its parameters, salt and key.
+func decodeArgon2id(encoded string) (*Argon2idHasher, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, nil, nil, errUnknownHashFormat
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return nil, nil, nil, err
+	}
+	if version != argon2.Version {
+		return nil, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+	p := &Argon2idHasher{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return nil, nil, nil, err
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	p.SaltLength, p.KeyLength = uint32(len(salt)), uint32(len(key))
+	if err := p.validate(); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid argon2id hash: %w", err)
+	}
+	return p, salt, key, nil
+}
+
+// BcryptHasher produces standard modular-crypt bcrypt strings ($2a$<cost>$...).
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	b, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (h *BcryptHasher) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost < h.Cost
+}
+
+// UpgradingHasher hashes new passwords with the preferred algorithm and verifies every
+// format we have ever stored: argon2id, bcrypt and legacy unsalted SHA-256 hex digests.
+// Anything that is not in the preferred format with at least the configured strength
+// is reported by NeedsRehash.
+type UpgradingHasher struct {
+	preferred PasswordHasher
+	argon2id  *Argon2idHasher
+	bcrypt    *BcryptHasher
+}
+
+func NewUpgradingHasher(preferred string, argon2id *Argon2idHasher, bc *BcryptHasher) (*UpgradingHasher, error) {
+	h := &UpgradingHasher{argon2id: argon2id, bcrypt: bc}
+	switch preferred {
+	case "argon2id":
+		h.preferred = argon2id
+	case "bcrypt":
+		h.preferred = bc
+	default:
+		return nil, fmt.Errorf("unsupported password hash algorithm %q", preferred)
+	}
+	return h, nil
+}
+
+// NewPasswordHasherFromEnv builds the hasher from PASSWORD_HASH_ALGORITHM, ARGON2_* and BCRYPT_COST.
+func NewPasswordHasherFromEnv() (*UpgradingHasher, error) {
+	a := &Argon2idHasher{Memory: 64 * 1024, Iterations: 3, Parallelism: 2, SaltLength: 16, KeyLength: 32}
+	memory, err := envUint("ARGON2_MEMORY_KIB", uint64(a.Memory), maxArgon2Memory)
+	if err != nil {
+		return nil, err
+	}
+	iterations, err := envUint("ARGON2_ITERATIONS", uint64(a.Iterations), maxArgon2Iterations)
+	if err != nil {
+		return nil, err
+	}
+	parallelism, err := envUint("ARGON2_PARALLELISM", uint64(a.Parallelism), 255)
+	if err != nil {
+		return nil, err
+	}
+	a.Memory, a.Iterations, a.Parallelism = uint32(memory), uint32(iterations), uint8(parallelism)
+	if err := a.validate(); err != nil {
+		return nil, err
+	}
+	cost, err := envUint("BCRYPT_COST", 12, uint64(bcrypt.MaxCost))
+	if err != nil {
+		return nil, err
+	}
+	if int(cost) < bcrypt.MinCost {
+		return nil, fmt.Errorf("BCRYPT_COST must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	b := &BcryptHasher{Cost: int(cost)}
+	algo := os.Getenv("PASSWORD_HASH_ALGORITHM")
+	if algo == "" {
+		algo = "argon2id"
+	}
+	return NewUpgradingHasher(algo, a, b)
+}
+
+func (h *UpgradingHasher) Hash(password string) (string, error) {
+	return h.preferred.Hash(password)
+}
+
+func (h *UpgradingHasher) Verify(password, encoded string) (bool, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return h.argon2id.Verify(password, encoded)
+	case isBcryptHash(encoded):
+		return h.bcrypt.Verify(password, encoded)
+	case isLegacySHA256(encoded):
+		sum := sha256.Sum256([]byte(password))
+		return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(strings.ToLower(encoded))) == 1, nil
+	default:
+		return false, errUnknownHashFormat
+	}
+}
+
+func (h *UpgradingHasher) NeedsRehash(encoded string) bool {
+	switch h.preferred.(type) {
+	case *Argon2idHasher:
+		if !strings.HasPrefix(encoded, "$argon2id$") {
+			return true
+		}
+	case *BcryptHasher:
+		if !isBcryptHash(encoded) {
+			return true
+		}
+	}
+	return h.preferred.NeedsRehash(encoded)
+}
+
+func isBcryptHash(s string) bool {
+	return strings.HasPrefix(s, "$2a$") || strings.HasPrefix(s, "$2b$") || strings.HasPrefix(s, "$2y$")
+}
+
+func isLegacySHA256(s string) bool {
+	if len(s) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// envUint reads a positive integer of at most max from key, or returns def when it is unset
+func envUint(key string, def, max uint64) (uint64, error) {
+	s := os.Getenv(key)
+	if s == "" {
+		return def, nil
+	}
+	v, err := strconv.ParseUint(s, 10, 32)
+	if err != nil || v == 0 || v > max {
+		return 0, fmt.Errorf("%s must be an integer between 1 and %d, got %q", key, max, s)
+	}
+	return v, nil
+}
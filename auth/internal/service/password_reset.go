@@ -0,0 +1,84 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/repository"
+)
+
+// passwordResetLimit caps reset emails per account and hour
+const passwordResetLimit = 3
+
+// PasswordResetTTLFromEnv returns the lifetime of reset links (PASSWORD_RESET_TTL, default 1h)
+func PasswordResetTTLFromEnv() time.Duration {
+	if s := os.Getenv("PASSWORD_RESET_TTL"); s != "" {
+		if ttl, err := time.ParseDuration(s); err == nil && ttl > 0 {
+			return ttl
+		}
+	}
+	return time.Hour
+}
+
+// RequestPasswordReset issues a reset link for the active account with the given email.
+// Unless EMAIL_VERIFICATION_POLICY is off, only verified addresses get one: an
+// unverified address may not belong to the user, e.g. after an email change. Like
+// ResendVerification it returns a nil user when nothing is sent (unknown or unverified
+// address, deactivated account, or too many recent requests) so the caller's answer
+// never reveals whether the account exists.
+func (s *AuthService) RequestPasswordReset(email string) (*models.User, *EmailLink, error) {
+	user, err := s.repo.GetByEmail(email)
+	if err != nil || !user.IsActive || (!user.EmailVerified && s.verification.Policy != EmailVerificationOff) {
+		return nil, nil, nil
+	}
+	recent, err := s.oneTimeTokens.CountSince(user.ID, models.TokenPurposePasswordReset, time.Now().Add(-time.Hour).UTC())
+	if err != nil {
+		return nil, nil, err
+	}
+	if recent >= passwordResetLimit {
+		log.Printf("password reset limit reached for user %d", user.ID)
+		return nil, nil, nil
+	}
+	token, expiresAt, err := s.issueOneTimeToken(user.ID, models.TokenPurposePasswordReset, s.passwordReset)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, &EmailLink{Link: s.appLink("/reset-password", token), ExpiresAt: expiresAt}, nil
+}
+
+// ResetPassword redeems a reset token, stores the new password and revokes every
+// token of the user, so sessions opened with the old password end. It returns the user.
+func (s *AuthService) ResetPassword(token, newPassword string) (*models.User, error) {
+	stored, err := s.oneTimeTokens.Consume(hashToken(token), models.TokenPurposePasswordReset)
+	if err != nil {
+		if errors.Is(err, repository.ErrTokenNotUsable) {
+			return nil, ErrInvalidResetToken
+		}
+		return nil, err
+	}
+	user, err := s.repo.GetByID(stored.UserID)
+	if err != nil {
+		return nil, ErrInvalidResetToken
+	}
+	if !user.IsActive {
+		return nil, ErrInvalidResetToken
+	}
+	hash, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdatePasswordHash(user.ID, hash); err != nil {
+		return nil, err
+	}
+	// Proving control of the mailbox also lifts a login lock
+	if err := s.guard.Unlock(user.Username); err != nil {
+		log.Printf("failed to unlock user %d after password reset: %v", user.ID, err)
+	}
+	if err := s.RevokeAllSessions(user.ID, models.RevokeReasonPasswordReset); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
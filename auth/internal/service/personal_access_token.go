@@ -0,0 +1,103 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// personalTokenTouchInterval limits how often a token's last-used time is written,
+// so a busy CI job does not cause a database write per request
+const personalTokenTouchInterval = time.Minute
+
+// CreatePersonalAccessToken creates a scoped token for userID. The token is returned
+// once; only its hash is stored.
+func (s *AuthService) CreatePersonalAccessToken(userID int, req models.CreatePersonalAccessTokenRequest) (*models.PersonalAccessTokenResponse, error) {
+	scopes, err := parseScopes(strings.Join(req.Scopes, " "))
+	if err != nil || len(scopes) == 0 {
+		return nil, ErrInvalidScope
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, ErrExpiryInPast
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	token := models.PersonalAccessTokenPrefix + secret
+	row := &models.PersonalAccessToken{UserID: userID, Name: strings.TrimSpace(req.Name), TokenHash: hashToken(token), TokenPrefix: token[:len(models.PersonalAccessTokenPrefix)+8], Scope: strings.Join(scopes, " ")}
+	if req.ExpiresAt != nil {
+		expiresAt := req.ExpiresAt.UTC()
+		row.ExpiresAt = &expiresAt
+	}
+	if err := s.personalTokens.Create(row); err != nil {
+		return nil, err
+	}
+	resp := row.ToResponse()
+	resp.Token = token
+	return &resp, nil
+}
+
+// ListPersonalAccessTokens returns a user's tokens without their secrets
+func (s *AuthService) ListPersonalAccessTokens(userID int) ([]models.PersonalAccessTokenResponse, error) {
+	tokens, err := s.personalTokens.ListForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]models.PersonalAccessTokenResponse, 0, len(tokens))
+	for i := range tokens {
+		responses = append(responses, tokens[i].ToResponse())
+	}
+	return responses, nil
+}
+
+// DeletePersonalAccessToken revokes one of the user's tokens immediately
+func (s *AuthService) DeletePersonalAccessToken(userID int, id int64) error {
+	deleted, err := s.personalTokens.Delete(userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrPersonalTokenNotFound
+	}
+	return nil
+}
+
+// validatePersonalAccessToken looks up a personal access token and returns claims
+// equivalent to those of an access JWT, with typ "personal" and the token's scope.
+// Expired tokens and tokens of deactivated users are rejected.
+func (s *AuthService) validatePersonalAccessToken(token string) (*models.Claims, error) {
+	row, err := s.personalTokens.GetByHash(hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if row == nil || (row.ExpiresAt != nil && now.After(*row.ExpiresAt)) {
+		return nil, errors.New("invalid token")
+	}
+	user, err := s.repo.GetByID(row.UserID)
+	if err != nil || !user.IsActive {
+		return nil, errors.New("invalid token")
+	}
+	if row.LastUsedAt == nil || now.Sub(*row.LastUsedAt) > personalTokenTouchInterval {
+		if err := s.personalTokens.TouchLastUsed(row.ID, now.UTC()); err != nil {
+			log.Printf("Failed to record use of personal access token %d: %v", row.ID, err)
+		}
+	}
+	perms, err := s.permissionsFor(user.Role)
+	if err != nil {
+		return nil, err
+	}
+	claims := &models.Claims{UserID: user.ID, Username: user.Username, Role: user.Role, Permissions: perms, TokenType: models.TokenTypePersonal, Scope: row.Scope, Restricted: s.isRestricted(user), Timezone: user.Timezone,
+		RegisteredClaims: jwt.RegisteredClaims{ID: strconv.FormatInt(row.ID, 10), Subject: strconv.Itoa(user.ID), IssuedAt: jwt.NewNumericDate(row.CreatedAt), Issuer: "auth-service", Audience: jwt.ClaimStrings{models.AudienceAPI}}}
+	if row.ExpiresAt != nil {
+		claims.ExpiresAt = jwt.NewNumericDate(*row.ExpiresAt)
+	}
+	return claims, nil
+}
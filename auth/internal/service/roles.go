@@ -0,0 +1,146 @@
+package service
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// ListRoles returns every role with its permissions
+func (s *AuthService) ListRoles() ([]models.RoleResponse, error) {
+	roles, err := s.roles.List()
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]models.RoleResponse, 0, len(roles))
+	for i := range roles {
+		responses = append(responses, roles[i].ToResponse())
+	}
+	return responses, nil
+}
+
+// GetRole returns a single role
+func (s *AuthService) GetRole(name string) (*models.RoleResponse, error) {
+	role, err := s.roles.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, ErrRoleNotFound
+	}
+	resp := role.ToResponse()
+	return &resp, nil
+}
+
+// RoleExists reports whether users can be given the named role
+func (s *AuthService) RoleExists(name string) (bool, error) {
+	role, err := s.roles.Get(name)
+	return role != nil, err
+}
+
+// CreateRole adds a role with the given permissions
+func (s *AuthService) CreateRole(req models.CreateRoleRequest) (*models.RoleResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if !models.ValidRoleName(name) {
+		return nil, ErrInvalidRoleName
+	}
+	perms, err := parsePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.roles.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrRoleExists
+	}
+	role := &models.Role{Name: name, Description: strings.TrimSpace(req.Description), Permissions: rolePermissions(name, perms)}
+	if err := s.roles.Create(role); err != nil {
+		return nil, err
+	}
+	return s.GetRole(name)
+}
+
+// UpdateRole changes a role's description and, when given, replaces its permissions.
+// The admin role always keeps every permission. Tokens already issued keep the
+// permissions they were signed with until they are refreshed.
+func (s *AuthService) UpdateRole(name string, req models.UpdateRoleRequest) (*models.RoleResponse, error) {
+	role, err := s.roles.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, ErrRoleNotFound
+	}
+	if req.Permissions != nil {
+		if role.Name == models.RoleAdmin {
+			return nil, ErrRoleLocked
+		}
+		perms, err := parsePermissions(req.Permissions)
+		if err != nil {
+			return nil, err
+		}
+		role.Permissions = rolePermissions(name, perms)
+	}
+	if req.Description != nil {
+		role.Description = strings.TrimSpace(*req.Description)
+	}
+	if err := s.roles.Update(role); err != nil {
+		return nil, err
+	}
+	return s.GetRole(name)
+}
+
+// DeleteRole removes a role that is neither built in nor assigned to any user
+func (s *AuthService) DeleteRole(name string) error {
+	role, err := s.roles.Get(name)
+	if err != nil {
+		return err
+	}
+	if role == nil {
+		return ErrRoleNotFound
+	}
+	if role.BuiltIn {
+		return ErrRoleBuiltIn
+	}
+	n, err := s.roles.CountUsers(name)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrRoleInUse
+	}
+	return s.roles.Delete(name)
+}
+
+// permissionsFor returns the permissions embedded in tokens of a user with role
+func (s *AuthService) permissionsFor(role string) ([]string, error) {
+	return s.roles.Permissions(role)
+}
+
+// RolePermissions returns the permissions a role grants; unknown roles grant none
+func (s *AuthService) RolePermissions(role string) ([]string, error) {
+	return s.permissionsFor(role)
+}
+
+// parsePermissions checks every permission against the catalog and removes duplicates
+func parsePermissions(perms []string) ([]string, error) {
+	unique := uniqueFields(perms)
+	for _, p := range unique {
+		if _, ok := models.PermissionDescriptions[p]; !ok {
+			return nil, ErrUnknownPermission
+		}
+	}
+	sort.Strings(unique)
+	return unique, nil
+}
+
+func rolePermissions(role string, perms []string) []models.RolePermission {
+	rows := make([]models.RolePermission, 0, len(perms))
+	for _, p := range perms {
+		rows = append(rows, models.RolePermission{Role: role, Permission: p})
+	}
+	return rows
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// TeamDirectory manages the teams in the team service that back SCIM groups; see
+// clients.ServiceClient. actorID is the user who made the change, for the team events.
+type TeamDirectory interface {
+	CreateTeam(name string, ownerID, actorID int, serviceToken string) (int, error)
+	RenameTeam(teamID int, name string, actorID int, serviceToken string) error
+	DeleteTeam(teamID, actorID int, serviceToken string) error
+	AddTeamMember(teamID, userID, actorID int, serviceToken string) error
+	RemoveTeamMember(teamID, userID, actorID int, serviceToken string) error
+}
+
+// SetTeamDirectory sets where the teams of SCIM groups are managed
+func (s *AuthService) SetTeamDirectory(teams TeamDirectory) {
+	s.teams = teams
+}
+
+// SCIMGroups returns a page of groups with their members, optionally only those whose
+// column (display_name or external_id) equals value, and the total number of matches
+func (s *AuthService) SCIMGroups(column, value string, offset, limit int) ([]models.SCIMGroup, int64, error) {
+	groups, total, err := s.groups.List(column, value, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range groups {
+		if groups[i].Members, err = s.groups.Members(groups[i].ID); err != nil {
+			return nil, 0, err
+		}
+	}
+	return groups, total, nil
+}
+
+// SCIMGroup returns a group with its members
+func (s *AuthService) SCIMGroup(id string) (*models.SCIMGroup, error) {
+	group, err := s.groups.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, ErrGroupNotFound
+	}
+	if group.Members, err = s.groups.Members(id); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// CreateSCIMGroup creates a group and the team backing it. The team is owned by actorID,
+// the user whose token pushed the group; members join it as regular members.
+func (s *AuthService) CreateSCIMGroup(actorID int, name, externalID string, members []int) (*models.SCIMGroup, error) {
+	name = strings.TrimSpace(name)
+	if err := s.checkSCIMGroup("", name, members); err != nil {
+		return nil, err
+	}
+	token, err := s.teamServiceToken()
+	if err != nil {
+		return nil, err
+	}
+	teamID, err := s.teams.CreateTeam(name, actorID, actorID, token)
+	if err != nil {
+		return nil, teamServiceError("create team", err)
+	}
+	id, err := newFamilyID()
+	if err != nil {
+		return nil, err
+	}
+	group := &models.SCIMGroup{ID: id, DisplayName: name, ExternalID: externalID, TeamID: teamID, OwnerID: actorID}
+	if err := s.groups.Create(group); err != nil {
+		if err := s.teams.DeleteTeam(teamID, actorID, token); err != nil {
+			log.Printf("failed to delete team %d of a group that could not be stored: %v", teamID, err)
+		}
+		return nil, err
+	}
+	if err := s.syncSCIMGroupMembers(group, nil, members, actorID, token); err != nil {
+		return nil, err
+	}
+	return s.SCIMGroup(id)
+}
+
+// ReplaceSCIMGroup sets the name, external ID and members of a group and updates its
+// team to match
+func (s *AuthService) ReplaceSCIMGroup(actorID int, id, name, externalID string, members []int) (*models.SCIMGroup, error) {
+	group, err := s.SCIMGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	name = strings.TrimSpace(name)
+	if err := s.checkSCIMGroup(id, name, members); err != nil {
+		return nil, err
+	}
+	token, err := s.teamServiceToken()
+	if err != nil {
+		return nil, err
+	}
+	if name != group.DisplayName {
+		if err := s.teams.RenameTeam(group.TeamID, name, actorID, token); err != nil {
+			return nil, teamServiceError("rename team", err)
+		}
+	}
+	group.DisplayName, group.ExternalID = name, externalID
+	if err := s.groups.Update(group); err != nil {
+		return nil, err
+	}
+	if err := s.syncSCIMGroupMembers(group, group.Members, members, actorID, token); err != nil {
+		return nil, err
+	}
+	return s.SCIMGroup(id)
+}
+
+// DeleteSCIMGroup deletes a group and its team
+func (s *AuthService) DeleteSCIMGroup(actorID int, id string) error {
+	group, err := s.groups.Get(id)
+	if err != nil {
+		return err
+	}
+	if group == nil {
+		return ErrGroupNotFound
+	}
+	token, err := s.teamServiceToken()
+	if err != nil {
+		return err
+	}
+	if err := s.teams.DeleteTeam(group.TeamID, actorID, token); err != nil {
+		return teamServiceError("delete team", err)
+	}
+	return s.groups.Delete(id)
+}
+
+// RemoveUserFromSCIMGroups drops an erased user from every group; the team service
+// removes them from the teams when it sees user.deleted
+func (s *AuthService) RemoveUserFromSCIMGroups(userID int) error {
+	return s.groups.RemoveUser(userID)
+}
+
+// checkSCIMGroup validates the name and members of a group other than exceptID
+func (s *AuthService) checkSCIMGroup(exceptID, name string, members []int) error {
+	if name == "" {
+		return ErrGroupNameRequired
+	}
+	taken, err := s.groups.ExistsByDisplayName(name, exceptID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrGroupNameTaken
+	}
+	for _, userID := range members {
+		if _, err := s.repo.GetByID(userID); err != nil {
+			return ErrMemberNotFound
+		}
+	}
+	if s.teams == nil {
+		return ErrTeamServiceUnavailable
+	}
+	return nil
+}
+
+// syncSCIMGroupMembers adds and removes members of a group and its team so that they
+// match desired. The team's owner stays in the team when removed from the group.
+func (s *AuthService) syncSCIMGroupMembers(group *models.SCIMGroup, current, desired []int, actorID int, token string) error {
+	want := map[int]bool{}
+	for _, userID := range desired {
+		want[userID] = true
+	}
+	have := map[int]bool{}
+	for _, userID := range current {
+		have[userID] = true
+		if want[userID] {
+			continue
+		}
+		if err := s.teams.RemoveTeamMember(group.TeamID, userID, actorID, token); err != nil {
+			return teamServiceError("remove team member", err)
+		}
+		if err := s.groups.RemoveMember(group.ID, userID); err != nil {
+			return err
+		}
+	}
+	added := make([]int, 0, len(want))
+	for userID := range want {
+		if !have[userID] {
+			added = append(added, userID)
+		}
+	}
+	sort.Ints(added)
+	for _, userID := range added {
+		if err := s.teams.AddTeamMember(group.TeamID, userID, actorID, token); err != nil {
+			return teamServiceError("add team member", err)
+		}
+		if err := s.groups.AddMember(group.ID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// teamServiceToken issues the service token the team service is called with
+func (s *AuthService) teamServiceToken() (string, error) {
+	token, err := s.signServiceToken(selfClientID)
+	if err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+// teamServiceError logs why a call to the team service failed and returns the error
+// reported to the caller
+func teamServiceError(action string, err error) error {
+	log.Printf("SCIM group sync: failed to %s: %v", action, err)
+	return ErrTeamServiceUnavailable
+}
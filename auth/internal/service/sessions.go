@@ -0,0 +1,133 @@
+package service
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// ListSessions returns a user's active login sessions; currentID marks the session
+// of the calling access token
+func (s *AuthService) ListSessions(userID int, currentID string) ([]models.SessionResponse, error) {
+	sessions, err := s.sessions.ListForUser(userID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]models.SessionResponse, 0, len(sessions))
+	for i := range sessions {
+		responses = append(responses, sessions[i].ToResponse(sessions[i].ID == currentID))
+	}
+	return responses, nil
+}
+
+// RevokeSession ends one of a user's sessions: its refresh tokens are revoked and the
+// access tokens issued to the device stop working (see checkSession and OnSessionRevoked).
+func (s *AuthService) RevokeSession(userID int, sessionID string) error {
+	session, err := s.sessions.Get(sessionID)
+	if err != nil {
+		return err
+	}
+	if session == nil || session.UserID != userID {
+		return ErrSessionNotFound
+	}
+	return s.revokeSession(sessionID)
+}
+
+// OnTokensRevoked registers a callback that runs after all of a user's tokens were
+// revoked, e.g. to publish a user.tokens_revoked event
+func (s *AuthService) OnTokensRevoked(fn func(userID, version int, reason string)) {
+	s.onTokensRevoked = fn
+}
+
+// RevokeAllSessions signs a user out everywhere and at once: bumping the user's token
+// version invalidates every access token issued so far, every refresh token (including
+// those held by OAuth clients) is revoked and all sessions are removed. reason is one
+// of the models.RevokeReason constants.
+func (s *AuthService) RevokeAllSessions(userID int, reason string) error {
+	version, err := s.repo.BumpTokenVersion(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.tokens.RevokeAllForUser(userID); err != nil {
+		return err
+	}
+	if err := s.sessions.DeleteForUser(userID); err != nil {
+		return err
+	}
+	if s.onTokensRevoked != nil {
+		s.onTokensRevoked(userID, version, reason)
+	}
+	return nil
+}
+
+// OnSessionRevoked registers a callback that runs after a single session was ended,
+// e.g. to publish a user.session_revoked event. Access tokens of the session may be
+// presented until until, when the last of them expires.
+func (s *AuthService) OnSessionRevoked(fn func(userID int, sessionID string, until time.Time)) {
+	s.onSessionRevoked = fn
+}
+
+// revokeSession revokes a refresh token family and removes its session, if any
+func (s *AuthService) revokeSession(familyID string) error {
+	if err := s.tokens.RevokeFamily(familyID); err != nil {
+		return err
+	}
+	session, err := s.sessions.Get(familyID)
+	if err != nil {
+		return err
+	}
+	if session == nil {
+		// OAuth grants have no session and their access tokens no session ID
+		return nil
+	}
+	if err := s.sessions.Delete(familyID); err != nil {
+		return err
+	}
+	if s.onSessionRevoked != nil {
+		s.onSessionRevoked(session.UserID, familyID, time.Now().Add(s.accessTTL))
+	}
+	return nil
+}
+
+// createSession records a new login from the client's device
+func (s *AuthService) createSession(userID int, familyID string, client models.ClientInfo) error {
+	now := time.Now().UTC()
+	return s.sessions.Create(&models.Session{ID: familyID, UserID: userID, Device: describeDevice(client.UserAgent), UserAgent: truncate(client.UserAgent, 255),
+		IPAddress: truncate(client.IPAddress, 45), LastSeenAt: now, ExpiresAt: now.Add(s.refreshTTL)})
+}
+
+// touchSession records a token refresh; failures only affect the session listing
+func (s *AuthService) touchSession(familyID string, client models.ClientInfo) {
+	now := time.Now().UTC()
+	if err := s.sessions.Touch(familyID, truncate(client.IPAddress, 45), truncate(client.UserAgent, 255), now, now.Add(s.refreshTTL)); err != nil {
+		log.Printf("failed to update session %s: %v", familyID, err)
+	}
+}
+
+// describeDevice turns a user agent into a short label such as "Firefox on Linux"
+func describeDevice(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+	browser := "Unknown browser"
+	for _, b := range []struct{ token, name string }{
+		{"Edg/", "Edge"}, {"OPR/", "Opera"}, {"Firefox/", "Firefox"}, {"Chrome/", "Chrome"},
+		{"Safari/", "Safari"}, {"curl/", "curl"}, {"PostmanRuntime/", "Postman"},
+	} {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	for _, platform := range []struct{ token, name string }{
+		{"iPhone", "iOS"}, {"iPad", "iPadOS"}, {"Android", "Android"}, {"Windows", "Windows"},
+		{"Mac OS X", "macOS"}, {"CrOS", "ChromeOS"}, {"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, platform.token) {
+			return browser + " on " + platform.name
+		}
+	}
+	return browser
+}
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/apperr"
+	"github.com/VerSysLabTin23/TodolistProject/auth/internal/models"
+)
+
+// MaxUserImportRows bounds the number of users in one import
+const MaxUserImportRows = 5000
+
+// userImportStaleAfter is how long an import may run before it is considered interrupted
+const userImportStaleAfter = 30 * time.Minute
+
+// userImportRetention is how long the report of a finished import is kept
+const userImportRetention = 30 * 24 * time.Hour
+
+// importColumns are the CSV columns of a user import, keyed by their lowercased header
+var importColumns = map[string]func(row *models.ImportUserRow, value string){
+	"username":  func(row *models.ImportUserRow, v string) { row.Username = v },
+	"email":     func(row *models.ImportUserRow, v string) { row.Email = v },
+	"password":  func(row *models.ImportUserRow, v string) { row.Password = v },
+	"firstname": func(row *models.ImportUserRow, v string) { row.FirstName = v },
+	"lastname":  func(row *models.ImportUserRow, v string) { row.LastName = v },
+	"role":      func(row *models.ImportUserRow, v string) { row.Role = v },
+}
+
+// importFormatError reports a CSV or JSON document that could not be read as a list of users
+func importFormatError(message string) error {
+	return &apperr.Error{Kind: apperr.Invalid, Code: "INVALID_IMPORT", Message: message}
+}
+
+// OnUserImported registers a callback that runs for every user an import created,
+// e.g. to publish a user.created event
+func (s *AuthService) OnUserImported(fn func(user *models.User)) {
+	s.onUserImported = fn
+}
+
+// ParseUserImport reads the users of an import from a CSV document with a header row
+// (username, email, password, firstName, lastName, role in any order) or a JSON
+// document holding an array of users or an object with a "users" array
+func ParseUserImport(format string, data []byte) ([]models.ImportUserRow, error) {
+	var rows []models.ImportUserRow
+	switch format {
+	case "csv":
+		r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+		r.TrimLeadingSpace = true
+		header, err := r.Read()
+		if err == io.EOF {
+			return nil, importFormatError("The CSV document is empty")
+		}
+		if err != nil {
+			return nil, importFormatError("Invalid CSV: " + err.Error())
+		}
+		setters := make([]func(*models.ImportUserRow, string), len(header))
+		seen := map[string]bool{}
+		for i, name := range header {
+			key := strings.ToLower(strings.TrimSpace(name))
+			set, ok := importColumns[key]
+			if !ok {
+				return nil, importFormatError(fmt.Sprintf("Unknown CSV column %q", name))
+			}
+			if seen[key] {
+				return nil, importFormatError(fmt.Sprintf("Duplicate CSV column %q", name))
+			}
+			seen[key] = true
+			setters[i] = set
+		}
+		if !seen["username"] || !seen["email"] {
+			return nil, importFormatError("The CSV header needs username and email columns")
+		}
+		for {
+			record, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, importFormatError("Invalid CSV: " + err.Error())
+			}
+			var row models.ImportUserRow
+			for i, value := range record {
+				setters[i](&row, value)
+			}
+			rows = append(rows, row)
+			if len(rows) > MaxUserImportRows {
+				break
+			}
+		}
+	case "json":
+		trimmed := bytes.TrimSpace(data)
+		if len(trimmed) > 0 && trimmed[0] == '{' {
+			var doc struct {
+				Users []models.ImportUserRow `json:"users"`
+			}
+			if err := json.Unmarshal(trimmed, &doc); err != nil {
+				return nil, importFormatError("Invalid JSON: " + err.Error())
+			}
+			rows = doc.Users
+		} else if err := json.Unmarshal(trimmed, &rows); err != nil {
+			return nil, importFormatError("Invalid JSON: " + err.Error())
+		}
+	default:
+		return nil, importFormatError("Send the users as text/csv or application/json")
+	}
+	if len(rows) == 0 {
+		return nil, importFormatError("The import contains no users")
+	}
+	if len(rows) > MaxUserImportRows {
+		return nil, importFormatError(fmt.Sprintf("An import may contain at most %d users", MaxUserImportRows))
+	}
+	return rows, nil
+}
+
+// StartUserImport stores the rows of an import by actorID and runs it in the background.
+// A dry run only validates the rows and reports which users would be created.
+func (s *AuthService) StartUserImport(actorID int, rows []models.ImportUserRow, dryRun bool) (*models.UserImport, error) {
+	for i := range rows {
+		rows[i].Username = strings.TrimSpace(rows[i].Username)
+		rows[i].Email = strings.TrimSpace(rows[i].Email)
+		rows[i].FirstName = strings.TrimSpace(rows[i].FirstName)
+		rows[i].LastName = strings.TrimSpace(rows[i].LastName)
+		rows[i].Role = strings.TrimSpace(rows[i].Role)
+		if rows[i].Role == "" {
+			rows[i].Role = models.RoleUser
+		}
+	}
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return nil, err
+	}
+	id, err := newFamilyID()
+	if err != nil {
+		return nil, err
+	}
+	imp := &models.UserImport{ID: id, ActorID: actorID, DryRun: dryRun, Status: models.UserImportPending, Total: len(rows), Rows: data}
+	if err := s.imports.Create(imp); err != nil {
+		return nil, err
+	}
+	go s.runUserImport(id)
+	return imp, nil
+}
+
+// UserImport returns an import with its report
+func (s *AuthService) UserImport(id string) (*models.UserImport, error) {
+	imp, err := s.imports.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if imp == nil {
+		return nil, ErrImportNotFound
+	}
+	return imp, nil
+}
+
+// ProcessUserImports runs imports that were not started right away, gives up on imports
+// whose instance stopped while running them, and drops old reports
+func (s *AuthService) ProcessUserImports(now time.Time) {
+	if n, err := s.imports.FailStale(now.Add(-userImportStaleAfter), now); err != nil {
+		log.Printf("failed to check for interrupted user imports: %v", err)
+	} else if n > 0 {
+		log.Printf("%d user import(s) were interrupted", n)
+	}
+	if _, err := s.imports.DeleteCompletedBefore(now.Add(-userImportRetention)); err != nil {
+		log.Printf("failed to purge old user imports: %v", err)
+	}
+	imps, err := s.imports.ListPending()
+	if err != nil {
+		log.Printf("failed to list user imports: %v", err)
+		return
+	}
+	for _, imp := range imps {
+		s.runUserImport(imp.ID)
+	}
+}
+
+// runUserImport claims an import, validates every row and, unless it is a dry run,
+// creates the users of the valid rows
+func (s *AuthService) runUserImport(id string) {
+	claimed, err := s.imports.Claim(id, time.Now().UTC())
+	if err != nil || !claimed {
+		if err != nil {
+			log.Printf("failed to claim user import %s: %v", id, err)
+		}
+		return
+	}
+	imp, err := s.imports.Get(id)
+	if err != nil || imp == nil {
+		log.Printf("failed to load user import %s: %v", id, err)
+		return
+	}
+	var rows []models.ImportUserRow
+	if err := json.Unmarshal(imp.Rows, &rows); err != nil {
+		s.failUserImport(id, "Could not read the rows", err)
+		return
+	}
+	report := make([]models.ImportRowResult, len(rows))
+	succeeded, failed := 0, 0
+	seenUsernames, seenEmails := map[string]bool{}, map[string]bool{}
+	roles := map[string]bool{}
+	for i, row := range rows {
+		result := models.ImportRowResult{Row: i + 1, Username: row.Username}
+		result.Errors, err = s.validateImportRow(row, seenUsernames, seenEmails, roles)
+		if err != nil {
+			s.failUserImport(id, "Could not validate the rows", err)
+			return
+		}
+		switch {
+		case len(result.Errors) > 0:
+			result.Status = models.ImportRowFailed
+		case imp.DryRun:
+			result.Status = models.ImportRowValid
+		default:
+			user, err := s.createImportedUser(row)
+			if err != nil {
+				log.Printf("user import %s: failed to create row %d: %v", id, i+1, err)
+				result.Errors = []string{"Failed to create the user"}
+				result.Status = models.ImportRowFailed
+				break
+			}
+			result.Status = models.ImportRowCreated
+			result.UserID = user.ID
+			if s.onUserImported != nil {
+				s.onUserImported(user)
+			}
+		}
+		if result.Status == models.ImportRowFailed {
+			failed++
+		} else {
+			succeeded++
+		}
+		report[i] = result
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		s.failUserImport(id, "Could not create the report", err)
+		return
+	}
+	if err := s.imports.Complete(id, succeeded, failed, data, time.Now().UTC()); err != nil {
+		log.Printf("failed to store report of user import %s: %v", id, err)
+		return
+	}
+	log.Printf("user import %s by user %d finished: %d ok, %d failed (dry run: %t)", id, imp.ActorID, succeeded, failed, imp.DryRun)
+}
+
+// validateImportRow returns what is wrong with a row; seen usernames and emails and
+// known roles are remembered across the rows of an import
+func (s *AuthService) validateImportRow(row models.ImportUserRow, seenUsernames, seenEmails, roles map[string]bool) ([]string, error) {
+	var problems []string
+	if n := utf8.RuneCountInString(row.Username); n < 3 || n > 50 {
+		problems = append(problems, "username must have 3 to 50 characters")
+	} else if key := strings.ToLower(row.Username); seenUsernames[key] {
+		problems = append(problems, "username appears more than once in the import")
+	} else {
+		seenUsernames[key] = true
+		exists, err := s.repo.ExistsByUsername(row.Username)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			problems = append(problems, "username already exists")
+		}
+	}
+	if addr, err := mail.ParseAddress(row.Email); err != nil || addr.Address != row.Email {
+		problems = append(problems, "email is not a valid address")
+	} else if key := strings.ToLower(row.Email); seenEmails[key] {
+		problems = append(problems, "email appears more than once in the import")
+	} else {
+		seenEmails[key] = true
+		exists, err := s.repo.ExistsByEmail(row.Email)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			problems = append(problems, "email already exists")
+		}
+	}
+	if row.Password != "" && len(row.Password) < 6 {
+		problems = append(problems, "password must have at least 6 characters")
+	}
+	known, checked := roles[row.Role]
+	if !checked {
+		exists, err := s.RoleExists(row.Role)
+		if err != nil {
+			return nil, err
+		}
+		roles[row.Role] = exists
+		known = exists
+	}
+	if !known {
+		problems = append(problems, fmt.Sprintf("role %q does not exist", row.Role))
+	}
+	return problems, nil
+}
+
+// createImportedUser creates the user of a valid row. Like accounts an admin creates
+// they skip email verification; without a password the user sets one through the
+// password reset.
+func (s *AuthService) createImportedUser(row models.ImportUserRow) (*models.User, error) {
+	var hash string
+	if row.Password != "" {
+		var err error
+		if hash, err = s.hasher.Hash(row.Password); err != nil {
+			return nil, err
+		}
+	}
+	now := time.Now().UTC()
+	user := &models.User{Username: row.Username, Email: row.Email, PasswordHash: hash, FirstName: row.FirstName, LastName: row.LastName,
+		Role: row.Role, IsActive: true, EmailVerified: true, EmailVerifiedAt: &now}
+	if err := s.repo.Create(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// failUserImport records a failed import; reason is shown to the admin, err is only logged
+func (s *AuthService) failUserImport(id, reason string, err error) {
+	log.Printf("user import %s failed: %s: %v", id, reason, err)
+	if err := s.imports.Fail(id, reason, time.Now().UTC()); err != nil {
+		log.Printf("failed to record failure of user import %s: %v", id, err)
+	}
+}
@@ -0,0 +1,107 @@
+package totp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 seed of RFC 6238 appendix B, "12345678901234567890", in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// TestRFC6238Vectors checks the SHA1 test vectors of RFC 6238 appendix B, truncated to
+// six digits
+func TestRFC6238Vectors(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := CodeAt(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	tests := []struct {
+		name     string
+		code     string
+		at       time.Time
+		skew     int
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", "050471", now, 1, Step(now), true},
+		{"spaces", " 050 471 ", now, 1, Step(now), true},
+		{"previous step within skew", "050471", now.Add(Period), 1, Step(now), true},
+		{"next step within skew", "050471", now.Add(-Period), 1, Step(now), true},
+		{"outside skew", "050471", now.Add(2 * Period), 1, 0, false},
+		{"no skew", "050471", now.Add(Period), 0, 0, false},
+		{"wrong code", "050472", now, 1, 0, false},
+		{"too short", "50471", now, 1, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, tt.code, tt.at, tt.skew)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("Validate = (%d, %v), want (%d, %v)", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestInvalidSecret(t *testing.T) {
+	if _, err := CodeAt("not base32!", 1); err == nil {
+		t.Error("CodeAt accepted an invalid secret")
+	}
+	if _, ok := Validate("not base32!", "123456", time.Now(), 1); ok {
+		t.Error("Validate accepted a code for an invalid secret")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := b32.DecodeString(secret)
+	if err != nil || len(key) != secretSize {
+		t.Fatalf("secret %q decodes to %d bytes (%v), want %d", secret, len(key), err, secretSize)
+	}
+	code, err := CodeAt(secret, Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Validate(secret, code, time.Now(), 1); !ok {
+		t.Error("current code of a generated secret does not validate")
+	}
+}
+
+func TestURI(t *testing.T) {
+	u, err := url.Parse(URI("Todo App", "jane@example.com", rfcSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Todo App:jane@example.com" {
+		t.Errorf("unexpected URI %s", u)
+	}
+	q := u.Query()
+	for key, want := range map[string]string{"secret": rfcSecret, "issuer": "Todo App", "algorithm": "SHA1", "digits": "6", "period": "30"} {
+		if got := q.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+-- migrate:up
+ALTER TABLE users
+    ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE AFTER is_active,
+    ADD COLUMN email_verified_at DATETIME NULL AFTER email_verified;
+
+-- Accounts that existed before verification was introduced are trusted as-is
+UPDATE users SET email_verified = TRUE, email_verified_at = UTC_TIMESTAMP();
+
+-- Hashed single-use tokens for email links (verification, later password reset)
+CREATE TABLE IF NOT EXISTS one_time_tokens (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    purpose VARCHAR(32) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_one_time_tokens_user_purpose (user_id, purpose),
+    INDEX idx_one_time_tokens_expires_at (expires_at),
+    CONSTRAINT fk_one_time_tokens_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- migrate:down
+DROP TABLE one_time_tokens;
+ALTER TABLE users DROP COLUMN email_verified_at, DROP COLUMN email_verified;
@@ -0,0 +1,28 @@
+-- migrate:up
+ALTER TABLE users
+    ADD COLUMN mfa_enabled BOOLEAN NOT NULL DEFAULT FALSE AFTER email_verified_at,
+    ADD COLUMN totp_secret VARCHAR(255) NULL AFTER mfa_enabled,
+    ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0 AFTER totp_secret;
+
+-- Single-use recovery codes, stored as SHA-256 hashes
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    used_at DATETIME NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_mfa_recovery_codes_user_hash (user_id, code_hash),
+    CONSTRAINT fk_mfa_recovery_codes_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Runtime settings changed by admins (e.g. which roles must use 2FA)
+CREATE TABLE IF NOT EXISTS settings (
+    name VARCHAR(64) PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+-- migrate:down
+DROP TABLE settings;
+DROP TABLE mfa_recovery_codes;
+ALTER TABLE users DROP COLUMN totp_last_step, DROP COLUMN totp_secret, DROP COLUMN mfa_enabled;
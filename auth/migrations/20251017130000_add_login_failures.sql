@@ -0,0 +1,12 @@
+-- migrate:up
+-- Failed-login counters for brute-force protection (used with LOGIN_COUNTER_STORE=database)
+CREATE TABLE IF NOT EXISTS login_failures (
+    counter_key VARCHAR(191) PRIMARY KEY,
+    failures INT NOT NULL,
+    last_failure_at DATETIME(3) NOT NULL,
+    expires_at DATETIME(3) NOT NULL,
+    INDEX idx_login_failures_expires_at (expires_at)
+);
+
+-- migrate:down
+DROP TABLE login_failures;
@@ -0,0 +1,29 @@
+-- migrate:up
+-- Accounts at external OIDC providers linked to local users
+CREATE TABLE IF NOT EXISTS user_identities (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    provider VARCHAR(64) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_login_at DATETIME NULL,
+    UNIQUE KEY uq_user_identities_provider_subject (provider, subject),
+    INDEX idx_user_identities_user_id (user_id),
+    CONSTRAINT fk_user_identities_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Pending logins between the redirect to a provider and its callback
+CREATE TABLE IF NOT EXISTS oidc_login_states (
+    state_hash CHAR(64) PRIMARY KEY,
+    provider VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    nonce VARCHAR(128) NOT NULL,
+    expires_at DATETIME NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_oidc_login_states_expires_at (expires_at)
+);
+
+-- migrate:down
+DROP TABLE oidc_login_states;
+DROP TABLE user_identities;
@@ -0,0 +1,59 @@
+-- migrate:up
+-- Third-party applications registered by users. Public clients (no secret) must use PKCE.
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    client_id VARCHAR(64) NOT NULL UNIQUE,
+    client_secret_hash VARCHAR(64) NOT NULL DEFAULT '',
+    name VARCHAR(100) NOT NULL,
+    redirect_uris TEXT NOT NULL,
+    scopes VARCHAR(255) NOT NULL,
+    grant_types VARCHAR(100) NOT NULL,
+    owner_id INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_oauth_clients_owner_id (owner_id),
+    CONSTRAINT fk_oauth_clients_owner FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Scopes a user has granted to a client; later requests within them skip the consent screen
+CREATE TABLE IF NOT EXISTS oauth_consents (
+    user_id INT NOT NULL,
+    client_id VARCHAR(64) NOT NULL,
+    scope VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, client_id),
+    INDEX idx_oauth_consents_client_id (client_id),
+    CONSTRAINT fk_oauth_consents_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_oauth_consents_client FOREIGN KEY (client_id) REFERENCES oauth_clients(client_id) ON DELETE CASCADE
+);
+
+-- Issued authorization codes until they are redeemed at /oauth/token
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+    code_hash CHAR(64) PRIMARY KEY,
+    client_id VARCHAR(64) NOT NULL,
+    user_id INT NOT NULL,
+    redirect_uri TEXT NOT NULL,
+    scope VARCHAR(255) NOT NULL,
+    code_challenge VARCHAR(128) NOT NULL,
+    expires_at DATETIME NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_oauth_authorization_codes_expires_at (expires_at),
+    CONSTRAINT fk_oauth_authorization_codes_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_oauth_authorization_codes_client FOREIGN KEY (client_id) REFERENCES oauth_clients(client_id) ON DELETE CASCADE
+);
+
+-- Refresh tokens issued to a client carry the client and the granted scope; both are
+-- empty for first-party logins
+ALTER TABLE refresh_tokens
+    ADD COLUMN client_id VARCHAR(64) NOT NULL DEFAULT '' AFTER family_id,
+    ADD COLUMN scope VARCHAR(255) NOT NULL DEFAULT '' AFTER client_id,
+    ADD INDEX idx_refresh_tokens_client_id (client_id);
+
+-- migrate:down
+ALTER TABLE refresh_tokens
+    DROP INDEX idx_refresh_tokens_client_id,
+    DROP COLUMN scope,
+    DROP COLUMN client_id;
+DROP TABLE oauth_authorization_codes;
+DROP TABLE oauth_consents;
+DROP TABLE oauth_clients;
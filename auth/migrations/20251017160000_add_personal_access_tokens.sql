@@ -0,0 +1,19 @@
+-- migrate:up
+-- Long-lived, scoped tokens users create for scripts and CI jobs; only the SHA-256 of
+-- the token is stored. expires_at is NULL for tokens that never expire.
+CREATE TABLE IF NOT EXISTS personal_access_tokens (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    token_prefix VARCHAR(16) NOT NULL,
+    scope VARCHAR(255) NOT NULL,
+    expires_at DATETIME NULL,
+    last_used_at DATETIME NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_personal_access_tokens_user_id (user_id),
+    CONSTRAINT fk_personal_access_tokens_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- migrate:down
+DROP TABLE personal_access_tokens;
@@ -0,0 +1,18 @@
+-- migrate:up
+-- First-party login sessions, one per refresh token family (id = refresh_tokens.family_id)
+CREATE TABLE IF NOT EXISTS sessions (
+    id CHAR(36) PRIMARY KEY,
+    user_id INT NOT NULL,
+    device VARCHAR(100) NOT NULL,
+    user_agent VARCHAR(255) NULL,
+    ip_address VARCHAR(45) NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    INDEX idx_sessions_user_id (user_id),
+    INDEX idx_sessions_expires_at (expires_at),
+    CONSTRAINT fk_sessions_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- migrate:down
+DROP TABLE sessions;
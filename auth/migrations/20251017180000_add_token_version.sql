@@ -0,0 +1,7 @@
+-- migrate:up
+-- Access and refresh tokens carry the user's token_version as their "ver" claim;
+-- bumping it revokes every token issued before.
+ALTER TABLE users ADD COLUMN token_version INT NOT NULL DEFAULT 0 AFTER totp_last_step;
+
+-- migrate:down
+ALTER TABLE users DROP COLUMN token_version;
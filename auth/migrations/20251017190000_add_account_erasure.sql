@@ -0,0 +1,33 @@
+-- migrate:up
+-- Deleted accounts are kept (soft-deleted) for a grace period and can be restored by
+-- an admin until their erasure starts
+ALTER TABLE users
+    ADD COLUMN deleted_at DATETIME NULL AFTER token_version,
+    ADD INDEX idx_users_deleted_at (deleted_at);
+
+-- Erasure of a deleted account across services. The user row is kept as an anonymized
+-- tombstone once the erasure starts; requested_by may name an admin erased since.
+CREATE TABLE IF NOT EXISTS account_erasures (
+    user_id INT PRIMARY KEY,
+    requested_by INT NOT NULL,
+    erase_after DATETIME NOT NULL,
+    started_at DATETIME NULL,
+    last_requested_at DATETIME NULL,
+    completed_at DATETIME NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_account_erasures_erase_after (erase_after)
+);
+
+-- One row per service that has to report the user's data as erased
+CREATE TABLE IF NOT EXISTS account_erasure_steps (
+    user_id INT NOT NULL,
+    service VARCHAR(32) NOT NULL,
+    completed_at DATETIME NULL,
+    PRIMARY KEY (user_id, service),
+    CONSTRAINT fk_account_erasure_steps_erasure FOREIGN KEY (user_id) REFERENCES account_erasures(user_id) ON DELETE CASCADE
+);
+
+-- migrate:down
+DROP TABLE account_erasure_steps;
+DROP TABLE account_erasures;
+ALTER TABLE users DROP INDEX idx_users_deleted_at, DROP COLUMN deleted_at;
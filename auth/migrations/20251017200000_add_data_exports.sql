@@ -0,0 +1,20 @@
+-- migrate:up
+-- Asynchronous exports of everything the system holds about a user. The finished
+-- archive (JSON) is kept until expires_at so the user can download it.
+CREATE TABLE IF NOT EXISTS data_exports (
+    id CHAR(36) PRIMARY KEY,
+    user_id INT NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    error VARCHAR(255) NOT NULL DEFAULT '',
+    archive MEDIUMBLOB NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at DATETIME NULL,
+    completed_at DATETIME NULL,
+    expires_at DATETIME NULL,
+    INDEX idx_data_exports_user_id (user_id),
+    INDEX idx_data_exports_status (status),
+    CONSTRAINT fk_data_exports_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- migrate:down
+DROP TABLE data_exports;
@@ -0,0 +1,43 @@
+-- migrate:up
+-- Roles are data: each has a set of named permissions that are embedded in access
+-- tokens. Built-in roles cannot be deleted; the permissions of "admin" cannot change.
+CREATE TABLE IF NOT EXISTS roles (
+    name VARCHAR(50) PRIMARY KEY,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    built_in BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role VARCHAR(50) NOT NULL,
+    permission VARCHAR(64) NOT NULL,
+    PRIMARY KEY (role, permission),
+    CONSTRAINT fk_role_permissions_role FOREIGN KEY (role) REFERENCES roles(name) ON DELETE CASCADE
+);
+
+INSERT INTO roles (name, description, built_in) VALUES
+    ('user', 'Regular user', TRUE),
+    ('admin', 'Administrator with every permission', TRUE);
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('user', 'teams.create'),
+    ('admin', 'users.read'),
+    ('admin', 'users.write'),
+    ('admin', 'users.delete'),
+    ('admin', 'roles.manage'),
+    ('admin', 'settings.manage'),
+    ('admin', 'oauth_clients.manage'),
+    ('admin', 'teams.create'),
+    ('admin', 'tasks.delete_any');
+
+-- A role in use cannot be deleted
+ALTER TABLE users MODIFY COLUMN role VARCHAR(50) NOT NULL DEFAULT 'user',
+    ADD CONSTRAINT fk_users_role FOREIGN KEY (role) REFERENCES roles(name);
+
+-- migrate:down
+ALTER TABLE users DROP FOREIGN KEY fk_users_role;
+UPDATE users SET role = 'user' WHERE role NOT IN ('user', 'admin');
+ALTER TABLE users MODIFY COLUMN role ENUM('user', 'admin') NOT NULL DEFAULT 'user';
+DROP TABLE role_permissions;
+DROP TABLE roles;
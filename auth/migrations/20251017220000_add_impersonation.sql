@@ -0,0 +1,22 @@
+-- migrate:up
+-- Audit trail of impersonation tokens issued to support staff; rows are kept after the
+-- tokens expire
+CREATE TABLE IF NOT EXISTS impersonations (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    actor_id INT NOT NULL,
+    user_id INT NOT NULL,
+    reason VARCHAR(255) NOT NULL,
+    token_id CHAR(32) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL,
+    INDEX idx_impersonations_user_id (user_id),
+    INDEX idx_impersonations_actor_id (actor_id),
+    CONSTRAINT fk_impersonations_actor FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_impersonations_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO role_permissions (role, permission) VALUES ('admin', 'users.impersonate');
+
+-- migrate:down
+DELETE FROM role_permissions WHERE permission = 'users.impersonate';
+DROP TABLE impersonations;
@@ -0,0 +1,10 @@
+-- migrate:up
+-- Display preferences (BCP 47 locale, IANA timezone) and the key of the current avatar
+-- in the avatar store; empty strings mean unset
+ALTER TABLE users
+    ADD COLUMN locale VARCHAR(35) NOT NULL DEFAULT '' AFTER last_name,
+    ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT '' AFTER locale,
+    ADD COLUMN avatar_key VARCHAR(32) NOT NULL DEFAULT '' AFTER timezone;
+
+-- migrate:down
+ALTER TABLE users DROP COLUMN avatar_key, DROP COLUMN timezone, DROP COLUMN locale;
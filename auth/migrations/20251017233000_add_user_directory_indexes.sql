@@ -0,0 +1,13 @@
+-- migrate:up
+-- Indexes for the user directory: name prefix searches and keyset pagination by
+-- creation date (ties broken by id)
+ALTER TABLE users
+    ADD INDEX idx_users_first_name (first_name),
+    ADD INDEX idx_users_last_name (last_name),
+    ADD INDEX idx_users_created_at_id (created_at, id);
+
+-- migrate:down
+ALTER TABLE users
+    DROP INDEX idx_users_created_at_id,
+    DROP INDEX idx_users_last_name,
+    DROP INDEX idx_users_first_name;
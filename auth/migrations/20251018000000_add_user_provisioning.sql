@@ -0,0 +1,51 @@
+-- migrate:up
+-- Bulk user imports run as background jobs. input holds the submitted users (JSON) until
+-- the import finished; report holds the outcome of every row.
+CREATE TABLE IF NOT EXISTS user_imports (
+    id CHAR(36) PRIMARY KEY,
+    actor_id INT NOT NULL,
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    total INT NOT NULL DEFAULT 0,
+    succeeded INT NOT NULL DEFAULT 0,
+    failed INT NOT NULL DEFAULT 0,
+    error VARCHAR(255) NOT NULL DEFAULT '',
+    input MEDIUMBLOB NULL,
+    report MEDIUMBLOB NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at DATETIME NULL,
+    completed_at DATETIME NULL,
+    INDEX idx_user_imports_status (status)
+);
+
+-- SCIM provisioning: the user's ID in the external directory, and groups pushed by it.
+-- Every group is backed by a team in the team service (team_id).
+ALTER TABLE users ADD COLUMN external_id VARCHAR(255) NOT NULL DEFAULT '' AFTER avatar_key,
+    ADD INDEX idx_users_external_id (external_id);
+
+CREATE TABLE IF NOT EXISTS scim_groups (
+    id CHAR(36) PRIMARY KEY,
+    display_name VARCHAR(255) NOT NULL,
+    external_id VARCHAR(255) NOT NULL DEFAULT '',
+    team_id INT NOT NULL,
+    owner_id INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE INDEX idx_scim_groups_display_name (display_name),
+    INDEX idx_scim_groups_external_id (external_id)
+);
+
+CREATE TABLE IF NOT EXISTS scim_group_members (
+    group_id CHAR(36) NOT NULL,
+    user_id INT NOT NULL,
+    PRIMARY KEY (group_id, user_id),
+    INDEX idx_scim_group_members_user_id (user_id),
+    CONSTRAINT fk_scim_group_members_group FOREIGN KEY (group_id) REFERENCES scim_groups(id) ON DELETE CASCADE,
+    CONSTRAINT fk_scim_group_members_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- migrate:down
+DROP TABLE scim_group_members;
+DROP TABLE scim_groups;
+ALTER TABLE users DROP INDEX idx_users_external_id, DROP COLUMN external_id;
+DROP TABLE user_imports;
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"time"
 )
 
 type AuthClient struct {
 	baseURL string
+	tokens  *ServiceTokenSource
 }
 
 type User struct {
@@ -16,6 +18,12 @@ type User struct {
 	Username string `json:"username"`
 	Email    string `json:"email"`
 	Role     string `json:"role"`
+	Timezone string `json:"timezone"`
+}
+
+// Location returns the user's preferred timezone, UTC if none is set
+func (u *User) Location() *time.Location {
+	return locationFor(u.Timezone)
 }
 
 func NewAuthClient() *AuthClient {
@@ -23,18 +31,32 @@ func NewAuthClient() *AuthClient {
 	if baseURL == "" {
 		baseURL = "http://auth-service:8084"
 	}
-	return &AuthClient{baseURL: baseURL}
+	return &AuthClient{baseURL: baseURL, tokens: NewServiceTokenSource()}
 }
 
 func (c *AuthClient) GetUserByID(userID int) (*User, error) {
 	url := fmt.Sprintf("%s/internal/users/%d", c.baseURL, userID)
 
-	resp, err := http.Get(url)
+	// /internal routes require a service token from the client credentials grant
+	token, err := c.tokens.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service token: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusUnauthorized {
+		c.tokens.Invalidate()
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("auth service returned status: %d", resp.StatusCode)
 	}
@@ -48,6 +48,9 @@ type TeamMemberEvent struct {
 	Payload   interface{} `json:"payload,omitempty"`
 }
 
+// sensitiveTopics carry single-use links in their payload, which must not end up in logs
+var sensitiveTopics = map[string]bool{"user.verification_requested": true, "user.password_reset_requested": true}
+
 func startKafkaConsumer(ctx context.Context, authClient *AuthClient, emailSender *EmailSender) func() {
 	brokers := os.Getenv("KAFKA_BROKERS")
 	if brokers == "" {
@@ -57,7 +60,8 @@ func startKafkaConsumer(ctx context.Context, authClient *AuthClient, emailSender
 		"task.created", "task.updated", "task.deleted", "task.completed",
 		"team.created", "team.updated", "team.deleted",
 		"team.member_added", "team.member_removed", "team.member_role_updated",
-		"user.created",
+		"user.created", "user.verification_requested", "user.password_reset_requested", "user.locked",
+		"user.export_ready",
 	}
 
 	log.Printf("Starting Kafka consumer with brokers: %s, topics: %v", brokers, topics)
@@ -81,7 +85,11 @@ func startKafkaConsumer(ctx context.Context, authClient *AuthClient, emailSender
 					continue
 				}
 
-				log.Printf("[kafka] %s key=%s value=%s", tp, string(m.Key), string(m.Value))
+				if sensitiveTopics[tp] {
+					log.Printf("[kafka] %s key=%s (value redacted)", tp, string(m.Key))
+				} else {
+					log.Printf("[kafka] %s key=%s value=%s", tp, string(m.Key), string(m.Value))
+				}
 
 				// Parse the event based on topic
 				switch tp {
@@ -116,6 +124,38 @@ func startKafkaConsumer(ctx context.Context, authClient *AuthClient, emailSender
 						continue
 					}
 					processUserEvent(emailSender, tp, event)
+
+				case "user.verification_requested":
+					var event UserEvent
+					if err := json.Unmarshal(m.Value, &event); err != nil {
+						log.Printf("failed to parse user event: %v", err)
+						continue
+					}
+					processVerificationEvent(emailSender, event)
+
+				case "user.password_reset_requested":
+					var event UserEvent
+					if err := json.Unmarshal(m.Value, &event); err != nil {
+						log.Printf("failed to parse user event: %v", err)
+						continue
+					}
+					processPasswordResetEvent(emailSender, event)
+
+				case "user.locked":
+					var event UserEvent
+					if err := json.Unmarshal(m.Value, &event); err != nil {
+						log.Printf("failed to parse user event: %v", err)
+						continue
+					}
+					processUserLockedEvent(emailSender, event)
+
+				case "user.export_ready":
+					var event UserEvent
+					if err := json.Unmarshal(m.Value, &event); err != nil {
+						log.Printf("failed to parse user event: %v", err)
+						continue
+					}
+					processExportReadyEvent(emailSender, event)
 				}
 			}
 		}()
@@ -217,6 +257,161 @@ func processUserEvent(emailSender *EmailSender, eventType string, event UserEven
 	log.Printf("User welcome email sent successfully to %s (%s)", email, username)
 }
 
+func processVerificationEvent(emailSender *EmailSender, event UserEvent) {
+	payload, ok := event.Payload.(map[string]interface{})
+	if !ok {
+		log.Printf("failed to parse verification event payload")
+		return
+	}
+
+	email, emailOk := payload["email"].(string)
+	username, _ := payload["username"].(string)
+	link, linkOk := payload["verificationLink"].(string)
+	if !emailOk || !linkOk {
+		log.Printf("failed to extract email or verification link from verification event")
+		return
+	}
+	expiresAt, _ := payload["expiresAt"].(string)
+	expiresAt = formatPayloadTime(expiresAt, payload)
+
+	subject := "Verify your email address"
+	body := createVerificationEmailBody(username, link, expiresAt)
+
+	if err := emailSender.Send(email, subject, body); err != nil {
+		log.Printf("failed to send verification email to user %d: %v", event.UserID, err)
+		return
+	}
+
+	// The link is a credential, so it is deliberately not logged
+	log.Printf("Verification email sent to user %d", event.UserID)
+}
+
+func createVerificationEmailBody(username, link, expiresAt string) string {
+	return fmt.Sprintf("Hello %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nThe link can be used once and expires at %s. If you did not create an account, you can ignore this email.\n\nBest regards,\nTodo App Team", username, link, expiresAt)
+}
+
+func processPasswordResetEvent(emailSender *EmailSender, event UserEvent) {
+	payload, ok := event.Payload.(map[string]interface{})
+	if !ok {
+		log.Printf("failed to parse password reset event payload")
+		return
+	}
+
+	email, emailOk := payload["email"].(string)
+	username, _ := payload["username"].(string)
+	link, linkOk := payload["resetLink"].(string)
+	if !emailOk || !linkOk {
+		log.Printf("failed to extract email or reset link from password reset event")
+		return
+	}
+	expiresAt, _ := payload["expiresAt"].(string)
+	expiresAt = formatPayloadTime(expiresAt, payload)
+
+	subject := "Reset your password"
+	body := createPasswordResetEmailBody(username, link, expiresAt)
+
+	if err := emailSender.Send(email, subject, body); err != nil {
+		log.Printf("failed to send password reset email to user %d: %v", event.UserID, err)
+		return
+	}
+
+	log.Printf("Password reset email sent to user %d", event.UserID)
+}
+
+func createPasswordResetEmailBody(username, link, expiresAt string) string {
+	return fmt.Sprintf("Hello %s,\n\nWe received a request to reset your password. Open the link below to choose a new one:\n\n%s\n\nThe link can be used once and expires at %s. Resetting your password signs you out on all devices.\n\nIf you did not request this, you can ignore this email; your password stays unchanged.\n\nBest regards,\nTodo App Team", username, link, expiresAt)
+}
+
+func processUserLockedEvent(emailSender *EmailSender, event UserEvent) {
+	payload, ok := event.Payload.(map[string]interface{})
+	if !ok {
+		log.Printf("failed to parse user locked event payload")
+		return
+	}
+
+	email, emailOk := payload["email"].(string)
+	if !emailOk {
+		log.Printf("failed to extract email from user locked event")
+		return
+	}
+	username, _ := payload["username"].(string)
+	lockedUntil, _ := payload["lockedUntil"].(string)
+	lockedUntil = formatPayloadTime(lockedUntil, payload)
+	ipAddress, _ := payload["ipAddress"].(string)
+
+	subject := "Your account was temporarily locked"
+	body := createUserLockedEmailBody(username, lockedUntil, ipAddress)
+
+	if err := emailSender.Send(email, subject, body); err != nil {
+		log.Printf("failed to send account locked email to user %d: %v", event.UserID, err)
+		return
+	}
+
+	log.Printf("Account locked email sent to user %d", event.UserID)
+}
+
+func createUserLockedEmailBody(username, lockedUntil, ipAddress string) string {
+	return fmt.Sprintf("Hello %s,\n\nAfter too many failed login attempts your account has been locked until %s. The last attempt came from %s.\n\nIf this was you, wait until then or reset your password to unlock it right away. If it was not you, someone may be trying to guess your password; consider choosing a stronger one and enabling two-factor authentication.\n\nBest regards,\nTodo App Team", username, lockedUntil, ipAddress)
+}
+
+func processExportReadyEvent(emailSender *EmailSender, event UserEvent) {
+	payload, ok := event.Payload.(map[string]interface{})
+	if !ok {
+		log.Printf("failed to parse export ready event payload")
+		return
+	}
+
+	email, emailOk := payload["email"].(string)
+	link, linkOk := payload["exportLink"].(string)
+	if !emailOk || !linkOk {
+		log.Printf("failed to extract email or export link from export ready event")
+		return
+	}
+	username, _ := payload["username"].(string)
+	expiresAt, _ := payload["expiresAt"].(string)
+	expiresAt = formatPayloadTime(expiresAt, payload)
+
+	subject := "Your data export is ready"
+	body := createExportReadyEmailBody(username, link, expiresAt)
+
+	if err := emailSender.Send(email, subject, body); err != nil {
+		log.Printf("failed to send export ready email to user %d: %v", event.UserID, err)
+		return
+	}
+
+	log.Printf("Export ready email sent to user %d", event.UserID)
+}
+
+func createExportReadyEmailBody(username, link, expiresAt string) string {
+	return fmt.Sprintf("Hello %s,\n\nThe export of your data you requested is ready. Sign in and download it here:\n\n%s\n\nThe archive contains your profile, linked accounts, sessions, team memberships and tasks. It can be downloaded until %s.\n\nIf you did not request this export, change your password and sign out all other devices.\n\nBest regards,\nTodo App Team", username, link, expiresAt)
+}
+
+// emailTimeLayout is how times appear in emails, in the recipient's timezone
+const emailTimeLayout = "Mon, 02 Jan 2006 15:04 MST"
+
+// locationFor returns the named IANA timezone, UTC if it is empty or unknown
+func locationFor(name string) *time.Location {
+	if loc, err := time.LoadLocation(name); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+func formatTime(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format(emailTimeLayout)
+}
+
+// formatPayloadTime formats an RFC 3339 time from a user event payload in the
+// timezone the payload names; other values are returned unchanged
+func formatPayloadTime(value string, payload map[string]interface{}) string {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return value
+	}
+	tz, _ := payload["timezone"].(string)
+	return formatTime(t, locationFor(tz))
+}
+
 func createWelcomeEmailBody(username string, userID int) string {
 	return fmt.Sprintf("Hello %s,\n\nWelcome to Todo App! 🎉\n\nYour account has been successfully created with User ID: %d\n\nWe're excited to have you on board. You can now:\n- Create and manage tasks\n- Join teams and collaborate\n- Track your progress\n\nBest regards,\nTodo App Team", username, userID)
 }
@@ -230,7 +425,7 @@ func sendTaskEmailToUser(authClient *AuthClient, emailSender *EmailSender, userI
 
 	// Create email subject and body
 	subject := eventType
-	body := createTaskEmailBody(eventType, event, user.Username)
+	body := createTaskEmailBody(eventType, event, user.Username, user.Location())
 
 	// Send email
 	if err := emailSender.Send(user.Email, subject, body); err != nil {
@@ -251,7 +446,7 @@ func sendTeamEmailToUser(authClient *AuthClient, emailSender *EmailSender, userI
 
 	// Create email subject and body
 	subject := eventType
-	body := createTeamEmailBody(eventType, event, user.Username)
+	body := createTeamEmailBody(eventType, event, user.Username, user.Location())
 
 	// Send email
 	if err := emailSender.Send(user.Email, subject, body); err != nil {
@@ -272,7 +467,7 @@ func sendTeamMemberEmailToUser(authClient *AuthClient, emailSender *EmailSender,
 
 	// Create email subject and body
 	subject := eventType
-	body := createTeamMemberEmailBody(eventType, event, user.Username)
+	body := createTeamMemberEmailBody(eventType, event, user.Username, user.Location())
 
 	// Send email
 	if err := emailSender.Send(user.Email, subject, body); err != nil {
@@ -284,17 +479,17 @@ func sendTeamMemberEmailToUser(authClient *AuthClient, emailSender *EmailSender,
 	return nil
 }
 
-func createTaskEmailBody(eventType string, event TaskEvent, username string) string {
+func createTaskEmailBody(eventType string, event TaskEvent, username string, loc *time.Location) string {
 	switch eventType {
 	case "task.created":
 		return fmt.Sprintf("Hello %s,\n\nA new task has been created:\n- Task ID: %d\n- Team ID: %d\n- Created by: User %d\n- Timestamp: %s\n\nBest regards,\nTodo App",
-			username, event.TaskID, event.TeamID, event.ActorID, event.Timestamp)
+			username, event.TaskID, event.TeamID, event.ActorID, formatTime(event.Timestamp, loc))
 	case "task.updated":
 		return fmt.Sprintf("Hello %s,\n\nA task has been updated:\n- Task ID: %d\n- Team ID: %d\n- Updated by: User %d\n- Timestamp: %s\n\nBest regards,\nTodo App",
-			username, event.TaskID, event.TeamID, event.ActorID, event.Timestamp)
+			username, event.TaskID, event.TeamID, event.ActorID, formatTime(event.Timestamp, loc))
 	case "task.deleted":
 		return fmt.Sprintf("Hello %s,\n\nA task has been deleted:\n- Task ID: %d\n- Team ID: %d\n- Deleted by: User %d\n- Timestamp: %s\n\nBest regards,\nTodo App",
-			username, event.TaskID, event.TeamID, event.ActorID, event.Timestamp)
+			username, event.TaskID, event.TeamID, event.ActorID, formatTime(event.Timestamp, loc))
 	case "task.completed":
 		completed := "completed"
 		if payload, ok := event.Payload.(map[string]interface{}); ok {
@@ -305,14 +500,14 @@ func createTaskEmailBody(eventType string, event TaskEvent, username string) str
 			}
 		}
 		return fmt.Sprintf("Hello %s,\n\nA task has been %s:\n- Task ID: %d\n- Team ID: %d\n- Action by: User %d\n- Timestamp: %s\n\nBest regards,\nTodo App",
-			username, completed, event.TaskID, event.TeamID, event.ActorID, event.Timestamp)
+			username, completed, event.TaskID, event.TeamID, event.ActorID, formatTime(event.Timestamp, loc))
 	default:
 		return fmt.Sprintf("Hello %s,\n\nA task event occurred:\n- Event: %s\n- Task ID: %d\n- Team ID: %d\n- Actor: User %d\n- Timestamp: %s\n\nBest regards,\nTodo App",
-			username, eventType, event.TaskID, event.TeamID, event.ActorID, event.Timestamp)
+			username, eventType, event.TaskID, event.TeamID, event.ActorID, formatTime(event.Timestamp, loc))
 	}
 }
 
-func createTeamEmailBody(eventType string, event TeamEvent, username string) string {
+func createTeamEmailBody(eventType string, event TeamEvent, username string, loc *time.Location) string {
 	var teamName string
 	if payload, ok := event.Payload.(map[string]interface{}); ok {
 		if name, exists := payload["name"].(string); exists {
@@ -323,32 +518,32 @@ func createTeamEmailBody(eventType string, event TeamEvent, username string) str
 	switch eventType {
 	case "team.created":
 		return fmt.Sprintf("Hello %s,\n\nA new team has been created:\n- Team ID: %d\n- Team Name: %s\n- Created by: User %d\n- Timestamp: %s\n\nBest regards,\nTodo App",
-			username, event.TeamID, teamName, event.ActorID, event.Timestamp)
+			username, event.TeamID, teamName, event.ActorID, formatTime(event.Timestamp, loc))
 	case "team.updated":
 		return fmt.Sprintf("Hello %s,\n\nYour team has been updated:\n- Team ID: %d\n- Team Name: %s\n- Updated by: User %d\n- Timestamp: %s\n\nBest regards,\nTodo App",
-			username, event.TeamID, teamName, event.ActorID, event.Timestamp)
+			username, event.TeamID, teamName, event.ActorID, formatTime(event.Timestamp, loc))
 	case "team.deleted":
 		return fmt.Sprintf("Hello %s,\n\nYour team has been deleted:\n- Team ID: %d\n- Team Name: %s\n- Deleted by: User %d\n- Timestamp: %s\n\nBest regards,\nTodo App",
-			username, event.TeamID, teamName, event.ActorID, event.Timestamp)
+			username, event.TeamID, teamName, event.ActorID, formatTime(event.Timestamp, loc))
 	default:
 		return fmt.Sprintf("Hello %s,\n\nA team event occurred:\n- Event: %s\n- Team ID: %d\n- Actor: User %d\n- Timestamp: %s\n\nBest regards,\nTodo App",
-			username, eventType, event.TeamID, event.ActorID, event.Timestamp)
+			username, eventType, event.TeamID, event.ActorID, formatTime(event.Timestamp, loc))
 	}
 }
 
-func createTeamMemberEmailBody(eventType string, event TeamMemberEvent, username string) string {
+func createTeamMemberEmailBody(eventType string, event TeamMemberEvent, username string, loc *time.Location) string {
 	switch eventType {
 	case "team.member_added":
 		return fmt.Sprintf("Hello %s,\n\nYou have been added to a team:\n- Team ID: %d\n- Role: %s\n- Added by: User %d\n- Timestamp: %s\n\nBest regards,\nTodo App",
-			username, event.TeamID, event.Role, event.ActorID, event.Timestamp)
+			username, event.TeamID, event.Role, event.ActorID, formatTime(event.Timestamp, loc))
 	case "team.member_removed":
 		return fmt.Sprintf("Hello %s,\n\nYou have been removed from a team:\n- Team ID: %d\n- Removed by: User %d\n- Timestamp: %s\n\nBest regards,\nTodo App",
-			username, event.TeamID, event.ActorID, event.Timestamp)
+			username, event.TeamID, event.ActorID, formatTime(event.Timestamp, loc))
 	case "team.member_role_updated":
 		return fmt.Sprintf("Hello %s,\n\nYour role in a team has been updated:\n- Team ID: %d\n- New Role: %s\n- Updated by: User %d\n- Timestamp: %s\n\nBest regards,\nTodo App",
-			username, event.TeamID, event.Role, event.ActorID, event.Timestamp)
+			username, event.TeamID, event.Role, event.ActorID, formatTime(event.Timestamp, loc))
 	default:
 		return fmt.Sprintf("Hello %s,\n\nA team membership event occurred:\n- Event: %s\n- Team ID: %d\n- User ID: %d\n- Actor: User %d\n- Timestamp: %s\n\nBest regards,\nTodo App",
-			username, eventType, event.TeamID, event.UserID, event.ActorID, event.Timestamp)
+			username, eventType, event.TeamID, event.UserID, event.ActorID, formatTime(event.Timestamp, loc))
 	}
 }
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os/signal"
 	"syscall"
+	_ "time/tzdata"
 
 	"github.com/gin-gonic/gin"
 )
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServiceTokenSource obtains service tokens from the auth service via the OAuth 2.0
+// client credentials grant and caches them until shortly before they expire.
+type ServiceTokenSource struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	httpClient   *http.Client
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// NewServiceTokenSource reads SERVICE_CLIENT_ID / SERVICE_CLIENT_SECRET and posts them
+// to AUTH_SERVICE_URL + /oauth/token
+func NewServiceTokenSource() *ServiceTokenSource {
+	baseURL := os.Getenv("AUTH_SERVICE_URL")
+	if baseURL == "" {
+		baseURL = "http://auth-service:8084"
+	}
+	return &ServiceTokenSource{
+		tokenURL:     baseURL + "/oauth/token",
+		clientID:     os.Getenv("SERVICE_CLIENT_ID"),
+		clientSecret: os.Getenv("SERVICE_CLIENT_SECRET"),
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Token returns a cached service token, fetching a new one when it is about to expire
+func (s *ServiceTokenSource) Token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && time.Until(s.expiresAt) > 30*time.Second {
+		return s.token, nil
+	}
+	if s.clientID == "" || s.clientSecret == "" {
+		return "", fmt.Errorf("SERVICE_CLIENT_ID and SERVICE_CLIENT_SECRET must be set")
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequest(http.MethodPost, s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(s.clientID, s.clientSecret)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request service token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("auth service returned status: %d", resp.StatusCode)
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode service token response: %w", err)
+	}
+	s.token = body.AccessToken
+	s.expiresAt = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	return s.token, nil
+}
+
+// Invalidate drops the cached token, e.g. after a 401 caused by a key rotation
+func (s *ServiceTokenSource) Invalidate() {
+	s.mu.Lock()
+	s.token = ""
+	s.mu.Unlock()
+}
@@ -66,6 +66,10 @@ const (
 
 	// User events
 	EventUserCreated = "user.created"
+	// EventUserTokensRevoked ends the user's connection instead of being broadcast
+	EventUserTokensRevoked = "user.tokens_revoked"
+	// EventUserSessionRevoked ends the connection opened from that session
+	EventUserSessionRevoked = "user.session_revoked"
 )
 
 // CreateUnifiedEvent creates a unified event from various event types
@@ -40,6 +40,7 @@ type KafkaConsumer struct {
 	hub        *Hub
 	topics     []string
 	teamAPIURL string
+	tokens     *ServiceTokenSource
 }
 
 // NewKafkaConsumer creates a new Kafka consumer
@@ -52,6 +53,7 @@ func NewKafkaConsumer(hub *Hub) *KafkaConsumer {
 	return &KafkaConsumer{
 		hub:        hub,
 		teamAPIURL: teamAPIURL,
+		tokens:     NewServiceTokenSource(),
 		topics: []string{
 			"task.created",
 			"task.updated",
@@ -209,13 +211,30 @@ func (kc *KafkaConsumer) getTeamMembers(teamID int) []TeamMember {
 
 	log.Printf("🔍 Fetching team members from: %s", url)
 
-	resp, err := http.Get(url)
+	// The team service only serves /internal routes to callers with a service token
+	token, err := kc.tokens.Token()
+	if err != nil {
+		log.Printf("❌ Failed to obtain service token: %v", err)
+		return []TeamMember{}
+	}
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		log.Printf("❌ Failed to build team members request for team %d: %v", teamID, err)
+		return []TeamMember{}
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		log.Printf("❌ Failed to get team members for team %d: %v", teamID, err)
 		return []TeamMember{}
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusUnauthorized {
+		kc.tokens.Invalidate()
+	}
+
 	log.Printf("📡 Team service response status: %d for team %d", resp.StatusCode, teamID)
 
 	if resp.StatusCode != http.StatusOK {
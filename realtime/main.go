@@ -17,10 +17,25 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Initialize WebSocket hub
-	hub := NewHub()
+	// Initialize WebSocket hub; sockets are opened with an access token
+	verifier := NewTokenVerifier()
+	hub := NewHub(verifier)
 	go hub.Run()
 
+	// Revoked tokens cannot open sockets anymore and end the user's live connection
+	brokers := os.Getenv("KAFKA_BROKERS")
+	if brokers == "" {
+		brokers = "dev_kafka:9092"
+	}
+	go ConsumeTokenRevocations(ctx, brokers, func(userID, tokenVersion int) {
+		verifier.Revoke(userID, tokenVersion)
+		hub.DisconnectUser(userID, tokenVersion, CreateUnifiedEvent(EventUserTokensRevoked, 0, userID, UserEventData{UserID: userID}))
+	})
+	go ConsumeSessionRevocations(ctx, brokers, func(userID int, sessionID string, until time.Time) {
+		verifier.RevokeSession(sessionID, until)
+		hub.DisconnectSession(userID, sessionID, CreateUnifiedEvent(EventUserSessionRevoked, 0, userID, UserEventData{UserID: userID}))
+	})
+
 	// Initialize and start Kafka consumer
 	consumer := NewKafkaConsumer(hub)
 	go consumer.Start(ctx)
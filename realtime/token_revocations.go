@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// tokensRevokedEvent is the part of a user.tokens_revoked event this service needs
+type tokensRevokedEvent struct {
+	UserID  int `json:"userId"`
+	Payload struct {
+		TokenVersion int    `json:"tokenVersion"`
+		Reason       string `json:"reason"`
+	} `json:"payload"`
+}
+
+// sessionRevokedEvent is the part of a user.session_revoked event this service needs
+type sessionRevokedEvent struct {
+	UserID  int `json:"userId"`
+	Payload struct {
+		SessionID string    `json:"sessionId"`
+		Until     time.Time `json:"until"`
+	} `json:"payload"`
+}
+
+// ConsumeTokenRevocations calls onRevoked for every user.tokens_revoked event until ctx
+// is cancelled. Every instance must see every event, so partitions are read directly
+// rather than through the realtime-service consumer group, starting at the oldest
+// retained message so a restarted instance relearns revocations of unexpired tokens.
+func ConsumeTokenRevocations(ctx context.Context, brokers string, onRevoked func(userID, tokenVersion int)) {
+	consumeAllPartitions(ctx, brokers, EventUserTokensRevoked, func(value []byte) {
+		var evt tokensRevokedEvent
+		if err := json.Unmarshal(value, &evt); err != nil || evt.UserID == 0 {
+			log.Printf("Ignoring malformed %s event: %s", EventUserTokensRevoked, string(value))
+			return
+		}
+		onRevoked(evt.UserID, evt.Payload.TokenVersion)
+	})
+}
+
+// ConsumeSessionRevocations calls onRevoked for every user.session_revoked event until
+// ctx is cancelled, reading the topic like ConsumeTokenRevocations. until is when the
+// last access token of the session expires.
+func ConsumeSessionRevocations(ctx context.Context, brokers string, onRevoked func(userID int, sessionID string, until time.Time)) {
+	consumeAllPartitions(ctx, brokers, EventUserSessionRevoked, func(value []byte) {
+		var evt sessionRevokedEvent
+		if err := json.Unmarshal(value, &evt); err != nil || evt.UserID == 0 || evt.Payload.SessionID == "" {
+			log.Printf("Ignoring malformed %s event: %s", EventUserSessionRevoked, string(value))
+			return
+		}
+		onRevoked(evt.UserID, evt.Payload.SessionID, evt.Payload.Until)
+	})
+}
+
+// consumeAllPartitions passes every message of topic to handle, reading each partition
+// from the oldest retained message, until ctx is cancelled
+func consumeAllPartitions(ctx context.Context, brokers, topic string, handle func(value []byte)) {
+	var partitions []kafka.Partition
+	for {
+		var err error
+		partitions, err = kafka.LookupPartitions(ctx, "tcp", brokers, topic)
+		if err == nil && len(partitions) > 0 {
+			break
+		}
+		// The topic only exists once auth has published to it
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(30 * time.Second):
+		}
+	}
+	for _, p := range partitions {
+		go consumePartition(ctx, brokers, topic, p.ID, handle)
+	}
+}
+
+func consumePartition(ctx context.Context, brokers, topic string, partition int, handle func(value []byte)) {
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   []string{brokers},
+		Topic:     topic,
+		Partition: partition,
+		MaxWait:   1 * time.Second,
+	})
+	defer r.Close()
+
+	for {
+		m, err := r.ReadMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Kafka read error on topic %s: %v", topic, err)
+			time.Sleep(time.Second)
+			continue
+		}
+		handle(m.Value)
+	}
+}
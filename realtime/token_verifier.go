@@ -0,0 +1,252 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// tokenClaims is the part of the auth service's access token claims the handshake needs
+type tokenClaims struct {
+	UserID    int    `json:"user_id"`
+	TokenType string `json:"typ"`
+	ClientID  string `json:"client_id"`
+	// TokenVersion is compared against the versions revoked by user.tokens_revoked
+	TokenVersion int `json:"ver"`
+	// SessionID is compared against the sessions ended by user.session_revoked
+	SessionID string `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// TokenVerifier authenticates WebSocket handshakes offline: access tokens are verified
+// against the auth service's JWKS (cached, refetched for unknown kids after a key
+// rotation) and rejected when user.tokens_revoked revoked their version or
+// user.session_revoked ended their session.
+type TokenVerifier struct {
+	jwksURL    string
+	httpClient *http.Client
+	cacheTTL   time.Duration
+	minRefresh time.Duration
+
+	mu         sync.RWMutex
+	keys       map[string]interface{}
+	fetchedAt  time.Time
+	minVersion map[int]int          // user ID -> lowest token version still valid
+	sessions   map[string]time.Time // ended session ID -> expiry of its last access token
+}
+
+// NewTokenVerifier creates a verifier for AUTH_JWKS_URL (default: AUTH_SERVICE_URL + /.well-known/jwks.json)
+func NewTokenVerifier() *TokenVerifier {
+	jwksURL := os.Getenv("AUTH_JWKS_URL")
+	if jwksURL == "" {
+		baseURL := os.Getenv("AUTH_SERVICE_URL")
+		if baseURL == "" {
+			baseURL = "http://auth-service:8084"
+		}
+		jwksURL = baseURL + "/.well-known/jwks.json"
+	}
+	return &TokenVerifier{
+		jwksURL:    jwksURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		cacheTTL:   5 * time.Minute,
+		minRefresh: 30 * time.Second,
+		keys:       map[string]interface{}{},
+		minVersion: map[int]int{},
+		sessions:   map[string]time.Time{},
+	}
+}
+
+// Verify checks signature, issuer, audience and expiry of a first-party access token
+// and returns its user ID, token version and session ID
+func (v *TokenVerifier) Verify(tokenString string) (userID, version int, sessionID string, err error) {
+	claims := &tokenClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, v.keyfunc,
+		jwt.WithValidMethods([]string{"RS256", "EdDSA"}),
+		jwt.WithIssuer("auth-service"),
+		jwt.WithAudience("todolist-api"),
+		jwt.WithExpirationRequired())
+	if err != nil {
+		return 0, 0, "", err
+	}
+	// Refresh tokens and tokens of third-party OAuth clients cannot open a socket
+	if !token.Valid || claims.TokenType != "access" || claims.ClientID != "" || claims.UserID <= 0 {
+		return 0, 0, "", errors.New("invalid token")
+	}
+	if v.IsRevoked(claims.UserID, claims.TokenVersion) || v.IsSessionRevoked(claims.SessionID) {
+		return 0, 0, "", errors.New("token revoked")
+	}
+	return claims.UserID, claims.TokenVersion, claims.SessionID, nil
+}
+
+// Revoke records that the user's tokens older than version are revoked. Events may
+// arrive out of order, so a lower version never replaces a higher one.
+func (v *TokenVerifier) Revoke(userID, version int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if version > v.minVersion[userID] {
+		v.minVersion[userID] = version
+	}
+}
+
+// IsRevoked reports whether a token of the user carrying version was revoked
+func (v *TokenVerifier) IsRevoked(userID, version int) bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return version < v.minVersion[userID]
+}
+
+// RevokeSession records that the access tokens of a session are revoked. Entries are
+// dropped once every access token they could match has expired.
+func (v *TokenVerifier) RevokeSession(sessionID string, until time.Time) {
+	now := time.Now()
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for id, expiry := range v.sessions {
+		if now.After(expiry) {
+			delete(v.sessions, id)
+		}
+	}
+	if until.After(now) {
+		v.sessions[sessionID] = until
+	}
+}
+
+// IsSessionRevoked reports whether the session a token belongs to was ended; tokens
+// without a session never are
+func (v *TokenVerifier) IsSessionRevoked(sessionID string) bool {
+	if sessionID == "" {
+		return false
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	_, ok := v.sessions[sessionID]
+	return ok
+}
+
+func (v *TokenVerifier) keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("missing kid header")
+	}
+	key, err := v.key(kid)
+	if err != nil {
+		return nil, err
+	}
+	switch key.(type) {
+	case *rsa.PublicKey:
+		if token.Method.Alg() != "RS256" {
+			return nil, errors.New("unexpected signing method")
+		}
+	case ed25519.PublicKey:
+		if token.Method.Alg() != "EdDSA" {
+			return nil, errors.New("unexpected signing method")
+		}
+	}
+	return key, nil
+}
+
+// key returns the cached key for kid, refreshing the key set when stale or unknown
+func (v *TokenVerifier) key(kid string) (interface{}, error) {
+	v.mu.RLock()
+	key, ok := v.keys[kid]
+	age := time.Since(v.fetchedAt)
+	v.mu.RUnlock()
+	if ok && age < v.cacheTTL {
+		return key, nil
+	}
+	if !ok && age < v.minRefresh {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if err := v.refresh(); err != nil {
+		if ok {
+			// Keep verifying with the last known key set while auth is unreachable
+			return key, nil
+		}
+		return nil, err
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+}
+
+func (v *TokenVerifier) refresh() error {
+	resp, err := v.httpClient.Get(v.jwksURL)
+	if err != nil {
+		return fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("auth service returned status: %d", resp.StatusCode)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("failed to decode jwks: %w", err)
+	}
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		pub, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+	v.mu.Lock()
+	v.keys = keys
+	v.fetchedAt = time.Now()
+	v.mu.Unlock()
+	return nil
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	b64 := base64.RawURLEncoding
+	switch k.Kty {
+	case "RSA":
+		n, err := b64.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := b64.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := b64.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
@@ -3,7 +3,7 @@ package main
 import (
 	"log"
 	"net/http"
-	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -27,7 +27,11 @@ type Client struct {
 	conn   *websocket.Conn
 	send   chan UnifiedEvent
 	userID int
-	hub    *Hub
+	// tokenVersion is the version of the access token the socket was opened with
+	tokenVersion int
+	// sessionID is the login session of that token
+	sessionID string
+	hub       *Hub
 }
 
 // Hub manages WebSocket connections and broadcasts
@@ -37,17 +41,19 @@ type Hub struct {
 	register    chan *Client
 	unregister  chan *Client
 	userClients map[int]*Client // userID -> client (one connection per user)
+	verifier    *TokenVerifier
 	mu          sync.RWMutex
 }
 
-// NewHub creates a new WebSocket hub
-func NewHub() *Hub {
+// NewHub creates a new WebSocket hub that authenticates handshakes with verifier
+func NewHub(verifier *TokenVerifier) *Hub {
 	return &Hub{
 		clients:     make(map[*Client]bool),
 		broadcast:   make(chan UnifiedEvent),
 		register:    make(chan *Client),
 		unregister:  make(chan *Client),
 		userClients: make(map[int]*Client),
+		verifier:    verifier,
 	}
 }
 
@@ -56,6 +62,11 @@ func (h *Hub) Run() {
 	for {
 		select {
 		case client := <-h.register:
+			// The token may have been revoked between the handshake and now
+			if h.verifier.IsRevoked(client.userID, client.tokenVersion) || h.verifier.IsSessionRevoked(client.sessionID) {
+				close(client.send)
+				continue
+			}
 			h.mu.Lock()
 			// If user already has a connection, close the old one
 			if existingClient, exists := h.userClients[client.userID]; exists {
@@ -116,6 +127,48 @@ func (h *Hub) BroadcastToUsers(event UnifiedEvent, userIDs []int) {
 	}
 }
 
+// DisconnectUser closes a user's live connection if it was opened with a token older
+// than tokenVersion, i.e. one revoked by user.tokens_revoked. The client receives event
+// as the last message before the close frame.
+func (h *Hub) DisconnectUser(userID, tokenVersion int, event UnifiedEvent) {
+	h.mu.RLock()
+	client, exists := h.userClients[userID]
+	exists = exists && client.tokenVersion < tokenVersion
+	if exists {
+		select {
+		case client.send <- event:
+		default:
+		}
+	}
+	h.mu.RUnlock()
+	if !exists {
+		return
+	}
+	log.Printf("🔒 Disconnecting user %d: %s", userID, event.Type)
+	h.unregister <- client
+}
+
+// DisconnectSession closes a user's live connection if it was opened with a token of
+// sessionID, i.e. one revoked by user.session_revoked. The client receives event as
+// the last message before the close frame.
+func (h *Hub) DisconnectSession(userID int, sessionID string, event UnifiedEvent) {
+	h.mu.RLock()
+	client, exists := h.userClients[userID]
+	exists = exists && client.sessionID == sessionID
+	if exists {
+		select {
+		case client.send <- event:
+		default:
+		}
+	}
+	h.mu.RUnlock()
+	if !exists {
+		return
+	}
+	log.Printf("🔒 Disconnecting user %d: %s", userID, event.Type)
+	h.unregister <- client
+}
+
 // getCurrentConnectedUsers returns list of currently connected user IDs
 func (h *Hub) getCurrentConnectedUsers() []int {
 	var users []int
@@ -130,24 +183,25 @@ func (h *Hub) BroadcastToTeam(event UnifiedEvent) {
 	log.Printf("Warning: BroadcastToTeam is deprecated, use BroadcastToUsers instead")
 }
 
-// HandleWebSocket handles WebSocket connection upgrade and management
+// HandleWebSocket authenticates the handshake and upgrades it to a WebSocket. The
+// access token comes from the Authorization header or, since browsers cannot set
+// headers on WebSocket requests, the token query parameter. The socket belongs to the
+// token's user; a userId parameter is ignored.
 func (h *Hub) HandleWebSocket(c *gin.Context) {
-	// Get user ID from query parameter (in production, extract from JWT)
-	userIDStr := c.Query("userId")
-	if userIDStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "userId parameter required"})
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if token == "" {
+		token = c.Query("token")
+	}
+	if token == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "access token required"})
 		return
 	}
-
-	userID, err := strconv.Atoi(userIDStr)
+	userID, tokenVersion, sessionID, err := h.verifier.Verify(token)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid userId"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
 		return
 	}
 
-	// TODO: Validate JWT token
-	// For now, we'll assume the user is authorized
-
 	// Upgrade HTTP connection to WebSocket
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -157,10 +211,12 @@ func (h *Hub) HandleWebSocket(c *gin.Context) {
 
 	// Create new client
 	client := &Client{
-		conn:   conn,
-		send:   make(chan UnifiedEvent, 256),
-		userID: userID,
-		hub:    h,
+		conn:         conn,
+		send:         make(chan UnifiedEvent, 256),
+		userID:       userID,
+		tokenVersion: tokenVersion,
+		sessionID:    sessionID,
+		hub:          h,
 	}
 
 	// Register client with hub
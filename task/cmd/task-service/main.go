@@ -1,13 +1,16 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	_ "time/tzdata"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 
+	"github.com/VerSysLabTin23/TodolistProject/task/internal/apperr"
 	"github.com/VerSysLabTin23/TodolistProject/task/internal/clients"
 	"github.com/VerSysLabTin23/TodolistProject/task/internal/events"
 	"github.com/VerSysLabTin23/TodolistProject/task/internal/handlers"
@@ -35,18 +38,30 @@ func main() {
 	repo := repository.NewTaskRepository(gdb)
 	producer := events.NewKafkaProducer()
 	teamClient := clients.NewTeamClient()
-	authClient := clients.NewAuthClient()
 	h := handlers.NewTaskHandlers(repo, teamClient)
 	// Attach producer to handlers via package-level setter (simple for now)
 	h.SetProducer(producer)
-	auth := middleware.NewAuthMiddleware(authClient)
+	// Tasks of users deleted in the auth service are unassigned
+	go events.ConsumeUserDeletions(context.Background(), "task-service", h.HandleUserDeleted)
+	// Erased accounts are replaced as task creators; completion is reported to auth
+	go events.ConsumeErasureRequests(context.Background(), "task-service", h.HandleUserErased)
+	auth := middleware.NewAuthMiddleware(newTokenValidator(), clients.NewJWKSVerifier())
 
 	// --- router ---
-	r := gin.Default()
+	// Every request gets an ID that is logged and returned in error responses
+	r := gin.New()
+	r.Use(apperr.RequestID(), gin.LoggerWithFormatter(apperr.LogFormatter), gin.CustomRecovery(apperr.Recovered))
+	r.NoRoute(apperr.NoRoute)
 
 	// Health check
 	r.GET("/healthz", h.HealthCheck)
 
+	// Internal service endpoints; callers must present a service token from auth's /oauth/token
+	internal := r.Group("/internal", auth.RequireService())
+	{
+		internal.GET("/users/:userId/tasks", h.GetUserTasks)
+	}
+
 	// Team-scoped task collection (recommended) - requires authentication
 	r.GET("/teams/:teamId/tasks", auth.RequireAuth(), h.ListTasksByTeam)
 	r.POST("/teams/:teamId/tasks", auth.RequireAuth(), h.CreateTaskInTeam)
@@ -69,6 +84,21 @@ func main() {
 	}
 }
 
+// newTokenValidator verifies tokens offline against the auth service's JWKS by default;
+// AUTH_TOKEN_VERIFICATION=remote falls back to calling /validate on every request.
+func newTokenValidator() clients.TokenValidator {
+	if getEnv("AUTH_TOKEN_VERIFICATION", "jwks") == "remote" {
+		return clients.NewAuthClient()
+	}
+	// Tokens revoked by the auth service (user.tokens_revoked, user.session_revoked) are
+	// rejected before they expire
+	revocations := clients.NewTokenRevocations()
+	go events.ConsumeTokenRevocations(context.Background(), revocations.Revoke)
+	go events.ConsumeSessionRevocations(context.Background(), revocations.RevokeSession)
+	// Personal access tokens are opaque and always checked with the auth service
+	return clients.WithPersonalTokens(clients.WithRevocations(clients.NewJWKSVerifier(), revocations), clients.NewAuthClient())
+}
+
 func getEnv(k, def string) string {
 	if v := os.Getenv(k); v != "" {
 		return v
@@ -0,0 +1,54 @@
+package apperr
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the request ID in requests and responses
+const RequestIDHeader = "X-Request-Id"
+
+const requestIDKey = "requestID"
+
+// Incoming IDs are reused only when they look like IDs, so they are safe to log
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID gives every request an ID: the caller's X-Request-Id (e.g. set by the
+// gateway) when present, otherwise a random one. It is echoed in the response header,
+// included in problems and logged with internal errors.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		c.Set(requestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// RequestIDFrom returns the ID set by RequestID, or "" outside of it
+func RequestIDFrom(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// LogFormatter is gin's default access log line with the request ID added
+func LogFormatter(p gin.LogFormatterParams) string {
+	id, _ := p.Keys[requestIDKey].(string)
+	return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v | %s\n%s",
+		p.TimeStamp.Format("2006/01/02 - 15:04:05"), p.StatusCode, p.Latency, p.ClientIP, p.Method, p.Path, id, p.ErrorMessage)
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -29,16 +30,72 @@ func NewAuthClient() *AuthClient {
 	}
 }
 
+// Token types accepted for API requests: access JWTs and the auth service's opaque
+// personal access tokens. Refresh tokens must never authenticate a call to this service.
+const (
+	TokenTypeAccess   = "access"
+	TokenTypePersonal = "personal"
+)
+
 // UserInfo represents user information from Auth Service
 type UserInfo struct {
-	Valid bool `json:"valid"`
-	User  struct {
+	Valid     bool   `json:"valid"`
+	TokenType string `json:"tokenType"`
+	// Restricted is set for users with an unverified email; they may only read
+	Restricted bool `json:"restricted"`
+	// ClientID and Scope are set on tokens issued to third-party OAuth clients;
+	// personal access tokens carry a Scope only
+	ClientID string `json:"clientId"`
+	Scope    string `json:"scope"`
+	// ImpersonatorID is the admin acting as the user with an impersonation token;
+	// events caused by such requests record it next to the actor
+	ImpersonatorID int `json:"impersonatorId"`
+	// TokenVersion is only known for tokens verified offline; the auth service
+	// checks it itself on /validate
+	TokenVersion int `json:"-"`
+	// SessionID is the login session of a first-party access token, also only known
+	// for tokens verified offline
+	SessionID string `json:"-"`
+	User      struct {
 		ID       int    `json:"id"`
 		Username string `json:"username"`
 		Role     string `json:"role"`
+		// Permissions are granted by the user's role in the auth service
+		Permissions []string `json:"permissions"`
+		// Timezone is the user's IANA timezone, empty if unset (treat as UTC)
+		Timezone string `json:"timezone"`
 	} `json:"user"`
 }
 
+// IsAPIToken reports whether the token may authenticate API requests
+func (u *UserInfo) IsAPIToken() bool {
+	return u.TokenType == TokenTypeAccess || u.TokenType == TokenTypePersonal
+}
+
+// HasPermission reports whether the user's role grants permission, e.g. "tasks.delete_any"
+func (u *UserInfo) HasPermission(permission string) bool {
+	for _, p := range u.User.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether the token may be used for an operation that needs scope.
+// First-party tokens (no client ID, not a personal access token) are not limited by scopes.
+func (u *UserInfo) HasScope(scope string) bool {
+	if u.ClientID == "" && u.TokenType != TokenTypePersonal {
+		return true
+	}
+	for _, s := range strings.Fields(u.Scope) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
 // ValidateToken validates a JWT token with Auth Service
 func (ac *AuthClient) ValidateToken(token string) (*UserInfo, error) {
 	url := fmt.Sprintf("%s/validate", ac.baseURL)
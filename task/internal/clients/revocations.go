@@ -0,0 +1,88 @@
+package clients
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// TokenRevocations remembers, per user, the token version below which access tokens
+// were revoked by the auth service, and the login sessions that were ended. It is fed
+// from user.tokens_revoked and user.session_revoked events so that offline
+// verification rejects revoked tokens before they expire.
+type TokenRevocations struct {
+	mu         sync.RWMutex
+	minVersion map[int]int          // user ID -> lowest token version still valid
+	sessions   map[string]time.Time // ended session ID -> expiry of its last access token
+}
+
+// NewTokenRevocations creates an empty revocation list
+func NewTokenRevocations() *TokenRevocations {
+	return &TokenRevocations{minVersion: map[int]int{}, sessions: map[string]time.Time{}}
+}
+
+// Revoke records that the user's tokens older than version are revoked. Events may
+// arrive out of order, so a lower version never replaces a higher one.
+func (r *TokenRevocations) Revoke(userID, version int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if version > r.minVersion[userID] {
+		r.minVersion[userID] = version
+	}
+}
+
+// IsRevoked reports whether a token of the user carrying version was revoked
+func (r *TokenRevocations) IsRevoked(userID, version int) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return version < r.minVersion[userID]
+}
+
+// RevokeSession records that the access tokens of a session are revoked. Entries are
+// dropped once every access token they could match has expired.
+func (r *TokenRevocations) RevokeSession(sessionID string, until time.Time) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, expiry := range r.sessions {
+		if now.After(expiry) {
+			delete(r.sessions, id)
+		}
+	}
+	if until.After(now) {
+		r.sessions[sessionID] = until
+	}
+}
+
+// IsSessionRevoked reports whether the session an access token belongs to was ended
+func (r *TokenRevocations) IsSessionRevoked(sessionID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.sessions[sessionID]
+	return ok
+}
+
+// revocationChecker rejects tokens the wrapped validator accepted but that were revoked since
+type revocationChecker struct {
+	next        TokenValidator
+	revocations *TokenRevocations
+}
+
+// WithRevocations wraps an offline validator so that tokens revoked by the auth service
+// are rejected immediately instead of when they expire
+func WithRevocations(next TokenValidator, revocations *TokenRevocations) TokenValidator {
+	return &revocationChecker{next: next, revocations: revocations}
+}
+
+// ValidateToken validates token with the wrapped validator and checks the revocation list
+func (c *revocationChecker) ValidateToken(token string) (*UserInfo, error) {
+	info, err := c.next.ValidateToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if c.revocations.IsRevoked(info.User.ID, info.TokenVersion) ||
+		(info.SessionID != "" && c.revocations.IsSessionRevoked(info.SessionID)) {
+		return nil, errors.New("token revoked")
+	}
+	return info, nil
+}
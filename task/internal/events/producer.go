@@ -22,6 +22,8 @@ type TaskEvent struct {
 	AssigneeID *int        `json:"assigneeId,omitempty"`
 	Timestamp  time.Time   `json:"timestamp"`
 	Payload    interface{} `json:"payload,omitempty"`
+	// ImpersonatorID is the admin who acted through an impersonation token of ActorID
+	ImpersonatorID int `json:"impersonatorId,omitempty"`
 }
 
 func NewKafkaProducer() *KafkaProducer {
@@ -45,10 +47,28 @@ func (p *KafkaProducer) Close() error {
 	return p.writer.Close()
 }
 
+type impersonatorKey struct{}
+
+// WithImpersonator marks events published with ctx as caused by an impersonation
+// session of impersonatorID; 0 leaves ctx unchanged
+func WithImpersonator(ctx context.Context, impersonatorID int) context.Context {
+	if impersonatorID == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, impersonatorKey{}, impersonatorID)
+}
+
+// impersonatorFrom returns the impersonator stored by WithImpersonator, or 0
+func impersonatorFrom(ctx context.Context) int {
+	id, _ := ctx.Value(impersonatorKey{}).(int)
+	return id
+}
+
 func (p *KafkaProducer) publish(ctx context.Context, topic string, evt TaskEvent) error {
 	if p == nil || p.writer == nil {
 		return nil
 	}
+	evt.ImpersonatorID = impersonatorFrom(ctx)
 	b, err := json.Marshal(evt)
 	if err != nil {
 		return err
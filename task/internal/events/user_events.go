@@ -0,0 +1,115 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Topics of the auth service's user lifecycle and erasure saga
+const (
+	UserDeletedTopic      = "user.deleted"
+	ErasureRequestedTopic = "user.erasure_requested"
+	ErasureCompletedTopic = "user.erasure_completed"
+)
+
+// userEvent is the part of an auth service user event this service needs
+type userEvent struct {
+	UserID  int `json:"userId"`
+	ActorID int `json:"actorId"`
+}
+
+// erasureCompletedEvent reports to the auth service that a service erased a user's data
+type erasureCompletedEvent struct {
+	EventType string    `json:"eventType"`
+	UserID    int       `json:"userId"`
+	Service   string    `json:"service"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ConsumeUserDeletions calls handle for every user.deleted event until ctx is cancelled.
+// All instances share the consumer group groupID, so each event is handled once. An
+// event is only committed after handle succeeded; failures are retried.
+func ConsumeUserDeletions(ctx context.Context, groupID string, handle func(userID, actorID int) error) {
+	consumeUserEvents(ctx, UserDeletedTopic, groupID, func(evt userEvent) error {
+		return handle(evt.UserID, evt.ActorID)
+	})
+}
+
+// ConsumeErasureRequests calls handle for every user.erasure_requested event until ctx
+// is cancelled, with the same delivery guarantees as ConsumeUserDeletions. The auth
+// service repeats requests that were not reported as completed.
+func ConsumeErasureRequests(ctx context.Context, groupID string, handle func(userID int) error) {
+	consumeUserEvents(ctx, ErasureRequestedTopic, groupID, func(evt userEvent) error {
+		return handle(evt.UserID)
+	})
+}
+
+// ErasureCompleted reports to the auth service that this service erased a user's data
+func (p *KafkaProducer) ErasureCompleted(ctx context.Context, userID int, service string) error {
+	if p == nil || p.writer == nil {
+		return nil
+	}
+	b, err := json.Marshal(erasureCompletedEvent{EventType: ErasureCompletedTopic, UserID: userID, Service: service, Timestamp: time.Now()})
+	if err != nil {
+		return err
+	}
+	return p.writer.WriteMessages(ctx, kafka.Message{
+		Topic: ErasureCompletedTopic,
+		Key:   []byte("user:" + itoa(userID)),
+		Value: b,
+		Time:  time.Now(),
+	})
+}
+
+// consumeUserEvents reads topic in the consumer group groupID and commits each event
+// once handle succeeded, retrying with backoff until then
+func consumeUserEvents(ctx context.Context, topic, groupID string, handle func(evt userEvent) error) {
+	brokers := os.Getenv("KAFKA_BROKERS")
+	if brokers == "" {
+		brokers = "dev_kafka:9092"
+	}
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: []string{brokers},
+		Topic:   topic,
+		GroupID: groupID,
+		MaxWait: 1 * time.Second,
+	})
+	defer r.Close()
+
+	for {
+		m, err := r.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Kafka read error on topic %s: %v", topic, err)
+			time.Sleep(time.Second)
+			continue
+		}
+		var evt userEvent
+		if err := json.Unmarshal(m.Value, &evt); err != nil || evt.UserID == 0 {
+			log.Printf("Ignoring malformed %s event: %s", topic, string(m.Value))
+		} else {
+			for backoff := time.Second; ; backoff = min(2*backoff, time.Minute) {
+				err := handle(evt)
+				if err == nil {
+					break
+				}
+				log.Printf("Failed to handle %s for user %d, retrying in %s: %v", topic, evt.UserID, backoff, err)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(backoff):
+				}
+			}
+		}
+		if err := r.CommitMessages(ctx, m); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to commit %s offset: %v", topic, err)
+		}
+	}
+}
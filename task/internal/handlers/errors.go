@@ -0,0 +1,14 @@
+package handlers
+
+import "github.com/VerSysLabTin23/TodolistProject/task/internal/apperr"
+
+// Errors reported by the task handlers; their codes are part of the API
+var (
+	ErrTaskNotFound      = apperr.New(apperr.NotFound, "TASK_NOT_FOUND", "Task not found")
+	ErrTeamNotFound      = apperr.New(apperr.NotFound, "TEAM_NOT_FOUND", "Team not found")
+	ErrNotTeamMember     = apperr.New(apperr.Forbidden, "NOT_TEAM_MEMBER", "user is not a member of this team")
+	ErrAssigneeNotMember = apperr.New(apperr.Invalid, "ASSIGNEE_NOT_MEMBER", "assignee must be a member of the team")
+	ErrInvalidPriority   = apperr.New(apperr.Invalid, "INVALID_PRIORITY", "priority must be one of: low, medium, high")
+	ErrInvalidDueDate    = apperr.New(apperr.Invalid, "INVALID_DUE_DATE", "due must be a valid date (YYYY-MM-DD)")
+	ErrInvalidDueFilter  = apperr.New(apperr.Invalid, "INVALID_DUE_FILTER", "due must be today, overdue or upcoming")
+)
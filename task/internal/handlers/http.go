@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/VerSysLabTin23/TodolistProject/task/internal/apperr"
 	"github.com/VerSysLabTin23/TodolistProject/task/internal/clients"
 	"github.com/VerSysLabTin23/TodolistProject/task/internal/events"
 	"github.com/VerSysLabTin23/TodolistProject/task/internal/middleware"
@@ -35,7 +36,7 @@ func (h *TaskHandlers) HealthCheck(c *gin.Context) {
 func (h *TaskHandlers) ListTasksByTeam(c *gin.Context) {
 	teamID, err := models.ParseID(c.Param("teamId"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid team id"))
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "invalid team id")
 		return
 	}
 
@@ -46,41 +47,48 @@ func (h *TaskHandlers) ListTasksByTeam(c *gin.Context) {
 	// Verify team exists by calling Team Service
 	team, err := h.teamClient.GetTeam(teamID, token)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "failed to verify team"))
+		apperr.Write(c, apperr.Wrap(err, "failed to verify team"))
 		return
 	}
 	if team == nil {
-		c.JSON(http.StatusNotFound, errResp("TEAM_NOT_FOUND", "Team not found"))
+		apperr.Write(c, ErrTeamNotFound)
 		return
 	}
 
 	var filters models.TaskFilters
 	if err := c.ShouldBindQuery(&filters); err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid query parameters"))
+		apperr.Write(c, apperr.Validation(err))
 		return
 	}
+	if filters.Due != nil {
+		if !models.ValidateDueFilter(*filters.Due) {
+			apperr.Write(c, ErrInvalidDueFilter)
+			return
+		}
+		filters.Today = models.TodayIn(middleware.UserLocation(c))
+	}
 
 	// Get user ID from JWT context
 	userID, exists := middleware.GetUserIDFromContext(c)
 	if !exists {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "user ID not found in context"))
+		apperr.Respond(c, http.StatusInternalServerError, "INTERNAL_ERROR", "user ID not found in context")
 		return
 	}
 
 	// Verify user is member of the team
 	isMember, err := h.teamClient.IsUserInTeam(userID, teamID, token)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "failed to verify team membership"))
+		apperr.Write(c, apperr.Wrap(err, "failed to verify team membership"))
 		return
 	}
 	if !isMember {
-		c.JSON(http.StatusForbidden, errResp("FORBIDDEN", "user is not a member of this team"))
+		apperr.Write(c, ErrNotTeamMember)
 		return
 	}
 
 	ts, err := h.repo.ListTasksByTeam(teamID, filters)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 
@@ -91,7 +99,7 @@ func (h *TaskHandlers) ListTasksByTeam(c *gin.Context) {
 func (h *TaskHandlers) CreateTaskInTeam(c *gin.Context) {
 	teamID, err := models.ParseID(c.Param("teamId"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid team id"))
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "invalid team id")
 		return
 	}
 
@@ -102,54 +110,48 @@ func (h *TaskHandlers) CreateTaskInTeam(c *gin.Context) {
 	// Verify team exists by calling Team Service
 	team, err := h.teamClient.GetTeam(teamID, token)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "failed to verify team"))
+		apperr.Write(c, apperr.Wrap(err, "failed to verify team"))
 		return
 	}
 	if team == nil {
-		c.JSON(http.StatusNotFound, errResp("TEAM_NOT_FOUND", "Team not found"))
+		apperr.Write(c, ErrTeamNotFound)
 		return
 	}
 
 	var req models.NewTaskInTeam
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid request body"))
-		return
-	}
-
-	// Validate required fields
-	if req.Title == "" || req.Priority == "" || req.Due == "" {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "title, priority, and due are required"))
+		apperr.Write(c, apperr.Validation(err))
 		return
 	}
 
 	// Validate priority
 	if !models.ValidatePriority(req.Priority) {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "priority must be one of: low, medium, high"))
+		apperr.Write(c, ErrInvalidPriority)
 		return
 	}
 
 	// Parse due date
 	due, err := models.ParseDateYYYYMMDD(req.Due)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "due must be a valid date (YYYY-MM-DD)"))
+		apperr.Write(c, ErrInvalidDueDate)
 		return
 	}
 
 	// Get creator ID from JWT context
 	creatorID, exists := middleware.GetUserIDFromContext(c)
 	if !exists {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "user ID not found in context"))
+		apperr.Respond(c, http.StatusInternalServerError, "INTERNAL_ERROR", "user ID not found in context")
 		return
 	}
 
 	// Verify user is member of the team
 	isMember, err := h.teamClient.IsUserInTeam(creatorID, teamID, token)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "failed to verify team membership"))
+		apperr.Write(c, apperr.Wrap(err, "failed to verify team membership"))
 		return
 	}
 	if !isMember {
-		c.JSON(http.StatusForbidden, errResp("FORBIDDEN", "user is not a member of this team"))
+		apperr.Write(c, ErrNotTeamMember)
 		return
 	}
 
@@ -165,7 +167,7 @@ func (h *TaskHandlers) CreateTaskInTeam(c *gin.Context) {
 	}
 
 	if err := h.repo.Create(t); err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 
@@ -173,7 +175,7 @@ func (h *TaskHandlers) CreateTaskInTeam(c *gin.Context) {
 
 	// Emit task.created event (best-effort)
 	if h.producer != nil {
-		_ = h.producer.TaskCreated(context.Background(), t.ID, t.TeamID, creatorID, t.CreatorID, t.AssigneeID, map[string]any{
+		_ = h.producer.TaskCreated(eventContext(c), t.ID, t.TeamID, creatorID, t.CreatorID, t.AssigneeID, map[string]any{
 			"title":       t.Title,
 			"description": t.Description,
 			"priority":    string(t.Priority),
@@ -182,17 +184,40 @@ func (h *TaskHandlers) CreateTaskInTeam(c *gin.Context) {
 	}
 }
 
+// GetUserTasks returns every task a user created or is assigned to, e.g. for the auth
+// service's data export (service tokens only)
+func (h *TaskHandlers) GetUserTasks(c *gin.Context) {
+	userID, err := models.ParseID(c.Param("userId"))
+	if err != nil {
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "invalid user id")
+		return
+	}
+	tasks, err := h.repo.ListByUser(userID)
+	if err != nil {
+		apperr.Write(c, apperr.Wrap(err, "failed to list tasks"))
+		return
+	}
+	c.JSON(http.StatusOK, models.MapTasks(tasks))
+}
+
 // ListTasksAcrossTeams returns tasks restricted to teams of the current user
 func (h *TaskHandlers) ListTasksAcrossTeams(c *gin.Context) {
 	var filters models.TaskFilters
 	if err := c.ShouldBindQuery(&filters); err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid query parameters"))
+		apperr.Write(c, apperr.Validation(err))
 		return
 	}
+	if filters.Due != nil {
+		if !models.ValidateDueFilter(*filters.Due) {
+			apperr.Write(c, ErrInvalidDueFilter)
+			return
+		}
+		filters.Today = models.TodayIn(middleware.UserLocation(c))
+	}
 
 	userID, exists := middleware.GetUserIDFromContext(c)
 	if !exists {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "user ID not found in context"))
+		apperr.Respond(c, http.StatusInternalServerError, "INTERNAL_ERROR", "user ID not found in context")
 		return
 	}
 
@@ -202,7 +227,7 @@ func (h *TaskHandlers) ListTasksAcrossTeams(c *gin.Context) {
 
 	teams, err := h.teamClient.GetUserTeams(userID, token)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "failed to fetch user teams"))
+		apperr.Write(c, apperr.Wrap(err, "failed to fetch user teams"))
 		return
 	}
 	if len(teams) == 0 {
@@ -217,7 +242,7 @@ func (h *TaskHandlers) ListTasksAcrossTeams(c *gin.Context) {
 
 	ts, err := h.repo.ListTasksByTeams(teamIDs, filters)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 
@@ -228,24 +253,24 @@ func (h *TaskHandlers) ListTasksAcrossTeams(c *gin.Context) {
 func (h *TaskHandlers) GetTask(c *gin.Context) {
 	id, err := models.ParseID(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid task id"))
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "invalid task id")
 		return
 	}
 
 	t, err := h.repo.GetByID(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 	if t == nil {
-		c.JSON(http.StatusNotFound, errResp("NOT_FOUND", "Task not found"))
+		apperr.Write(c, ErrTaskNotFound)
 		return
 	}
 
 	// Get user ID from JWT context
 	userID, exists := middleware.GetUserIDFromContext(c)
 	if !exists {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "user ID not found in context"))
+		apperr.Respond(c, http.StatusInternalServerError, "INTERNAL_ERROR", "user ID not found in context")
 		return
 	}
 
@@ -256,11 +281,11 @@ func (h *TaskHandlers) GetTask(c *gin.Context) {
 	// Verify user is member of the team
 	isMember, err := h.teamClient.IsUserInTeam(userID, t.TeamID, token)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "failed to verify team membership"))
+		apperr.Write(c, apperr.Wrap(err, "failed to verify team membership"))
 		return
 	}
 	if !isMember {
-		c.JSON(http.StatusForbidden, errResp("FORBIDDEN", "user is not a member of this team"))
+		apperr.Write(c, ErrNotTeamMember)
 		return
 	}
 
@@ -271,30 +296,30 @@ func (h *TaskHandlers) GetTask(c *gin.Context) {
 func (h *TaskHandlers) UpdateTask(c *gin.Context) {
 	id, err := models.ParseID(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid task id"))
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "invalid task id")
 		return
 	}
 
 	var req models.UpdateTask
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid request body"))
+		apperr.Write(c, apperr.Validation(err))
 		return
 	}
 
 	t, err := h.repo.GetByID(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 	if t == nil {
-		c.JSON(http.StatusNotFound, errResp("NOT_FOUND", "Task not found"))
+		apperr.Write(c, ErrTaskNotFound)
 		return
 	}
 
 	// Get user ID from JWT context
 	userID, exists := middleware.GetUserIDFromContext(c)
 	if !exists {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "user ID not found in context"))
+		apperr.Respond(c, http.StatusInternalServerError, "INTERNAL_ERROR", "user ID not found in context")
 		return
 	}
 
@@ -305,11 +330,11 @@ func (h *TaskHandlers) UpdateTask(c *gin.Context) {
 	// Verify user is member of the team
 	isMember, err := h.teamClient.IsUserInTeam(userID, t.TeamID, token)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "failed to verify team membership"))
+		apperr.Write(c, apperr.Wrap(err, "failed to verify team membership"))
 		return
 	}
 	if !isMember {
-		c.JSON(http.StatusForbidden, errResp("FORBIDDEN", "user is not a member of this team"))
+		apperr.Write(c, ErrNotTeamMember)
 		return
 	}
 
@@ -325,7 +350,7 @@ func (h *TaskHandlers) UpdateTask(c *gin.Context) {
 	}
 	if req.Priority != nil {
 		if !models.ValidatePriority(*req.Priority) {
-			c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "priority must be one of: low, medium, high"))
+			apperr.Write(c, ErrInvalidPriority)
 			return
 		}
 		t.Priority = models.Priority(*req.Priority)
@@ -333,7 +358,7 @@ func (h *TaskHandlers) UpdateTask(c *gin.Context) {
 	if req.Due != nil {
 		d, err := models.ParseDateYYYYMMDD(*req.Due)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "due must be a valid date (YYYY-MM-DD)"))
+			apperr.Write(c, ErrInvalidDueDate)
 			return
 		}
 		t.Due = d
@@ -342,10 +367,10 @@ func (h *TaskHandlers) UpdateTask(c *gin.Context) {
 		// Verify assignee is member of the team
 		if *req.AssigneeID != 0 {
 			if ok, err := h.teamClient.IsUserInTeam(*req.AssigneeID, t.TeamID, token); err != nil {
-				c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "failed to verify assignee team membership"))
+				apperr.Write(c, apperr.Wrap(err, "failed to verify assignee team membership"))
 				return
 			} else if !ok {
-				c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "assignee must be a member of the team"))
+				apperr.Write(c, ErrAssigneeNotMember)
 				return
 			}
 		}
@@ -353,7 +378,7 @@ func (h *TaskHandlers) UpdateTask(c *gin.Context) {
 	}
 
 	if err := h.repo.Update(t); err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 
@@ -361,7 +386,7 @@ func (h *TaskHandlers) UpdateTask(c *gin.Context) {
 
 	// Emit task.updated event (best-effort)
 	if h.producer != nil {
-		_ = h.producer.TaskUpdated(context.Background(), t.ID, t.TeamID, userID, t.CreatorID, t.AssigneeID, map[string]any{
+		_ = h.producer.TaskUpdated(eventContext(c), t.ID, t.TeamID, userID, t.CreatorID, t.AssigneeID, map[string]any{
 			"title":       t.Title,
 			"completed":   t.Completed,
 			"priority":    string(t.Priority),
@@ -376,24 +401,24 @@ func (h *TaskHandlers) UpdateTask(c *gin.Context) {
 func (h *TaskHandlers) DeleteTask(c *gin.Context) {
 	id, err := models.ParseID(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid task id"))
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "invalid task id")
 		return
 	}
 
 	t, err := h.repo.GetByID(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 	if t == nil {
-		c.JSON(http.StatusNotFound, errResp("NOT_FOUND", "Task not found"))
+		apperr.Write(c, ErrTaskNotFound)
 		return
 	}
 
 	// Get user ID from JWT context
 	userID, exists := middleware.GetUserIDFromContext(c)
 	if !exists {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "user ID not found in context"))
+		apperr.Respond(c, http.StatusInternalServerError, "INTERNAL_ERROR", "user ID not found in context")
 		return
 	}
 
@@ -401,22 +426,24 @@ func (h *TaskHandlers) DeleteTask(c *gin.Context) {
 	bt, _ := c.Get("authToken")
 	token, _ := bt.(string)
 
-	// Verify user is member of the team
-	isMember, err := h.teamClient.IsUserInTeam(userID, t.TeamID, token)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "failed to verify team membership"))
-		return
-	}
-	if !isMember {
-		c.JSON(http.StatusForbidden, errResp("FORBIDDEN", "user is not a member of this team"))
-		return
+	// Verify user is member of the team, unless their role may delete any task
+	if !middleware.HasPermission(c, middleware.PermTasksDeleteAny) {
+		isMember, err := h.teamClient.IsUserInTeam(userID, t.TeamID, token)
+		if err != nil {
+			apperr.Write(c, apperr.Wrap(err, "failed to verify team membership"))
+			return
+		}
+		if !isMember {
+			apperr.Write(c, ErrNotTeamMember)
+			return
+		}
 	}
 
 	// TODO: Add additional permission check - only team owner and admin can delete tasks
 	// For now, all team members can delete tasks
 
 	if err := h.repo.Delete(id); err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 
@@ -424,7 +451,7 @@ func (h *TaskHandlers) DeleteTask(c *gin.Context) {
 
 	// Emit task.deleted event (best-effort)
 	if h.producer != nil {
-		_ = h.producer.TaskDeleted(context.Background(), t.ID, t.TeamID, userID, t.CreatorID, t.AssigneeID, map[string]any{
+		_ = h.producer.TaskDeleted(eventContext(c), t.ID, t.TeamID, userID, t.CreatorID, t.AssigneeID, map[string]any{
 			"title": t.Title,
 		})
 	}
@@ -434,31 +461,31 @@ func (h *TaskHandlers) DeleteTask(c *gin.Context) {
 func (h *TaskHandlers) SetAssignee(c *gin.Context) {
 	id, err := models.ParseID(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid task id"))
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "invalid task id")
 		return
 	}
 
 	var req models.SetAssignee
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid request body"))
+		apperr.Write(c, apperr.Validation(err))
 		return
 	}
 
 	// Get user ID from JWT context
 	userID, exists := middleware.GetUserIDFromContext(c)
 	if !exists {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "user ID not found in context"))
+		apperr.Respond(c, http.StatusInternalServerError, "INTERNAL_ERROR", "user ID not found in context")
 		return
 	}
 
 	// Get task to verify team membership
 	task, err := h.repo.GetByID(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 	if task == nil {
-		c.JSON(http.StatusNotFound, errResp("NOT_FOUND", "Task not found"))
+		apperr.Write(c, ErrTaskNotFound)
 		return
 	}
 
@@ -469,27 +496,27 @@ func (h *TaskHandlers) SetAssignee(c *gin.Context) {
 	// Verify user is member of the team
 	isMember, err := h.teamClient.IsUserInTeam(userID, task.TeamID, token)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "failed to verify team membership"))
+		apperr.Write(c, apperr.Wrap(err, "failed to verify team membership"))
 		return
 	}
 	if !isMember {
-		c.JSON(http.StatusForbidden, errResp("FORBIDDEN", "user is not a member of this team"))
+		apperr.Write(c, ErrNotTeamMember)
 		return
 	}
 
 	if err := h.repo.UpdateAssignee(id, req.AssigneeID); err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 
 	// Get updated task to return
 	t, err := h.repo.GetByID(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 	if t == nil {
-		c.JSON(http.StatusNotFound, errResp("NOT_FOUND", "Task not found"))
+		apperr.Write(c, ErrTaskNotFound)
 		return
 	}
 
@@ -497,7 +524,7 @@ func (h *TaskHandlers) SetAssignee(c *gin.Context) {
 
 	// Emit task.updated event (assignee changed)
 	if h.producer != nil {
-		_ = h.producer.TaskUpdated(context.Background(), t.ID, t.TeamID, userID, t.CreatorID, t.AssigneeID, map[string]any{"assigneeId": t.AssigneeID})
+		_ = h.producer.TaskUpdated(eventContext(c), t.ID, t.TeamID, userID, t.CreatorID, t.AssigneeID, map[string]any{"assigneeId": t.AssigneeID})
 	}
 }
 
@@ -505,7 +532,7 @@ func (h *TaskHandlers) SetAssignee(c *gin.Context) {
 func (h *TaskHandlers) UpdateCompletion(c *gin.Context) {
 	id, err := models.ParseID(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid task id"))
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "invalid task id")
 		return
 	}
 
@@ -513,25 +540,25 @@ func (h *TaskHandlers) UpdateCompletion(c *gin.Context) {
 		Completed bool `json:"completed"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid request body"))
+		apperr.Write(c, apperr.Validation(err))
 		return
 	}
 
 	// Get user ID from JWT context
 	userID, exists := middleware.GetUserIDFromContext(c)
 	if !exists {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "user ID not found in context"))
+		apperr.Respond(c, http.StatusInternalServerError, "INTERNAL_ERROR", "user ID not found in context")
 		return
 	}
 
 	// Get task to verify team membership
 	task, err := h.repo.GetByID(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 	if task == nil {
-		c.JSON(http.StatusNotFound, errResp("NOT_FOUND", "Task not found"))
+		apperr.Write(c, ErrTaskNotFound)
 		return
 	}
 
@@ -542,27 +569,27 @@ func (h *TaskHandlers) UpdateCompletion(c *gin.Context) {
 	// Verify user is member of the team
 	isMember, err := h.teamClient.IsUserInTeam(userID, task.TeamID, token)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "failed to verify team membership"))
+		apperr.Write(c, apperr.Wrap(err, "failed to verify team membership"))
 		return
 	}
 	if !isMember {
-		c.JSON(http.StatusForbidden, errResp("FORBIDDEN", "user is not a member of this team"))
+		apperr.Write(c, ErrNotTeamMember)
 		return
 	}
 
 	if err := h.repo.UpdateCompletion(id, req.Completed); err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 
 	// Get updated task to return
 	t, err := h.repo.GetByID(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 	if t == nil {
-		c.JSON(http.StatusNotFound, errResp("NOT_FOUND", "Task not found"))
+		apperr.Write(c, ErrTaskNotFound)
 		return
 	}
 
@@ -570,15 +597,12 @@ func (h *TaskHandlers) UpdateCompletion(c *gin.Context) {
 
 	// Emit task.completed event
 	if h.producer != nil {
-		_ = h.producer.TaskCompleted(context.Background(), t.ID, t.TeamID, userID, t.CreatorID, t.AssigneeID, req.Completed)
+		_ = h.producer.TaskCompleted(eventContext(c), t.ID, t.TeamID, userID, t.CreatorID, t.AssigneeID, req.Completed)
 	}
 }
 
-// --- error helper ---
-
-type errorResponse struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
+// eventContext returns the context to publish events of the request with; events of
+// requests made with an impersonation token carry the real actor as impersonatorId
+func eventContext(c *gin.Context) context.Context {
+	return events.WithImpersonator(context.Background(), c.GetInt("impersonatorID"))
 }
-
-func errResp(code, msg string) errorResponse { return errorResponse{Code: code, Message: msg} }
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"log"
+
+	"github.com/VerSysLabTin23/TodolistProject/task/internal/models"
+)
+
+// HandleUserDeleted unassigns the tasks of a user deleted in the auth service and
+// announces each change as task.updated. It is idempotent, so a redelivered
+// user.deleted event does no harm.
+func (h *TaskHandlers) HandleUserDeleted(userID, actorID int) error {
+	tasks, err := h.repo.UnassignUser(userID)
+	if err != nil {
+		return err
+	}
+	log.Printf("Unassigned %d task(s) of deleted user %d", len(tasks), userID)
+	if h.producer == nil {
+		return nil
+	}
+	for _, t := range tasks {
+		_ = h.producer.TaskUpdated(context.Background(), t.ID, t.TeamID, actorID, t.CreatorID, nil, map[string]interface{}{
+			"assigneeId":         nil,
+			"previousAssigneeId": userID,
+		})
+	}
+	return nil
+}
+
+// HandleUserErased erases what this service knows about a user whose account erasure
+// started in the auth service: remaining assignments are cleared and the user is
+// replaced as creator by models.DeletedUserID. Completion is reported back to auth.
+func (h *TaskHandlers) HandleUserErased(userID int) error {
+	if err := h.HandleUserDeleted(userID, 0); err != nil {
+		return err
+	}
+	tasks, err := h.repo.AnonymizeCreator(userID)
+	if err != nil {
+		return err
+	}
+	log.Printf("Anonymized the creator of %d task(s) of erased user %d", len(tasks), userID)
+	if h.producer == nil {
+		return nil
+	}
+	ctx := context.Background()
+	for _, t := range tasks {
+		_ = h.producer.TaskUpdated(ctx, t.ID, t.TeamID, 0, t.CreatorID, t.AssigneeID, map[string]interface{}{
+			"creatorId":         models.DeletedUserID,
+			"previousCreatorId": userID,
+		})
+	}
+	return h.producer.ErasureCompleted(ctx, userID, "task")
+}
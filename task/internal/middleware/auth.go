@@ -3,20 +3,60 @@ package middleware
 import (
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/VerSysLabTin23/TodolistProject/task/internal/apperr"
 	"github.com/VerSysLabTin23/TodolistProject/task/internal/clients"
 	"github.com/gin-gonic/gin"
 )
 
+// OAuth scopes of the task API; only enforced for tokens issued to third-party clients
+// and for personal access tokens
+const (
+	ScopeTasksRead  = "tasks:read"
+	ScopeTasksWrite = "tasks:write"
+)
+
+// Permissions granted by roles in the auth service that the task service checks
+const (
+	// PermTasksDeleteAny allows deleting tasks of teams the user is not a member of
+	PermTasksDeleteAny = "tasks.delete_any"
+)
+
 // AuthMiddleware handles authentication and authorization for Task Service
 type AuthMiddleware struct {
-	authClient *clients.AuthClient
+	validator clients.TokenValidator
+	services  clients.ServiceTokenValidator
 }
 
-// NewAuthMiddleware creates a new auth middleware
-func NewAuthMiddleware(authClient *clients.AuthClient) *AuthMiddleware {
+// NewAuthMiddleware creates a new auth middleware; validator is usually a
+// JWKSVerifier (offline) or the AuthClient (remote /validate call), services
+// verifies the service tokens presented on /internal routes
+func NewAuthMiddleware(validator clients.TokenValidator, services clients.ServiceTokenValidator) *AuthMiddleware {
 	return &AuthMiddleware{
-		authClient: authClient,
+		validator: validator,
+		services:  services,
+	}
+}
+
+// RequireService accepts only service tokens issued by the auth service's client
+// credentials grant; user tokens are rejected. The caller is stored as "serviceClient".
+func (am *AuthMiddleware) RequireService() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			apperr.Respond(c, http.StatusUnauthorized, "UNAUTHORIZED", "Missing or invalid service credentials")
+			c.Abort()
+			return
+		}
+		clientID, err := am.services.ValidateServiceToken(strings.TrimPrefix(authHeader, "Bearer "))
+		if err != nil {
+			apperr.Respond(c, http.StatusUnauthorized, "UNAUTHORIZED", "Invalid or expired service token")
+			c.Abort()
+			return
+		}
+		c.Set("serviceClient", clientID)
+		c.Next()
 	}
 }
 
@@ -26,20 +66,14 @@ func (am *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 		// Extract token from Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    "UNAUTHORIZED",
-				"message": "Missing authorization header",
-			})
+			apperr.Respond(c, http.StatusUnauthorized, "UNAUTHORIZED", "Missing authorization header")
 			c.Abort()
 			return
 		}
 
 		// Check if it's a Bearer token
 		if !strings.HasPrefix(authHeader, "Bearer ") {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    "UNAUTHORIZED",
-				"message": "Invalid authorization header format",
-			})
+			apperr.Respond(c, http.StatusUnauthorized, "UNAUTHORIZED", "Invalid authorization header format")
 			c.Abort()
 			return
 		}
@@ -47,22 +81,44 @@ func (am *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 		// Extract token
 		token := strings.TrimPrefix(authHeader, "Bearer ")
 
-		// Validate token with Auth Service
-		userInfo, err := am.authClient.ValidateToken(token)
+		// Validate token (offline against the JWKS, or with Auth Service)
+		userInfo, err := am.validator.ValidateToken(token)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    "UNAUTHORIZED",
-				"message": "Invalid or expired token",
-			})
+			apperr.Respond(c, http.StatusUnauthorized, "UNAUTHORIZED", "Invalid or expired token")
 			c.Abort()
 			return
 		}
 
 		if !userInfo.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    "UNAUTHORIZED",
-				"message": "Invalid token",
-			})
+			apperr.Respond(c, http.StatusUnauthorized, "UNAUTHORIZED", "Invalid token")
+			c.Abort()
+			return
+		}
+
+		// Only access and personal access tokens may call the API; refresh tokens are
+		// for /auth/refresh only
+		if !userInfo.IsAPIToken() {
+			apperr.Respond(c, http.StatusUnauthorized, "UNAUTHORIZED", "Access token required")
+			c.Abort()
+			return
+		}
+
+		// Users who have not verified their email yet may only read
+		if userInfo.Restricted && !isSafeMethod(c.Request.Method) {
+			apperr.Respond(c, http.StatusForbidden, "EMAIL_NOT_VERIFIED", "Verify your email address to modify tasks")
+			c.Abort()
+			return
+		}
+
+		// OAuth clients and personal access tokens need tasks:read to read and
+		// tasks:write to modify tasks
+		scope := ScopeTasksWrite
+		if isSafeMethod(c.Request.Method) {
+			scope = ScopeTasksRead
+		}
+		if !userInfo.HasScope(scope) {
+			c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+			apperr.Respond(c, http.StatusForbidden, "INSUFFICIENT_SCOPE", "Token lacks the "+scope+" scope")
 			c.Abort()
 			return
 		}
@@ -71,14 +127,22 @@ func (am *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 		c.Set("userID", userInfo.User.ID)
 		c.Set("username", userInfo.User.Username)
 		c.Set("userRole", userInfo.User.Role)
+		c.Set("permissions", userInfo.User.Permissions)
+		c.Set("impersonatorID", userInfo.ImpersonatorID)
+		c.Set("timezone", userInfo.User.Timezone)
 		c.Set("authToken", token)
 
 		c.Next()
 	}
 }
 
-// RequireRole ensures the user has a specific role
-func (am *AuthMiddleware) RequireRole(requiredRole string) gin.HandlerFunc {
+// isSafeMethod reports whether the HTTP method only reads data
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// RequirePermission ensures the user is authenticated and their role grants permission
+func (am *AuthMiddleware) RequirePermission(permission string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// First ensure authentication
 		am.RequireAuth()(c)
@@ -86,22 +150,9 @@ func (am *AuthMiddleware) RequireRole(requiredRole string) gin.HandlerFunc {
 			return
 		}
 
-		// Check role
-		userRole, exists := c.Get("userRole")
-		if !exists {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"code":    "INTERNAL_ERROR",
-				"message": "User role not found in context",
-			})
-			c.Abort()
-			return
-		}
-
-		if userRole != requiredRole {
-			c.JSON(http.StatusForbidden, gin.H{
-				"code":    "FORBIDDEN",
-				"message": "Insufficient permissions",
-			})
+		if !HasPermission(c, permission) {
+			apperr.RespondWith(c, http.StatusForbidden, "FORBIDDEN", "This endpoint requires the "+permission+" permission",
+				gin.H{"reason": "PERMISSION_REQUIRED", "permission": permission})
 			c.Abort()
 			return
 		}
@@ -110,9 +161,23 @@ func (am *AuthMiddleware) RequireRole(requiredRole string) gin.HandlerFunc {
 	}
 }
 
-// RequireAdmin ensures the user is an admin
-func (am *AuthMiddleware) RequireAdmin() gin.HandlerFunc {
-	return am.RequireRole("admin")
+// HasPermission reports whether the authenticated user's role grants permission
+func HasPermission(c *gin.Context, permission string) bool {
+	for _, p := range c.GetStringSlice("permissions") {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// UserLocation returns the caller's timezone from their token, UTC if none is set or
+// it is unknown here
+func UserLocation(c *gin.Context) *time.Location {
+	if loc, err := time.LoadLocation(c.GetString("timezone")); err == nil {
+		return loc
+	}
+	return time.UTC
 }
 
 // GetUserIDFromContext extracts user ID from gin context
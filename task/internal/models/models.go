@@ -16,6 +16,9 @@ const (
 	PriorityHigh   Priority = "high"
 )
 
+// DeletedUserID is the creator of tasks whose creator's account was erased
+const DeletedUserID = 0
+
 type Task struct {
 	ID          int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
 	TeamID      int       `gorm:"column:team_id;not null" json:"teamId"`
@@ -47,10 +50,10 @@ type TaskResponse struct {
 }
 
 type NewTaskInTeam struct {
-	Title       string  `json:"title"`
+	Title       string  `json:"title" binding:"required"`
 	Description *string `json:"description"`
-	Priority    string  `json:"priority"`
-	Due         string  `json:"due"` // YYYY-MM-DD
+	Priority    string  `json:"priority" binding:"required"`
+	Due         string  `json:"due" binding:"required"` // YYYY-MM-DD
 	AssigneeID  *int    `json:"assigneeId"`
 }
 
@@ -75,6 +78,10 @@ type TaskFilters struct {
 	Query      *string `form:"q"`
 	Limit      *int    `form:"limit"`
 	Offset     *int    `form:"offset"`
+	// Due is "today", "overdue" (open tasks due before today) or "upcoming" (due after
+	// today). Today is the current date in the caller's timezone, set by the handler.
+	Due   *string `form:"due"`
+	Today string  `form:"-"`
 }
 
 // --- helpers ---
@@ -115,6 +122,16 @@ func ParseDateYYYYMMDD(s string) (time.Time, error) {
 	return time.Parse("2006-01-02", s)
 }
 
+// ValidateDueFilter reports whether due is a known value of the due filter
+func ValidateDueFilter(due string) bool {
+	return due == "today" || due == "overdue" || due == "upcoming"
+}
+
+// TodayIn returns the current date in loc as YYYY-MM-DD, the format of due dates
+func TodayIn(loc *time.Location) string {
+	return time.Now().In(loc).Format("2006-01-02")
+}
+
 func ValidatePriority(priority string) bool {
 	return priority == "low" || priority == "medium" || priority == "high"
 }
@@ -19,12 +19,31 @@ type TaskRepository interface {
 	Delete(id int) error
 	UpdateAssignee(id int, assigneeID *int) error
 	UpdateCompletion(id int, completed bool) error
+	ListByUser(userID int) ([]models.Task, error)
+	UnassignUser(userID int) ([]models.Task, error)
+	AnonymizeCreator(userID int) ([]models.Task, error)
 }
 
 type taskRepo struct{ db *gorm.DB }
 
 func NewTaskRepository(db *gorm.DB) TaskRepository { return &taskRepo{db: db} }
 
+// applyDueFilter restricts query to tasks due today, overdue or upcoming relative to filters.Today
+func applyDueFilter(query *gorm.DB, filters models.TaskFilters) *gorm.DB {
+	if filters.Due == nil || filters.Today == "" {
+		return query
+	}
+	switch *filters.Due {
+	case "today":
+		return query.Where("due = ?", filters.Today)
+	case "overdue":
+		return query.Where("due < ? AND completed = ?", filters.Today, false)
+	case "upcoming":
+		return query.Where("due > ?", filters.Today)
+	}
+	return query
+}
+
 // ListTasksByTeam returns tasks in a specific team, sorted by priority then due date
 func (r *taskRepo) ListTasksByTeam(teamID int, filters models.TaskFilters) ([]models.Task, error) {
 	var ts []models.Task
@@ -44,6 +63,7 @@ func (r *taskRepo) ListTasksByTeam(teamID int, filters models.TaskFilters) ([]mo
 		query = query.Where("(title LIKE ? OR description LIKE ?)",
 			"%"+*filters.Query+"%", "%"+*filters.Query+"%") // WHERE title LIKE '%keyword%' OR description LIKE '%keyword%'
 	}
+	query = applyDueFilter(query, filters)
 
 	// Apply pagination
 	limit := 20 // default
@@ -88,6 +108,7 @@ func (r *taskRepo) ListTasksAcrossTeams(filters models.TaskFilters) ([]models.Ta
 		query = query.Where("(title LIKE ? OR description LIKE ?)",
 			"%"+*filters.Query+"%", "%"+*filters.Query+"%")
 	}
+	query = applyDueFilter(query, filters)
 
 	// Apply pagination
 	limit := 20 // default
@@ -131,6 +152,7 @@ func (r *taskRepo) ListTasksByTeams(teamIDs []int, filters models.TaskFilters) (
 	if filters.Query != nil && *filters.Query != "" {
 		query = query.Where("(title LIKE ? OR description LIKE ?)", "%"+*filters.Query+"%", "%"+*filters.Query+"%")
 	}
+	query = applyDueFilter(query, filters)
 
 	limit := 20
 	if filters.Limit != nil {
@@ -169,3 +191,47 @@ func (r *taskRepo) UpdateAssignee(id int, assigneeID *int) error {
 func (r *taskRepo) UpdateCompletion(id int, completed bool) error {
 	return r.db.Model(&models.Task{}).Where("id = ?", id).Update("completed", completed).Error
 }
+
+// ListByUser returns the tasks a user created or is assigned to, in all teams
+func (r *taskRepo) ListByUser(userID int) ([]models.Task, error) {
+	var ts []models.Task
+	err := r.db.Where("creator_id = ? OR assignee_id = ?", userID, userID).Order("team_id, id").Find(&ts).Error
+	return ts, err
+}
+
+// UnassignUser clears the assignee of every task assigned to userID and returns those tasks
+func (r *taskRepo) UnassignUser(userID int) ([]models.Task, error) {
+	var ts []models.Task
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("assignee_id = ?", userID).Find(&ts).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Task{}).Where("assignee_id = ?", userID).Update("assignee_id", nil).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range ts {
+		ts[i].AssigneeID = nil
+	}
+	return ts, nil
+}
+
+// AnonymizeCreator replaces userID as the creator of tasks with models.DeletedUserID
+// and returns those tasks
+func (r *taskRepo) AnonymizeCreator(userID int) ([]models.Task, error) {
+	var ts []models.Task
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("creator_id = ?", userID).Find(&ts).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Task{}).Where("creator_id = ?", userID).Update("creator_id", models.DeletedUserID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range ts {
+		ts[i].CreatorID = models.DeletedUserID
+	}
+	return ts, nil
+}
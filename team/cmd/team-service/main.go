@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 
@@ -8,6 +9,7 @@ import (
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 
+	"github.com/VerSysLabTin23/TodolistProject/team/internal/apperr"
 	"github.com/VerSysLabTin23/TodolistProject/team/internal/clients"
 	"github.com/VerSysLabTin23/TodolistProject/team/internal/events"
 	"github.com/VerSysLabTin23/TodolistProject/team/internal/handlers"
@@ -35,36 +37,56 @@ func main() {
 
 	// Wire repositories, handlers, and middleware
 	repo := repository.NewTeamRepository(gdb)
-	authClient := clients.NewAuthClient()
 	h := handlers.NewTeamHandlers(repo)
-	auth := middleware.NewAuthMiddleware(repo, authClient)
+	auth := middleware.NewAuthMiddleware(repo, newTokenValidator(), clients.NewJWKSVerifier())
 
 	// Initialize Kafka producer (optional)
 	producer := events.NewKafkaProducer()
 	h.SetProducer(producer)
+	// Users deleted in the auth service are removed from their teams
+	go events.ConsumeUserDeletions(context.Background(), "team-service", h.HandleUserDeleted)
+	go events.ConsumeErasureRequests(context.Background(), "team-service", h.HandleUserErased)
 	defer func() {
 		if err := producer.Close(); err != nil {
 			log.Printf("failed to close kafka producer: %v", err)
 		}
 	}()
 
-	// Setup router
-	r := gin.Default()
+	// Setup router; every request gets an ID that is logged and returned in error responses
+	r := gin.New()
+	r.Use(apperr.RequestID(), gin.LoggerWithFormatter(apperr.LogFormatter), gin.CustomRecovery(apperr.Recovered))
+	r.NoRoute(apperr.NoRoute)
 
 	// Health check
 	r.GET("/healthz", h.HealthCheck)
 
-	// Internal service endpoints (no auth required for service-to-service communication)
-	r.GET("/internal/teams/:id/members", h.GetTeamMembers)
+	// Internal service endpoints; callers must present a service token from auth's /oauth/token
+	internal := r.Group("/internal", auth.RequireService())
+	{
+		internal.GET("/teams/:id/members", h.GetTeamMembers)
+		internal.GET("/users/:userId/memberships", h.GetUserMemberships)
+	}
+	// Teams backing SCIM groups of the auth service; idempotent, actor in X-Actor-Id.
+	// Only auth's own service client (AUTH_SERVICE_CLIENT_ID) may manage them.
+	provisioning := internal.Group("", auth.RequireServiceClient(getEnv("AUTH_SERVICE_CLIENT_ID", "auth")))
+	{
+		provisioning.POST("/teams", h.CreateProvisionedTeam)
+		provisioning.PUT("/teams/:id", h.UpdateProvisionedTeam)
+		provisioning.DELETE("/teams/:id", h.DeleteProvisionedTeam)
+		provisioning.PUT("/teams/:id/members/:userId", h.PutProvisionedMember)
+		provisioning.DELETE("/teams/:id/members/:userId", h.DeleteProvisionedMember)
+	}
 
 	// Public endpoints
 	r.GET("/teams", h.ListTeams)
-	r.POST("/teams", h.CreateTeam)
 	r.GET("/teams/:id", h.GetTeam)
 	r.GET("/users/:userId/teams", h.GetUserTeams)
 
-	// Team management (requires team membership)
-	r.PUT("/teams/:id", auth.RequireTeamMembership(), h.UpdateTeam)
+	// Creating teams needs the teams.create permission (granted to every built-in role)
+	r.POST("/teams", auth.RequirePermission(middleware.PermTeamsCreate), h.CreateTeam)
+
+	// Team management (owners and admins update, owners delete)
+	r.PUT("/teams/:id", auth.RequireTeamAdmin(), h.UpdateTeam)
 	r.DELETE("/teams/:id", auth.RequireTeamOwner(), h.DeleteTeam)
 
 	// Team membership management (requires admin privileges)
@@ -78,6 +100,21 @@ func main() {
 	}
 }
 
+// newTokenValidator verifies tokens offline against the auth service's JWKS by default;
+// AUTH_TOKEN_VERIFICATION=remote falls back to calling /validate on every request.
+func newTokenValidator() clients.TokenValidator {
+	if getEnv("AUTH_TOKEN_VERIFICATION", "jwks") == "remote" {
+		return clients.NewAuthClient()
+	}
+	// Tokens revoked by the auth service (user.tokens_revoked, user.session_revoked) are
+	// rejected before they expire
+	revocations := clients.NewTokenRevocations()
+	go events.ConsumeTokenRevocations(context.Background(), revocations.Revoke)
+	go events.ConsumeSessionRevocations(context.Background(), revocations.RevokeSession)
+	// Personal access tokens are opaque and always checked with the auth service
+	return clients.WithPersonalTokens(clients.WithRevocations(clients.NewJWKSVerifier(), revocations), clients.NewAuthClient())
+}
+
 func getEnv(k, def string) string {
 	if v := os.Getenv(k); v != "" {
 		return v
@@ -0,0 +1,145 @@
+// Package apperr defines typed errors with stable, machine-readable codes and writes
+// them as RFC 7807 problem details (application/problem+json).
+//
+// The auth, task and team services are separate modules built from their own
+// directories, so each keeps an identical copy of this package. Change the auth copy
+// and copy it over; tests/check_apperr_sync.sh, run in CI, fails when they differ.
+package apperr
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Kind classifies an error and decides the HTTP status it is reported with
+type Kind int
+
+const (
+	Internal Kind = iota
+	Invalid
+	Unauthorized
+	Forbidden
+	NotFound
+	Conflict
+	TooLarge
+	UnsupportedMediaType
+	BadGateway
+	Unavailable
+)
+
+var kindStatus = map[Kind]int{
+	Internal:             http.StatusInternalServerError,
+	Invalid:              http.StatusBadRequest,
+	Unauthorized:         http.StatusUnauthorized,
+	Forbidden:            http.StatusForbidden,
+	NotFound:             http.StatusNotFound,
+	Conflict:             http.StatusConflict,
+	TooLarge:             http.StatusRequestEntityTooLarge,
+	UnsupportedMediaType: http.StatusUnsupportedMediaType,
+	BadGateway:           http.StatusBadGateway,
+	Unavailable:          http.StatusServiceUnavailable,
+}
+
+// Status is the HTTP status errors of this kind are reported with
+func (k Kind) Status() int {
+	if s, ok := kindStatus[k]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+// Error is a domain error. Code is stable and meant for programs, Message is shown to
+// clients as the problem's detail, and Err is an optional cause that is only logged.
+// Services declare their errors as package-level sentinels compared with errors.Is.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+// FieldError points at a request field that failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// New creates an error; it is usually assigned to an exported Err* variable
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Wrap reports err as an internal error with a message that is safe to show;
+// err itself is logged but never sent to the client
+func Wrap(err error, message string) *Error {
+	return &Error{Kind: Internal, Code: "INTERNAL_ERROR", Message: message, Err: err}
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Write answers with err as a problem. Errors that are not an *Error are treated as
+// internal: the client gets a generic 500 and the cause is logged with the request
+// ID, so database and driver messages never reach the response.
+func Write(c *gin.Context, err error) {
+	var e *Error
+	if !errors.As(err, &e) {
+		e = Wrap(err, "An unexpected error occurred")
+	}
+	if e.Kind == Internal && e.Err != nil {
+		log.Printf("request %s: %s: %v", RequestIDFrom(c), e.Message, e.Err)
+	}
+	write(c, e.Kind.Status(), e.Code, e.Message, e.Fields, nil)
+}
+
+// Respond answers with a problem built from a status, code and detail, for errors
+// that only the handler knows about (bad path parameters, missing headers, ...)
+func Respond(c *gin.Context, status int, code, detail string) {
+	write(c, status, code, detail, nil, nil)
+}
+
+// RespondWith is Respond with extension members added to the problem
+func RespondWith(c *gin.Context, status int, code, detail string, extensions gin.H) {
+	write(c, status, code, detail, nil, extensions)
+}
+
+// write renders the problem. type is about:blank, so title is the status text and
+// code identifies the error; requestId lets clients quote the request in bug reports.
+func write(c *gin.Context, status int, code, detail string, fields []FieldError, extensions gin.H) {
+	body := gin.H{
+		"type":     "about:blank",
+		"title":    http.StatusText(status),
+		"status":   status,
+		"detail":   detail,
+		"instance": c.Request.URL.Path,
+		"code":     code,
+	}
+	if id := RequestIDFrom(c); id != "" {
+		body["requestId"] = id
+	}
+	if len(fields) > 0 {
+		body["errors"] = fields
+	}
+	for k, v := range extensions {
+		body[k] = v
+	}
+	c.Header("Content-Type", "application/problem+json")
+	c.JSON(status, body)
+}
+
+// NoRoute answers unknown paths with a problem instead of gin's plain text 404
+func NoRoute(c *gin.Context) {
+	Respond(c, http.StatusNotFound, "NOT_FOUND", "No such endpoint")
+}
+
+// Recovered answers requests whose handler panicked; use with gin.CustomRecovery
+func Recovered(c *gin.Context, recovered interface{}) {
+	log.Printf("request %s: panic: %v", RequestIDFrom(c), recovered)
+	Respond(c, http.StatusInternalServerError, "INTERNAL_ERROR", "An unexpected error occurred")
+	c.Abort()
+}
@@ -0,0 +1,269 @@
+package clients
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenValidator validates a bearer token and returns the caller's identity.
+// Both AuthClient (remote /validate call) and JWKSVerifier (offline) implement it.
+type TokenValidator interface {
+	ValidateToken(token string) (*UserInfo, error)
+}
+
+// ServiceTokenValidator validates a service token from the client credentials grant
+// and returns the calling client's ID. Only JWKSVerifier implements it: service
+// tokens are always verified offline.
+type ServiceTokenValidator interface {
+	ValidateServiceToken(token string) (string, error)
+}
+
+// PersonalAccessTokenPrefix starts the auth service's personal access tokens. They
+// are opaque, so only the auth service can validate them.
+const PersonalAccessTokenPrefix = "pat_"
+
+// personalTokenRouter sends personal access tokens to a remote validator and all
+// other tokens to the offline one
+type personalTokenRouter struct {
+	offline TokenValidator
+	remote  TokenValidator
+}
+
+// WithPersonalTokens wraps an offline validator so that personal access tokens, which
+// cannot be verified against the JWKS, are checked by remote (usually the AuthClient)
+func WithPersonalTokens(offline, remote TokenValidator) TokenValidator {
+	return &personalTokenRouter{offline: offline, remote: remote}
+}
+
+// ValidateToken validates token with the validator responsible for its kind
+func (r *personalTokenRouter) ValidateToken(token string) (*UserInfo, error) {
+	if strings.HasPrefix(token, PersonalAccessTokenPrefix) {
+		return r.remote.ValidateToken(token)
+	}
+	return r.offline.ValidateToken(token)
+}
+
+// tokenClaims mirrors the claims the auth service puts into its JWTs
+type tokenClaims struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	// Permissions come from the user's role (claim "perms")
+	Permissions []string `json:"perms"`
+	TokenType   string   `json:"typ"`
+	Restricted  bool     `json:"restricted"`
+	ClientID    string   `json:"client_id"`
+	Scope       string   `json:"scope"`
+	// TokenVersion is compared against TokenRevocations (see WithRevocations)
+	TokenVersion int `json:"ver"`
+	// SessionID is compared against the sessions ended by user.session_revoked
+	SessionID string `json:"sid"`
+	// ImpersonatorID is set on impersonation tokens
+	ImpersonatorID int `json:"impersonator_id"`
+	// Timezone is the user's IANA timezone when the token was issued
+	Timezone string `json:"tz"`
+	jwt.RegisteredClaims
+}
+
+// JWKSVerifier validates access tokens locally against the auth service's published
+// signing keys. The key set is cached and refetched when it expires or when a token
+// references an unknown kid (e.g. right after a key rotation).
+type JWKSVerifier struct {
+	jwksURL    string
+	httpClient *http.Client
+	cacheTTL   time.Duration
+	minRefresh time.Duration
+
+	mu        sync.RWMutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+// NewJWKSVerifier creates a verifier for AUTH_JWKS_URL (default: AUTH_SERVICE_URL + /.well-known/jwks.json)
+func NewJWKSVerifier() *JWKSVerifier {
+	jwksURL := os.Getenv("AUTH_JWKS_URL")
+	if jwksURL == "" {
+		baseURL := os.Getenv("AUTH_SERVICE_URL")
+		if baseURL == "" {
+			baseURL = "http://localhost:8084" // fallback for local development
+		}
+		jwksURL = baseURL + "/.well-known/jwks.json"
+	}
+	return &JWKSVerifier{
+		jwksURL:    jwksURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		cacheTTL:   5 * time.Minute,
+		minRefresh: 30 * time.Second,
+		keys:       map[string]interface{}{},
+	}
+}
+
+// ValidateToken verifies signature, issuer, audience, expiry and token type offline
+func (v *JWKSVerifier) ValidateToken(tokenString string) (*UserInfo, error) {
+	claims := &tokenClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, v.keyfunc,
+		jwt.WithValidMethods([]string{"RS256", "EdDSA"}),
+		jwt.WithIssuer("auth-service"),
+		jwt.WithAudience("todolist-api"))
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	info := &UserInfo{Valid: true, TokenType: claims.TokenType, Restricted: claims.Restricted, ClientID: claims.ClientID, Scope: claims.Scope, TokenVersion: claims.TokenVersion, SessionID: claims.SessionID, ImpersonatorID: claims.ImpersonatorID}
+	info.User.ID = claims.UserID
+	info.User.Username = claims.Username
+	info.User.Role = claims.Role
+	info.User.Permissions = claims.Permissions
+	info.User.Timezone = claims.Timezone
+	return info, nil
+}
+
+// ValidateServiceToken verifies a service token (typ "service", aud "todolist-internal")
+// and returns its client ID
+func (v *JWKSVerifier) ValidateServiceToken(tokenString string) (string, error) {
+	claims := &tokenClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, v.keyfunc,
+		jwt.WithValidMethods([]string{"RS256", "EdDSA"}),
+		jwt.WithIssuer("auth-service"),
+		jwt.WithAudience("todolist-internal"))
+	if err != nil {
+		return "", err
+	}
+	if !token.Valid || claims.TokenType != "service" || claims.ClientID == "" {
+		return "", errors.New("invalid service token")
+	}
+	return claims.ClientID, nil
+}
+
+func (v *JWKSVerifier) keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("missing kid header")
+	}
+	key, err := v.key(kid)
+	if err != nil {
+		return nil, err
+	}
+	switch key.(type) {
+	case *rsa.PublicKey:
+		if token.Method.Alg() != "RS256" {
+			return nil, errors.New("unexpected signing method")
+		}
+	case ed25519.PublicKey:
+		if token.Method.Alg() != "EdDSA" {
+			return nil, errors.New("unexpected signing method")
+		}
+	}
+	return key, nil
+}
+
+// key returns the cached key for kid, refreshing the key set when stale or unknown
+func (v *JWKSVerifier) key(kid string) (interface{}, error) {
+	v.mu.RLock()
+	key, ok := v.keys[kid]
+	age := time.Since(v.fetchedAt)
+	v.mu.RUnlock()
+	if ok && age < v.cacheTTL {
+		return key, nil
+	}
+	if !ok && age < v.minRefresh {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if err := v.refresh(); err != nil {
+		if ok {
+			// Keep verifying with the last known key set while auth is unreachable
+			return key, nil
+		}
+		return nil, err
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+}
+
+func (v *JWKSVerifier) refresh() error {
+	resp, err := v.httpClient.Get(v.jwksURL)
+	if err != nil {
+		return fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("auth service returned status: %d", resp.StatusCode)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("failed to decode jwks: %w", err)
+	}
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		pub, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+	v.mu.Lock()
+	v.keys = keys
+	v.fetchedAt = time.Now()
+	v.mu.Unlock()
+	return nil
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	b64 := base64.RawURLEncoding
+	switch k.Kty {
+	case "RSA":
+		n, err := b64.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := b64.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := b64.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
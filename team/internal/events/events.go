@@ -20,6 +20,8 @@ type TeamEvent struct {
 	OwnerID   int         `json:"ownerId"`
 	Timestamp time.Time   `json:"timestamp"`
 	Payload   interface{} `json:"payload,omitempty"`
+	// ImpersonatorID is the admin who acted through an impersonation token of ActorID
+	ImpersonatorID int `json:"impersonatorId,omitempty"`
 }
 
 type TeamMemberEvent struct {
@@ -30,6 +32,8 @@ type TeamMemberEvent struct {
 	Role      string      `json:"role,omitempty"`
 	Timestamp time.Time   `json:"timestamp"`
 	Payload   interface{} `json:"payload,omitempty"`
+	// ImpersonatorID is the admin who acted through an impersonation token of ActorID
+	ImpersonatorID int `json:"impersonatorId,omitempty"`
 }
 
 func NewKafkaProducer() *KafkaProducer {
@@ -53,10 +57,28 @@ func (p *KafkaProducer) Close() error {
 	return p.writer.Close()
 }
 
+type impersonatorKey struct{}
+
+// WithImpersonator marks events published with ctx as caused by an impersonation
+// session of impersonatorID; 0 leaves ctx unchanged
+func WithImpersonator(ctx context.Context, impersonatorID int) context.Context {
+	if impersonatorID == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, impersonatorKey{}, impersonatorID)
+}
+
+// impersonatorFrom returns the impersonator stored by WithImpersonator, or 0
+func impersonatorFrom(ctx context.Context) int {
+	id, _ := ctx.Value(impersonatorKey{}).(int)
+	return id
+}
+
 func (p *KafkaProducer) publishTeamEvent(ctx context.Context, topic string, evt TeamEvent) error {
 	if p == nil || p.writer == nil {
 		return nil
 	}
+	evt.ImpersonatorID = impersonatorFrom(ctx)
 	b, err := json.Marshal(evt)
 	if err != nil {
 		return err
@@ -73,6 +95,7 @@ func (p *KafkaProducer) publishMemberEvent(ctx context.Context, topic string, ev
 	if p == nil || p.writer == nil {
 		return nil
 	}
+	evt.ImpersonatorID = impersonatorFrom(ctx)
 	b, err := json.Marshal(evt)
 	if err != nil {
 		return err
@@ -0,0 +1,10 @@
+package handlers
+
+import "github.com/VerSysLabTin23/TodolistProject/team/internal/apperr"
+
+// Errors reported by the team handlers; their codes are part of the API
+var (
+	ErrTeamNotFound  = apperr.New(apperr.NotFound, "TEAM_NOT_FOUND", "Team not found")
+	ErrInvalidRole   = apperr.New(apperr.Invalid, "INVALID_ROLE", "invalid role")
+	ErrOwnerRequired = apperr.New(apperr.Conflict, "OWNER_REQUIRED", "The team owner cannot be removed")
+)
@@ -3,10 +3,13 @@ package handlers
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/VerSysLabTin23/TodolistProject/team/internal/apperr"
 	"github.com/VerSysLabTin23/TodolistProject/team/internal/events"
+	"github.com/VerSysLabTin23/TodolistProject/team/internal/middleware"
 	"github.com/VerSysLabTin23/TodolistProject/team/internal/models"
 	"github.com/VerSysLabTin23/TodolistProject/team/internal/repository"
 )
@@ -30,13 +33,13 @@ func (h *TeamHandlers) HealthCheck(c *gin.Context) {
 func (h *TeamHandlers) ListTeams(c *gin.Context) {
 	var filters models.TeamFilters
 	if err := c.ShouldBindQuery(&filters); err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid query parameters"))
+		apperr.Write(c, apperr.Validation(err))
 		return
 	}
 
 	teams, err := h.repo.ListTeams(filters)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 
@@ -47,19 +50,16 @@ func (h *TeamHandlers) ListTeams(c *gin.Context) {
 func (h *TeamHandlers) CreateTeam(c *gin.Context) {
 	var req models.NewTeam
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid request body"))
+		apperr.Write(c, apperr.Validation(err))
 		return
 	}
 
-	// Validate required fields
-	if req.Name == "" {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "team name is required"))
+	// The caller becomes the owner
+	ownerID, ok := callerID(c)
+	if !ok {
 		return
 	}
 
-	// TODO: Get owner ID from authenticated user context
-	ownerID := 1 // Placeholder - should come from JWT token
-
 	team := &models.Team{
 		Name:        req.Name,
 		Description: req.Description,
@@ -67,7 +67,7 @@ func (h *TeamHandlers) CreateTeam(c *gin.Context) {
 	}
 
 	if err := h.repo.Create(team); err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 
@@ -75,7 +75,7 @@ func (h *TeamHandlers) CreateTeam(c *gin.Context) {
 
 	// Emit team.created event (best-effort)
 	if h.producer != nil {
-		_ = h.producer.TeamCreated(context.Background(), team.ID, ownerID, team.OwnerID, map[string]any{
+		_ = h.producer.TeamCreated(eventContext(c), team.ID, ownerID, team.OwnerID, map[string]any{
 			"name":        team.Name,
 			"description": team.Description,
 		})
@@ -86,17 +86,17 @@ func (h *TeamHandlers) CreateTeam(c *gin.Context) {
 func (h *TeamHandlers) GetTeam(c *gin.Context) {
 	id, err := models.ParseID(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid team id"))
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "invalid team id")
 		return
 	}
 
 	team, err := h.repo.GetByID(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 	if team == nil {
-		c.JSON(http.StatusNotFound, errResp("NOT_FOUND", "Team not found"))
+		apperr.Write(c, ErrTeamNotFound)
 		return
 	}
 
@@ -110,29 +110,31 @@ func (h *TeamHandlers) GetTeam(c *gin.Context) {
 func (h *TeamHandlers) UpdateTeam(c *gin.Context) {
 	id, err := models.ParseID(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid team id"))
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "invalid team id")
 		return
 	}
 
 	var req models.UpdateTeam
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid request body"))
+		apperr.Write(c, apperr.Validation(err))
+		return
+	}
+
+	actorID, ok := callerID(c)
+	if !ok {
 		return
 	}
 
 	team, err := h.repo.GetByID(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 	if team == nil {
-		c.JSON(http.StatusNotFound, errResp("NOT_FOUND", "Team not found"))
+		apperr.Write(c, ErrTeamNotFound)
 		return
 	}
 
-	// TODO: Add ownership check here - only owner can update team
-	// For now, we'll assume the user has permission to update
-
 	// Update fields if provided
 	if req.Name != nil {
 		team.Name = *req.Name
@@ -142,7 +144,7 @@ func (h *TeamHandlers) UpdateTeam(c *gin.Context) {
 	}
 
 	if err := h.repo.Update(team); err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 
@@ -150,7 +152,7 @@ func (h *TeamHandlers) UpdateTeam(c *gin.Context) {
 
 	// Emit team.updated event (best-effort)
 	if h.producer != nil {
-		_ = h.producer.TeamUpdated(context.Background(), team.ID, 1, team.OwnerID, map[string]any{
+		_ = h.producer.TeamUpdated(eventContext(c), team.ID, actorID, team.OwnerID, map[string]any{
 			"name":        team.Name,
 			"description": team.Description,
 		})
@@ -161,26 +163,28 @@ func (h *TeamHandlers) UpdateTeam(c *gin.Context) {
 func (h *TeamHandlers) DeleteTeam(c *gin.Context) {
 	id, err := models.ParseID(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid team id"))
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "invalid team id")
+		return
+	}
+
+	actorID, ok := callerID(c)
+	if !ok {
 		return
 	}
 
 	// Get team info before deletion for event
 	team, err := h.repo.GetByID(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 	if team == nil {
-		c.JSON(http.StatusNotFound, errResp("NOT_FOUND", "Team not found"))
+		apperr.Write(c, ErrTeamNotFound)
 		return
 	}
 
-	// TODO: Add ownership check here - only owner can delete team
-	// For now, we'll assume the user has permission to delete
-
 	if err := h.repo.Delete(id); err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 
@@ -188,7 +192,7 @@ func (h *TeamHandlers) DeleteTeam(c *gin.Context) {
 
 	// Emit team.deleted event (best-effort)
 	if h.producer != nil {
-		_ = h.producer.TeamDeleted(context.Background(), team.ID, 1, team.OwnerID, map[string]any{
+		_ = h.producer.TeamDeleted(eventContext(c), team.ID, actorID, team.OwnerID, map[string]any{
 			"name": team.Name,
 		})
 	}
@@ -198,7 +202,7 @@ func (h *TeamHandlers) DeleteTeam(c *gin.Context) {
 func (h *TeamHandlers) GetTeamMembers(c *gin.Context) {
 	teamID, err := models.ParseID(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid team id"))
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "invalid team id")
 		return
 	}
 
@@ -207,7 +211,7 @@ func (h *TeamHandlers) GetTeamMembers(c *gin.Context) {
 
 	members, err := h.repo.GetTeamMembers(teamID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 
@@ -218,34 +222,36 @@ func (h *TeamHandlers) GetTeamMembers(c *gin.Context) {
 func (h *TeamHandlers) AddMember(c *gin.Context) {
 	teamID, err := models.ParseID(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid team id"))
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "invalid team id")
 		return
 	}
 
 	var req models.AddMember
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid request body"))
+		apperr.Write(c, apperr.Validation(err))
 		return
 	}
 
 	// Validate role
 	if !models.ValidateRole(string(req.Role)) {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid role"))
+		apperr.Write(c, ErrInvalidRole)
 		return
 	}
 
-	// TODO: Add permission check here - only owner and admin can add members
-	// For now, we'll assume the user has permission to add members
+	actorID, ok := callerID(c)
+	if !ok {
+		return
+	}
 
 	if err := h.repo.AddMember(teamID, req.UserID, req.Role); err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 
 	// Get the added member to return
 	members, err := h.repo.GetTeamMembers(teamID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 
@@ -256,7 +262,7 @@ func (h *TeamHandlers) AddMember(c *gin.Context) {
 
 			// Emit team.member_added event (best-effort)
 			if h.producer != nil {
-				_ = h.producer.MemberAdded(context.Background(), teamID, req.UserID, 1, string(req.Role), map[string]any{
+				_ = h.producer.MemberAdded(eventContext(c), teamID, req.UserID, actorID, string(req.Role), map[string]any{
 					"role": string(req.Role),
 				})
 			}
@@ -264,28 +270,30 @@ func (h *TeamHandlers) AddMember(c *gin.Context) {
 		}
 	}
 
-	c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "failed to retrieve added member"))
+	apperr.Respond(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to retrieve added member")
 }
 
 // RemoveMember removes a user from a team
 func (h *TeamHandlers) RemoveMember(c *gin.Context) {
 	teamID, err := models.ParseID(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid team id"))
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "invalid team id")
 		return
 	}
 
 	userID, err := models.ParseID(c.Param("userId"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid user id"))
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "invalid user id")
 		return
 	}
 
-	// TODO: Add permission check here - only owner and admin can remove members
-	// For now, we'll assume the user has permission to remove members
+	actorID, ok := callerID(c)
+	if !ok {
+		return
+	}
 
 	if err := h.repo.RemoveMember(teamID, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 
@@ -293,7 +301,7 @@ func (h *TeamHandlers) RemoveMember(c *gin.Context) {
 
 	// Emit team.member_removed event (best-effort)
 	if h.producer != nil {
-		_ = h.producer.MemberRemoved(context.Background(), teamID, userID, 1, map[string]any{
+		_ = h.producer.MemberRemoved(eventContext(c), teamID, userID, actorID, map[string]any{
 			"userID": userID,
 		})
 	}
@@ -303,7 +311,7 @@ func (h *TeamHandlers) RemoveMember(c *gin.Context) {
 func (h *TeamHandlers) GetUserTeams(c *gin.Context) {
 	userID, err := models.ParseID(c.Param("userId"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid user id"))
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "invalid user id")
 		return
 	}
 
@@ -312,17 +320,53 @@ func (h *TeamHandlers) GetUserTeams(c *gin.Context) {
 
 	teams, err := h.repo.GetUserTeams(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", err.Error()))
+		apperr.Write(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, models.MapTeams(teams))
 }
 
-// Error helper
-type errorResponse struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
+// GetUserMemberships returns the teams a user belongs to with their role and join date,
+// e.g. for the auth service's data export (service tokens only)
+func (h *TeamHandlers) GetUserMemberships(c *gin.Context) {
+	userID, err := models.ParseID(c.Param("userId"))
+	if err != nil {
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "invalid user id")
+		return
+	}
+	memberships, err := h.repo.GetUserMemberships(userID)
+	if err != nil {
+		apperr.Write(c, apperr.Wrap(err, "failed to list memberships"))
+		return
+	}
+	out := make([]models.UserMembershipResponse, 0, len(memberships))
+	for _, m := range memberships {
+		team, err := h.repo.GetByID(m.TeamID)
+		if err != nil {
+			apperr.Write(c, apperr.Wrap(err, "failed to load team"))
+			return
+		}
+		if team == nil {
+			continue
+		}
+		out = append(out, models.UserMembershipResponse{Team: models.MapTeam(*team), Role: string(m.Role), JoinedAt: m.JoinedAt.UTC().Format(time.RFC3339)})
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// callerID returns the authenticated user, who owns new teams and is the actor of team
+// events; it answers 401 when the route is missing its auth middleware
+func callerID(c *gin.Context) (int, bool) {
+	id, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		apperr.Respond(c, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+	}
+	return id, ok
 }
 
-func errResp(code, msg string) errorResponse { return errorResponse{Code: code, Message: msg} }
+// eventContext returns the context to publish events of the request with; events of
+// requests made with an impersonation token carry the real actor as impersonatorId
+func eventContext(c *gin.Context) context.Context {
+	return events.WithImpersonator(context.Background(), c.GetInt("impersonatorID"))
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/VerSysLabTin23/TodolistProject/team/internal/apperr"
+	"github.com/VerSysLabTin23/TodolistProject/team/internal/models"
+)
+
+// Teams provisioned by the auth service for SCIM groups. The calls are idempotent so
+// auth can repeat them after a failure; the user who made the change in auth is passed
+// in the X-Actor-Id header and reported as the actor of the resulting events.
+
+// CreateProvisionedTeam creates a team owned by ownerId (service tokens only)
+func (h *TeamHandlers) CreateProvisionedTeam(c *gin.Context) {
+	var req models.ProvisionTeam
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Write(c, apperr.Validation(err))
+		return
+	}
+	team := &models.Team{Name: req.Name, Description: req.Description, OwnerID: req.OwnerID}
+	if err := h.repo.Create(team); err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, models.MapTeam(*team))
+	if h.producer != nil {
+		_ = h.producer.TeamCreated(context.Background(), team.ID, provisioningActor(c), team.OwnerID, map[string]any{
+			"name":        team.Name,
+			"description": team.Description,
+			"source":      "scim",
+		})
+	}
+}
+
+// UpdateProvisionedTeam renames a provisioned team (service tokens only)
+func (h *TeamHandlers) UpdateProvisionedTeam(c *gin.Context) {
+	id, err := models.ParseID(c.Param("id"))
+	if err != nil {
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "invalid team id")
+		return
+	}
+	var req models.UpdateTeam
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Write(c, apperr.Validation(err))
+		return
+	}
+	team, err := h.repo.GetByID(id)
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	if team == nil {
+		apperr.Write(c, ErrTeamNotFound)
+		return
+	}
+	changed := false
+	if req.Name != nil && *req.Name != "" && *req.Name != team.Name {
+		team.Name = *req.Name
+		changed = true
+	}
+	if req.Description != nil {
+		team.Description = req.Description
+		changed = true
+	}
+	if !changed {
+		c.JSON(http.StatusOK, models.MapTeam(*team))
+		return
+	}
+	if err := h.repo.Update(team); err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.MapTeam(*team))
+	if h.producer != nil {
+		_ = h.producer.TeamUpdated(context.Background(), team.ID, provisioningActor(c), team.OwnerID, map[string]any{
+			"name":        team.Name,
+			"description": team.Description,
+			"source":      "scim",
+		})
+	}
+}
+
+// DeleteProvisionedTeam deletes a provisioned team with its memberships; deleting a
+// team that does not exist succeeds (service tokens only)
+func (h *TeamHandlers) DeleteProvisionedTeam(c *gin.Context) {
+	id, err := models.ParseID(c.Param("id"))
+	if err != nil {
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "invalid team id")
+		return
+	}
+	team, err := h.repo.GetByID(id)
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	if team == nil {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	if err := h.repo.Delete(id); err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+	if h.producer != nil {
+		_ = h.producer.TeamDeleted(context.Background(), team.ID, provisioningActor(c), team.OwnerID, map[string]any{
+			"name":   team.Name,
+			"source": "scim",
+		})
+	}
+}
+
+// PutProvisionedMember makes a user a member of a provisioned team; users who already
+// belong to the team keep their role (service tokens only)
+func (h *TeamHandlers) PutProvisionedMember(c *gin.Context) {
+	teamID, userID, ok := memberParams(c)
+	if !ok {
+		return
+	}
+	team, err := h.repo.GetByID(teamID)
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	if team == nil {
+		apperr.Write(c, ErrTeamNotFound)
+		return
+	}
+	role, err := h.repo.GetUserRoleInTeam(userID, teamID)
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	if role != nil {
+		c.JSON(http.StatusOK, models.TeamMemberResponse{UserID: userID, TeamID: teamID, Role: string(*role)})
+		return
+	}
+	if err := h.repo.AddMember(teamID, userID, models.RoleMember); err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, models.TeamMemberResponse{UserID: userID, TeamID: teamID, Role: string(models.RoleMember)})
+	if h.producer != nil {
+		_ = h.producer.MemberAdded(context.Background(), teamID, userID, provisioningActor(c), string(models.RoleMember), map[string]any{
+			"role":   string(models.RoleMember),
+			"source": "scim",
+		})
+	}
+}
+
+// DeleteProvisionedMember removes a user from a provisioned team; the owner cannot be
+// removed and removing a non-member succeeds (service tokens only)
+func (h *TeamHandlers) DeleteProvisionedMember(c *gin.Context) {
+	teamID, userID, ok := memberParams(c)
+	if !ok {
+		return
+	}
+	role, err := h.repo.GetUserRoleInTeam(userID, teamID)
+	if err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	if role == nil {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	if *role == models.RoleOwner {
+		apperr.Write(c, ErrOwnerRequired)
+		return
+	}
+	if err := h.repo.RemoveMember(teamID, userID); err != nil {
+		apperr.Write(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+	if h.producer != nil {
+		_ = h.producer.MemberRemoved(context.Background(), teamID, userID, provisioningActor(c), map[string]any{
+			"userID": userID,
+			"source": "scim",
+		})
+	}
+}
+
+// memberParams parses the team and user IDs of a membership route, answering 400 if they are invalid
+func memberParams(c *gin.Context) (teamID, userID int, ok bool) {
+	teamID, err := models.ParseID(c.Param("id"))
+	if err != nil {
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "invalid team id")
+		return 0, 0, false
+	}
+	userID, err = models.ParseID(c.Param("userId"))
+	if err != nil {
+		apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "invalid user id")
+		return 0, 0, false
+	}
+	return teamID, userID, true
+}
+
+// provisioningActor returns the auth user passed in X-Actor-Id, or 0 if there is none
+func provisioningActor(c *gin.Context) int {
+	id, _ := strconv.Atoi(c.GetHeader("X-Actor-Id"))
+	return id
+}
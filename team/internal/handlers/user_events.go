@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"log"
+
+	"github.com/VerSysLabTin23/TodolistProject/team/internal/models"
+)
+
+// HandleUserDeleted removes a user deleted in the auth service from all teams and
+// announces the resulting membership changes. It is idempotent, so a redelivered
+// user.deleted event does no harm.
+func (h *TeamHandlers) HandleUserDeleted(userID, actorID int) error {
+	removal, err := h.repo.RemoveUser(userID)
+	if err != nil {
+		return err
+	}
+	log.Printf("Removed deleted user %d from %d team(s), %d team(s) deleted", userID, len(removal.Memberships), len(removal.DeletedTeams))
+	if h.producer == nil {
+		return nil
+	}
+	ctx := context.Background()
+	deleted := map[int]bool{}
+	for _, t := range removal.DeletedTeams {
+		deleted[t.ID] = true
+		_ = h.producer.TeamDeleted(ctx, t.ID, actorID, t.OwnerID, map[string]interface{}{
+			"name":   t.Name,
+			"reason": "owner_deleted",
+		})
+	}
+	for _, m := range removal.Memberships {
+		if deleted[m.TeamID] {
+			continue
+		}
+		_ = h.producer.MemberRemoved(ctx, m.TeamID, userID, actorID, map[string]interface{}{
+			"reason": "user_deleted",
+		})
+		if newOwner, ok := removal.NewOwners[m.TeamID]; ok {
+			_ = h.producer.MemberRoleUpdated(ctx, m.TeamID, newOwner, actorID, string(models.RoleOwner), map[string]interface{}{
+				"previousOwnerId": userID,
+			})
+		}
+	}
+	return nil
+}
+
+// HandleUserErased removes a user whose account erasure started in the auth service
+// from all teams, in case user.deleted was missed, and reports completion back to auth
+func (h *TeamHandlers) HandleUserErased(userID int) error {
+	if err := h.HandleUserDeleted(userID, 0); err != nil {
+		return err
+	}
+	if h.producer == nil {
+		return nil
+	}
+	return h.producer.ErasureCompleted(context.Background(), userID, "team")
+}
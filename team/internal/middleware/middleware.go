@@ -6,55 +6,160 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/VerSysLabTin23/TodolistProject/team/internal/apperr"
 	"github.com/VerSysLabTin23/TodolistProject/team/internal/clients"
 	"github.com/VerSysLabTin23/TodolistProject/team/internal/models"
 	"github.com/VerSysLabTin23/TodolistProject/team/internal/repository"
 )
 
+// OAuth scopes of the team API; only enforced for tokens issued to third-party clients
+// and for personal access tokens
+const (
+	ScopeTeamsRead  = "teams:read"
+	ScopeTeamsWrite = "teams:write"
+	ScopeTeamsAdmin = "teams:admin"
+)
+
+// Permissions granted by roles in the auth service that the team service checks
+const (
+	PermTeamsCreate = "teams.create"
+)
+
 // AuthMiddleware handles authentication and authorization
 type AuthMiddleware struct {
-	repo       repository.TeamRepository
-	authClient *clients.AuthClient
+	repo      repository.TeamRepository
+	validator clients.TokenValidator
+	services  clients.ServiceTokenValidator
 }
 
-// NewAuthMiddleware creates a new auth middleware
-func NewAuthMiddleware(repo repository.TeamRepository, authClient *clients.AuthClient) *AuthMiddleware {
+// NewAuthMiddleware creates a new auth middleware; validator is usually a
+// JWKSVerifier (offline) or the AuthClient (remote /validate call), services
+// verifies the service tokens presented on /internal routes
+func NewAuthMiddleware(repo repository.TeamRepository, validator clients.TokenValidator, services clients.ServiceTokenValidator) *AuthMiddleware {
 	return &AuthMiddleware{
-		repo:       repo,
-		authClient: authClient,
+		repo:      repo,
+		validator: validator,
+		services:  services,
+	}
+}
+
+// RequireService accepts only service tokens issued by the auth service's client
+// credentials grant; user tokens are rejected. The caller is stored as "serviceClient".
+func (am *AuthMiddleware) RequireService() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			apperr.Respond(c, http.StatusUnauthorized, "UNAUTHORIZED", "Missing or invalid service credentials")
+			c.Abort()
+			return
+		}
+		clientID, err := am.services.ValidateServiceToken(strings.TrimPrefix(authHeader, "Bearer "))
+		if err != nil {
+			apperr.Respond(c, http.StatusUnauthorized, "UNAUTHORIZED", "Invalid or expired service token")
+			c.Abort()
+			return
+		}
+		c.Set("serviceClient", clientID)
+		c.Next()
+	}
+}
+
+// RequireServiceClient must run after RequireService; it only lets the given service
+// clients through, e.g. so that only the auth service manages the teams of SCIM groups
+func (am *AuthMiddleware) RequireServiceClient(clientIDs ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		caller := c.GetString("serviceClient")
+		for _, id := range clientIDs {
+			if caller == id {
+				c.Next()
+				return
+			}
+		}
+		apperr.Respond(c, http.StatusForbidden, "FORBIDDEN", "This endpoint is not available to service client "+caller)
+		c.Abort()
+	}
+}
+
+// RequirePermission ensures the user is authenticated and their role grants permission
+func (am *AuthMiddleware) RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Validate Authorization header (offline JWKS or Auth Service) and set user in context
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+			apperr.Respond(c, http.StatusUnauthorized, "UNAUTHORIZED", "Missing or invalid authorization header")
+			c.Abort()
+			return
+		}
+		token := strings.TrimPrefix(authHeader, "Bearer ")
+		userInfo, err := am.validator.ValidateToken(token)
+		if err != nil || !userInfo.Valid || !userInfo.IsAPIToken() {
+			apperr.Respond(c, http.StatusUnauthorized, "UNAUTHORIZED", "Invalid or expired token")
+			c.Abort()
+			return
+		}
+		if userInfo.Restricted && !isSafeMethod(c.Request.Method) {
+			apperr.Respond(c, http.StatusForbidden, "EMAIL_NOT_VERIFIED", "Verify your email address to modify teams")
+			c.Abort()
+			return
+		}
+		if !am.checkScope(c, userInfo, membershipScope(c.Request.Method)) {
+			return
+		}
+
+		if !userInfo.HasPermission(permission) {
+			apperr.RespondWith(c, http.StatusForbidden, "FORBIDDEN", "This endpoint requires the "+permission+" permission",
+				gin.H{"reason": "PERMISSION_REQUIRED", "permission": permission})
+			c.Abort()
+			return
+		}
+
+		// Store user info in context for handlers to use
+		c.Set("userID", userInfo.User.ID)
+		c.Set("impersonatorID", userInfo.ImpersonatorID)
+		c.Set("permissions", userInfo.User.Permissions)
+		c.Next()
 	}
 }
 
 // RequireTeamMembership ensures user is a member of the team
 func (am *AuthMiddleware) RequireTeamMembership() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Validate Authorization header with Auth Service and set user in context
+		// Validate Authorization header (offline JWKS or Auth Service) and set user in context
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			c.JSON(http.StatusUnauthorized, errResp("UNAUTHORIZED", "Missing or invalid authorization header"))
+			apperr.Respond(c, http.StatusUnauthorized, "UNAUTHORIZED", "Missing or invalid authorization header")
 			c.Abort()
 			return
 		}
 		token := strings.TrimPrefix(authHeader, "Bearer ")
-		userInfo, err := am.authClient.ValidateToken(token)
-		if err != nil || !userInfo.Valid {
-			c.JSON(http.StatusUnauthorized, errResp("UNAUTHORIZED", "Invalid or expired token"))
+		userInfo, err := am.validator.ValidateToken(token)
+		if err != nil || !userInfo.Valid || !userInfo.IsAPIToken() {
+			apperr.Respond(c, http.StatusUnauthorized, "UNAUTHORIZED", "Invalid or expired token")
 			c.Abort()
 			return
 		}
+		if userInfo.Restricted && !isSafeMethod(c.Request.Method) {
+			apperr.Respond(c, http.StatusForbidden, "EMAIL_NOT_VERIFIED", "Verify your email address to modify teams")
+			c.Abort()
+			return
+		}
+		if !am.checkScope(c, userInfo, membershipScope(c.Request.Method)) {
+			return
+		}
 		c.Set("userID", userInfo.User.ID)
+		c.Set("impersonatorID", userInfo.ImpersonatorID)
 
 		userID, _ := c.Get("userID")
 		userIDInt, ok := userID.(int)
 		if !ok {
-			c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "invalid user ID type"))
+			apperr.Respond(c, http.StatusInternalServerError, "INTERNAL_ERROR", "invalid user ID type")
 			c.Abort()
 			return
 		}
 
 		teamID, err := models.ParseID(c.Param("id"))
 		if err != nil {
-			c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid team id"))
+			apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "invalid team id")
 			c.Abort()
 			return
 		}
@@ -62,13 +167,13 @@ func (am *AuthMiddleware) RequireTeamMembership() gin.HandlerFunc {
 		// Check if user is member of the team
 		isMember, err := am.repo.IsUserInTeam(userIDInt, teamID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "failed to check team membership"))
+			apperr.Write(c, apperr.Wrap(err, "failed to check team membership"))
 			c.Abort()
 			return
 		}
 
 		if !isMember {
-			c.JSON(http.StatusForbidden, errResp("FORBIDDEN", "user is not a member of this team"))
+			apperr.Respond(c, http.StatusForbidden, "NOT_TEAM_MEMBER", "user is not a member of this team")
 			c.Abort()
 			return
 		}
@@ -83,33 +188,42 @@ func (am *AuthMiddleware) RequireTeamMembership() gin.HandlerFunc {
 // RequireTeamOwner ensures user is the owner of the team
 func (am *AuthMiddleware) RequireTeamOwner() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Validate Authorization header with Auth Service and set user in context
+		// Validate Authorization header (offline JWKS or Auth Service) and set user in context
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			c.JSON(http.StatusUnauthorized, errResp("UNAUTHORIZED", "Missing or invalid authorization header"))
+			apperr.Respond(c, http.StatusUnauthorized, "UNAUTHORIZED", "Missing or invalid authorization header")
 			c.Abort()
 			return
 		}
 		token := strings.TrimPrefix(authHeader, "Bearer ")
-		userInfo, err := am.authClient.ValidateToken(token)
-		if err != nil || !userInfo.Valid {
-			c.JSON(http.StatusUnauthorized, errResp("UNAUTHORIZED", "Invalid or expired token"))
+		userInfo, err := am.validator.ValidateToken(token)
+		if err != nil || !userInfo.Valid || !userInfo.IsAPIToken() {
+			apperr.Respond(c, http.StatusUnauthorized, "UNAUTHORIZED", "Invalid or expired token")
 			c.Abort()
 			return
 		}
+		if userInfo.Restricted && !isSafeMethod(c.Request.Method) {
+			apperr.Respond(c, http.StatusForbidden, "EMAIL_NOT_VERIFIED", "Verify your email address to modify teams")
+			c.Abort()
+			return
+		}
+		if !am.checkScope(c, userInfo, ScopeTeamsAdmin) {
+			return
+		}
 		c.Set("userID", userInfo.User.ID)
+		c.Set("impersonatorID", userInfo.ImpersonatorID)
 
 		userID, _ := c.Get("userID")
 		userIDInt, ok := userID.(int)
 		if !ok {
-			c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "invalid user ID type"))
+			apperr.Respond(c, http.StatusInternalServerError, "INTERNAL_ERROR", "invalid user ID type")
 			c.Abort()
 			return
 		}
 
 		teamID, err := models.ParseID(c.Param("id"))
 		if err != nil {
-			c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid team id"))
+			apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "invalid team id")
 			c.Abort()
 			return
 		}
@@ -117,13 +231,13 @@ func (am *AuthMiddleware) RequireTeamOwner() gin.HandlerFunc {
 		// Check if user is owner of the team
 		role, err := am.repo.GetUserRoleInTeam(userIDInt, teamID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "failed to check user role"))
+			apperr.Write(c, apperr.Wrap(err, "failed to check user role"))
 			c.Abort()
 			return
 		}
 
 		if role == nil || *role != models.RoleOwner {
-			c.JSON(http.StatusForbidden, errResp("FORBIDDEN", "only team owner can perform this action"))
+			apperr.Respond(c, http.StatusForbidden, "NOT_TEAM_OWNER", "only team owner can perform this action")
 			c.Abort()
 			return
 		}
@@ -138,33 +252,42 @@ func (am *AuthMiddleware) RequireTeamOwner() gin.HandlerFunc {
 // RequireTeamAdmin ensures user is owner or admin of the team
 func (am *AuthMiddleware) RequireTeamAdmin() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Validate Authorization header with Auth Service and set user in context
+		// Validate Authorization header (offline JWKS or Auth Service) and set user in context
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			c.JSON(http.StatusUnauthorized, errResp("UNAUTHORIZED", "Missing or invalid authorization header"))
+			apperr.Respond(c, http.StatusUnauthorized, "UNAUTHORIZED", "Missing or invalid authorization header")
 			c.Abort()
 			return
 		}
 		token := strings.TrimPrefix(authHeader, "Bearer ")
-		userInfo, err := am.authClient.ValidateToken(token)
-		if err != nil || !userInfo.Valid {
-			c.JSON(http.StatusUnauthorized, errResp("UNAUTHORIZED", "Invalid or expired token"))
+		userInfo, err := am.validator.ValidateToken(token)
+		if err != nil || !userInfo.Valid || !userInfo.IsAPIToken() {
+			apperr.Respond(c, http.StatusUnauthorized, "UNAUTHORIZED", "Invalid or expired token")
 			c.Abort()
 			return
 		}
+		if userInfo.Restricted && !isSafeMethod(c.Request.Method) {
+			apperr.Respond(c, http.StatusForbidden, "EMAIL_NOT_VERIFIED", "Verify your email address to modify teams")
+			c.Abort()
+			return
+		}
+		if !am.checkScope(c, userInfo, ScopeTeamsAdmin) {
+			return
+		}
 		c.Set("userID", userInfo.User.ID)
+		c.Set("impersonatorID", userInfo.ImpersonatorID)
 
 		userID, _ := c.Get("userID")
 		userIDInt, ok := userID.(int)
 		if !ok {
-			c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "invalid user ID type"))
+			apperr.Respond(c, http.StatusInternalServerError, "INTERNAL_ERROR", "invalid user ID type")
 			c.Abort()
 			return
 		}
 
 		teamID, err := models.ParseID(c.Param("id"))
 		if err != nil {
-			c.JSON(http.StatusBadRequest, errResp("BAD_REQUEST", "invalid team id"))
+			apperr.Respond(c, http.StatusBadRequest, "BAD_REQUEST", "invalid team id")
 			c.Abort()
 			return
 		}
@@ -172,13 +295,13 @@ func (am *AuthMiddleware) RequireTeamAdmin() gin.HandlerFunc {
 		// Check if user is owner or admin of the team
 		role, err := am.repo.GetUserRoleInTeam(userIDInt, teamID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, errResp("INTERNAL_ERROR", "failed to check user role"))
+			apperr.Write(c, apperr.Wrap(err, "failed to check user role"))
 			c.Abort()
 			return
 		}
 
 		if role == nil || (*role != models.RoleOwner && *role != models.RoleAdmin) {
-			c.JSON(http.StatusForbidden, errResp("FORBIDDEN", "only team owner and admin can perform this action"))
+			apperr.Respond(c, http.StatusForbidden, "NOT_TEAM_ADMIN", "only team owner and admin can perform this action")
 			c.Abort()
 			return
 		}
@@ -190,10 +313,39 @@ func (am *AuthMiddleware) RequireTeamAdmin() gin.HandlerFunc {
 	}
 }
 
-// Error helper
-type errorResponse struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
+// membershipScope is the scope member-level routes need: teams:read to read,
+// teams:write to modify
+func membershipScope(method string) string {
+	if isSafeMethod(method) {
+		return ScopeTeamsRead
+	}
+	return ScopeTeamsWrite
+}
+
+// checkScope rejects tokens of OAuth clients that were not granted scope
+func (am *AuthMiddleware) checkScope(c *gin.Context, userInfo *clients.UserInfo, scope string) bool {
+	if userInfo.HasScope(scope) {
+		return true
+	}
+	c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+	apperr.Respond(c, http.StatusForbidden, "INSUFFICIENT_SCOPE", "Token lacks the "+scope+" scope")
+	c.Abort()
+	return false
 }
 
-func errResp(code, msg string) errorResponse { return errorResponse{Code: code, Message: msg} }
+// GetUserIDFromContext returns the ID of the authenticated caller set by the Require*
+// middlewares; it is the owner of teams they create and the actor of team events
+func GetUserIDFromContext(c *gin.Context) (int, bool) {
+	id, ok := c.Get("userID")
+	if !ok {
+		return 0, false
+	}
+	userID, ok := id.(int)
+	return userID, ok && userID > 0
+}
+
+// isSafeMethod reports whether the HTTP method only reads data; restricted tokens
+// (users with an unverified email) are limited to these
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
@@ -45,8 +45,15 @@ type TeamResponse struct {
 }
 
 type NewTeam struct {
-	Name        string  `json:"name"`
+	Name        string  `json:"name" binding:"required"`
+	Description *string `json:"description"`
+}
+
+// ProvisionTeam creates a team for a group pushed to the auth service through SCIM
+type ProvisionTeam struct {
+	Name        string  `json:"name" binding:"required"`
 	Description *string `json:"description"`
+	OwnerID     int     `json:"ownerId" binding:"required,min=1"`
 }
 
 type UpdateTeam struct {
@@ -61,11 +68,25 @@ type TeamMemberResponse struct {
 	JoinedAt string `json:"joinedAt"`
 }
 
+// UserMembershipResponse is a team the user belongs to, with the user's role in it
+type UserMembershipResponse struct {
+	Team     TeamResponse `json:"team"`
+	Role     string       `json:"role"`
+	JoinedAt string       `json:"joinedAt"`
+}
+
 type AddMember struct {
 	UserID int  `json:"userId"`
 	Role   Role `json:"role"`
 }
 
+// UserRemoval records what removing a deleted user from all teams changed
+type UserRemoval struct {
+	Memberships  []TeamMember // memberships that were removed
+	NewOwners    map[int]int  // team ID -> member who took over ownership
+	DeletedTeams []Team       // owned teams without other members, deleted with the user
+}
+
 type TeamFilters struct {
 	Query  *string `form:"q"`
 	Limit  *int    `form:"limit"`
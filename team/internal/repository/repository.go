@@ -18,8 +18,10 @@ type TeamRepository interface {
 	AddMember(teamID int, userID int, role models.Role) error
 	RemoveMember(teamID int, userID int) error
 	GetUserTeams(userID int) ([]models.Team, error)
+	GetUserMemberships(userID int) ([]models.TeamMember, error)
 	IsUserInTeam(userID int, teamID int) (bool, error)
 	GetUserRoleInTeam(userID int, teamID int) (*models.Role, error)
+	RemoveUser(userID int) (*models.UserRemoval, error)
 }
 
 type teamRepo struct{ db *gorm.DB }
@@ -84,7 +86,16 @@ func (r *teamRepo) Create(t *models.Team) error {
 }
 
 func (r *teamRepo) Update(t *models.Team) error { return r.db.Save(t).Error }
-func (r *teamRepo) Delete(id int) error         { return r.db.Delete(&models.Team{}, id).Error }
+
+// Delete removes a team together with its memberships
+func (r *teamRepo) Delete(id int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("team_id = ?", id).Delete(&models.TeamMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Team{}, id).Error
+	})
+}
 
 func (r *teamRepo) GetTeamMembers(teamID int) ([]models.TeamMember, error) {
 	var members []models.TeamMember
@@ -113,6 +124,13 @@ func (r *teamRepo) GetUserTeams(userID int) ([]models.Team, error) {
 	return teams, err
 }
 
+// GetUserMemberships returns a user's memberships, oldest first
+func (r *teamRepo) GetUserMemberships(userID int) ([]models.TeamMember, error) {
+	var members []models.TeamMember
+	err := r.db.Where("user_id = ?", userID).Order("joined_at, team_id").Find(&members).Error
+	return members, err
+}
+
 func (r *teamRepo) IsUserInTeam(userID int, teamID int) (bool, error) {
 	var count int64
 	err := r.db.Model(&models.TeamMember{}).
@@ -132,3 +150,48 @@ func (r *teamRepo) GetUserRoleInTeam(userID int, teamID int) (*models.Role, erro
 	}
 	return &member.Role, nil
 }
+
+// RemoveUser drops a deleted user from every team. Ownership of the user's teams passes
+// to the longest-standing admin, or member if there is no admin; teams without any other
+// member are deleted.
+func (r *teamRepo) RemoveUser(userID int) (*models.UserRemoval, error) {
+	removal := &models.UserRemoval{NewOwners: map[int]int{}}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Find(&removal.Memberships).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.TeamMember{}).Error; err != nil {
+			return err
+		}
+		var owned []models.Team
+		if err := tx.Where("owner_id = ?", userID).Find(&owned).Error; err != nil {
+			return err
+		}
+		for _, t := range owned {
+			var successor models.TeamMember
+			err := tx.Where("team_id = ?", t.ID).Order("FIELD(role, 'owner', 'admin', 'member'), joined_at, user_id").First(&successor).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				if err := tx.Delete(&models.Team{}, t.ID).Error; err != nil {
+					return err
+				}
+				removal.DeletedTeams = append(removal.DeletedTeams, t)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Team{}).Where("id = ?", t.ID).Update("owner_id", successor.UserID).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.TeamMember{}).Where("team_id = ? AND user_id = ?", t.ID, successor.UserID).Update("role", models.RoleOwner).Error; err != nil {
+				return err
+			}
+			removal.NewOwners[t.ID] = successor.UserID
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return removal, nil
+}
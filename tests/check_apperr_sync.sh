@@ -0,0 +1,18 @@
+#!/bin/bash
+
+# The auth, task and team services are separate modules, so each keeps a copy of the
+# apperr package. The copies must stay identical: this fails when task or team differs
+# from auth and prints the difference. Change auth/internal/apperr and copy it over:
+#   for svc in task team; do cp auth/internal/apperr/*.go $svc/internal/apperr/; done
+
+cd "$(dirname "$0")/.." || exit 1
+
+status=0
+for svc in task team; do
+    if ! diff -ru auth/internal/apperr "$svc/internal/apperr"; then
+        echo "❌ $svc/internal/apperr differs from auth/internal/apperr" >&2
+        status=1
+    fi
+done
+[ "$status" -eq 0 ] && echo "✅ apperr copies are identical"
+exit $status
@@ -0,0 +1,55 @@
+#!/bin/bash
+
+# Helpers shared by the test scripts. Source it after setting AUTH_URL:
+#   source "$(dirname "$0")/lib.sh"
+
+# Colors for output
+RED='\033[0;31m'
+GREEN='\033[0;32m'
+YELLOW='\033[1;33m'
+NC='\033[0m' # No Color
+
+FAILURES=0
+
+# fail <message> [response]: reports a failed check
+fail() {
+    echo -e "${RED}❌ $1${NC}"
+    [ -n "$2" ] && echo "   Response: $2"
+    FAILURES=$((FAILURES + 1))
+}
+
+# ok <message>: reports a passed check
+ok() {
+    echo -e "${GREEN}✅ $1${NC}"
+}
+
+# login <username> [password]: prints the login response; the seeded users' password
+# is the default
+login() {
+    curl -s -X POST "$AUTH_URL/auth/login" \
+        -H "Content-Type: application/json" \
+        -d "{\"username\": \"$1\", \"password\": \"${2:-password}\"}"
+}
+
+# field <name>: prints the first string value of name in the JSON read from stdin
+field() {
+    grep -o "\"$1\":\"[^\"]*\"" | head -n1 | cut -d'"' -f4
+}
+
+# claims <jwt>: prints the decoded payload of a token
+claims() {
+    local payload
+    payload=$(echo "$1" | cut -d. -f2 | tr '_-' '/+')
+    while [ $(( ${#payload} % 4 )) -ne 0 ]; do payload="$payload="; done
+    echo "$payload" | base64 -d 2>/dev/null
+}
+
+# finish <name>: prints the summary of the <name> checks and exits with 1 if any failed
+finish() {
+    if [ "$FAILURES" -eq 0 ]; then
+        echo -e "\n${GREEN}🎉 All $1 checks passed!${NC}"
+    else
+        echo -e "\n${RED}❌ $FAILURES $1 check(s) failed${NC}"
+        exit 1
+    fi
+}
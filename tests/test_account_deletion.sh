@@ -0,0 +1,73 @@
+#!/bin/bash
+
+echo "🗑️  Testing Account Deletion"
+echo "==========================="
+
+# Make sure auth (8084) is running with the seeded admin (password: password). The
+# erasure itself starts after ACCOUNT_DELETION_GRACE and is not waited for here. Team
+# memberships are checked when the team service (8083) is running.
+
+AUTH_URL="http://localhost:8084"
+TEAM_URL="http://localhost:8083"
+
+source "$(dirname "$0")/lib.sh"
+
+ADMIN_TOKEN=$(login admin password | field accessToken)
+if [ -z "$ADMIN_TOKEN" ]; then
+    echo -e "${RED}❌ Could not log in the seeded admin${NC}"
+    exit 1
+fi
+
+NAME="erasure_$(date +%s)"
+resp=$(curl -s -X POST "$AUTH_URL/users" -H "Authorization: Bearer $ADMIN_TOKEN" -H "Content-Type: application/json" \
+    -d "{\"username\": \"$NAME\", \"email\": \"$NAME@example.com\", \"password\": \"password123\", \"firstName\": \"Test\", \"lastName\": \"User\", \"role\": \"user\"}")
+USER_ID=$(echo "$resp" | grep -o '"id":[0-9]*' | head -n1 | cut -d: -f2)
+[ -n "$USER_ID" ] && ok "Created user $NAME" || { fail "could not create user" "$resp"; exit 1; }
+USER_TOKEN=$(login "$NAME" password123 | field accessToken)
+TEAM_ID=""
+if curl -s -o /dev/null "$TEAM_URL/healthz"; then
+    TEAM_ID=$(curl -s -X POST "$TEAM_URL/teams" -H "Authorization: Bearer $USER_TOKEN" -H "Content-Type: application/json" \
+        -d "{\"name\": \"Team of $NAME\"}" | grep -o '"id":[0-9]*' | head -n1 | cut -d: -f2)
+    [ -n "$TEAM_ID" ] && ok "$NAME owns team $TEAM_ID" || fail "could not create a team for $NAME"
+fi
+
+echo -e "\n${YELLOW}1. Deleting your own account${NC}"
+code=$(curl -s -o /dev/null -w "%{http_code}" -X DELETE "$AUTH_URL/users/profile" -H "Authorization: Bearer $USER_TOKEN" \
+    -H "Content-Type: application/json" -d '{"password": "wrong"}')
+[ "$code" = "401" ] && ok "Wrong password rejected" || fail "wrong password returned HTTP $code"
+code=$(curl -s -o /dev/null -w "%{http_code}" -X DELETE "$AUTH_URL/users/profile" -H "Authorization: Bearer $ADMIN_TOKEN" \
+    -H "Content-Type: application/json" -d '{"password": "password"}')
+[ "$code" = "403" ] && ok "Admins cannot delete their own account" || fail "admin self-deletion returned HTTP $code"
+resp=$(curl -s -w "\n%{http_code}" -X DELETE "$AUTH_URL/users/profile" -H "Authorization: Bearer $USER_TOKEN" \
+    -H "Content-Type: application/json" -d '{"password": "password123"}')
+[ "$(echo "$resp" | tail -n1)" = "202" ] && echo "$resp" | grep -q '"eraseAfter"' && ok "Account deleted, erasure scheduled" || fail "deletion failed" "$resp"
+code=$(curl -s -o /dev/null -w "%{http_code}" "$AUTH_URL/users/profile" -H "Authorization: Bearer $USER_TOKEN")
+[ "$code" = "401" ] && ok "Tokens of the deleted user are revoked" || fail "old token returned HTTP $code"
+[ -z "$(login "$NAME" password123 | field accessToken)" ] && ok "Deleted user cannot log in" || fail "deleted user could log in"
+
+echo -e "\n${YELLOW}2. Grace period${NC}"
+resp=$(curl -s "$AUTH_URL/users/$USER_ID/erasure" -H "Authorization: Bearer $ADMIN_TOKEN")
+echo "$resp" | grep -q "\"requestedBy\":$USER_ID" && ok "Admin sees the pending erasure" || fail "erasure status missing" "$resp"
+code=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$AUTH_URL/users" -H "Authorization: Bearer $ADMIN_TOKEN" -H "Content-Type: application/json" \
+    -d "{\"username\": \"$NAME\", \"email\": \"other_$NAME@example.com\", \"password\": \"password123\", \"role\": \"user\"}")
+[ "$code" = "409" ] && ok "Username stays reserved" || fail "reusing the username returned HTTP $code"
+resp=$(curl -s -w "\n%{http_code}" -X POST "$AUTH_URL/users/$USER_ID/restore" -H "Authorization: Bearer $ADMIN_TOKEN")
+[ "$(echo "$resp" | tail -n1)" = "200" ] && ok "Admin restored the account" || fail "restore failed" "$resp"
+[ -n "$(login "$NAME" password123 | field accessToken)" ] && ok "Restored user can log in again" || fail "restored user cannot log in"
+if [ -n "$TEAM_ID" ]; then
+    # Give the team service time to consume the deletion events
+    sleep 3
+    members=$(curl -s "$TEAM_URL/teams/$TEAM_ID/members" -H "Authorization: Bearer $ADMIN_TOKEN")
+    echo "$members" | grep -q "\"userId\":$USER_ID,\"teamId\":$TEAM_ID,\"role\":\"owner\"" \
+        && ok "Restored user still owns their team" || fail "team membership was lost during the grace period" "$members"
+fi
+code=$(curl -s -o /dev/null -w "%{http_code}" "$AUTH_URL/users/$USER_ID/erasure" -H "Authorization: Bearer $ADMIN_TOKEN")
+[ "$code" = "404" ] && ok "Erasure was cancelled" || fail "erasure status returned HTTP $code"
+
+echo -e "\n${YELLOW}3. Admin deletion${NC}"
+code=$(curl -s -o /dev/null -w "%{http_code}" -X DELETE "$AUTH_URL/users/$USER_ID" -H "Authorization: Bearer $ADMIN_TOKEN")
+[ "$code" = "204" ] && ok "Admin deleted the user" || fail "admin deletion returned HTTP $code"
+code=$(curl -s -o /dev/null -w "%{http_code}" "$AUTH_URL/users/$USER_ID" -H "Authorization: Bearer $ADMIN_TOKEN")
+[ "$code" = "404" ] && ok "Deleted user is hidden" || fail "deleted user returned HTTP $code"
+
+finish "account deletion"
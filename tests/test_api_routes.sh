@@ -48,8 +48,8 @@ test_route "http://localhost/api/tasks/1" "401" "获取特定任务 (需要认
 
 echo ""
 echo -e "${BLUE}🔄 WebSocket 测试${NC}"
-echo "WebSocket 端点: ws://localhost/ws?teamId=1&userId=1"
-echo "使用 wscat 测试: wscat -c 'ws://localhost/ws?teamId=1&userId=1'"
+echo "WebSocket 端点: ws://localhost/ws?token=<access token>"
+echo "使用 wscat 测试: wscat -c 'ws://localhost/ws' -H 'Authorization: Bearer <access token>'"
 
 echo ""
 echo -e "${GREEN}🎉 API 路由测试完成！${NC}"
@@ -0,0 +1,51 @@
+#!/bin/bash
+
+echo "📦 Testing Data Export"
+echo "======================"
+
+# Make sure auth (8084), team (8083) and task (8081) are running with the seeded users
+# (john_doe, password: password)
+
+AUTH_URL="http://localhost:8084"
+
+source "$(dirname "$0")/lib.sh"
+
+TOKEN=$(login john_doe | field accessToken)
+if [ -z "$TOKEN" ]; then
+    echo -e "${RED}❌ Could not log in john_doe${NC}"
+    exit 1
+fi
+
+echo -e "\n${YELLOW}1. Requesting an export${NC}"
+resp=$(curl -s -w "\n%{http_code}" -X POST "$AUTH_URL/users/profile/export" -H "Authorization: Bearer $TOKEN")
+code=$(echo "$resp" | tail -n1)
+EXPORT_ID=$(echo "$resp" | field id)
+[ "$code" = "202" ] || [ "$code" = "200" ] && [ -n "$EXPORT_ID" ] && ok "Export $EXPORT_ID started" || { fail "request returned HTTP $code" "$resp"; exit 1; }
+
+echo -e "\n${YELLOW}2. Polling the status${NC}"
+STATUS=""
+for _ in $(seq 1 20); do
+    STATUS=$(curl -s "$AUTH_URL/users/profile/export/$EXPORT_ID" -H "Authorization: Bearer $TOKEN" | field status)
+    [ "$STATUS" = "ready" ] || [ "$STATUS" = "failed" ] && break
+    sleep 1
+done
+[ "$STATUS" = "ready" ] && ok "Export is ready" || fail "export ended as '$STATUS'"
+
+echo -e "\n${YELLOW}3. Downloading${NC}"
+TMP=$(mktemp -d)
+code=$(curl -s -o "$TMP/export.zip" -w "%{http_code}" "$AUTH_URL/users/profile/export/$EXPORT_ID/download" -H "Authorization: Bearer $TOKEN")
+[ "$code" = "200" ] && ok "ZIP downloaded" || fail "download returned HTTP $code"
+if command -v unzip >/dev/null; then
+    for section in profile teams tasks; do
+        unzip -l "$TMP/export.zip" | grep -q "$section.json" && ok "Archive contains $section.json" || fail "$section.json missing from the archive"
+    done
+fi
+resp=$(curl -s "$AUTH_URL/users/profile/export/$EXPORT_ID/download?format=json" -H "Authorization: Bearer $TOKEN")
+echo "$resp" | grep -q '"username": "john_doe"' && ok "JSON download contains the profile" || fail "profile missing from JSON download"
+rm -rf "$TMP"
+
+ADMIN_TOKEN=$(login admin | field accessToken)
+code=$(curl -s -o /dev/null -w "%{http_code}" "$AUTH_URL/users/profile/export/$EXPORT_ID" -H "Authorization: Bearer $ADMIN_TOKEN")
+[ "$code" = "404" ] && ok "Other users cannot see the export" || fail "another user got HTTP $code"
+
+finish "data export"
@@ -0,0 +1,77 @@
+#!/bin/bash
+
+echo "🧯 Testing Problem Details and Request IDs"
+echo "=========================================="
+
+# Make sure the auth service is running on port 8084 with the seeded users
+# (admin / john_doe / jane_smith, password: password). The task (8081) and team (8083)
+# services are checked when they are running.
+
+AUTH_URL="http://localhost:8084"
+TASK_URL="http://localhost:8081"
+TEAM_URL="http://localhost:8083"
+
+source "$(dirname "$0")/lib.sh"
+
+# problem <name> <response with headers> <status> <code>: checks a problem response
+problem() {
+    local headers body
+    headers=$(echo "$2" | sed '/^\r$/q')
+    body=$(echo "$2" | sed '1,/^\r$/d')
+    echo "$headers" | grep -qi '^content-type: application/problem+json' || fail "$1: not application/problem+json" "$headers"
+    echo "$body" | grep -q "\"status\":$3" || fail "$1: status is not $3" "$body"
+    [ "$(echo "$body" | field code)" = "$4" ] && ok "$1 returns $4" || fail "$1: code is not $4" "$body"
+    local id
+    id=$(echo "$headers" | grep -i '^x-request-id:' | cut -d' ' -f2 | tr -d '\r')
+    [ -n "$id" ] && [ "$(echo "$body" | field requestId)" = "$id" ] || fail "$1: requestId does not match X-Request-Id" "$2"
+}
+
+TOKEN=$(login jane_smith | field accessToken)
+ADMIN_TOKEN=$(login admin | field accessToken)
+if [ -z "$TOKEN" ] || [ -z "$ADMIN_TOKEN" ]; then
+    echo -e "${RED}❌ Could not log in jane_smith and admin${NC}"
+    exit 1
+fi
+
+echo -e "\n${YELLOW}Auth service${NC}"
+problem "Wrong password" "$(curl -s -i -X POST "$AUTH_URL/auth/login" -H "Content-Type: application/json" \
+    -d '{"username": "jane_smith", "password": "wrong"}')" 401 INVALID_CREDENTIALS
+problem "Taken username" "$(curl -s -i -X POST "$AUTH_URL/auth/register" -H "Content-Type: application/json" \
+    -d '{"username": "jane_smith", "email": "jane.other@example.com", "password": "password123", "firstName": "J", "lastName": "S"}')" 409 USERNAME_TAKEN
+problem "Unknown user" "$(curl -s -i "$AUTH_URL/users/999999" -H "Authorization: Bearer $ADMIN_TOKEN")" 404 USER_NOT_FOUND
+problem "Unknown endpoint" "$(curl -s -i "$AUTH_URL/no-such-endpoint")" 404 NOT_FOUND
+
+invalid=$(curl -s -i -X POST "$AUTH_URL/auth/register" -H "Content-Type: application/json" \
+    -d '{"username": "x", "email": "not-an-email", "password": "short"}')
+problem "Invalid registration" "$invalid" 400 VALIDATION_FAILED
+for f in username email password; do
+    echo "$invalid" | grep -q "\"field\":\"$f\"" && ok "errors lists $f" || fail "errors does not list $f" "$invalid"
+done
+malformed=$(curl -s -i -X POST "$AUTH_URL/auth/login" -H "Content-Type: application/json" -d '{"username":')
+problem "Malformed JSON" "$malformed" 400 VALIDATION_FAILED
+
+echo -e "\n${YELLOW}Request IDs${NC}"
+echoed=$(curl -s -D - -o /dev/null "$AUTH_URL/healthz" -H "X-Request-Id: test-request-42" | grep -i '^x-request-id:' | tr -d '\r')
+[ "$echoed" = "X-Request-Id: test-request-42" ] || [ "$echoed" = "x-request-id: test-request-42" ] \
+    && ok "Incoming X-Request-Id is reused" || fail "X-Request-Id not echoed" "$echoed"
+replaced=$(curl -s -D - -o /dev/null "$AUTH_URL/healthz" -H "X-Request-Id: bad id with spaces" | grep -i '^x-request-id:')
+echo "$replaced" | grep -q "bad id" && fail "Invalid X-Request-Id was reused" "$replaced" || ok "Invalid X-Request-Id is replaced"
+
+if curl -s -o /dev/null "$TASK_URL/healthz"; then
+    echo -e "\n${YELLOW}Task service${NC}"
+    problem "Unknown team" "$(curl -s -i "$TASK_URL/teams/999999/tasks" -H "Authorization: Bearer $TOKEN")" 404 TEAM_NOT_FOUND
+    problem "Bad task id" "$(curl -s -i "$TASK_URL/tasks/abc" -H "Authorization: Bearer $TOKEN")" 400 BAD_REQUEST
+else
+    echo -e "${YELLOW}⚠️  Task service not running, skipping its checks${NC}"
+fi
+
+if curl -s -o /dev/null "$TEAM_URL/healthz"; then
+    echo -e "\n${YELLOW}Team service${NC}"
+    problem "Team without name" "$(curl -s -i -X POST "$TEAM_URL/teams" -H "Authorization: Bearer $TOKEN" \
+        -H "Content-Type: application/json" -d '{}')" 400 VALIDATION_FAILED
+    problem "Unknown team" "$(curl -s -i "$TEAM_URL/teams/999999" -H "Authorization: Bearer $TOKEN")" 404 TEAM_NOT_FOUND
+else
+    echo -e "${YELLOW}⚠️  Team service not running, skipping its checks${NC}"
+fi
+
+finish "error response"
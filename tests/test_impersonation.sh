@@ -0,0 +1,87 @@
+#!/bin/bash
+
+echo "🕵️  Testing Admin Impersonation"
+echo "==============================="
+
+# Make sure the auth service is running on port 8084 with the seeded users
+# (admin / john_doe / jane_smith, password: password)
+
+AUTH_URL="http://localhost:8084"
+
+source "$(dirname "$0")/lib.sh"
+
+# status <method> <url> <token> [body]: prints the HTTP status
+status() {
+    curl -s -o /dev/null -w "%{http_code}" -X "$1" "$2" -H "Authorization: Bearer $3" \
+        -H "Content-Type: application/json" ${4:+-d "$4"}
+}
+
+ADMIN_TOKEN=$(login admin | field accessToken)
+USER_TOKEN=$(login john_doe | field accessToken)
+if [ -z "$ADMIN_TOKEN" ] || [ -z "$USER_TOKEN" ]; then
+    echo -e "${RED}❌ Could not log in seeded users${NC}"
+    exit 1
+fi
+ADMIN_ID=1
+JANE_ID=3
+
+echo -e "\n${YELLOW}1. Only users.impersonate may impersonate${NC}"
+response=$(curl -s -w "\n%{http_code}" -X POST "$AUTH_URL/users/$JANE_ID/impersonate" -H "Authorization: Bearer $USER_TOKEN" \
+    -H "Content-Type: application/json" -d '{"reason": "curious"}')
+if [ "$(echo "$response" | tail -n1)" = "403" ] && echo "$response" | grep -q '"permission":"users.impersonate"'; then
+    ok "Regular user gets PERMISSION_REQUIRED"
+else
+    fail "unexpected response for a regular user" "$response"
+fi
+[ "$(status POST "$AUTH_URL/users/$JANE_ID/impersonate" "$ADMIN_TOKEN" '{}')" = "400" ] && ok "Reason required" || fail "impersonation without reason accepted"
+[ "$(status POST "$AUTH_URL/users/$ADMIN_ID/impersonate" "$ADMIN_TOKEN" '{"reason": "self"}')" = "400" ] && ok "Self-impersonation rejected" || fail "admin impersonated themselves"
+[ "$(status POST "$AUTH_URL/users/999999/impersonate" "$ADMIN_TOKEN" '{"reason": "ghost"}')" = "404" ] && ok "Unknown user rejected" || fail "unknown user impersonated"
+
+echo -e "\n${YELLOW}2. Issuing an impersonation token${NC}"
+issued=$(curl -s -X POST "$AUTH_URL/users/$JANE_ID/impersonate" -H "Authorization: Bearer $ADMIN_TOKEN" \
+    -H "Content-Type: application/json" -d '{"reason": "Ticket 4711: tasks missing"}')
+IMP_TOKEN=$(echo "$issued" | field accessToken)
+if [ -n "$IMP_TOKEN" ] && echo "$issued" | grep -q "\"impersonatorId\":$ADMIN_ID"; then
+    ok "Impersonation token issued"
+else
+    fail "impersonation failed" "$issued"
+fi
+echo "$issued" | grep -q '"refreshToken"' && fail "a refresh token was issued" "$issued" || ok "No refresh token issued"
+payload=$(claims "$IMP_TOKEN")
+echo "$payload" | grep -q "\"user_id\":$JANE_ID" && ok "Token is for jane_smith" || fail "wrong user_id" "$payload"
+echo "$payload" | grep -q "\"impersonator_id\":$ADMIN_ID" && echo "$payload" | grep -q "\"act\":{\"sub\":\"$ADMIN_ID\"" \
+    && ok "Token names the real actor in impersonator_id and act" || fail "actor claims missing" "$payload"
+
+echo -e "\n${YELLOW}3. Impersonated sessions are flagged${NC}"
+validate=$(curl -s -X POST "$AUTH_URL/validate" -H "Authorization: Bearer $IMP_TOKEN")
+echo "$validate" | grep -q "\"impersonated\":true" && echo "$validate" | grep -q "\"impersonatorId\":$ADMIN_ID" \
+    && ok "/validate reports the impersonation" || fail "/validate does not flag the impersonation" "$validate"
+validate=$(curl -s -X POST "$AUTH_URL/validate" -H "Authorization: Bearer $ADMIN_TOKEN")
+echo "$validate" | grep -q '"impersonated":false' && ok "Regular tokens are not flagged" || fail "regular token flagged" "$validate"
+profile=$(curl -s "$AUTH_URL/users/profile" -H "Authorization: Bearer $IMP_TOKEN")
+echo "$profile" | grep -q '"username":"jane_smith"' && ok "Impersonation token acts as jane_smith" || fail "profile lookup failed" "$profile"
+
+echo -e "\n${YELLOW}4. Credentials cannot be changed while impersonating${NC}"
+for route in "POST /users/change-password {\"currentPassword\":\"password\",\"newPassword\":\"hijacked123\"}" \
+             "POST /users/profile/mfa/enroll {}" \
+             "DELETE /users/profile/mfa {\"code\":\"000000\"}" \
+             "POST /users/profile/tokens {\"name\":\"backdoor\",\"scopes\":[\"tasks:read\"]}" \
+             "PUT /users/profile {\"email\":\"taken.over@example.com\"}" \
+             "PUT /users/$JANE_ID {\"email\":\"taken.over@example.com\"}" \
+             "POST /users/$ADMIN_ID/impersonate {\"reason\":\"chain\"}"; do
+    set -- $route
+    response=$(curl -s -w "\n%{http_code}" -X "$1" "$AUTH_URL$2" -H "Authorization: Bearer $IMP_TOKEN" -H "Content-Type: application/json" -d "$3")
+    if [ "$(echo "$response" | tail -n1)" = "403" ] && echo "$response" | grep -q '"IMPERSONATION_FORBIDDEN"'; then
+        ok "$1 $2 refused"
+    else
+        fail "$1 $2 allowed while impersonating" "$response"
+    fi
+done
+
+echo -e "\n${YELLOW}5. Audit trail${NC}"
+history=$(curl -s "$AUTH_URL/users/$JANE_ID/impersonations" -H "Authorization: Bearer $ADMIN_TOKEN")
+echo "$history" | grep -q "\"actorId\":$ADMIN_ID,\"userId\":$JANE_ID,\"reason\":\"Ticket 4711: tasks missing\"" \
+    && ok "Impersonation recorded with actor and reason" || fail "impersonation not recorded" "$history"
+[ "$(status GET "$AUTH_URL/users/$JANE_ID/impersonations" "$USER_TOKEN")" = "403" ] && ok "Audit trail needs users.read" || fail "regular user read the audit trail"
+
+finish "impersonation"
@@ -53,6 +53,21 @@ test_jwt_flow() {
         return 1
     fi
     
+    # New accounts are restricted (read-only) until their email is verified;
+    # pick the verification link up from Mailpit
+    echo -n "   Verifying email via Mailpit... "
+    sleep 3
+    message_id=$(curl -s "http://localhost:8025/api/v1/search?query=to:testjwt@example.com%20subject:verify" | grep -o '"ID":"[^"]*"' | head -1 | cut -d'"' -f4)
+    verify_token=$(curl -s "http://localhost:8025/api/v1/message/$message_id" | grep -o 'token=[0-9a-f]*' | head -1 | cut -d'=' -f2)
+    verify_status=$(curl -s -o /dev/null -w "%{http_code}" -X POST http://localhost:8084/auth/verify-email \
+        -H "Content-Type: application/json" \
+        -d "{\"token\": \"$verify_token\"}")
+    if [ "$verify_status" = "200" ]; then
+        echo -e "${GREEN}✅ OK${NC}"
+    else
+        echo -e "${YELLOW}⚠️  Skipped ($verify_status); write requests may return EMAIL_NOT_VERIFIED${NC}"
+    fi
+
     # Test user login
     echo -n "2. Testing user login... "
     login_response=$(curl -s -X POST http://localhost:8084/auth/login \
@@ -0,0 +1,95 @@
+#!/bin/bash
+
+echo "🔑 Testing Signing Key Rotation"
+echo "==============================="
+
+# Make sure the auth service is running in docker compose (container auth_service, keys in
+# /data/jwt-keys) with the seeded users (john_doe, password: password). The task service
+# (8081) is checked too when it is running. A new key is added to the key directory the
+# way an operator rotates by hand; it is published within a minute and signs after five.
+# Set SKIP_ACTIVATION=true to stop before waiting for it to sign. The key is removed at
+# the end, so tokens it signed stop verifying.
+
+AUTH_URL="http://localhost:8084"
+TASK_URL="http://localhost:8081"
+AUTH_CONTAINER="${AUTH_CONTAINER:-auth_service}"
+KEYS_DIR="/data/jwt-keys"
+
+source "$(dirname "$0")/lib.sh"
+
+# kid <jwt>: prints the kid header of a token
+kid() {
+    local header
+    header=$(echo "$1" | cut -d. -f1 | tr '_-' '/+')
+    while [ $(( ${#header} % 4 )) -ne 0 ]; do header="$header="; done
+    echo "$header" | base64 -d 2>/dev/null | field kid
+}
+
+# published <kid>: succeeds when the JWKS lists the kid
+published() {
+    curl -s "$AUTH_URL/.well-known/jwks.json" | grep -q "\"kid\":\"$1\""
+}
+
+# valid <token>: prints the HTTP status of validating the token
+valid() {
+    curl -s -o /dev/null -w "%{http_code}" -X POST "$AUTH_URL/validate" -H "Authorization: Bearer $1"
+}
+
+OLD_TOKEN=$(login john_doe | field accessToken)
+OLD_KID=$(kid "$OLD_TOKEN")
+if [ -z "$OLD_KID" ]; then
+    echo -e "${RED}❌ Could not log in john_doe${NC}"
+    exit 1
+fi
+
+# Named like the keys the service generates, so it sorts after the current ones
+NEW_KID="$(date -u +%Y%m%dT%H%M%SZ)-rotation-test"
+KEY_FILE=$(mktemp)
+trap 'rm -f "$KEY_FILE"; docker exec "$AUTH_CONTAINER" rm -f "$KEYS_DIR/$NEW_KID.pem" 2>/dev/null' EXIT
+openssl genpkey -algorithm ED25519 -out "$KEY_FILE" 2>/dev/null && chmod 644 "$KEY_FILE"
+if ! docker cp "$KEY_FILE" "$AUTH_CONTAINER:$KEYS_DIR/$NEW_KID.pem" >/dev/null; then
+    echo -e "${RED}❌ Could not add a key to $KEYS_DIR in container $AUTH_CONTAINER${NC}"
+    exit 1
+fi
+
+echo -e "\n${YELLOW}1. The new key is published first${NC}"
+published "$OLD_KID" && ok "Current key $OLD_KID is in the JWKS" || fail "current key missing from the JWKS"
+for _ in $(seq 1 14); do
+    published "$NEW_KID" && break
+    sleep 5
+done
+published "$NEW_KID" && ok "New key $NEW_KID is published within a minute" || fail "new key was not published"
+
+echo -e "\n${YELLOW}2. The old key keeps signing during the publish delay${NC}"
+TOKEN=$(login john_doe | field accessToken)
+signer=$(kid "$TOKEN")
+[ "$signer" = "$OLD_KID" ] && ok "New tokens are still signed with $OLD_KID" || fail "new key signs before the publish delay: $signer"
+[ "$(valid "$OLD_TOKEN")" = "200" ] && ok "Tokens of the old key verify" || fail "token of the old key rejected"
+
+if [ "$SKIP_ACTIVATION" = "true" ]; then
+    finish "key rotation"
+    exit 0
+fi
+
+echo -e "\n${YELLOW}3. The new key signs after the publish delay${NC}"
+echo "   Waiting up to 7 minutes..."
+for _ in $(seq 1 42); do
+    TOKEN=$(login john_doe | field accessToken)
+    [ "$(kid "$TOKEN")" = "$NEW_KID" ] && break
+    sleep 10
+done
+signer=$(kid "$TOKEN")
+[ "$signer" = "$NEW_KID" ] && ok "New tokens are signed with $NEW_KID" || fail "new key did not become the signing key: $signer"
+[ "$(valid "$TOKEN")" = "200" ] && ok "Tokens of the new key verify" || fail "token of the new key rejected"
+[ "$(valid "$OLD_TOKEN")" = "200" ] && ok "Tokens of the retired key still verify" || fail "token of the retired key rejected"
+published "$OLD_KID" && ok "Retired key stays in the JWKS" || fail "retired key removed from the JWKS"
+
+if curl -s -o /dev/null "$TASK_URL/healthz"; then
+    status() { curl -s -o /dev/null -w "%{http_code}" "$TASK_URL/tasks" -H "Authorization: Bearer $1"; }
+    [ "$(status "$TOKEN")" = "200" ] && ok "Task service accepts the new key" || fail "task service rejected a token of the new key"
+    [ "$(status "$OLD_TOKEN")" = "200" ] && ok "Task service accepts the retired key" || fail "task service rejected a token of the retired key"
+else
+    echo -e "${YELLOW}⚠️  The task service is not running, skipped${NC}"
+fi
+
+finish "key rotation"
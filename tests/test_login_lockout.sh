@@ -0,0 +1,87 @@
+#!/bin/bash
+
+echo "🔒 Testing Login Backoff and Lockout"
+echo "===================================="
+
+# Make sure the auth service is running on port 8084 with the seeded users (admin /
+# john_doe, password: password) and the default LOGIN_* settings: three free attempts,
+# then a delay doubling from one second, and a 15 minute lock after ten failures. Reaching
+# the lock takes about a minute. The failures also count against this machine's IP,
+# which is throttled after 20 within 15 minutes, so do not rerun the script right away.
+
+AUTH_URL="http://localhost:8084"
+
+source "$(dirname "$0")/lib.sh"
+
+# attempt <password>: prints the login response, its Retry-After header and its HTTP status
+attempt() {
+    local headers body
+    headers=$(mktemp)
+    body=$(curl -s -D "$headers" -w "\n%{http_code}" -X POST "$AUTH_URL/auth/login" -H "Content-Type: application/json" \
+        -d "{\"username\": \"$NAME\", \"password\": \"$1\"}")
+    echo "$body" | head -n1
+    grep -i '^Retry-After:' "$headers" | tr -dc '0-9'
+    echo
+    echo "$body" | tail -n1
+    rm -f "$headers"
+}
+
+ADMIN_TOKEN=$(login admin | field accessToken)
+JOHN_TOKEN=$(login john_doe | field accessToken)
+if [ -z "$ADMIN_TOKEN" ] || [ -z "$JOHN_TOKEN" ]; then
+    echo -e "${RED}❌ Could not log in seeded users${NC}"
+    exit 1
+fi
+
+NAME="lock_$(date +%s)"
+resp=$(curl -s -X POST "$AUTH_URL/users" -H "Authorization: Bearer $ADMIN_TOKEN" -H "Content-Type: application/json" \
+    -d "{\"username\": \"$NAME\", \"email\": \"$NAME@example.com\", \"password\": \"password123\", \"role\": \"user\"}")
+USER_ID=$(echo "$resp" | grep -o '"id":[0-9]*' | head -n1 | cut -d: -f2)
+[ -n "$USER_ID" ] || { fail "could not create user" "$resp"; exit 1; }
+
+echo -e "\n${YELLOW}1. Backoff${NC}"
+for i in 1 2 3 4; do
+    resp=$(attempt wrong-password)
+    [ "$(echo "$resp" | tail -n1)" = "401" ] || fail "wrong password $i returned HTTP $(echo "$resp" | tail -n1)" "$(echo "$resp" | head -n1)"
+done
+ok "Four wrong passwords rejected with 401"
+resp=$(attempt password123)
+retry=$(echo "$resp" | sed -n 2p)
+if [ "$(echo "$resp" | tail -n1)" = "429" ] && echo "$resp" | grep -q '"code":"LOGIN_THROTTLED"' && [ -n "$retry" ]; then
+    ok "Even the right password must wait after the fourth failure (Retry-After: $retry)"
+else
+    fail "no backoff after four failures" "$resp"
+fi
+sleep "${retry:-1}"
+resp=$(attempt password123)
+[ "$(echo "$resp" | tail -n1)" = "200" ] && ok "Login succeeds after waiting" || fail "login after the backoff failed" "$resp"
+
+echo -e "\n${YELLOW}2. Lockout${NC}"
+echo "   Failing ten times with growing delays..."
+failures=0
+while [ "$failures" -lt 10 ]; do
+    resp=$(attempt wrong-password)
+    case "$(echo "$resp" | tail -n1)" in
+        401) failures=$((failures + 1)) ;;
+        429) sleep "$(echo "$resp" | sed -n 2p)" ;;
+        *) fail "unexpected response while failing" "$resp"; break ;;
+    esac
+done
+resp=$(attempt password123)
+if [ "$(echo "$resp" | tail -n1)" = "429" ] && echo "$resp" | grep -q '"code":"ACCOUNT_LOCKED"'; then
+    ok "Ten failures lock the account (Retry-After: $(echo "$resp" | sed -n 2p)s)"
+else
+    fail "account not locked after ten failures" "$resp"
+fi
+
+echo -e "\n${YELLOW}3. Admin unlock${NC}"
+code=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$AUTH_URL/users/$USER_ID/unlock" -H "Authorization: Bearer $JOHN_TOKEN")
+[ "$code" = "403" ] && ok "Users cannot unlock accounts" || fail "unlock by a user returned HTTP $code"
+code=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$AUTH_URL/users/$USER_ID/unlock" -H "Authorization: Bearer $ADMIN_TOKEN")
+[ "$code" = "204" ] && ok "Admin unlocks the account" || fail "unlock returned HTTP $code"
+resp=$(attempt password123)
+[ "$(echo "$resp" | tail -n1)" = "200" ] && ok "The user can log in again" || fail "login after unlock failed" "$resp"
+
+curl -s -o /dev/null -X DELETE "$AUTH_URL/users/$USER_ID" -H "Authorization: Bearer $ADMIN_TOKEN"
+
+finish "login lockout"
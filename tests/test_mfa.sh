@@ -0,0 +1,103 @@
+#!/bin/bash
+
+echo "🔐 Testing Two-Factor Authentication"
+echo "===================================="
+
+# Make sure the auth service is running on port 8084 with the seeded admin (password:
+# password). python3 computes the TOTP codes. The script waits for the next 30 second
+# time step once, since every step's code is accepted only once.
+
+AUTH_URL="http://localhost:8084"
+
+source "$(dirname "$0")/lib.sh"
+
+# totp <secret> [step offset]: prints the current RFC 6238 code of a base32 secret
+totp() {
+    python3 - "$1" "${2:-0}" <<'EOF'
+import base64, hashlib, hmac, struct, sys, time
+key = base64.b32decode(sys.argv[1] + "=" * (-len(sys.argv[1]) % 8))
+mac = hmac.new(key, struct.pack(">Q", int(time.time()) // 30 + int(sys.argv[2])), hashlib.sha1).digest()
+offset = mac[-1] & 0x0f
+print("%06d" % ((struct.unpack(">I", mac[offset:offset + 4])[0] & 0x7fffffff) % 1000000))
+EOF
+}
+
+# challenge: prints the challenge token of a password login of the test user
+challenge() {
+    login "$NAME" password123 | field challengeToken
+}
+
+# second_factor <challenge token> <code>: prints the response followed by its HTTP status
+second_factor() {
+    curl -s -w "\n%{http_code}" -X POST "$AUTH_URL/auth/login/mfa" -H "Content-Type: application/json" \
+        -d "{\"challengeToken\": \"$1\", \"code\": \"$2\"}"
+}
+
+# accepted <response>: succeeds when second_factor issued tokens
+accepted() {
+    [ "$(echo "$1" | tail -n1)" = "200" ] && [ -n "$(echo "$1" | head -n1 | field accessToken)" ]
+}
+
+# rejected <response>: succeeds when second_factor answered INVALID_MFA_CODE
+rejected() {
+    [ "$(echo "$1" | tail -n1)" = "401" ] && echo "$1" | grep -q '"code":"INVALID_MFA_CODE"'
+}
+
+if ! command -v python3 >/dev/null; then
+    echo -e "${RED}❌ python3 is required to compute TOTP codes${NC}"
+    exit 1
+fi
+ADMIN_TOKEN=$(login admin | field accessToken)
+if [ -z "$ADMIN_TOKEN" ]; then
+    echo -e "${RED}❌ Could not log in the seeded admin${NC}"
+    exit 1
+fi
+
+NAME="mfa_$(date +%s)"
+resp=$(curl -s -X POST "$AUTH_URL/users" -H "Authorization: Bearer $ADMIN_TOKEN" -H "Content-Type: application/json" \
+    -d "{\"username\": \"$NAME\", \"email\": \"$NAME@example.com\", \"password\": \"password123\", \"role\": \"user\"}")
+USER_ID=$(echo "$resp" | grep -o '"id":[0-9]*' | head -n1 | cut -d: -f2)
+[ -n "$USER_ID" ] || { fail "could not create user" "$resp"; exit 1; }
+TOKEN=$(login "$NAME" password123 | field accessToken)
+
+echo -e "\n${YELLOW}1. Enrollment${NC}"
+SECRET=$(curl -s -X POST "$AUTH_URL/users/profile/mfa/enroll" -H "Authorization: Bearer $TOKEN" | field secret)
+[ -n "$SECRET" ] && ok "Enrollment returns a secret" || fail "enrollment failed"
+resp=$(curl -s -X POST "$AUTH_URL/users/profile/mfa/confirm" -H "Authorization: Bearer $TOKEN" \
+    -H "Content-Type: application/json" -d "{\"code\": \"000000\"}")
+echo "$resp" | grep -q '"code":"INVALID_MFA_CODE"' && ok "Wrong code does not enable 2FA" || fail "confirm accepted a wrong code" "$resp"
+CONFIRM_CODE=$(totp "$SECRET")
+resp=$(curl -s -X POST "$AUTH_URL/users/profile/mfa/confirm" -H "Authorization: Bearer $TOKEN" \
+    -H "Content-Type: application/json" -d "{\"code\": \"$CONFIRM_CODE\"}")
+RECOVERY=($(echo "$resp" | grep -o '[A-Z2-7]\{5\}-[A-Z2-7]\{5\}'))
+[ "${#RECOVERY[@]}" -gt 1 ] && ok "2FA enabled with ${#RECOVERY[@]} recovery codes" || fail "confirm failed" "$resp"
+
+echo -e "\n${YELLOW}2. Login with a TOTP code${NC}"
+resp=$(login "$NAME" password123)
+if echo "$resp" | grep -q '"mfaRequired":true' && [ -z "$(echo "$resp" | field accessToken)" ]; then
+    ok "The password alone only returns a challenge"
+else
+    fail "password login skipped the second factor" "$resp"
+fi
+rejected "$(second_factor "$(challenge)" "$CONFIRM_CODE")" \
+    && ok "The code used to confirm enrollment cannot be replayed" || fail "enrollment code accepted again"
+echo "   Waiting for the next time step..."
+sleep $(( 30 - $(date +%s) % 30 + 1 ))
+CODE=$(totp "$SECRET")
+accepted "$(second_factor "$(challenge)" "$CODE")" && ok "Current code completes the login" || fail "login with a current code failed"
+rejected "$(second_factor "$(challenge)" "$CODE")" && ok "The same code is rejected on replay" || fail "replayed TOTP code accepted"
+rejected "$(second_factor "$(challenge)" "$(totp "$SECRET" 3)")" \
+    && ok "Codes outside the allowed clock skew are rejected" || fail "code three steps ahead accepted"
+
+echo -e "\n${YELLOW}3. Recovery codes${NC}"
+accepted "$(second_factor "$(challenge)" "${RECOVERY[0]}")" && ok "Recovery code completes the login" || fail "recovery code rejected"
+rejected "$(second_factor "$(challenge)" "${RECOVERY[0]}")" && ok "Recovery codes are single-use" || fail "recovery code accepted twice"
+lower=$(echo "${RECOVERY[1]}" | tr -d '-' | tr 'A-Z' 'a-z')
+accepted "$(second_factor "$(challenge)" "$lower")" && ok "Recovery codes ignore case and dashes" || fail "normalized recovery code rejected"
+code=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$AUTH_URL/auth/login/mfa" -H "Content-Type: application/json" \
+    -d "{\"challengeToken\": \"$TOKEN\", \"code\": \"${RECOVERY[2]}\"}")
+[ "$code" = "401" ] && ok "An access token is not a challenge token" || fail "access token accepted as a challenge: HTTP $code"
+
+curl -s -o /dev/null -X DELETE "$AUTH_URL/users/$USER_ID" -H "Authorization: Bearer $ADMIN_TOKEN"
+
+finish "2FA"
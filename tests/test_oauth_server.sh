@@ -0,0 +1,87 @@
+#!/bin/bash
+
+echo "🔐 Testing the OAuth 2.0 Authorization Server"
+echo "============================================="
+
+# Make sure the auth service (8084) and the task service (8081) are running with the
+# seeded users (admin / john_doe / jane_smith, password: password)
+
+AUTH_URL="http://localhost:8084"
+TASK_URL="http://localhost:8081"
+REDIRECT_URI="http://localhost:3000/callback"
+
+source "$(dirname "$0")/lib.sh"
+
+USER_TOKEN=$(login john_doe | field accessToken)
+if [ -z "$USER_TOKEN" ]; then
+    echo -e "${RED}❌ Could not log in john_doe${NC}"
+    exit 1
+fi
+
+echo -e "\n${YELLOW}1. Register a confidential client${NC}"
+client=$(curl -s -X POST "$AUTH_URL/oauth/clients" \
+    -H "Authorization: Bearer $USER_TOKEN" -H "Content-Type: application/json" \
+    -d "{\"name\": \"Script Client\", \"redirectUris\": [\"$REDIRECT_URI\"], \"scopes\": [\"tasks:read\"], \"grantTypes\": [\"authorization_code\", \"refresh_token\", \"client_credentials\"]}")
+CLIENT_ID=$(echo "$client" | field clientId)
+CLIENT_SECRET=$(echo "$client" | field clientSecret)
+if [ -n "$CLIENT_ID" ] && [ -n "$CLIENT_SECRET" ]; then
+    ok "Client $CLIENT_ID registered"
+else
+    fail "client registration failed" "$client"
+    exit 1
+fi
+
+echo -e "\n${YELLOW}2. Authorization code with PKCE${NC}"
+VERIFIER=$(openssl rand -hex 32)
+CHALLENGE=$(printf '%s' "$VERIFIER" | openssl dgst -sha256 -binary | openssl base64 -A | tr '+/' '-_' | tr -d '=')
+QUERY="response_type=code&client_id=$CLIENT_ID&redirect_uri=$REDIRECT_URI&scope=tasks:read&state=xyz&code_challenge=$CHALLENGE&code_challenge_method=S256"
+prepare=$(curl -s "$AUTH_URL/oauth/authorize?$QUERY" -H "Authorization: Bearer $USER_TOKEN")
+echo "$prepare" | grep -q '"tasks:read"' && ok "Consent screen lists tasks:read" || fail "authorization request was rejected" "$prepare"
+
+decision=$(curl -s -X POST "$AUTH_URL/oauth/authorize" \
+    -H "Authorization: Bearer $USER_TOKEN" -H "Content-Type: application/json" \
+    -d "{\"response_type\": \"code\", \"client_id\": \"$CLIENT_ID\", \"redirect_uri\": \"$REDIRECT_URI\", \"scope\": \"tasks:read\", \"state\": \"xyz\", \"code_challenge\": \"$CHALLENGE\", \"code_challenge_method\": \"S256\", \"approve\": true}")
+CODE=$(echo "$decision" | field redirectTo | sed -n 's/.*[?&]code=\([^&]*\).*/\1/p')
+[ -n "$CODE" ] && ok "Approval returned an authorization code" || fail "no authorization code" "$decision"
+
+tokens=$(curl -s -X POST "$AUTH_URL/oauth/token" -u "$CLIENT_ID:$CLIENT_SECRET" \
+    -d "grant_type=authorization_code&code=$CODE&redirect_uri=$REDIRECT_URI&code_verifier=$VERIFIER")
+ACCESS_TOKEN=$(echo "$tokens" | field access_token)
+REFRESH_TOKEN=$(echo "$tokens" | field refresh_token)
+[ -n "$ACCESS_TOKEN" ] && [ -n "$REFRESH_TOKEN" ] && ok "Code exchanged for tokens" || fail "code exchange failed" "$tokens"
+
+replay=$(curl -s -X POST "$AUTH_URL/oauth/token" -u "$CLIENT_ID:$CLIENT_SECRET" \
+    -d "grant_type=authorization_code&code=$CODE&redirect_uri=$REDIRECT_URI&code_verifier=$VERIFIER")
+echo "$replay" | grep -q '"error":"invalid_grant"' && ok "Code cannot be redeemed twice" || fail "code replay was accepted" "$replay"
+
+echo -e "\n${YELLOW}3. Scopes are enforced${NC}"
+status=$(curl -s -o /dev/null -w "%{http_code}" "$TASK_URL/tasks" -H "Authorization: Bearer $ACCESS_TOKEN")
+[ "$status" = "200" ] && ok "tasks:read token can list tasks" || fail "listing tasks returned HTTP $status"
+status=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$TASK_URL/teams/1/tasks" -H "Authorization: Bearer $ACCESS_TOKEN" \
+    -H "Content-Type: application/json" -d '{"title": "nope"}')
+[ "$status" = "403" ] && ok "tasks:read token cannot create tasks" || fail "creating a task returned HTTP $status"
+status=$(curl -s -o /dev/null -w "%{http_code}" "$AUTH_URL/users/profile" -H "Authorization: Bearer $ACCESS_TOKEN")
+[ "$status" = "403" ] && ok "Client token is refused by /users" || fail "/users/profile returned HTTP $status"
+
+echo -e "\n${YELLOW}4. Refresh and introspection${NC}"
+refreshed=$(curl -s -X POST "$AUTH_URL/oauth/token" -u "$CLIENT_ID:$CLIENT_SECRET" \
+    -d "grant_type=refresh_token&refresh_token=$REFRESH_TOKEN")
+NEW_REFRESH=$(echo "$refreshed" | field refresh_token)
+[ -n "$NEW_REFRESH" ] && ok "Refresh token rotated" || fail "refresh failed" "$refreshed"
+
+introspection=$(curl -s -X POST "$AUTH_URL/oauth/introspect" -u "$CLIENT_ID:$CLIENT_SECRET" -d "token=$ACCESS_TOKEN")
+echo "$introspection" | grep -q '"active":true' && ok "Access token is active" || fail "introspection failed" "$introspection"
+
+cc=$(curl -s -X POST "$AUTH_URL/oauth/token" -u "$CLIENT_ID:$CLIENT_SECRET" -d "grant_type=client_credentials&scope=tasks:read")
+echo "$cc" | grep -q '"access_token"' && ok "Client credentials grant works" || fail "client credentials failed" "$cc"
+
+echo -e "\n${YELLOW}5. Revoking the authorization${NC}"
+status=$(curl -s -o /dev/null -w "%{http_code}" -X DELETE "$AUTH_URL/users/profile/authorizations/$CLIENT_ID" -H "Authorization: Bearer $USER_TOKEN")
+[ "$status" = "204" ] && ok "Authorization revoked" || fail "revoking returned HTTP $status"
+revoked=$(curl -s -X POST "$AUTH_URL/oauth/token" -u "$CLIENT_ID:$CLIENT_SECRET" \
+    -d "grant_type=refresh_token&refresh_token=$NEW_REFRESH")
+echo "$revoked" | grep -q '"error":"invalid_grant"' && ok "Refresh token no longer works" || fail "refresh after revocation succeeded" "$revoked"
+
+curl -s -o /dev/null -X DELETE "$AUTH_URL/oauth/clients/$CLIENT_ID" -H "Authorization: Bearer $USER_TOKEN"
+
+finish "OAuth"
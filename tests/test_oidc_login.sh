@@ -0,0 +1,81 @@
+#!/bin/bash
+
+echo "🔑 Testing OIDC Login with the Stub Provider"
+echo "============================================"
+
+# Start the stub provider and the auth service configured for it:
+#   (cd auth && go run ./cmd/oidc-stub)        # listens on :9999
+#   OIDC_PROVIDERS=stub OIDC_STUB_ISSUER=http://localhost:9999 \
+#   OIDC_STUB_CLIENT_ID=todo-app OIDC_STUB_CLIENT_SECRET=stub-secret \
+#   AUTH_PUBLIC_URL=http://localhost:8084 go run ./cmd/auth-service
+
+AUTH_URL="http://localhost:8084"
+EMAIL="oidc.$(date +%s)@example.com"
+
+source "$(dirname "$0")/lib.sh"
+
+# oidc_login <email>: follows the redirects by hand and prints the callback response
+oidc_login() {
+    local authorize callback
+    authorize=$(curl -s -o /dev/null -w "%{redirect_url}" "$AUTH_URL/auth/oidc/stub/login")
+    [ -z "$authorize" ] && return 1
+    callback=$(curl -s -o /dev/null -w "%{redirect_url}" "$authorize&login_hint=$1")
+    [ -z "$callback" ] && return 1
+    curl -s "$callback"
+}
+
+echo -e "\n${YELLOW}1. Provider is listed${NC}"
+providers=$(curl -s "$AUTH_URL/auth/oidc/providers")
+if echo "$providers" | grep -q '"name":"stub"'; then
+    ok "stub provider configured"
+else
+    fail "stub provider missing" "$providers"
+    exit 1
+fi
+
+echo -e "\n${YELLOW}2. First login provisions a user${NC}"
+first=$(oidc_login "$EMAIL")
+TOKEN=$(echo "$first" | grep -o '"accessToken":"[^"]*"' | cut -d'"' -f4)
+USER_ID=$(echo "$first" | grep -o '"user":{"id":[0-9]*' | grep -o '[0-9]*$')
+if [ -n "$TOKEN" ] && echo "$first" | grep -q '"role":"user"'; then
+    ok "Logged in as new user $USER_ID"
+else
+    fail "first login failed" "$first"
+fi
+
+echo -e "\n${YELLOW}3. Second login reuses the linked user${NC}"
+second=$(oidc_login "$EMAIL")
+if echo "$second" | grep -q "\"user\":{\"id\":$USER_ID,"; then
+    ok "Same user $USER_ID"
+else
+    fail "second login returned a different user" "$second"
+fi
+
+echo -e "\n${YELLOW}4. Identity is linked to the profile${NC}"
+identities=$(curl -s "$AUTH_URL/users/profile/identities" -H "Authorization: Bearer $TOKEN")
+if echo "$identities" | grep -q '"provider":"stub"'; then
+    ok "Identity listed"
+else
+    fail "identity not listed" "$identities"
+fi
+
+echo -e "\n${YELLOW}5. Replayed callback is rejected${NC}"
+authorize=$(curl -s -o /dev/null -w "%{redirect_url}" "$AUTH_URL/auth/oidc/stub/login")
+callback=$(curl -s -o /dev/null -w "%{redirect_url}" "$authorize&login_hint=$EMAIL")
+curl -s -o /dev/null "$callback"
+status=$(curl -s -o /dev/null -w "%{http_code}" "$callback")
+if [ "$status" = "400" ]; then
+    ok "Replay rejected"
+else
+    fail "replay returned HTTP $status"
+fi
+
+echo -e "\n${YELLOW}6. Existing local email is not taken over${NC}"
+conflict=$(oidc_login "john@example.com")
+if echo "$conflict" | grep -q '"code":"EMAIL_TAKEN"'; then
+    ok "Conflict reported"
+else
+    fail "existing account was linked without OIDC_STUB_TRUST_EMAIL" "$conflict"
+fi
+
+finish "OIDC"
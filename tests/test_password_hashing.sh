@@ -0,0 +1,75 @@
+#!/bin/bash
+
+echo "🧂 Testing Password Hashing and Rehash on Login"
+echo "=============================================="
+
+# Make sure the auth service (8084) and its database container are running with the
+# seeded admin (password: password) and the default PASSWORD_HASH_ALGORITHM=argon2id.
+# Legacy hashes are planted directly in the database.
+
+AUTH_URL="http://localhost:8084"
+DB_CONTAINER="${AUTH_DB_CONTAINER:-auth_mysql}"
+# The seeded users' bcrypt hash of "password"
+SEED_BCRYPT='$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi'
+
+source "$(dirname "$0")/lib.sh"
+
+# sql <statement>: runs a statement against the auth database and prints the result
+sql() {
+    docker exec "$DB_CONTAINER" mysql -uroot -ppass authdb -N -s -e "$1" 2>/dev/null
+}
+
+# stored_hash: prints the test user's password hash
+stored_hash() {
+    sql "SELECT password_hash FROM users WHERE id = $USER_ID"
+}
+
+# login_status <password>: prints the HTTP status of logging in the test user
+login_status() {
+    curl -s -o /dev/null -w "%{http_code}" -X POST "$AUTH_URL/auth/login" -H "Content-Type: application/json" \
+        -d "{\"username\": \"$NAME\", \"password\": \"$1\"}"
+}
+
+ADMIN_TOKEN=$(login admin | field accessToken)
+if [ -z "$ADMIN_TOKEN" ]; then
+    echo -e "${RED}❌ Could not log in the seeded admin${NC}"
+    exit 1
+fi
+if [ -z "$(sql 'SELECT 1')" ]; then
+    echo -e "${RED}❌ Could not reach the database in container $DB_CONTAINER${NC}"
+    exit 1
+fi
+
+NAME="hash_$(date +%s)"
+resp=$(curl -s -X POST "$AUTH_URL/users" -H "Authorization: Bearer $ADMIN_TOKEN" -H "Content-Type: application/json" \
+    -d "{\"username\": \"$NAME\", \"email\": \"$NAME@example.com\", \"password\": \"password123\", \"role\": \"user\"}")
+USER_ID=$(echo "$resp" | grep -o '"id":[0-9]*' | head -n1 | cut -d: -f2)
+[ -n "$USER_ID" ] || { fail "could not create user" "$resp"; exit 1; }
+
+echo -e "\n${YELLOW}1. New passwords${NC}"
+hash=$(stored_hash)
+[[ "$hash" == '$argon2id$v=19$m='* ]] && ok "Stored as a PHC argon2id string" || fail "unexpected hash format" "$hash"
+
+echo -e "\n${YELLOW}2. Unsalted SHA-256${NC}"
+sql "UPDATE users SET password_hash = SHA2('password123', 256) WHERE id = $USER_ID"
+[ "$(login_status wrong-password)" = "401" ] && ok "Wrong password rejected" || fail "wrong password accepted"
+[ "$(stored_hash)" = "$(sql "SELECT SHA2('password123', 256)")" ] && ok "Failed login keeps the hash" || fail "hash changed on a failed login"
+[ "$(login_status password123)" = "200" ] && ok "Legacy hash still verifies" || fail "login with a SHA-256 hash failed"
+hash=$(stored_hash)
+[[ "$hash" == '$argon2id$'* ]] && ok "Rehashed to argon2id on login" || fail "SHA-256 hash not upgraded" "$hash"
+[ "$(login_status password123)" = "200" ] && ok "Upgraded hash verifies" || fail "login after the upgrade failed"
+
+echo -e "\n${YELLOW}3. bcrypt${NC}"
+sql "UPDATE users SET password_hash = '$SEED_BCRYPT' WHERE id = $USER_ID"
+[ "$(login_status password)" = "200" ] && ok "bcrypt hash verifies" || fail "login with a bcrypt hash failed"
+hash=$(stored_hash)
+[[ "$hash" == '$argon2id$'* ]] && ok "Rehashed to argon2id on login" || fail "bcrypt hash not upgraded" "$hash"
+
+echo -e "\n${YELLOW}4. Current parameters${NC}"
+before=$(stored_hash)
+[ "$(login_status password)" = "200" ] && [ "$(stored_hash)" = "$before" ] \
+    && ok "A hash with the current parameters is kept" || fail "hash with current parameters was rewritten"
+
+curl -s -o /dev/null -X DELETE "$AUTH_URL/users/$USER_ID" -H "Authorization: Bearer $ADMIN_TOKEN"
+
+finish "password hashing"
@@ -0,0 +1,83 @@
+#!/bin/bash
+
+echo "🔁 Testing Password Reset"
+echo "========================"
+
+# Make sure the auth service (8084), the notification service and Mailpit (8025) are
+# running with the seeded admin (password: password). The email endpoints allow five
+# requests per 15 minutes and client IP; this script makes three.
+
+AUTH_URL="http://localhost:8084"
+MAILPIT_URL="http://localhost:8025"
+
+source "$(dirname "$0")/lib.sh"
+
+# reset <token> <new password>: prints the reset response followed by its HTTP status
+reset() {
+    curl -s -w "\n%{http_code}" -X POST "$AUTH_URL/auth/reset-password" -H "Content-Type: application/json" \
+        -d "{\"token\": \"$1\", \"newPassword\": \"$2\"}"
+}
+
+# refresh_status <refresh token>: prints the HTTP status of redeeming the token
+refresh_status() {
+    curl -s -o /dev/null -w "%{http_code}" -X POST "$AUTH_URL/auth/refresh" \
+        -H "Content-Type: application/json" -d "{\"refreshToken\": \"$1\"}"
+}
+
+ADMIN_TOKEN=$(login admin | field accessToken)
+if [ -z "$ADMIN_TOKEN" ]; then
+    echo -e "${RED}❌ Could not log in the seeded admin${NC}"
+    exit 1
+fi
+
+# Accounts created by an admin have a verified address, so they can reset their password
+NAME="reset_$(date +%s)"
+EMAIL="$NAME@example.com"
+resp=$(curl -s -X POST "$AUTH_URL/users" -H "Authorization: Bearer $ADMIN_TOKEN" -H "Content-Type: application/json" \
+    -d "{\"username\": \"$NAME\", \"email\": \"$EMAIL\", \"password\": \"password123\", \"role\": \"user\"}")
+USER_ID=$(echo "$resp" | grep -o '"id":[0-9]*' | head -n1 | cut -d: -f2)
+[ -n "$USER_ID" ] || { fail "could not create user" "$resp"; exit 1; }
+SESSION=$(login "$NAME" password123)
+ACCESS=$(echo "$SESSION" | field accessToken)
+REFRESH=$(echo "$SESSION" | field refreshToken)
+
+echo -e "\n${YELLOW}1. Requesting a reset link${NC}"
+code=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$AUTH_URL/auth/forgot-password" \
+    -H "Content-Type: application/json" -d "{\"email\": \"$EMAIL\"}")
+[ "$code" = "202" ] && ok "Reset requested" || fail "forgot-password returned HTTP $code"
+TOKEN=""
+for _ in $(seq 1 10); do
+    sleep 1
+    message_id=$(curl -s "$MAILPIT_URL/api/v1/search?query=to:$EMAIL%20subject:reset" | grep -o '"ID":"[^"]*"' | head -1 | cut -d'"' -f4)
+    [ -n "$message_id" ] || continue
+    TOKEN=$(curl -s "$MAILPIT_URL/api/v1/message/$message_id" | grep -o 'token=[0-9a-f]*' | head -1 | cut -d'=' -f2)
+    break
+done
+if [ -z "$TOKEN" ]; then
+    fail "no reset email arrived in Mailpit"
+    curl -s -o /dev/null -X DELETE "$AUTH_URL/users/$USER_ID" -H "Authorization: Bearer $ADMIN_TOKEN"
+    finish "password reset"
+fi
+ok "Reset email delivered"
+
+echo -e "\n${YELLOW}2. Redeeming the token${NC}"
+resp=$(reset "$TOKEN" newpassword456)
+[ "$(echo "$resp" | tail -n1)" = "200" ] && ok "Password reset" || fail "reset failed" "$resp"
+resp=$(reset "$TOKEN" otherpassword789)
+if [ "$(echo "$resp" | tail -n1)" = "400" ] && echo "$resp" | grep -q '"code":"INVALID_TOKEN"'; then
+    ok "The token cannot be used twice"
+else
+    fail "reset token redeemed twice" "$resp"
+fi
+
+echo -e "\n${YELLOW}3. New password and sessions${NC}"
+[ -z "$(login "$NAME" password123 | field accessToken)" ] && ok "Old password rejected" || fail "old password still works"
+[ -n "$(login "$NAME" newpassword456 | field accessToken)" ] && ok "New password works" || fail "login with the new password failed"
+code=$(refresh_status "$REFRESH")
+[ "$code" = "401" ] && ok "Existing sessions are revoked" || fail "refresh token from before the reset returned HTTP $code"
+code=$(curl -s -o /dev/null -w "%{http_code}" "$AUTH_URL/users/profile" -H "Authorization: Bearer $ACCESS")
+[ "$code" = "401" ] && ok "Access tokens from before the reset are rejected" || fail "old access token returned HTTP $code"
+
+curl -s -o /dev/null -X DELETE "$AUTH_URL/users/$USER_ID" -H "Authorization: Bearer $ADMIN_TOKEN"
+
+finish "password reset"
@@ -0,0 +1,67 @@
+#!/bin/bash
+
+echo "🎫 Testing Personal Access Tokens"
+echo "================================="
+
+# Make sure the auth service (8084) and the task service (8081) are running with the
+# seeded users (admin / john_doe / jane_smith, password: password). john_doe must be a
+# member of team 1.
+
+AUTH_URL="http://localhost:8084"
+TASK_URL="http://localhost:8081"
+
+source "$(dirname "$0")/lib.sh"
+
+# create_token <name> <scopes as JSON array>: prints the creation response
+create_token() {
+    curl -s -X POST "$AUTH_URL/users/profile/tokens" \
+        -H "Authorization: Bearer $USER_TOKEN" -H "Content-Type: application/json" \
+        -d "{\"name\": \"$1\", \"scopes\": $2}"
+}
+
+USER_TOKEN=$(login john_doe | field accessToken)
+if [ -z "$USER_TOKEN" ]; then
+    echo -e "${RED}❌ Could not log in john_doe${NC}"
+    exit 1
+fi
+
+echo -e "\n${YELLOW}1. Create tokens${NC}"
+created=$(create_token "ci" '["tasks:read", "tasks:write"]')
+PAT=$(echo "$created" | grep -o '"token":"pat_[^"]*"' | cut -d'"' -f4)
+PAT_ID=$(echo "$created" | grep -o '"id":[0-9]*' | head -n1 | cut -d: -f2)
+[ -n "$PAT" ] && ok "Read/write token created" || fail "token creation failed" "$created"
+READ_PAT=$(create_token "reader" '["tasks:read"]' | grep -o '"token":"pat_[^"]*"' | cut -d'"' -f4)
+[ -n "$READ_PAT" ] && ok "Read-only token created" || fail "read-only token creation failed"
+
+invalid=$(create_token "bad" '["tasks:everything"]')
+echo "$invalid" | grep -q '"code":"INVALID_SCOPE"' && ok "Unknown scope rejected" || fail "unknown scope accepted" "$invalid"
+
+listed=$(curl -s "$AUTH_URL/users/profile/tokens" -H "Authorization: Bearer $USER_TOKEN")
+if echo "$listed" | grep -q '"name":"ci"' && ! echo "$listed" | grep -q '"token":'; then
+    ok "Listing shows tokens without secrets"
+else
+    fail "unexpected token listing" "$listed"
+fi
+
+echo -e "\n${YELLOW}2. Tokens authenticate API calls${NC}"
+validated=$(curl -s -X POST "$AUTH_URL/validate" -H "Authorization: Bearer $PAT")
+echo "$validated" | grep -q '"tokenType":"personal"' && ok "/validate accepts the token" || fail "/validate rejected the token" "$validated"
+
+status=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$TASK_URL/teams/1/tasks" -H "Authorization: Bearer $PAT" \
+    -H "Content-Type: application/json" -d '{"title": "Created by CI"}')
+[ "$status" = "201" ] && ok "Read/write token creates a task" || fail "creating a task returned HTTP $status"
+
+status=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$TASK_URL/teams/1/tasks" -H "Authorization: Bearer $READ_PAT" \
+    -H "Content-Type: application/json" -d '{"title": "Should not exist"}')
+[ "$status" = "403" ] && ok "Read-only token cannot create tasks" || fail "read-only token returned HTTP $status"
+
+status=$(curl -s -o /dev/null -w "%{http_code}" "$AUTH_URL/users/profile/tokens" -H "Authorization: Bearer $PAT")
+[ "$status" = "403" ] && ok "Token cannot manage tokens" || fail "/users/profile/tokens returned HTTP $status"
+
+echo -e "\n${YELLOW}3. Revocation${NC}"
+status=$(curl -s -o /dev/null -w "%{http_code}" -X DELETE "$AUTH_URL/users/profile/tokens/$PAT_ID" -H "Authorization: Bearer $USER_TOKEN")
+[ "$status" = "204" ] && ok "Token deleted" || fail "deleting returned HTTP $status"
+status=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$AUTH_URL/validate" -H "Authorization: Bearer $PAT")
+[ "$status" = "401" ] && ok "Deleted token is rejected" || fail "deleted token returned HTTP $status"
+
+finish "personal access token"
@@ -0,0 +1,83 @@
+#!/bin/bash
+
+echo "🔄 Testing Refresh Token Rotation and Logout"
+echo "============================================"
+
+# Make sure the auth service is running on port 8084 with the seeded users
+# (john_doe / jane_smith, password: password)
+
+AUTH_URL="http://localhost:8084"
+
+source "$(dirname "$0")/lib.sh"
+
+# refresh <refresh token>: prints the refresh response followed by its HTTP status
+refresh() {
+    curl -s -w "\n%{http_code}" -X POST "$AUTH_URL/auth/refresh" \
+        -H "Content-Type: application/json" -d "{\"refreshToken\": \"$1\"}"
+}
+
+# logout <refresh token> [allSessions]: prints the HTTP status of logging out
+logout() {
+    curl -s -o /dev/null -w "%{http_code}" -X POST "$AUTH_URL/auth/logout" \
+        -H "Content-Type: application/json" -d "{\"refreshToken\": \"$1\", \"allSessions\": ${2:-false}}"
+}
+
+# me_status <access token>: prints the HTTP status of fetching the caller's profile
+me_status() {
+    curl -s -o /dev/null -w "%{http_code}" "$AUTH_URL/users/profile" -H "Authorization: Bearer $1"
+}
+
+FIRST=$(login john_doe)
+R1=$(echo "$FIRST" | field refreshToken)
+A1=$(echo "$FIRST" | field accessToken)
+if [ -z "$R1" ]; then
+    echo -e "${RED}❌ Could not log in john_doe${NC}"
+    exit 1
+fi
+
+echo -e "\n${YELLOW}1. Rotation${NC}"
+resp=$(refresh "$R1")
+R2=$(echo "$resp" | head -n1 | field refreshToken)
+if [ "$(echo "$resp" | tail -n1)" = "200" ] && [ -n "$R2" ] && [ "$R2" != "$R1" ]; then
+    ok "Refresh returns a new refresh token"
+else
+    fail "refresh did not rotate the token" "$resp"
+fi
+resp=$(refresh "$R2")
+R3=$(echo "$resp" | head -n1 | field refreshToken)
+A3=$(echo "$resp" | head -n1 | field accessToken)
+[ "$(echo "$resp" | tail -n1)" = "200" ] && ok "The rotated token refreshes once" || fail "refresh with the rotated token failed" "$resp"
+
+echo -e "\n${YELLOW}2. Reuse detection${NC}"
+resp=$(refresh "$R1")
+if [ "$(echo "$resp" | tail -n1)" = "401" ] && echo "$resp" | grep -q '"code":"TOKEN_REUSED"'; then
+    ok "Replaying a used token is rejected with TOKEN_REUSED"
+else
+    fail "reused refresh token was not rejected" "$resp"
+fi
+status=$(refresh "$R3" | tail -n1)
+[ "$status" = "401" ] && ok "Reuse revoked the newest token of the family" || fail "family token still refreshes: HTTP $status"
+status=$(me_status "$A3")
+[ "$status" = "401" ] && ok "Reuse revoked the family's access tokens" || fail "family access token still works: HTTP $status"
+status=$(me_status "$A1")
+[ "$status" = "401" ] && ok "The first access token of the family is revoked too" || fail "first access token still works: HTTP $status"
+
+echo -e "\n${YELLOW}3. Logout${NC}"
+R=$(login john_doe | field refreshToken)
+OTHER=$(login john_doe | field refreshToken)
+status=$(logout "$R")
+[ "$status" = "200" ] && ok "Logout succeeds" || fail "logout returned HTTP $status"
+status=$(refresh "$R" | tail -n1)
+[ "$status" = "401" ] && ok "Logged out token can no longer refresh" || fail "refresh after logout returned HTTP $status"
+status=$(refresh "$OTHER" | tail -n1)
+[ "$status" = "200" ] && ok "Other sessions stay signed in" || fail "logout ended another session: HTTP $status"
+
+echo -e "\n${YELLOW}4. Logout from all sessions${NC}"
+R=$(login jane_smith | field refreshToken)
+OTHER=$(login jane_smith | field refreshToken)
+status=$(logout "$R" true)
+[ "$status" = "200" ] && ok "Logout from all sessions succeeds" || fail "logout with allSessions returned HTTP $status"
+status=$(refresh "$OTHER" | tail -n1)
+[ "$status" = "401" ] && ok "Every session of the user is revoked" || fail "other session still refreshes: HTTP $status"
+
+finish "refresh token"
@@ -0,0 +1,76 @@
+#!/bin/bash
+
+echo "🎭 Testing Roles and Permissions"
+echo "================================"
+
+# Make sure the auth service is running on port 8084 with the seeded users
+# (admin / john_doe / jane_smith, password: password). The team service on port 8083
+# is used for the teams.create check when it is running.
+
+AUTH_URL="http://localhost:8084"
+TEAM_URL="http://localhost:8083"
+
+source "$(dirname "$0")/lib.sh"
+
+# status <method> <url> <token> [body]: prints the HTTP status
+status() {
+    curl -s -o /dev/null -w "%{http_code}" -X "$1" "$2" -H "Authorization: Bearer $3" \
+        -H "Content-Type: application/json" ${4:+-d "$4"}
+}
+
+ADMIN_TOKEN=$(login admin | field accessToken)
+USER_TOKEN=$(login john_doe | field accessToken)
+if [ -z "$ADMIN_TOKEN" ] || [ -z "$USER_TOKEN" ]; then
+    echo -e "${RED}❌ Could not log in seeded users${NC}"
+    exit 1
+fi
+JANE_ID=3
+
+echo -e "\n${YELLOW}1. Permissions in tokens${NC}"
+claims "$ADMIN_TOKEN" | grep -q '"perms":\[[^]]*"roles.manage"' && ok "Admin token carries roles.manage" || fail "admin token lacks roles.manage" "$(claims "$ADMIN_TOKEN")"
+claims "$USER_TOKEN" | grep -q '"perms":\["teams.create"\]' && ok "User token carries teams.create only" || fail "unexpected user permissions" "$(claims "$USER_TOKEN")"
+validate=$(curl -s -X POST "$AUTH_URL/validate" -H "Authorization: Bearer $USER_TOKEN")
+echo "$validate" | grep -q '"permissions":\["teams.create"\]' && ok "/validate reports the permissions" || fail "/validate lacks permissions" "$validate"
+
+echo -e "\n${YELLOW}2. Role management needs roles.manage${NC}"
+response=$(curl -s -w "\n%{http_code}" "$AUTH_URL/roles" -H "Authorization: Bearer $USER_TOKEN")
+if [ "$(echo "$response" | tail -n1)" = "403" ] && echo "$response" | grep -q '"reason":"PERMISSION_REQUIRED".*"permission":"roles.manage"\|"permission":"roles.manage".*"reason":"PERMISSION_REQUIRED"'; then
+    ok "Regular user gets PERMISSION_REQUIRED"
+else
+    fail "unexpected response for a regular user" "$response"
+fi
+catalog=$(curl -s "$AUTH_URL/roles/permissions" -H "Authorization: Bearer $ADMIN_TOKEN")
+echo "$catalog" | grep -q '"name":"tasks.delete_any"' && ok "Permission catalog listed" || fail "catalog incomplete" "$catalog"
+
+echo -e "\n${YELLOW}3. Creating a role${NC}"
+curl -s -o /dev/null -X DELETE "$AUTH_URL/roles/support" -H "Authorization: Bearer $ADMIN_TOKEN"
+[ "$(status POST "$AUTH_URL/roles" "$ADMIN_TOKEN" '{"name": "support", "permissions": ["users.fly"]}')" = "400" ] && ok "Unknown permission rejected" || fail "unknown permission accepted"
+[ "$(status POST "$AUTH_URL/roles" "$ADMIN_TOKEN" '{"name": "Support!", "permissions": []}')" = "400" ] && ok "Invalid name rejected" || fail "invalid name accepted"
+created=$(curl -s -X POST "$AUTH_URL/roles" -H "Authorization: Bearer $ADMIN_TOKEN" -H "Content-Type: application/json" \
+    -d '{"name": "support", "description": "Helpdesk", "permissions": ["users.read", "users.read"]}')
+echo "$created" | grep -q '"name":"support".*"permissions":\["users.read"\]' && ok "Role created" || fail "role creation failed" "$created"
+[ "$(status POST "$AUTH_URL/roles" "$ADMIN_TOKEN" '{"name": "support"}')" = "409" ] && ok "Duplicate role rejected" || fail "duplicate role accepted"
+
+echo -e "\n${YELLOW}4. Assigning a role${NC}"
+[ "$(status PUT "$AUTH_URL/users/$JANE_ID" "$ADMIN_TOKEN" '{"role": "nonexistent"}')" = "400" ] && ok "Unknown role rejected" || fail "unknown role accepted"
+[ "$(status PUT "$AUTH_URL/users/$JANE_ID" "$ADMIN_TOKEN" '{"role": "support"}')" = "200" ] && ok "support assigned to jane_smith" || fail "role assignment failed"
+JANE_TOKEN=$(login jane_smith | field accessToken)
+[ "$(status GET "$AUTH_URL/users" "$JANE_TOKEN")" = "200" ] && ok "users.read lets jane_smith list users" || fail "support cannot list users"
+[ "$(status POST "$AUTH_URL/users" "$JANE_TOKEN" '{"username": "probe", "email": "probe@example.com", "password": "secret123", "role": "user"}')" = "403" ] && ok "users.write still required to create users" || fail "support created a user"
+if curl -s -o /dev/null "$TEAM_URL/healthz"; then
+    [ "$(status POST "$TEAM_URL/teams" "$JANE_TOKEN" '{"name": "No permission"}')" = "403" ] && ok "Team service requires teams.create" || fail "team created without teams.create"
+else
+    echo "   (team service not running, skipping teams.create check)"
+fi
+
+echo -e "\n${YELLOW}5. Changing and deleting roles${NC}"
+updated=$(curl -s -X PUT "$AUTH_URL/roles/support" -H "Authorization: Bearer $ADMIN_TOKEN" -H "Content-Type: application/json" \
+    -d '{"permissions": ["users.read", "teams.create"]}')
+echo "$updated" | grep -q '"permissions":\["teams.create","users.read"\]' && ok "Permissions replaced" || fail "update failed" "$updated"
+[ "$(status PUT "$AUTH_URL/roles/admin" "$ADMIN_TOKEN" '{"permissions": []}')" = "409" ] && ok "admin permissions are locked" || fail "admin permissions changed"
+[ "$(status DELETE "$AUTH_URL/roles/user" "$ADMIN_TOKEN")" = "409" ] && ok "Built-in role cannot be deleted" || fail "built-in role deleted"
+[ "$(status DELETE "$AUTH_URL/roles/support" "$ADMIN_TOKEN")" = "409" ] && ok "Role in use cannot be deleted" || fail "role in use deleted"
+status PUT "$AUTH_URL/users/$JANE_ID" "$ADMIN_TOKEN" '{"role": "user"}' > /dev/null
+[ "$(status DELETE "$AUTH_URL/roles/support" "$ADMIN_TOKEN")" = "204" ] && ok "Unused role deleted" || fail "deleting unused role failed"
+
+finish "role"
@@ -0,0 +1,82 @@
+#!/bin/bash
+
+echo "💻 Testing Session Management"
+echo "============================="
+
+# Make sure the auth service is running on port 8084 with the seeded users
+# (admin / john_doe / jane_smith, password: password). The task service (8081) is
+# checked too when it is running.
+
+AUTH_URL="http://localhost:8084"
+TASK_URL="http://localhost:8081"
+
+source "$(dirname "$0")/lib.sh"
+
+# login_from <username> <user agent>: prints the login response
+login_from() {
+    curl -s -X POST "$AUTH_URL/auth/login" -A "$2" \
+        -H "Content-Type: application/json" \
+        -d "{\"username\": \"$1\", \"password\": \"password\"}"
+}
+
+refresh_status() {
+    curl -s -o /dev/null -w "%{http_code}" -X POST "$AUTH_URL/auth/refresh" \
+        -H "Content-Type: application/json" -d "{\"refreshToken\": \"$1\"}"
+}
+
+ADMIN_TOKEN=$(login_from admin "curl/8.0" | field accessToken)
+LAPTOP=$(login_from john_doe "Mozilla/5.0 (X11; Linux x86_64) Firefox/128.0")
+PHONE=$(login_from john_doe "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Safari/604.1")
+LAPTOP_TOKEN=$(echo "$LAPTOP" | field accessToken)
+PHONE_TOKEN=$(echo "$PHONE" | field accessToken)
+PHONE_REFRESH=$(echo "$PHONE" | field refreshToken)
+if [ -z "$ADMIN_TOKEN" ] || [ -z "$LAPTOP_TOKEN" ] || [ -z "$PHONE_REFRESH" ]; then
+    echo -e "${RED}❌ Could not log in seeded users${NC}"
+    exit 1
+fi
+
+echo -e "\n${YELLOW}1. Users see their devices${NC}"
+sessions=$(curl -s "$AUTH_URL/users/profile/sessions" -H "Authorization: Bearer $LAPTOP_TOKEN")
+if echo "$sessions" | grep -q '"device":"Firefox on Linux","[^}]*"current":true' && echo "$sessions" | grep -q '"device":"Safari on iOS"'; then
+    ok "Both devices listed, laptop marked current"
+else
+    fail "unexpected session list" "$sessions"
+fi
+
+echo -e "\n${YELLOW}2. Logging out one device${NC}"
+PHONE_SESSION=$(echo "$sessions" | grep -o '"id":"[^"]*","device":"Safari on iOS"' | cut -d'"' -f4)
+status=$(curl -s -o /dev/null -w "%{http_code}" -X DELETE "$AUTH_URL/users/profile/sessions/$PHONE_SESSION" -H "Authorization: Bearer $LAPTOP_TOKEN")
+[ "$status" = "204" ] && ok "Phone session revoked" || fail "revoking returned HTTP $status"
+status=$(refresh_status "$PHONE_REFRESH")
+[ "$status" = "401" ] && ok "Phone can no longer refresh" || fail "phone refresh returned HTTP $status"
+status=$(curl -s -o /dev/null -w "%{http_code}" "$AUTH_URL/users/profile" -H "Authorization: Bearer $PHONE_TOKEN")
+[ "$status" = "401" ] && ok "Phone's access token rejected" || fail "phone access token returned HTTP $status"
+status=$(curl -s -o /dev/null -w "%{http_code}" "$AUTH_URL/users/profile" -H "Authorization: Bearer $LAPTOP_TOKEN")
+[ "$status" = "200" ] && ok "Laptop's access token still works" || fail "laptop access token returned HTTP $status"
+if curl -s -o /dev/null "$TASK_URL/healthz"; then
+    # The task service learns about the ended session from user.session_revoked
+    sleep 3
+    status=$(curl -s -o /dev/null -w "%{http_code}" "$TASK_URL/tasks" -H "Authorization: Bearer $PHONE_TOKEN")
+    [ "$status" = "401" ] && ok "Task service rejects the phone's access token" || fail "task service returned HTTP $status"
+fi
+
+echo -e "\n${YELLOW}3. Admin view and deactivation${NC}"
+JOHN_ID=$(curl -s "$AUTH_URL/users/profile" -H "Authorization: Bearer $LAPTOP_TOKEN" | grep -o '"id":[0-9]*' | head -n1 | cut -d: -f2)
+admin_view=$(curl -s "$AUTH_URL/users/$JOHN_ID/sessions" -H "Authorization: Bearer $ADMIN_TOKEN")
+echo "$admin_view" | grep -q '"device":"Firefox on Linux"' && ok "Admin sees the user's sessions" || fail "admin session list failed" "$admin_view"
+
+JANE=$(login_from jane_smith "curl/8.0")
+JANE_TOKEN=$(echo "$JANE" | field accessToken)
+JANE_REFRESH=$(echo "$JANE" | field refreshToken)
+JANE_ID=$(curl -s "$AUTH_URL/users/profile" -H "Authorization: Bearer $JANE_TOKEN" | grep -o '"id":[0-9]*' | head -n1 | cut -d: -f2)
+status=$(curl -s -o /dev/null -w "%{http_code}" "$AUTH_URL/users/$JANE_ID/sessions" -H "Authorization: Bearer $LAPTOP_TOKEN")
+[ "$status" = "403" ] && ok "Users cannot see other users' sessions" || fail "foreign session list returned HTTP $status"
+
+curl -s -o /dev/null -X PUT "$AUTH_URL/users/$JANE_ID" -H "Authorization: Bearer $ADMIN_TOKEN" \
+    -H "Content-Type: application/json" -d '{"isActive": false}'
+status=$(refresh_status "$JANE_REFRESH")
+[ "$status" = "401" ] && ok "Deactivation revoked the user's sessions" || fail "refresh after deactivation returned HTTP $status"
+curl -s -o /dev/null -X PUT "$AUTH_URL/users/$JANE_ID" -H "Authorization: Bearer $ADMIN_TOKEN" \
+    -H "Content-Type: application/json" -d '{"isActive": true}'
+
+finish "session"
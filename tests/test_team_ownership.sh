@@ -0,0 +1,78 @@
+#!/bin/bash
+
+echo "👑 Testing Team Ownership"
+echo "========================="
+
+# Make sure the auth service is running on port 8084 with the seeded users
+# (admin / john_doe / jane_smith, password: password) and the team service on port 8083.
+
+AUTH_URL="http://localhost:8084"
+TEAM_URL="http://localhost:8083"
+
+source "$(dirname "$0")/lib.sh"
+
+number() {
+    grep -o "\"$1\":[0-9]*" | head -n1 | cut -d: -f2
+}
+
+# status <method> <url> <token> [body]: prints the HTTP status
+status() {
+    curl -s -o /dev/null -w "%{http_code}" -X "$1" "$2" ${3:+-H "Authorization: Bearer $3"} \
+        -H "Content-Type: application/json" ${4:+-d "$4"}
+}
+
+JANE=$(login jane_smith)
+TOKEN=$(echo "$JANE" | field accessToken)
+JANE_ID=$(echo "$JANE" | grep -o '"user":{"id":[0-9]*' | grep -o '[0-9]*$')
+JOHN=$(login john_doe)
+JOHN_TOKEN=$(echo "$JOHN" | field accessToken)
+JOHN_ID=$(echo "$JOHN" | grep -o '"user":{"id":[0-9]*' | grep -o '[0-9]*$')
+if [ -z "$TOKEN" ] || [ -z "$JANE_ID" ] || [ -z "$JOHN_ID" ]; then
+    echo -e "${RED}❌ Could not log in jane_smith and john_doe${NC}"
+    exit 1
+fi
+
+echo -e "\n${YELLOW}Creating teams${NC}"
+code=$(status POST "$TEAM_URL/teams" "" '{"name": "Anonymous team"}')
+[ "$code" = "401" ] && ok "Unauthenticated creation is rejected" || fail "unauthenticated creation returned HTTP $code"
+
+created=$(curl -s -X POST "$TEAM_URL/teams" -H "Authorization: Bearer $TOKEN" -H "Content-Type: application/json" \
+    -d "{\"name\": \"Ownership test $$\", \"ownerId\": $JOHN_ID}")
+TEAM_ID=$(echo "$created" | number id)
+[ -n "$TEAM_ID" ] || { fail "team was not created" "$created"; exit 1; }
+[ "$(echo "$created" | number ownerId)" = "$JANE_ID" ] && ok "Caller owns the new team" || fail "ownerId is not $JANE_ID" "$created"
+
+members=$(curl -s "$TEAM_URL/teams/$TEAM_ID/members" -H "Authorization: Bearer $TOKEN")
+echo "$members" | grep -q "\"userId\":$JANE_ID,\"teamId\":$TEAM_ID,\"role\":\"owner\"" \
+    && ok "Caller is the first member with the owner role" || fail "caller is not an owner member" "$members"
+
+echo -e "\n${YELLOW}Managing the team${NC}"
+code=$(status POST "$TEAM_URL/teams/$TEAM_ID/members" "$TOKEN" "{\"userId\": $JOHN_ID, \"role\": \"member\"}")
+[ "$code" = "201" ] && ok "Owner adds a member" || fail "adding a member returned HTTP $code"
+code=$(status PUT "$TEAM_URL/teams/$TEAM_ID" "$JOHN_TOKEN" '{"name": "Renamed by a member"}')
+[ "$code" = "403" ] && ok "Members cannot update the team" || fail "update by a member returned HTTP $code"
+code=$(status DELETE "$TEAM_URL/teams/$TEAM_ID/members/$JOHN_ID" "")
+[ "$code" = "401" ] && ok "Unauthenticated member removal is rejected" || fail "unauthenticated removal returned HTTP $code"
+code=$(status DELETE "$TEAM_URL/teams/$TEAM_ID/members/$JOHN_ID" "$TOKEN")
+[ "$code" = "204" ] && ok "Owner removes the member" || fail "removing the member returned HTTP $code"
+code=$(status PUT "$TEAM_URL/teams/$TEAM_ID" "$TOKEN" '{"description": "Owned by the caller"}')
+[ "$code" = "200" ] && ok "Owner updates the team" || fail "update returned HTTP $code"
+code=$(status DELETE "$TEAM_URL/teams/$TEAM_ID" "")
+[ "$code" = "401" ] && ok "Unauthenticated deletion is rejected" || fail "unauthenticated deletion returned HTTP $code"
+code=$(status DELETE "$TEAM_URL/teams/$TEAM_ID" "$TOKEN")
+[ "$code" = "204" ] && ok "Owner deletes the team" || fail "deletion returned HTTP $code"
+
+echo -e "\n${YELLOW}Internal team routes${NC}"
+# Service clients other than auth itself may read but not manage teams (compose secrets)
+SERVICE_TOKEN=$(curl -s -X POST "$AUTH_URL/oauth/token" -u "notification:dev-notification-secret" \
+    -d "grant_type=client_credentials" | field access_token)
+if [ -n "$SERVICE_TOKEN" ]; then
+    code=$(status POST "$TEAM_URL/internal/teams" "$SERVICE_TOKEN" "{\"name\": \"Not from auth\", \"ownerId\": $JANE_ID}")
+    [ "$code" = "403" ] && ok "Other service clients cannot create teams" || fail "notification client created a team (HTTP $code)"
+    code=$(status DELETE "$TEAM_URL/internal/teams/1" "$SERVICE_TOKEN")
+    [ "$code" = "403" ] && ok "Other service clients cannot delete teams" || fail "notification client deleted a team (HTTP $code)"
+else
+    echo -e "${YELLOW}⚠️  No notification service token, skipping internal route checks${NC}"
+fi
+
+finish "team ownership"
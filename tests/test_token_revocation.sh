@@ -0,0 +1,49 @@
+#!/bin/bash
+
+echo "🚫 Testing Immediate Token Revocation"
+echo "====================================="
+
+# Make sure auth (8084), task (8081) and Kafka are running with the seeded users
+# (admin / john_doe, password: password)
+
+AUTH_URL="http://localhost:8084"
+TASK_URL="http://localhost:8081"
+
+source "$(dirname "$0")/lib.sh"
+
+# status <url> <token>: prints the HTTP status of an authenticated GET
+status() {
+    curl -s -o /dev/null -w "%{http_code}" "$1" -H "Authorization: Bearer $2"
+}
+
+ADMIN_TOKEN=$(login admin password | field accessToken)
+JOHN_TOKEN=$(login john_doe password | field accessToken)
+if [ -z "$ADMIN_TOKEN" ] || [ -z "$JOHN_TOKEN" ]; then
+    echo -e "${RED}❌ Could not log in seeded users${NC}"
+    exit 1
+fi
+JOHN_ID=$(curl -s "$AUTH_URL/users/profile" -H "Authorization: Bearer $JOHN_TOKEN" | grep -o '"id":[0-9]*' | head -n1 | cut -d: -f2)
+
+echo -e "\n${YELLOW}1. Signing a user out everywhere${NC}"
+[ "$(status "$TASK_URL/tasks" "$JOHN_TOKEN")" = "200" ] && ok "Token accepted by the task service" || fail "token rejected before revocation"
+code=$(curl -s -o /dev/null -w "%{http_code}" -X DELETE "$AUTH_URL/users/$JOHN_ID/sessions" -H "Authorization: Bearer $ADMIN_TOKEN")
+[ "$code" = "204" ] && ok "Admin revoked all sessions" || fail "revoking returned HTTP $code"
+code=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$AUTH_URL/validate" -H "Authorization: Bearer $JOHN_TOKEN")
+[ "$code" = "401" ] && ok "/validate rejects the old access token" || fail "/validate returned HTTP $code"
+# The task service learns about the revocation from Kafka
+sleep 3
+code=$(status "$TASK_URL/tasks" "$JOHN_TOKEN")
+[ "$code" = "401" ] && ok "Task service rejects the old access token" || fail "task service returned HTTP $code"
+
+echo -e "\n${YELLOW}2. Changing the password${NC}"
+OLD_TOKEN=$(login john_doe password | field accessToken)
+resp=$(curl -s -X POST "$AUTH_URL/users/change-password" -H "Authorization: Bearer $OLD_TOKEN" \
+    -H "Content-Type: application/json" -d '{"currentPassword": "password", "newPassword": "password2"}')
+NEW_TOKEN=$(echo "$resp" | field accessToken)
+[ -n "$NEW_TOKEN" ] && ok "Caller received a new token pair" || fail "no new token in response" "$resp"
+[ "$(status "$AUTH_URL/users/profile" "$OLD_TOKEN")" = "401" ] && ok "Old token revoked" || fail "old token still accepted"
+[ "$(status "$AUTH_URL/users/profile" "$NEW_TOKEN")" = "200" ] && ok "New token works" || fail "new token rejected"
+curl -s -o /dev/null -X POST "$AUTH_URL/users/change-password" -H "Authorization: Bearer $NEW_TOKEN" \
+    -H "Content-Type: application/json" -d '{"currentPassword": "password2", "newPassword": "password"}'
+
+finish "revocation"
@@ -0,0 +1,120 @@
+#!/bin/bash
+
+echo "🛡️  Testing /users Authorization Policy"
+echo "======================================="
+
+# Make sure the auth service is running on port 8084 with the seeded users
+# (admin / john_doe / jane_smith, password: password)
+
+AUTH_URL="http://localhost:8084"
+
+source "$(dirname "$0")/lib.sh"
+
+# expect <description> <expected status> <expected reason or ""> <curl args...>
+expect() {
+    local description=$1
+    local expected_status=$2
+    local expected_reason=$3
+    shift 3
+
+    echo -n "$description... "
+    response=$(curl -s -w "\n%{http_code}" "$@")
+    status=$(echo "$response" | tail -n1)
+    body=$(echo "$response" | sed '$d')
+
+    if [ "$status" != "$expected_status" ]; then
+        echo -e "${RED}❌ Failed (expected HTTP $expected_status, got $status)${NC}"
+        echo "   Response: $body"
+        FAILURES=$((FAILURES + 1))
+        return 1
+    fi
+    if [ -n "$expected_reason" ] && ! echo "$body" | grep -q "\"reason\":\"$expected_reason\""; then
+        echo -e "${RED}❌ Failed (expected reason $expected_reason)${NC}"
+        echo "   Response: $body"
+        FAILURES=$((FAILURES + 1))
+        return 1
+    fi
+    echo -e "${GREEN}✅ OK${NC}"
+}
+
+echo -e "\n${YELLOW}Logging in seeded users:${NC}"
+ADMIN_TOKEN=$(login admin | field accessToken)
+USER_TOKEN=$(login john_doe | field accessToken)
+if [ -z "$ADMIN_TOKEN" ] || [ -z "$USER_TOKEN" ]; then
+    echo -e "${RED}❌ Could not log in seeded users${NC}"
+    exit 1
+fi
+echo -e "${GREEN}✅ admin and john_doe logged in${NC}"
+
+echo -e "\n${YELLOW}Admin-only routes as a regular user:${NC}"
+expect "1. List users" 403 PERMISSION_REQUIRED \
+    -X GET "$AUTH_URL/users" -H "Authorization: Bearer $USER_TOKEN"
+expect "2. Create user" 403 PERMISSION_REQUIRED \
+    -X POST "$AUTH_URL/users" -H "Authorization: Bearer $USER_TOKEN" -H "Content-Type: application/json" \
+    -d '{"username": "policy_probe", "email": "policy_probe@example.com", "password": "secret123", "role": "user"}'
+expect "3. Delete another user" 403 PERMISSION_REQUIRED \
+    -X DELETE "$AUTH_URL/users/3" -H "Authorization: Bearer $USER_TOKEN"
+
+echo -e "\n${YELLOW}Self-or-admin routes as a regular user:${NC}"
+expect "4. Get own user" 200 "" \
+    -X GET "$AUTH_URL/users/2" -H "Authorization: Bearer $USER_TOKEN"
+expect "5. Get another user" 403 NOT_SELF_OR_ADMIN \
+    -X GET "$AUTH_URL/users/3" -H "Authorization: Bearer $USER_TOKEN"
+expect "6. Update own name" 200 "" \
+    -X PUT "$AUTH_URL/users/2" -H "Authorization: Bearer $USER_TOKEN" -H "Content-Type: application/json" \
+    -d '{"firstName": "John"}'
+expect "7. Update another user" 403 NOT_SELF_OR_ADMIN \
+    -X PUT "$AUTH_URL/users/3" -H "Authorization: Bearer $USER_TOKEN" -H "Content-Type: application/json" \
+    -d '{"firstName": "Mallory"}'
+expect "8. Promote self to admin" 403 FIELD_RESTRICTED \
+    -X PUT "$AUTH_URL/users/2" -H "Authorization: Bearer $USER_TOKEN" -H "Content-Type: application/json" \
+    -d '{"role": "admin"}'
+expect "9. Deactivate self" 403 FIELD_RESTRICTED \
+    -X PUT "$AUTH_URL/users/2" -H "Authorization: Bearer $USER_TOKEN" -H "Content-Type: application/json" \
+    -d '{"isActive": false}'
+
+echo -e "\n${YELLOW}Admin access:${NC}"
+expect "10. List users" 200 "" \
+    -X GET "$AUTH_URL/users" -H "Authorization: Bearer $ADMIN_TOKEN"
+expect "11. Get another user" 200 "" \
+    -X GET "$AUTH_URL/users/3" -H "Authorization: Bearer $ADMIN_TOKEN"
+expect "12. Change another user's role" 200 "" \
+    -X PUT "$AUTH_URL/users/3" -H "Authorization: Bearer $ADMIN_TOKEN" -H "Content-Type: application/json" \
+    -d '{"role": "user"}'
+expect "13. Reject unknown role" 400 "" \
+    -X PUT "$AUTH_URL/users/3" -H "Authorization: Bearer $ADMIN_TOKEN" -H "Content-Type: application/json" \
+    -d '{"role": "superuser"}'
+expect "14. Demote self" 403 SELF_LOCKOUT \
+    -X PUT "$AUTH_URL/users/1" -H "Authorization: Bearer $ADMIN_TOKEN" -H "Content-Type: application/json" \
+    -d '{"role": "user"}'
+expect "15. Delete self" 403 SELF_LOCKOUT \
+    -X DELETE "$AUTH_URL/users/1" -H "Authorization: Bearer $ADMIN_TOKEN"
+
+echo -e "\n${YELLOW}Unauthenticated access:${NC}"
+expect "16. List users without token" 401 "" -X GET "$AUTH_URL/users"
+
+echo -e "\n${YELLOW}Acting on more privileged users:${NC}"
+# A helpdesk role with users.write must not take over admins or hand out admin
+ROLE="helpdesk_$$"
+curl -s -o /dev/null -X POST "$AUTH_URL/roles" -H "Authorization: Bearer $ADMIN_TOKEN" -H "Content-Type: application/json" \
+    -d "{\"name\": \"$ROLE\", \"permissions\": [\"users.read\", \"users.write\", \"teams.create\"]}"
+curl -s -o /dev/null -X POST "$AUTH_URL/users" -H "Authorization: Bearer $ADMIN_TOKEN" -H "Content-Type: application/json" \
+    -d "{\"username\": \"$ROLE\", \"email\": \"$ROLE@example.com\", \"password\": \"password123\", \"role\": \"$ROLE\"}"
+HELPDESK_TOKEN=$(login "$ROLE" password123 | field accessToken)
+if [ -z "$HELPDESK_TOKEN" ]; then
+    echo -e "${RED}❌ Could not create and log in $ROLE${NC}"
+    FAILURES=$((FAILURES + 1))
+else
+    expect "17. Change an admin's email" 403 TARGET_PRIVILEGED \
+        -X PUT "$AUTH_URL/users/1" -H "Authorization: Bearer $HELPDESK_TOKEN" -H "Content-Type: application/json" \
+        -d '{"email": "mallory@example.com"}'
+    expect "18. Reset an admin's 2FA" 403 TARGET_PRIVILEGED \
+        -X DELETE "$AUTH_URL/users/1/mfa" -H "Authorization: Bearer $HELPDESK_TOKEN"
+    expect "19. Sign an admin out" 403 TARGET_PRIVILEGED \
+        -X DELETE "$AUTH_URL/users/1/sessions" -H "Authorization: Bearer $HELPDESK_TOKEN"
+    expect "20. Update a regular user" 200 "" \
+        -X PUT "$AUTH_URL/users/3" -H "Authorization: Bearer $HELPDESK_TOKEN" -H "Content-Type: application/json" \
+        -d '{"firstName": "Jane"}'
+fi
+
+finish "authorization"
@@ -0,0 +1,98 @@
+#!/bin/bash
+
+echo "🧹 Testing Cleanup after user.deleted"
+echo "====================================="
+
+# Make sure auth (8084), task (8081), team (8083) and Kafka are running with the seeded
+# admin (password: password). A user is deleted by an admin; the team service must drop
+# their memberships and the task service must unassign their tasks.
+
+AUTH_URL="http://localhost:8084"
+TASK_URL="http://localhost:8081"
+TEAM_URL="http://localhost:8083"
+
+source "$(dirname "$0")/lib.sh"
+
+# create_user <name>: creates a user with password password123 and prints their ID
+create_user() {
+    curl -s -X POST "$AUTH_URL/users" -H "Authorization: Bearer $ADMIN_TOKEN" -H "Content-Type: application/json" \
+        -d "{\"username\": \"$1\", \"email\": \"$1@example.com\", \"password\": \"password123\", \"role\": \"user\"}" \
+        | grep -o '"id":[0-9]*' | head -n1 | cut -d: -f2
+}
+
+# create_team <token> <name>: prints the ID of a new team of the token's user
+create_team() {
+    curl -s -X POST "$TEAM_URL/teams" -H "Authorization: Bearer $1" -H "Content-Type: application/json" \
+        -d "{\"name\": \"$2\"}" | grep -o '"id":[0-9]*' | head -n1 | cut -d: -f2
+}
+
+# add_member <token> <team> <user> <role>: prints the HTTP status of adding a member
+add_member() {
+    curl -s -o /dev/null -w "%{http_code}" -X POST "$TEAM_URL/teams/$2/members" -H "Authorization: Bearer $1" \
+        -H "Content-Type: application/json" -d "{\"userId\": $3, \"role\": \"$4\"}"
+}
+
+# members <team>: prints the members of a team as seen by the admin
+members() {
+    curl -s "$TEAM_URL/teams/$1/members" -H "Authorization: Bearer $ADMIN_TOKEN"
+}
+
+for url in "$TASK_URL" "$TEAM_URL"; do
+    if ! curl -s -o /dev/null "$url/healthz"; then
+        echo -e "${RED}❌ $url is not reachable${NC}"
+        exit 1
+    fi
+done
+ADMIN_TOKEN=$(login admin | field accessToken)
+if [ -z "$ADMIN_TOKEN" ]; then
+    echo -e "${RED}❌ Could not log in the seeded admin${NC}"
+    exit 1
+fi
+
+SUFFIX=$(date +%s)
+KEEP_ID=$(create_user "keep_$SUFFIX")
+GONE_ID=$(create_user "gone_$SUFFIX")
+[ -n "$KEEP_ID" ] && [ -n "$GONE_ID" ] || { fail "could not create users"; exit 1; }
+KEEP_TOKEN=$(login "keep_$SUFFIX" password123 | field accessToken)
+GONE_TOKEN=$(login "gone_$SUFFIX" password123 | field accessToken)
+
+echo -e "\n${YELLOW}1. Setup${NC}"
+# SHARED is owned by keep with gone as a member, HANDOVER is owned by gone with keep as
+# an admin, and SOLO only has gone
+SHARED=$(create_team "$KEEP_TOKEN" "Shared $SUFFIX")
+HANDOVER=$(create_team "$GONE_TOKEN" "Handover $SUFFIX")
+SOLO=$(create_team "$GONE_TOKEN" "Solo $SUFFIX")
+[ -n "$SHARED" ] && [ -n "$HANDOVER" ] && [ -n "$SOLO" ] && ok "Created three teams" || { fail "could not create teams"; exit 1; }
+[ "$(add_member "$KEEP_TOKEN" "$SHARED" "$GONE_ID" member)" = "201" ] && [ "$(add_member "$GONE_TOKEN" "$HANDOVER" "$KEEP_ID" admin)" = "201" ] \
+    && ok "Added the members" || fail "could not add members"
+TASK_ID=$(curl -s -X POST "$TASK_URL/teams/$SHARED/tasks" -H "Authorization: Bearer $KEEP_TOKEN" -H "Content-Type: application/json" \
+    -d "{\"title\": \"Task of gone_$SUFFIX\", \"priority\": \"low\", \"due\": \"2030-01-01\", \"assigneeId\": $GONE_ID}" \
+    | grep -o '"id":[0-9]*' | head -n1 | cut -d: -f2)
+[ -n "$TASK_ID" ] && ok "Assigned task $TASK_ID to gone_$SUFFIX" || fail "could not create the task"
+
+echo -e "\n${YELLOW}2. Deleting the user${NC}"
+code=$(curl -s -o /dev/null -w "%{http_code}" -X DELETE "$AUTH_URL/users/$GONE_ID" -H "Authorization: Bearer $ADMIN_TOKEN")
+[ "$code" = "204" ] && ok "Admin deleted gone_$SUFFIX" || fail "deletion returned HTTP $code"
+# Give the task and team services time to consume user.deleted
+sleep 5
+
+echo -e "\n${YELLOW}3. Teams${NC}"
+members "$SHARED" | grep -q "\"userId\":$GONE_ID," && fail "deleted user is still a member" "$(members "$SHARED")" \
+    || ok "Deleted user removed from a team they joined"
+resp=$(members "$HANDOVER")
+echo "$resp" | grep -q "\"userId\":$KEEP_ID,\"teamId\":$HANDOVER,\"role\":\"owner\"" && ! echo "$resp" | grep -q "\"userId\":$GONE_ID," \
+    && ok "Ownership of their team passed to the remaining admin" || fail "team of the deleted user was not handed over" "$resp"
+code=$(curl -s -o /dev/null -w "%{http_code}" "$TEAM_URL/teams/$SOLO")
+[ "$code" = "404" ] && ok "Their team without other members was deleted" || fail "team without members returned HTTP $code"
+
+echo -e "\n${YELLOW}4. Tasks${NC}"
+if [ -n "$TASK_ID" ]; then
+    resp=$(curl -s "$TASK_URL/tasks/$TASK_ID" -H "Authorization: Bearer $KEEP_TOKEN")
+    echo "$resp" | grep -q '"assigneeId":null' && ok "Their task is unassigned" || fail "task still assigned" "$resp"
+fi
+
+curl -s -o /dev/null -X DELETE "$TEAM_URL/teams/$SHARED" -H "Authorization: Bearer $KEEP_TOKEN"
+curl -s -o /dev/null -X DELETE "$TEAM_URL/teams/$HANDOVER" -H "Authorization: Bearer $KEEP_TOKEN"
+curl -s -o /dev/null -X DELETE "$AUTH_URL/users/$KEEP_ID" -H "Authorization: Bearer $ADMIN_TOKEN"
+
+finish "user.deleted cleanup"
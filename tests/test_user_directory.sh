@@ -0,0 +1,73 @@
+#!/bin/bash
+
+echo "📇 Testing the User Directory"
+echo "============================="
+
+# Make sure the auth service is running on port 8084 with the seeded users
+# (admin / john_doe / jane_smith, password: password)
+
+AUTH_URL="http://localhost:8084"
+
+source "$(dirname "$0")/lib.sh"
+
+# usernames: prints the usernames of a user list, one per line
+usernames() {
+    grep -o '"username":"[^"]*"' | cut -d'"' -f4
+}
+
+# status <url> <token>: prints the HTTP status of a GET
+status() {
+    curl -s -o /dev/null -w "%{http_code}" "$1" -H "Authorization: Bearer $2"
+}
+
+ADMIN_TOKEN=$(login admin | field accessToken)
+USER_TOKEN=$(login john_doe | field accessToken)
+if [ -z "$ADMIN_TOKEN" ] || [ -z "$USER_TOKEN" ]; then
+    echo -e "${RED}❌ Could not log in seeded users${NC}"
+    exit 1
+fi
+
+echo -e "\n${YELLOW}1. Paging with a cursor${NC}"
+first=$(curl -s "$AUTH_URL/users?sort=username&limit=2" -H "Authorization: Bearer $ADMIN_TOKEN")
+CURSOR=$(echo "$first" | field nextCursor)
+[ "$(echo "$first" | usernames | wc -l)" -eq 2 ] && [ -n "$CURSOR" ] && ok "First page has 2 users and a nextCursor" || fail "unexpected first page" "$first"
+second=$(curl -s "$AUTH_URL/users?sort=username&limit=2&cursor=$CURSOR" -H "Authorization: Bearer $ADMIN_TOKEN")
+if [ -n "$(comm -12 <(echo "$first" | usernames | sort) <(echo "$second" | usernames | sort))" ]; then
+    fail "pages overlap" "$second"
+elif [ "$(echo "$second" | usernames | head -n1)" \> "$(echo "$first" | usernames | tail -n1)" ]; then
+    ok "Second page continues after the first"
+else
+    fail "second page is out of order" "$second"
+fi
+all=$(curl -s "$AUTH_URL/users?sort=-username&limit=200" -H "Authorization: Bearer $ADMIN_TOKEN")
+echo "$all" | grep -q '"nextCursor"' && fail "nextCursor on the last page" "$all" || ok "No nextCursor on the last page"
+[ "$(echo "$all" | usernames)" = "$(echo "$all" | usernames | sort -r)" ] && ok "Descending sort" || fail "not sorted descending" "$all"
+
+echo -e "\n${YELLOW}2. Validation${NC}"
+[ "$(status "$AUTH_URL/users?sort=password_hash" "$ADMIN_TOKEN")" = "400" ] && ok "Unknown sort field rejected" || fail "unknown sort field accepted"
+[ "$(status "$AUTH_URL/users?sort=email&cursor=$CURSOR" "$ADMIN_TOKEN")" = "400" ] && ok "Cursor of another sort rejected" || fail "cursor reused across sorts"
+[ "$(status "$AUTH_URL/users?cursor=garbage" "$ADMIN_TOKEN")" = "400" ] && ok "Malformed cursor rejected" || fail "malformed cursor accepted"
+[ "$(status "$AUTH_URL/users?createdAfter=yesterday" "$ADMIN_TOKEN")" = "400" ] && ok "Malformed date rejected" || fail "malformed date accepted"
+
+echo -e "\n${YELLOW}3. Filters${NC}"
+admins=$(curl -s "$AUTH_URL/users?role=admin" -H "Authorization: Bearer $ADMIN_TOKEN")
+[ "$(echo "$admins" | usernames)" = "admin" ] && ok "role=admin returns only admin" || fail "role filter failed" "$admins"
+future=$(curl -s "$AUTH_URL/users?createdAfter=2999-01-01T00:00:00Z" -H "Authorization: Bearer $ADMIN_TOKEN")
+echo "$future" | grep -q '"users":\[\]' && ok "createdAfter in the future returns nothing" || fail "date filter failed" "$future"
+prefix=$(curl -s "$AUTH_URL/users?q=jane" -H "Authorization: Bearer $ADMIN_TOKEN")
+echo "$prefix" | usernames | grep -qx "jane_smith" && ok "q matches the start of the username" || fail "q found nothing" "$prefix"
+wildcard=$(curl -s "$AUTH_URL/users?q=%25" -H "Authorization: Bearer $ADMIN_TOKEN")
+echo "$wildcard" | grep -q '"users":\[\]' && ok "LIKE wildcards in q are matched literally" || fail "q=% matched users" "$wildcard"
+[ "$(status "$AUTH_URL/users" "$USER_TOKEN")" = "403" ] && ok "Listing still needs users.read" || fail "regular user listed users"
+
+echo -e "\n${YELLOW}4. Typeahead search${NC}"
+found=$(curl -s "$AUTH_URL/users/search?q=ja" -H "Authorization: Bearer $USER_TOKEN")
+echo "$found" | usernames | grep -qx "jane_smith" && ok "Regular user finds jane_smith" || fail "search failed" "$found"
+echo "$found" | grep -q '"email"\|"role"\|"isActive"' && fail "search returns private fields" "$found" || ok "Only public fields returned"
+byname=$(curl -s "$AUTH_URL/users/search?q=Jane%20Sm" -H "Authorization: Bearer $USER_TOKEN")
+echo "$byname" | usernames | grep -qx "jane_smith" && ok "First and last name matched together" || fail "full name search failed" "$byname"
+[ "$(status "$AUTH_URL/users/search?q=j" "$USER_TOKEN")" = "400" ] && ok "One-character query rejected" || fail "one-character query accepted"
+[ "$(status "$AUTH_URL/users/search?q=ja&limit=100" "$USER_TOKEN")" = "400" ] && ok "Oversized limit rejected" || fail "oversized limit accepted"
+[ "$(status "$AUTH_URL/users/search?q=ja" "")" = "401" ] && ok "Search requires a token" || fail "anonymous search allowed"
+
+finish "user directory"
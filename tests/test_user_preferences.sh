@@ -0,0 +1,79 @@
+#!/bin/bash
+
+echo "🖼️  Testing Avatars, Locale and Timezone"
+echo "========================================"
+
+# Make sure the auth service is running on port 8084 with the seeded users
+# (admin / john_doe / jane_smith, password: password). The task service on port 8081
+# is used for the due=today check when it is running.
+
+AUTH_URL="http://localhost:8084"
+TASK_URL="http://localhost:8081"
+
+source "$(dirname "$0")/lib.sh"
+
+# status <method> <url> <token> [body]: prints the HTTP status
+status() {
+    curl -s -o /dev/null -w "%{http_code}" -X "$1" "$2" -H "Authorization: Bearer $3" \
+        -H "Content-Type: application/json" ${4:+-d "$4"}
+}
+
+TOKEN=$(login jane_smith | field accessToken)
+if [ -z "$TOKEN" ]; then
+    echo -e "${RED}❌ Could not log in jane_smith${NC}"
+    exit 1
+fi
+
+TMP=$(mktemp -d)
+trap 'rm -rf "$TMP"' EXIT
+# A 3x2 red PNG
+echo "iVBORw0KGgoAAAANSUhEUgAAAAMAAAACCAIAAAASFvFNAAAAEElEQVR4nGP4z8AAQQxwFgBB0gX7h/C5SAAAAABJRU5ErkJggg==" | base64 -d > "$TMP/avatar.png"
+echo "not an image" > "$TMP/avatar.txt"
+
+echo -e "\n${YELLOW}1. Locale and timezone${NC}"
+updated=$(curl -s -X PUT "$AUTH_URL/users/profile" -H "Authorization: Bearer $TOKEN" -H "Content-Type: application/json" \
+    -d '{"locale": "de-ch", "timezone": "Europe/Zurich"}')
+echo "$updated" | grep -q '"locale":"de-CH"' && ok "Locale normalized to de-CH" || fail "locale not stored" "$updated"
+echo "$updated" | grep -q '"timezone":"Europe/Zurich"' && ok "Timezone stored" || fail "timezone not stored" "$updated"
+[ "$(status PUT "$AUTH_URL/users/profile" "$TOKEN" '{"timezone": "Mars/Olympus_Mons"}')" = "400" ] && ok "Unknown timezone rejected" || fail "unknown timezone accepted"
+[ "$(status PUT "$AUTH_URL/users/profile" "$TOKEN" '{"locale": "not a locale"}')" = "400" ] && ok "Invalid locale rejected" || fail "invalid locale accepted"
+TOKEN=$(login jane_smith | field accessToken)
+claims "$TOKEN" | grep -q '"tz":"Europe/Zurich"' && ok "New tokens carry the tz claim" || fail "tz claim missing" "$(claims "$TOKEN")"
+validate=$(curl -s -X POST "$AUTH_URL/validate" -H "Authorization: Bearer $TOKEN")
+echo "$validate" | grep -q '"timezone":"Europe/Zurich"' && ok "/validate reports the timezone" || fail "/validate lacks the timezone" "$validate"
+
+if curl -s -o /dev/null "$TASK_URL/healthz"; then
+    [ "$(status GET "$TASK_URL/tasks?due=today" "$TOKEN")" = "200" ] && ok "Task service accepts due=today" || fail "due=today rejected"
+    [ "$(status GET "$TASK_URL/tasks?due=yesterday" "$TOKEN")" = "400" ] && ok "Unknown due filter rejected" || fail "unknown due filter accepted"
+else
+    echo "   (task service not running, skipping due filter checks)"
+fi
+
+echo -e "\n${YELLOW}2. Avatar upload${NC}"
+response=$(curl -s -w "\n%{http_code}" -X PUT "$AUTH_URL/users/profile/avatar" -H "Authorization: Bearer $TOKEN" \
+    -F "avatar=@$TMP/avatar.txt;type=image/png")
+[ "$(echo "$response" | tail -n1)" = "415" ] && ok "Non-image rejected despite image/png content type" || fail "non-image accepted" "$response"
+uploaded=$(curl -s -X PUT "$AUTH_URL/users/profile/avatar" -H "Authorization: Bearer $TOKEN" \
+    -F "avatar=@$TMP/avatar.png;type=application/octet-stream")
+AVATAR_URL=$(echo "$uploaded" | field avatarUrl)
+[ -n "$AVATAR_URL" ] && ok "Avatar uploaded: $AVATAR_URL" || fail "upload failed" "$uploaded"
+
+headers=$(curl -s -D - -o "$TMP/thumb.png" "$AUTH_URL$AVATAR_URL?size=64")
+echo "$headers" | grep -qi '^content-type: image/png' && ok "Thumbnail served as PNG" || fail "unexpected thumbnail response" "$headers"
+size=$(od -An -tu1 -j16 -N8 "$TMP/thumb.png" | awk '{print $3*256+$4 "x" $7*256+$8}')
+[ "$size" = "64x64" ] && ok "Thumbnail is 64x64" || fail "thumbnail is $size"
+[ "$(curl -s -o /dev/null -w "%{http_code}" "$AUTH_URL$AVATAR_URL?size=100")" = "400" ] && ok "Unsupported size rejected" || fail "unsupported size accepted"
+
+echo -e "\n${YELLOW}3. Replacing and removing the avatar${NC}"
+replaced=$(curl -s -X PUT "$AUTH_URL/users/profile/avatar" -H "Authorization: Bearer $TOKEN" -F "avatar=@$TMP/avatar.png")
+NEW_URL=$(echo "$replaced" | field avatarUrl)
+[ -n "$NEW_URL" ] && [ "$NEW_URL" != "$AVATAR_URL" ] && ok "New upload gets a new URL" || fail "avatar URL unchanged" "$replaced"
+[ "$(curl -s -o /dev/null -w "%{http_code}" "$AUTH_URL$AVATAR_URL")" = "404" ] && ok "Old thumbnails deleted" || fail "old thumbnails still served"
+removed=$(curl -s -X DELETE "$AUTH_URL/users/profile/avatar" -H "Authorization: Bearer $TOKEN")
+echo "$removed" | grep -q '"avatarUrl"' && fail "avatar still set" "$removed" || ok "Avatar removed"
+[ "$(curl -s -o /dev/null -w "%{http_code}" "$AUTH_URL$NEW_URL")" = "404" ] && ok "Removed avatar no longer served" || fail "removed avatar still served"
+
+# Clear the preferences again
+status PUT "$AUTH_URL/users/profile" "$TOKEN" '{"locale": "", "timezone": ""}' > /dev/null
+
+finish "preference"
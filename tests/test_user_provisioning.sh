@@ -0,0 +1,159 @@
+#!/bin/bash
+
+echo "🏭 Testing Bulk Import and SCIM Provisioning"
+echo "============================================"
+
+# Make sure auth (8084) and team (8083) are running with the seeded users
+# (admin / john_doe, password: password)
+
+AUTH_URL="http://localhost:8084"
+TEAM_URL="http://localhost:8083"
+SUFFIX=$(date +%s)
+
+source "$(dirname "$0")/lib.sh"
+
+# import <token> <content type> <body> [query]: prints the response and the HTTP status
+import() {
+    curl -s -w "\n%{http_code}" -X POST "$AUTH_URL/users/import$4" -H "Authorization: Bearer $1" \
+        -H "Content-Type: $2" --data-binary "$3"
+}
+
+# wait_import <id>: polls an import until it finished and prints it
+wait_import() {
+    local resp
+    for _ in $(seq 1 20); do
+        resp=$(curl -s "$AUTH_URL/users/import/$1" -H "Authorization: Bearer $ADMIN_TOKEN")
+        echo "$resp" | grep -q '"status":"completed"\|"status":"failed"' && break
+        sleep 1
+    done
+    echo "$resp"
+}
+
+# scim <method> <path> [body]: prints the response of a SCIM request made with the PAT
+scim() {
+    curl -s -X "$1" "$AUTH_URL/scim/v2$2" -H "Authorization: Bearer $PAT" \
+        -H "Content-Type: application/scim+json" ${3:+-d "$3"}
+}
+
+# scim_status <method> <path> [body]: prints the HTTP status of a SCIM request
+scim_status() {
+    curl -s -o /dev/null -w "%{http_code}" -X "$1" "$AUTH_URL/scim/v2$2" -H "Authorization: Bearer $PAT" \
+        -H "Content-Type: application/scim+json" ${3:+-d "$3"}
+}
+
+ADMIN_TOKEN=$(login admin | field accessToken)
+USER_TOKEN=$(login john_doe | field accessToken)
+if [ -z "$ADMIN_TOKEN" ] || [ -z "$USER_TOKEN" ]; then
+    echo -e "${RED}❌ Could not log in seeded users${NC}"
+    exit 1
+fi
+
+CSV="username,Email,firstName,lastName
+imp_a_$SUFFIX,imp_a_$SUFFIX@example.com,Ada,Import
+imp_b_$SUFFIX,not-an-email,Bob,Import
+imp_a_$SUFFIX,imp_c_$SUFFIX@example.com,Dup,Import
+john_doe,imp_d_$SUFFIX@example.com,John,Again"
+
+echo -e "\n${YELLOW}1. Dry run${NC}"
+resp=$(import "$ADMIN_TOKEN" text/csv "$CSV" "?dryRun=true")
+[ "$(echo "$resp" | tail -n1)" = "202" ] && ok "Dry run accepted (202)" || fail "dry run not accepted" "$resp"
+DRY=$(wait_import "$(echo "$resp" | field id)")
+echo "$DRY" | grep -q '"succeeded":1,"failed":3' && ok "One valid row, three invalid" || fail "unexpected dry run counts" "$DRY"
+echo "$DRY" | grep -q '"status":"valid"' && ok "Valid row reported as valid" || fail "valid row missing" "$DRY"
+echo "$DRY" | grep -q 'email is not a valid address' && ok "Invalid email reported" || fail "invalid email not reported" "$DRY"
+echo "$DRY" | grep -q 'appears more than once' && ok "Duplicate within the file reported" || fail "duplicate not reported" "$DRY"
+echo "$DRY" | grep -q 'username already exists' && ok "Existing username reported" || fail "existing user not reported" "$DRY"
+curl -s "$AUTH_URL/users?q=imp_a_$SUFFIX" -H "Authorization: Bearer $ADMIN_TOKEN" | grep -q "imp_a_$SUFFIX" && fail "dry run created a user" || ok "Dry run created nothing"
+
+echo -e "\n${YELLOW}2. Import${NC}"
+resp=$(import "$ADMIN_TOKEN" application/json "{\"users\": [{\"username\": \"imp_j_$SUFFIX\", \"email\": \"imp_j_$SUFFIX@example.com\", \"password\": \"password\"}, {\"username\": \"x\", \"email\": \"imp_x_$SUFFIX@example.com\"}]}")
+DONE=$(wait_import "$(echo "$resp" | field id)")
+echo "$DONE" | grep -q '"status":"created","userId":[0-9]*' && ok "Valid row created" || fail "row not created" "$DONE"
+echo "$DONE" | grep -q '"succeeded":1,"failed":1' && ok "Invalid row skipped" || fail "unexpected import counts" "$DONE"
+login "imp_j_$SUFFIX" | grep -q accessToken && ok "Imported user can log in" || fail "imported user cannot log in"
+IMPORTED_ID=$(echo "$DONE" | grep -o '"userId":[0-9]*' | head -n1 | cut -d: -f2)
+
+echo -e "\n${YELLOW}3. Import validation${NC}"
+resp=$(import "$ADMIN_TOKEN" text/csv "username,shoeSize
+x,42")
+[ "$(echo "$resp" | tail -n1)" = "400" ] && ok "Unknown column rejected" || fail "unknown column accepted" "$resp"
+resp=$(import "$ADMIN_TOKEN" text/plain "hello")
+[ "$(echo "$resp" | tail -n1)" = "415" ] && ok "Other content types rejected" || fail "text/plain accepted" "$resp"
+resp=$(import "$ADMIN_TOKEN" application/json "[{\"username\": \"imp_r_$SUFFIX\", \"email\": \"imp_r_$SUFFIX@example.com\", \"role\": \"admin\"}]")
+[ "$(echo "$resp" | tail -n1)" = "202" ] && ok "Admin may import admins" || fail "admin role import refused" "$resp"
+resp=$(import "$USER_TOKEN" text/csv "$CSV")
+[ "$(echo "$resp" | tail -n1)" = "403" ] && ok "Regular users cannot import" || fail "regular user imported" "$resp"
+
+echo -e "\n${YELLOW}4. SCIM users${NC}"
+PAT=$(curl -s -X POST "$AUTH_URL/users/profile/tokens" -H "Authorization: Bearer $ADMIN_TOKEN" \
+    -H "Content-Type: application/json" -d "{\"name\": \"scim-$SUFFIX\", \"scopes\": [\"scim\"]}" | grep -o '"token":"pat_[^"]*"' | cut -d'"' -f4)
+[ -n "$PAT" ] && ok "Created a personal access token with the scim scope" || { fail "could not create a PAT"; exit 1; }
+headers=$(curl -s -D - -o /dev/null "$AUTH_URL/scim/v2/ServiceProviderConfig" -H "Authorization: Bearer $PAT")
+echo "$headers" | grep -qi '^content-type: application/scim+json' && ok "Responses use application/scim+json" || fail "wrong content type" "$headers"
+created=$(scim POST /Users "{\"schemas\": [\"urn:ietf:params:scim:schemas:core:2.0:User\"], \"userName\": \"scim_$SUFFIX\", \"externalId\": \"ext-$SUFFIX\",
+    \"name\": {\"givenName\": \"Sam\", \"familyName\": \"Scim\"}, \"emails\": [{\"value\": \"scim_$SUFFIX@example.com\", \"primary\": true}]}")
+SCIM_ID=$(echo "$created" | field id)
+[ -n "$SCIM_ID" ] && ok "User provisioned with id $SCIM_ID" || fail "provisioning failed" "$created"
+[ "$(scim_status POST /Users "{\"userName\": \"scim_$SUFFIX\", \"emails\": [{\"value\": \"other_$SUFFIX@example.com\"}]}")" = "409" ] && ok "Duplicate userName rejected (409)" || fail "duplicate accepted"
+found=$(scim GET "/Users?filter=externalId%20eq%20%22ext-$SUFFIX%22")
+echo "$found" | grep -q '"totalResults":1' && ok "Filter by externalId" || fail "filter failed" "$found"
+[ "$(scim_status GET "/Users?filter=userName%20co%20%22s%22")" = "400" ] && ok "Unsupported filter rejected" || fail "unsupported filter accepted"
+SCIM_TOKEN=$(curl -s -X POST "$AUTH_URL/users/$SCIM_ID/impersonate" -H "Authorization: Bearer $ADMIN_TOKEN" -H "Content-Type: application/json" \
+    -d '{"reason": "SCIM test"}' | field accessToken)
+patched=$(scim PATCH "/Users/$SCIM_ID" '{"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"], "Operations": [{"op": "Replace", "path": "active", "value": "False"}]}')
+echo "$patched" | grep -q '"active":false' && ok "PATCH active=False deactivates" || fail "deactivation failed" "$patched"
+if [ -n "$SCIM_TOKEN" ]; then
+    code=$(curl -s -o /dev/null -w "%{http_code}" "$AUTH_URL/users/profile" -H "Authorization: Bearer $SCIM_TOKEN")
+    [ "$code" = "401" ] && ok "Deactivation revoked the user's tokens" || fail "token of deactivated user returned HTTP $code"
+fi
+scim PATCH "/Users/$SCIM_ID" "{\"schemas\": [\"urn:ietf:params:scim:api:messages:2.0:PatchOp\"], \"Operations\": [{\"op\": \"replace\", \"path\": \"emails\", \"value\": [{\"value\": \"scim_new_$SUFFIX@example.com\", \"primary\": true}]}]}" > /dev/null
+user=$(curl -s "$AUTH_URL/users/$SCIM_ID" -H "Authorization: Bearer $ADMIN_TOKEN")
+echo "$user" | grep -q "scim_new_$SUFFIX@example.com" && echo "$user" | grep -q '"emailVerified":false' \
+    && ok "A new email has to be verified again" || fail "email change kept the verification" "$user"
+code=$(curl -s -o /dev/null -w "%{http_code}" "$AUTH_URL/scim/v2/Users" -H "Authorization: Bearer $USER_TOKEN")
+[ "$code" = "403" ] && ok "Users without users.write are refused" || fail "regular user got HTTP $code"
+
+# A helpdesk role with users.write must not set passwords of admins through SCIM
+ROLE="helpdesk_$SUFFIX"
+curl -s -o /dev/null -X POST "$AUTH_URL/roles" -H "Authorization: Bearer $ADMIN_TOKEN" -H "Content-Type: application/json" \
+    -d "{\"name\": \"$ROLE\", \"permissions\": [\"users.read\", \"users.write\", \"teams.create\"]}"
+curl -s -o /dev/null -X POST "$AUTH_URL/users" -H "Authorization: Bearer $ADMIN_TOKEN" -H "Content-Type: application/json" \
+    -d "{\"username\": \"$ROLE\", \"email\": \"$ROLE@example.com\", \"password\": \"password\", \"role\": \"$ROLE\"}"
+HELPDESK_PAT=$(curl -s -X POST "$AUTH_URL/users/profile/tokens" -H "Authorization: Bearer $(login "$ROLE" | field accessToken)" \
+    -H "Content-Type: application/json" -d "{\"name\": \"scim-$SUFFIX\", \"scopes\": [\"scim\"]}" | grep -o '"token":"pat_[^"]*"' | cut -d'"' -f4)
+if [ -z "$HELPDESK_PAT" ]; then
+    fail "could not create a PAT for $ROLE"
+else
+    code=$(PAT=$HELPDESK_PAT scim_status PATCH /Users/1 '{"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"], "Operations": [{"op": "replace", "path": "password", "value": "hijacked"}]}')
+    [ "$code" = "403" ] && ok "Cannot set the password of an admin" || fail "helpdesk PATCH of the admin got HTTP $code"
+fi
+
+echo -e "\n${YELLOW}5. SCIM groups and teams${NC}"
+group=$(scim POST /Groups "{\"schemas\": [\"urn:ietf:params:scim:schemas:core:2.0:Group\"], \"displayName\": \"SCIM Team $SUFFIX\", \"members\": [{\"value\": \"$SCIM_ID\"}]}")
+GROUP_ID=$(echo "$group" | field id)
+if [ -z "$GROUP_ID" ]; then
+    fail "group not created (is the team service running?)" "$group"
+else
+    ok "Group created"
+    TEAM_ID=$(curl -s "$TEAM_URL/teams?q=SCIM%20Team%20$SUFFIX" | grep -o '"id":[0-9]*' | head -n1 | cut -d: -f2)
+    [ -n "$TEAM_ID" ] && ok "Team $TEAM_ID created for the group" || fail "no team for the group"
+    teams=$(curl -s "$TEAM_URL/users/$SCIM_ID/teams")
+    echo "$teams" | grep -q "\"id\":$TEAM_ID[,}]" && ok "Group member joined the team" || fail "member missing from the team" "$teams"
+    scim PATCH "/Groups/$GROUP_ID" "{\"schemas\": [\"urn:ietf:params:scim:api:messages:2.0:PatchOp\"], \"Operations\": [{\"op\": \"add\", \"path\": \"members\", \"value\": [{\"value\": \"$IMPORTED_ID\"}]}, {\"op\": \"remove\", \"path\": \"members[value eq \\\"$SCIM_ID\\\"]\"}]}" > /dev/null
+    added=$(curl -s "$TEAM_URL/users/$IMPORTED_ID/teams")
+    removed=$(curl -s "$TEAM_URL/users/$SCIM_ID/teams")
+    echo "$added" | grep -q "\"id\":$TEAM_ID[,}]" && ! echo "$removed" | grep -q "\"id\":$TEAM_ID[,}]" && ok "PATCH members synced to the team" || fail "team members not synced" "$added $removed"
+    [ "$(scim_status POST /Groups "{\"displayName\": \"SCIM Team $SUFFIX\"}")" = "409" ] && ok "Duplicate group name rejected" || fail "duplicate group name accepted"
+    [ "$(scim_status DELETE "/Groups/$GROUP_ID")" = "204" ] && ok "Group deleted" || fail "group not deleted"
+    code=$(curl -s -o /dev/null -w "%{http_code}" "$TEAM_URL/teams/$TEAM_ID")
+    [ "$code" = "404" ] && ok "Team deleted with the group" || fail "team still exists (HTTP $code)"
+fi
+
+# Remove the users created by this run
+[ "$(scim_status DELETE "/Users/$SCIM_ID")" = "204" ] && ok "SCIM user deleted" || fail "SCIM user not deleted"
+for name in "imp_j_$SUFFIX" "imp_r_$SUFFIX" "$ROLE"; do
+    id=$(curl -s "$AUTH_URL/users?q=$name" -H "Authorization: Bearer $ADMIN_TOKEN" | grep -o '"id":[0-9]*' | head -n1 | cut -d: -f2)
+    [ -n "$id" ] && curl -s -o /dev/null -X DELETE "$AUTH_URL/users/$id" -H "Authorization: Bearer $ADMIN_TOKEN"
+done
+
+finish "provisioning"
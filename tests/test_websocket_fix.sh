@@ -50,13 +50,24 @@ fi
 
 echo -e "${BLUE}🔍 第四步：测试内部API端点${NC}"
 
+# 内部端点需要 service token (client credentials grant)
+echo -n "未携带 service token 访问 /internal/teams/1/members: "
+anon_status=$(curl -s -o /dev/null -w "%{http_code}" http://localhost:8083/internal/teams/1/members)
+if [ "$anon_status" = "401" ]; then
+    echo -e "${GREEN}✅ 已拒绝 ($anon_status)${NC}"
+else
+    echo -e "${RED}❌ 异常 ($anon_status)${NC}"
+fi
+
+SERVICE_TOKEN=$(curl -s -u realtime:dev-realtime-secret -d grant_type=client_credentials http://localhost:8084/oauth/token | jq -r '.access_token')
+
 # 测试内部端点
 echo -n "测试 /internal/teams/1/members: "
-internal_status=$(curl -s -o /dev/null -w "%{http_code}" http://localhost:8083/internal/teams/1/members)
+internal_status=$(curl -s -o /dev/null -w "%{http_code}" -H "Authorization: Bearer $SERVICE_TOKEN" http://localhost:8083/internal/teams/1/members)
 if [ "$internal_status" = "200" ]; then
     echo -e "${GREEN}✅ 正常 ($internal_status)${NC}"
     echo "获取团队成员数据:"
-    curl -s http://localhost:8083/internal/teams/1/members | head -c 200
+    curl -s -H "Authorization: Bearer $SERVICE_TOKEN" http://localhost:8083/internal/teams/1/members | head -c 200
     echo ""
 else
     echo -e "${RED}❌ 异常 ($internal_status)${NC}"
@@ -70,7 +81,7 @@ echo ""
 echo -e "${GREEN}🎉 修复完成！${NC}"
 echo ""
 echo -e "${YELLOW}📝 后续测试步骤：${NC}"
-echo "1. 确保用户3和用户5连接到WebSocket: ws://localhost/ws?userId=3 和 ws://localhost/ws?userId=5"
+echo "1. 确保用户3和用户5连接到WebSocket: ws://localhost/ws?token=<用户3的token> 和 ws://localhost/ws?token=<用户5的token>"
 echo "2. 让用户4在团队2中创建任务"
 echo "3. 观察realtime服务的详细日志: docker-compose logs -f realtime"
 echo "4. 验证用户3和用户5是否收到WebSocket消息"
@@ -67,7 +67,7 @@ echo "1. 导入 Postman Collection: docs/Todo_WebSocket_Tests.postman_collection
 echo "2. 运行 '注册用户' 请求"
 echo "3. 运行 '登录' 请求 (会自动保存 JWT token)"
 echo "4. 运行 '创建团队' 请求 (会自动保存团队 ID)"
-echo "5. 创建 WebSocket 连接: ws://localhost/ws?teamId={{team_id}}&userId={{user_id}}"
+echo "5. 创建 WebSocket 连接: ws://localhost/ws?token={{access_token}}"
 echo "6. 运行 '创建任务' 请求来触发 WebSocket 事件"
 echo ""
 echo -e "${BLUE}🔗 有用的链接：${NC}"